@@ -0,0 +1,9 @@
+package config
+
+const (
+	// HeaderAPIKey carries a personal API key, it is only read when the request has no Authorization header
+	HeaderAPIKey = "X-API-Key"
+
+	// APIKeyPrefix starts every API key so leaked keys are easy to recognize, e.g. by secret scanners
+	APIKeyPrefix = "fgk_"
+)
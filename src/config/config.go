@@ -2,40 +2,75 @@ package config
 
 import (
 	"app/src/utils"
+	"app/src/validation"
+	"fmt"
 
 	"github.com/spf13/viper"
 )
 
 var (
-	IsProd              bool
-	AppHost             string
-	AppPort             int
-	DBHost              string
-	DBUser              string
-	DBPassword          string
-	DBName              string
-	DBPort              int
-	JWTSecret           string
-	JWTAccessExp        int
-	JWTRefreshExp       int
-	JWTResetPasswordExp int
-	JWTVerifyEmailExp   int
-	SMTPHost            string
-	SMTPPort            int
-	SMTPUsername        string
-	SMTPPassword        string
-	EmailFrom           string
-	GoogleClientID      string
-	GoogleClientSecret  string
-	RedirectURL         string
-	StorageType         string
-	StorageLocalPath    string
-	StorageMaxFileSize  int64
-	MinIOEndpoint       string
-	MinIOAccessKey      string
-	MinIOSecretKey      string
-	MinIOBucketName     string
-	MinIOUseSSL         bool
+	IsProd                  bool
+	AppHost                 string
+	AppPort                 int
+	AppURL                  string
+	DBHost                  string
+	DBUser                  string
+	DBPassword              string
+	DBName                  string
+	DBPort                  int
+	JWTSecret               string
+	JWTAlgorithm            string
+	JWTSigningKeyFile       string
+	JWTVerificationKeyFiles []string
+	JWTKeySet               *utils.KeySet
+	JWTAccessExp            int
+	JWTRefreshExp           int
+	JWTResetPasswordExp     int
+	JWTVerifyEmailExp       int
+	JWTMFAPendingExp        int
+	JWTMagicLinkExp         int
+	JWTImpersonationExp     int
+	JWTRevertEmailExp       int
+	MagicLinkMaxRequests    int
+	MagicLinkWindow         int
+	LoginMaxAttempts        int
+	LoginLockoutDuration    int
+	LoginAttemptWindow      int
+	LoginBackoffBase        int
+	LoginIPMaxAttempts      int
+	RoleCacheTTL            int
+	InvitationExp           int
+	RevocationStore         string
+	RevocationCacheSize     int
+	RevocationPruneInterval int
+	TOTPIssuer              string
+	SMTPHost                string
+	SMTPPort                int
+	SMTPUsername            string
+	SMTPPassword            string
+	EmailFrom               string
+	GoogleClientID          string
+	GoogleClientSecret      string
+	RedirectURL             string
+	OAuthFrontendURL        string
+	OAuthConsentURL         string
+	WebAuthnRPID            string
+	WebAuthnRPName          string
+	WebAuthnRPOrigins       []string
+	StorageType             string
+	StorageLocalPath        string
+	StorageMaxFileSize      int64
+	StorageUploadTempPath   string
+	UploadExpiration        int
+	PresignedURLExp         int
+	StorageSigningKey       string
+	StoragePrivateByDefault bool
+	StoragePublicFolders    []string
+	MinIOEndpoint           string
+	MinIOAccessKey          string
+	MinIOSecretKey          string
+	MinIOBucketName         string
+	MinIOUseSSL             bool
 )
 
 func init() {
@@ -45,6 +80,10 @@ func init() {
 	IsProd = viper.GetString("APP_ENV") == "prod"
 	AppHost = viper.GetString("APP_HOST")
 	AppPort = viper.GetInt("APP_PORT")
+	AppURL = viper.GetString("APP_URL")
+	if AppURL == "" {
+		AppURL = fmt.Sprintf("http://%s:%d", AppHost, AppPort)
+	}
 
 	// database configuration
 	DBHost = viper.GetString("DB_HOST")
@@ -55,10 +94,48 @@ func init() {
 
 	// jwt configuration
 	JWTSecret = viper.GetString("JWT_SECRET")
+	JWTAlgorithm = viper.GetString("JWT_ALGORITHM")
+	JWTSigningKeyFile = viper.GetString("JWT_SIGNING_KEY_FILE")
+	JWTVerificationKeyFiles = utils.SplitList(viper.GetString("JWT_VERIFICATION_KEY_FILES"))
+	JWTKeySet = loadKeySet()
 	JWTAccessExp = viper.GetInt("JWT_ACCESS_EXP_MINUTES")
 	JWTRefreshExp = viper.GetInt("JWT_REFRESH_EXP_DAYS")
 	JWTResetPasswordExp = viper.GetInt("JWT_RESET_PASSWORD_EXP_MINUTES")
 	JWTVerifyEmailExp = viper.GetInt("JWT_VERIFY_EMAIL_EXP_MINUTES")
+	JWTMFAPendingExp = viper.GetInt("JWT_MFA_PENDING_EXP_MINUTES")
+	JWTMagicLinkExp = viper.GetInt("JWT_MAGIC_LINK_EXP_MINUTES")
+	JWTImpersonationExp = viper.GetInt("JWT_IMPERSONATION_EXP_MINUTES")
+	JWTRevertEmailExp = viper.GetInt("JWT_REVERT_EMAIL_EXP_DAYS")
+
+	// magic link configuration
+	MagicLinkMaxRequests = viper.GetInt("MAGIC_LINK_MAX_REQUESTS")
+	MagicLinkWindow = viper.GetInt("MAGIC_LINK_WINDOW_MINUTES")
+
+	// login throttling configuration
+	LoginMaxAttempts = viper.GetInt("LOGIN_MAX_ATTEMPTS")
+	LoginLockoutDuration = viper.GetInt("LOGIN_LOCKOUT_MINUTES")
+	LoginAttemptWindow = viper.GetInt("LOGIN_ATTEMPT_WINDOW_MINUTES")
+	LoginBackoffBase = viper.GetInt("LOGIN_BACKOFF_SECONDS")
+	LoginIPMaxAttempts = viper.GetInt("LOGIN_IP_MAX_ATTEMPTS")
+
+	// password policy configuration
+	validation.PasswordRules = loadPasswordPolicy()
+	PasswordHistory = viper.GetInt("PASSWORD_HISTORY")
+	PasswordMaxAge = viper.GetInt("PASSWORD_MAX_AGE_DAYS")
+
+	// role configuration
+	RoleCacheTTL = viper.GetInt("ROLE_CACHE_TTL_SECONDS")
+
+	// organization configuration
+	InvitationExp = viper.GetInt("INVITATION_EXP_HOURS")
+
+	// access token revocation configuration
+	RevocationStore = viper.GetString("REVOCATION_STORE")
+	RevocationCacheSize = viper.GetInt("REVOCATION_CACHE_SIZE")
+	RevocationPruneInterval = viper.GetInt("REVOCATION_PRUNE_INTERVAL_MINUTES")
+
+	// two-factor authentication configuration
+	TOTPIssuer = viper.GetString("TOTP_ISSUER")
 
 	// SMTP configuration
 	SMTPHost = viper.GetString("SMTP_HOST")
@@ -71,11 +148,29 @@ func init() {
 	GoogleClientID = viper.GetString("GOOGLE_CLIENT_ID")
 	GoogleClientSecret = viper.GetString("GOOGLE_CLIENT_SECRET")
 	RedirectURL = viper.GetString("REDIRECT_URL")
+	OAuthProviders = loadOAuthProviders()
+	OAuthFrontendURL = viper.GetString("OAUTH_FRONTEND_REDIRECT_URL")
+
+	// oauth2 authorization server configuration
+	OAuthConsentURL = viper.GetString("OAUTH_CONSENT_URL")
+
+	// webauthn configuration
+	loadWebAuthn()
 
 	// file storage configuration
 	StorageType = viper.GetString("STORAGE_TYPE")
 	StorageLocalPath = viper.GetString("STORAGE_LOCAL_PATH")
 	StorageMaxFileSize = viper.GetInt64("STORAGE_MAX_FILE_SIZE")
+	StorageUploadTempPath = viper.GetString("STORAGE_UPLOAD_TEMP_PATH")
+	UploadExpiration = viper.GetInt("UPLOAD_EXPIRATION_HOURS")
+	PresignedURLExp = viper.GetInt("PRESIGNED_URL_EXP_MINUTES")
+	StorageSigningKey = viper.GetString("STORAGE_SIGNING_KEY")
+	StoragePrivateByDefault = viper.GetBool("STORAGE_PRIVATE_BY_DEFAULT")
+	StoragePublicFolders = utils.SplitList(viper.GetString("STORAGE_PUBLIC_FOLDERS"))
+	loadStoragePolicies()
+
+	// image processing configuration
+	loadImages()
 
 	// minio configuration
 	MinIOEndpoint = viper.GetString("MINIO_ENDPOINT")
@@ -85,6 +180,20 @@ func init() {
 	MinIOUseSSL = viper.GetBool("MINIO_USE_SSL")
 }
 
+// loadKeySet uses the shared JWT_SECRET for HS256 and the key files for RS256 and EdDSA
+func loadKeySet() *utils.KeySet {
+	if JWTAlgorithm == "" || JWTAlgorithm == "HS256" {
+		return utils.NewHMACKeySet(JWTSecret)
+	}
+
+	keySet, err := utils.LoadKeySet(JWTAlgorithm, JWTSigningKeyFile, JWTVerificationKeyFiles)
+	if err != nil {
+		utils.Log.Fatalf("Failed to load jwt keys: %+v", err)
+	}
+
+	return keySet
+}
+
 func loadConfig() {
 	configPaths := []string{
 		"./",     // For app
@@ -7,6 +7,15 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
+// multipartOverhead leaves room for the boundaries and fields of a multipart upload
+const multipartOverhead = 1 << 20
+
+// BodyLimit lets upload requests carry a file of MaxUploadSize in one body,
+// whether as a tus chunk, a signed PUT or a multipart form
+func BodyLimit() int {
+	return max(fiber.DefaultBodyLimit, int(MaxUploadSize())+multipartOverhead)
+}
+
 func FiberConfig() fiber.Config {
 	return fiber.Config{
 		Prefork:       IsProd,
@@ -14,6 +23,7 @@ func FiberConfig() fiber.Config {
 		ServerHeader:  "Fiber",
 		AppName:       "Fiber API",
 		ErrorHandler:  utils.ErrorHandler,
+		BodyLimit:     BodyLimit(),
 		JSONEncoder:   sonic.Marshal,
 		JSONDecoder:   sonic.Unmarshal,
 	}
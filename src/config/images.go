@@ -0,0 +1,107 @@
+package config
+
+import (
+	"app/src/utils"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	ImageFormatJPEG = "jpeg"
+	ImageFormatPNG  = "png"
+	ImageFormatWebP = "webp"
+)
+
+// ImageVariant is an image derived from every uploaded image and stored next to the original.
+// With Crop the variant fills the whole box, otherwise the image fits inside it. Quality is only used by JPEG.
+type ImageVariant struct {
+	Name    string
+	Width   int
+	Height  int
+	Format  string
+	Crop    bool
+	Quality int
+}
+
+var (
+	// ImageVariants are generated in this order, an empty list turns off the background processing
+	ImageVariants []ImageVariant
+	// ImageStripMetadata stores uploaded originals upright and without EXIF (including GPS), XMP and IPTC
+	ImageStripMetadata bool
+	// ImageWorkers is how many images are processed at the same time
+	ImageWorkers int
+)
+
+var defaultImageVariants = map[string]ImageVariant{
+	"thumbnail": {Name: "thumbnail", Width: 200, Height: 200, Format: ImageFormatJPEG, Crop: true, Quality: 80},
+	"medium":    {Name: "medium", Width: 800, Height: 800, Format: ImageFormatJPEG, Quality: 85},
+	"webp":      {Name: "webp", Width: 1600, Height: 1600, Format: ImageFormatWebP},
+}
+
+var imageVariantName = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// loadImages reads IMAGE_VARIANTS and the IMAGE_VARIANT_<NAME>_* variables of every listed variant.
+// Without IMAGE_VARIANTS thumbnail, medium and webp are generated, "none" disables the variants.
+func loadImages() {
+	ImageStripMetadata = boolOr("IMAGE_STRIP_METADATA", true)
+	ImageWorkers = viper.GetInt("IMAGE_WORKERS")
+
+	names := utils.SplitList(viper.GetString("IMAGE_VARIANTS"))
+	if len(names) == 0 {
+		names = []string{"thumbnail", "medium", "webp"}
+	}
+
+	ImageVariants = nil
+	for _, name := range names {
+		name = strings.ToLower(name)
+		if name == "none" {
+			ImageVariants = nil
+			return
+		}
+
+		if !imageVariantName.MatchString(name) {
+			utils.Log.Errorf("Image variant name %q may only contain a-z, 0-9, _ and -, skipping it", name)
+			continue
+		}
+
+		prefix := "IMAGE_VARIANT_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		variant := defaultImageVariants[name]
+		variant.Name = name
+
+		if width := viper.GetInt(prefix + "WIDTH"); width > 0 {
+			variant.Width = width
+		}
+		if height := viper.GetInt(prefix + "HEIGHT"); height > 0 {
+			variant.Height = height
+		}
+		if format := strings.ToLower(viper.GetString(prefix + "FORMAT")); format == "jpg" {
+			variant.Format = ImageFormatJPEG
+		} else if format != "" {
+			variant.Format = format
+		}
+		if fit := strings.ToLower(viper.GetString(prefix + "FIT")); fit != "" {
+			variant.Crop = fit == "cover"
+		}
+		if quality := viper.GetInt(prefix + "QUALITY"); quality > 0 {
+			variant.Quality = min(quality, 100)
+		}
+
+		if variant.Width <= 0 || variant.Height <= 0 {
+			utils.Log.Errorf("Image variant %s needs a width and height, skipping it", name)
+			continue
+		}
+
+		switch variant.Format {
+		case ImageFormatJPEG, ImageFormatPNG, ImageFormatWebP:
+		case "":
+			variant.Format = ImageFormatJPEG
+		default:
+			utils.Log.Errorf("Image variant %s has unknown format %s, skipping it", name, variant.Format)
+			continue
+		}
+
+		ImageVariants = append(ImageVariants, variant)
+	}
+}
@@ -1,27 +1,93 @@
 package config
 
 import (
-	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
+	"app/src/utils"
+	"strings"
+
+	"github.com/spf13/viper"
 )
 
-type Config struct {
-	GoogleLoginConfig oauth2.Config
+const googleIssuer = "https://accounts.google.com"
+
+const (
+	// OAuthStateExpMinutes is how long a login or link flow may take between the redirect and the callback
+	OAuthStateExpMinutes = 5
+	// OAuthCodeExpMinutes is how long the frontend has to exchange the one-time code for tokens
+	OAuthCodeExpMinutes = 1
+)
+
+// OAuthProvider is an external login provider. OpenID Connect providers only need an issuer,
+// their endpoints and signing keys are discovered from the issuer. Plain OAuth2 providers
+// without discovery (e.g. GitHub) set the endpoints explicitly instead.
+type OAuthProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	RedirectURL  string
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
 }
 
-var AppConfig Config
-
-func GoogleConfig() oauth2.Config {
-	AppConfig.GoogleLoginConfig = oauth2.Config{
-		RedirectURL:  RedirectURL,
-		ClientID:     GoogleClientID,
-		ClientSecret: GoogleClientSecret,
-		Scopes: []string{
-			"https://www.googleapis.com/auth/userinfo.email",
-			"https://www.googleapis.com/auth/userinfo.profile",
-		},
-		Endpoint: google.Endpoint,
+// OAuthProviders is keyed by the provider name used in /v1/auth/oauth/:provider
+var OAuthProviders = map[string]OAuthProvider{}
+
+// loadOAuthProviders reads every provider listed in OAUTH_PROVIDERS from its OAUTH_<NAME>_* variables
+func loadOAuthProviders() map[string]OAuthProvider {
+	providers := map[string]OAuthProvider{}
+
+	for _, name := range utils.SplitList(viper.GetString("OAUTH_PROVIDERS")) {
+		name = strings.ToLower(name)
+		prefix := "OAUTH_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+
+		provider := OAuthProvider{
+			Name:         name,
+			Issuer:       strings.TrimSuffix(viper.GetString(prefix+"ISSUER"), "/"),
+			ClientID:     viper.GetString(prefix + "CLIENT_ID"),
+			ClientSecret: viper.GetString(prefix + "CLIENT_SECRET"),
+			Scopes:       strings.Fields(viper.GetString(prefix + "SCOPES")),
+			RedirectURL:  viper.GetString(prefix + "REDIRECT_URL"),
+			AuthURL:      viper.GetString(prefix + "AUTH_URL"),
+			TokenURL:     viper.GetString(prefix + "TOKEN_URL"),
+			UserInfoURL:  viper.GetString(prefix + "USERINFO_URL"),
+		}
+
+		if provider.Issuer == "" && (provider.AuthURL == "" || provider.TokenURL == "" || provider.UserInfoURL == "") {
+			utils.Log.Errorf("OAuth provider %s needs an issuer or auth, token and userinfo urls, skipping it", name)
+			continue
+		}
+
+		providers[name] = withProviderDefaults(provider)
+	}
+
+	// The Google variables predate the registry and keep working without OAUTH_PROVIDERS
+	if _, ok := providers["google"]; !ok && GoogleClientID != "" {
+		providers["google"] = withProviderDefaults(OAuthProvider{
+			Name:         "google",
+			Issuer:       googleIssuer,
+			ClientID:     GoogleClientID,
+			ClientSecret: GoogleClientSecret,
+			RedirectURL:  RedirectURL,
+		})
+	}
+
+	return providers
+}
+
+func withProviderDefaults(provider OAuthProvider) OAuthProvider {
+	if len(provider.Scopes) == 0 {
+		if provider.Issuer != "" {
+			provider.Scopes = []string{"openid", "email", "profile"}
+		} else {
+			provider.Scopes = []string{"read:user", "user:email"}
+		}
+	}
+
+	if provider.RedirectURL == "" {
+		provider.RedirectURL = strings.TrimSuffix(AppURL, "/") + "/v1/auth/oauth/" + provider.Name + "/callback"
 	}
 
-	return AppConfig.GoogleLoginConfig
+	return provider
 }
@@ -0,0 +1,12 @@
+package config
+
+const (
+	// OAuthAuthorizationCodeExpMinutes is how long a client has to exchange an authorization code for a token
+	OAuthAuthorizationCodeExpMinutes = 1
+
+	// OAuthClientSecretPrefix starts every client secret so leaked secrets are easy to recognize
+	OAuthClientSecretPrefix = "fgs_"
+
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeClientCredentials = "client_credentials"
+)
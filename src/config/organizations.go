@@ -0,0 +1,20 @@
+package config
+
+const (
+	// HeaderOrganizationID selects the active organization of a request, it overrides the org claim of the access token
+	// except for impersonation tokens, which stay in the organization they were issued for
+	HeaderOrganizationID = "X-Organization-ID"
+
+	OrganizationRoleOwner  = "owner"
+	OrganizationRoleAdmin  = "admin"
+	OrganizationRoleMember = "member"
+)
+
+// OrganizationRoles are the roles of a membership and the rights they grant while the organization is active.
+// getUsers and manageFiles only reach the users and files of the active organization, the other rights
+// are checked by the organization endpoints.
+var OrganizationRoles = map[string][]string{
+	OrganizationRoleOwner:  {"manageOrganization", "manageMembers", "getUsers", "manageFiles"},
+	OrganizationRoleAdmin:  {"manageMembers", "getUsers", "manageFiles"},
+	OrganizationRoleMember: {},
+}
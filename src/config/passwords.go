@@ -0,0 +1,47 @@
+package config
+
+import (
+	"app/src/validation"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	// PasswordHistory is how many previous passwords of a user can not be used again, 0 disables the check
+	PasswordHistory int
+	// PasswordMaxAge is the number of days after which a password login asks for a reset, 0 disables it
+	PasswordMaxAge int
+)
+
+// loadPasswordPolicy reads the PASSWORD_* variables, unset variables keep the default rules of the validation package
+func loadPasswordPolicy() validation.PasswordPolicy {
+	policy := validation.PasswordRules
+
+	if minLength := viper.GetInt("PASSWORD_MIN_LENGTH"); minLength > 0 {
+		policy.MinLength = minLength
+	}
+
+	if maxLength := viper.GetInt("PASSWORD_MAX_LENGTH"); maxLength > 0 {
+		policy.MaxLength = maxLength
+	}
+
+	if policy.MaxLength < policy.MinLength {
+		policy.MaxLength = policy.MinLength
+	}
+
+	policy.RequireLetter = boolOr("PASSWORD_REQUIRE_LETTER", policy.RequireLetter)
+	policy.RequireMixedCase = boolOr("PASSWORD_REQUIRE_MIXED_CASE", policy.RequireMixedCase)
+	policy.RequireDigit = boolOr("PASSWORD_REQUIRE_DIGIT", policy.RequireDigit)
+	policy.RequireSymbol = boolOr("PASSWORD_REQUIRE_SYMBOL", policy.RequireSymbol)
+	policy.RejectCommon = boolOr("PASSWORD_REJECT_COMMON", policy.RejectCommon)
+
+	return policy
+}
+
+func boolOr(key string, fallback bool) bool {
+	if !viper.IsSet(key) || viper.GetString(key) == "" {
+		return fallback
+	}
+
+	return viper.GetBool(key)
+}
@@ -1,17 +1,23 @@
 package config
 
-var allRoles = map[string][]string{
-	"user":  {},
-	"admin": {"getUsers", "manageUsers"},
-}
+// DefaultRole is given to users who register themselves, it can not be deleted
+const DefaultRole = "user"
 
-var Roles = getKeys(allRoles)
-var RoleRights = allRoles
+// Permissions are the rights checked by the routes, roles can only be granted these
+var Permissions = map[string]string{
+	"getUsers":           "List and read users",
+	"manageUsers":        "Create, update, delete and unlock users and revoke their sessions",
+	"getRoles":           "List and read roles and permissions",
+	"manageRoles":        "Create, update and delete roles",
+	"manageFiles":        "Read and delete the files of every user",
+	"impersonateUsers":   "Act as another user to reproduce issues, every impersonation is recorded",
+	"manageOAuthClients": "Register and delete the OAuth clients of third-party apps",
+}
 
-func getKeys(m map[string][]string) []string {
-	keys := make([]string, 0, len(m))
-	for k := range m {
-		keys = append(keys, k)
-	}
-	return keys
+// DefaultRoles are created by the migrations and the role seeder, admins manage them at /v1/roles
+var DefaultRoles = map[string][]string{
+	DefaultRole: {},
+	"admin": {
+		"getUsers", "manageUsers", "getRoles", "manageRoles", "manageFiles", "impersonateUsers", "manageOAuthClients",
+	},
 }
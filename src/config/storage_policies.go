@@ -0,0 +1,112 @@
+package config
+
+import (
+	"app/src/utils"
+	"path"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// FilePolicy limits the files uploaded to a folder. AllowedTypes holds extensions (.pdf) and MIME types
+// (application/pdf or image/*), a file must match one of them. MaxCount is the number of files a user may keep
+// in the folder, 0 is unlimited.
+type FilePolicy struct {
+	Folder       string
+	AllowedTypes []string
+	MaxSize      int64
+	MaxCount     int
+}
+
+var (
+	// StorageDefaultPolicy applies to every folder without a policy of its own
+	StorageDefaultPolicy FilePolicy
+	// StorageFolderPolicies is keyed by the folder, policies also apply to the subfolders of their folder
+	StorageFolderPolicies map[string]FilePolicy
+)
+
+var defaultAllowedTypes = []string{".jpg", ".jpeg", ".png", ".gif", ".pdf", ".doc", ".docx", ".txt"}
+
+// loadStoragePolicies reads the default policy from STORAGE_ALLOWED_TYPES, STORAGE_MAX_FILE_SIZE and
+// STORAGE_MAX_FILE_COUNT, and the policy of every folder in STORAGE_FOLDERS from its STORAGE_FOLDER_<NAME>_*
+// variables. Unset folder variables fall back to the default policy.
+func loadStoragePolicies() {
+	StorageDefaultPolicy = FilePolicy{
+		AllowedTypes: normalizeTypes(utils.SplitList(viper.GetString("STORAGE_ALLOWED_TYPES"))),
+		MaxSize:      StorageMaxFileSize,
+		MaxCount:     viper.GetInt("STORAGE_MAX_FILE_COUNT"),
+	}
+	if len(StorageDefaultPolicy.AllowedTypes) == 0 {
+		StorageDefaultPolicy.AllowedTypes = defaultAllowedTypes
+	}
+
+	StorageFolderPolicies = map[string]FilePolicy{}
+	for _, folder := range utils.SplitList(viper.GetString("STORAGE_FOLDERS")) {
+		folder = path.Clean(strings.Trim(folder, "/"))
+		prefix := "STORAGE_FOLDER_" + strings.ToUpper(strings.NewReplacer("/", "_", "-", "_").Replace(folder)) + "_"
+
+		policy := StorageDefaultPolicy
+		policy.Folder = folder
+
+		if allowedTypes := normalizeTypes(utils.SplitList(viper.GetString(prefix + "ALLOWED_TYPES"))); len(allowedTypes) > 0 {
+			policy.AllowedTypes = allowedTypes
+		}
+		if maxSize := viper.GetInt64(prefix + "MAX_SIZE"); maxSize > 0 {
+			policy.MaxSize = maxSize
+		}
+		if viper.IsSet(prefix+"MAX_COUNT") && viper.GetString(prefix+"MAX_COUNT") != "" {
+			policy.MaxCount = viper.GetInt(prefix + "MAX_COUNT")
+		}
+
+		StorageFolderPolicies[folder] = policy
+	}
+}
+
+// FolderPolicy returns the policy of the folder or of its closest parent folder with a policy
+func FolderPolicy(folder string) FilePolicy {
+	for folder = strings.Trim(folder, "/"); folder != "." && folder != ""; folder = path.Dir(folder) {
+		if policy, ok := StorageFolderPolicies[folder]; ok {
+			return policy
+		}
+	}
+
+	return StorageDefaultPolicy
+}
+
+// MaxUploadSize is the largest file any folder accepts
+func MaxUploadSize() int64 {
+	maxSize := StorageDefaultPolicy.MaxSize
+	for _, policy := range StorageFolderPolicies {
+		maxSize = max(maxSize, policy.MaxSize)
+	}
+	return maxSize
+}
+
+// Allows reports whether a file with the extension and MIME type may be uploaded
+func (p FilePolicy) Allows(ext, mimeType string) bool {
+	ext = strings.ToLower(ext)
+
+	for _, allowed := range p.AllowedTypes {
+		switch {
+		case strings.HasPrefix(allowed, "."):
+			if allowed == ext {
+				return true
+			}
+		case strings.HasSuffix(allowed, "/*"):
+			if strings.HasPrefix(mimeType, strings.TrimSuffix(allowed, "*")) {
+				return true
+			}
+		case allowed == mimeType:
+			return true
+		}
+	}
+
+	return false
+}
+
+func normalizeTypes(types []string) []string {
+	for i, allowedType := range types {
+		types[i] = strings.ToLower(allowedType)
+	}
+	return types
+}
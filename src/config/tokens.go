@@ -5,4 +5,9 @@ const (
 	TokenTypeRefresh       = "refresh"
 	TokenTypeResetPassword = "resetPassword"
 	TokenTypeVerifyEmail   = "verifyEmail"
+	TokenTypeMFAPending    = "mfaPending"
+	TokenTypeOAuthCode     = "oauthCode"
+	TokenTypeMagicLink     = "magicLink"
+	TokenTypeChangeEmail   = "changeEmail"
+	TokenTypeRevertEmail   = "revertEmail"
 )
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"app/src/utils"
+	"net/url"
+
+	"github.com/spf13/viper"
+)
+
+// WebAuthnSessionExpMinutes is how long a passkey registration or login may take between begin and finish
+const WebAuthnSessionExpMinutes = 5
+
+// loadWebAuthn reads the relying party. The id defaults to the host of APP_URL and the origins to APP_URL,
+// browsers only hand out passkeys to origins on the relying party id or its subdomains.
+func loadWebAuthn() {
+	WebAuthnRPName = viper.GetString("WEBAUTHN_RP_NAME")
+	if WebAuthnRPName == "" {
+		WebAuthnRPName = "Fiber API"
+	}
+
+	WebAuthnRPID = viper.GetString("WEBAUTHN_RP_ID")
+	if WebAuthnRPID == "" {
+		if appURL, err := url.Parse(AppURL); err == nil {
+			WebAuthnRPID = appURL.Hostname()
+		}
+	}
+
+	WebAuthnRPOrigins = utils.SplitList(viper.GetString("WEBAUTHN_RP_ORIGINS"))
+	if len(WebAuthnRPOrigins) == 0 {
+		WebAuthnRPOrigins = []string{AppURL}
+	}
+}
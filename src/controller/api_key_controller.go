@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"app/src/model"
+	"app/src/response"
+	"app/src/service"
+	"app/src/validation"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type APIKeyController struct {
+	APIKeyService service.APIKeyService
+}
+
+func NewAPIKeyController(apiKeyService service.APIKeyService) *APIKeyController {
+	return &APIKeyController{
+		APIKeyService: apiKeyService,
+	}
+}
+
+// @Tags         API Keys
+// @Summary      List my API keys
+// @Description  The keys themselves are never returned again, only their prefix.
+// @Security BearerAuth
+// @Produce      json
+// @Router       /auth/api-keys [get]
+// @Success      200  {object}  example.GetAPIKeysResponse
+func (a *APIKeyController) GetAPIKeys(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+
+	apiKeys, err := a.APIKeyService.GetAPIKeys(c, user.ID.String())
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithAPIKeys{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Get API keys successfully",
+			APIKeys: apiKeys,
+		})
+}
+
+// @Tags         API Keys
+// @Summary      Create an API key
+// @Description  The key is only shown in this response, send it in the X-API-Key header. The scopes must be rights the user has, a key without scopes can only use routes that need no rights. Keys can not be created with an API key.
+// @Security BearerAuth
+// @Produce      json
+// @Param        request  body  validation.CreateAPIKey  true  "Request body"
+// @Router       /auth/api-keys [post]
+// @Success      201  {object}  example.CreateAPIKeyResponse
+func (a *APIKeyController) CreateAPIKey(c *fiber.Ctx) error {
+	req := new(validation.CreateAPIKey)
+	user, _ := c.Locals("user").(*model.User)
+
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	apiKey, key, err := a.APIKeyService.CreateAPIKey(c, user, req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).
+		JSON(response.SuccessWithAPIKey{
+			Code:    fiber.StatusCreated,
+			Status:  "success",
+			Message: "Create API key successfully",
+			Key:     key,
+			APIKey:  *apiKey,
+		})
+}
+
+// @Tags         API Keys
+// @Summary      Revoke one of my API keys
+// @Security BearerAuth
+// @Produce      json
+// @Param        apiKeyId  path  string  true  "API key id"
+// @Router       /auth/api-keys/{apiKeyId} [delete]
+// @Success      200  {object}  example.RevokeAPIKeyResponse
+func (a *APIKeyController) RevokeAPIKey(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+
+	if err := a.APIKeyService.RevokeAPIKey(c, user.ID.String(), c.Params("apiKeyId")); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.Common{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Revoke API key successfully",
+		})
+}
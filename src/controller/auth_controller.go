@@ -6,31 +6,33 @@ import (
 	"app/src/response"
 	"app/src/service"
 	"app/src/validation"
-	"context"
-	"encoding/json"
-	"io"
-	"net/http"
+	"errors"
+	"net/url"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/google/uuid"
 )
 
 type AuthController struct {
-	AuthService  service.AuthService
-	UserService  service.UserService
-	TokenService service.TokenService
-	EmailService service.EmailService
+	AuthService     service.AuthService
+	UserService     service.UserService
+	TokenService    service.TokenService
+	EmailService    service.EmailService
+	OAuthService    service.OAuthService
+	IdentityService service.IdentityService
 }
 
 func NewAuthController(
 	authService service.AuthService, userService service.UserService,
 	tokenService service.TokenService, emailService service.EmailService,
+	oauthService service.OAuthService, identityService service.IdentityService,
 ) *AuthController {
 	return &AuthController{
-		AuthService:  authService,
-		UserService:  userService,
-		TokenService: tokenService,
-		EmailService: emailService,
+		AuthService:     authService,
+		UserService:     userService,
+		TokenService:    tokenService,
+		EmailService:    emailService,
+		OAuthService:    oauthService,
+		IdentityService: identityService,
 	}
 }
 
@@ -70,6 +72,7 @@ func (a *AuthController) Register(c *fiber.Ctx) error {
 
 // @Tags         Auth
 // @Summary      Login
+// @Description  When two-factor authentication is enabled, an mfa token is returned instead of auth tokens. Exchange it at /auth/2fa/verify. A password older than PASSWORD_MAX_AGE_DAYS is rejected with 403 and a reset password email is sent.
 // @Accept       json
 // @Produce      json
 // @Param        request  body  validation.Login  true  "Request body"
@@ -83,23 +86,26 @@ func (a *AuthController) Login(c *fiber.Ctx) error {
 	}
 
 	user, err := a.AuthService.Login(c, req)
-	if err != nil {
-		return err
+
+	// The password is right but too old, the user has to reset it before logging in
+	if errors.Is(err, service.ErrPasswordExpired) {
+		forgotPassword := &validation.ForgotPassword{Email: req.Email}
+
+		resetPasswordToken, errToken := a.TokenService.GenerateResetPasswordToken(c, forgotPassword)
+		if errToken != nil {
+			return errToken
+		}
+
+		if errEmail := a.EmailService.SendResetPasswordEmail(req.Email, resetPasswordToken); errEmail != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to send reset password email")
+		}
 	}
 
-	tokens, err := a.TokenService.GenerateAuthTokens(c, user)
 	if err != nil {
 		return err
 	}
 
-	return c.Status(fiber.StatusOK).
-		JSON(response.SuccessWithTokens{
-			Code:    fiber.StatusOK,
-			Status:  "success",
-			Message: "Login successfully",
-			User:    *user,
-			Tokens:  *tokens,
-		})
+	return a.loginSuccess(c, user)
 }
 
 // @Tags         Auth
@@ -267,70 +273,334 @@ func (a *AuthController) VerifyEmail(c *fiber.Ctx) error {
 		})
 }
 
+// @Tags         Auth
+// @Summary      Confirm an email change
+// @Description  Opened from the link sent to the new email. The new email replaces the previous one and is verified, a link to undo the change is sent to the previous email.
+// @Produce      json
+// @Param        token   query  string  true  "The confirm email change token"
+// @Router       /auth/confirm-email-change [post]
+// @Success      200  {object}  example.ConfirmEmailChangeResponse
+func (a *AuthController) ConfirmEmailChange(c *fiber.Ctx) error {
+	query := &validation.Token{
+		Token: c.Query("token"),
+	}
+
+	user, previousEmail, err := a.AuthService.ConfirmEmailChange(c, query)
+	if err != nil {
+		return err
+	}
+
+	revertEmailToken, err := a.TokenService.GenerateRevertEmailToken(c, user, previousEmail)
+	if err != nil {
+		return err
+	}
+
+	if errEmail := a.EmailService.SendEmailChangedEmail(previousEmail, revertEmailToken); errEmail != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to send email changed email")
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.Common{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Change email successfully",
+		})
+}
+
+// @Tags         Auth
+// @Summary      Revert an email change
+// @Description  Opened from the link sent to the previous email after a change. Restores the previous email and logs out every session.
+// @Produce      json
+// @Param        token   query  string  true  "The revert email change token"
+// @Router       /auth/revert-email-change [post]
+// @Success      200  {object}  example.RevertEmailChangeResponse
+func (a *AuthController) RevertEmailChange(c *fiber.Ctx) error {
+	query := &validation.Token{
+		Token: c.Query("token"),
+	}
+
+	if err := a.AuthService.RevertEmailChange(c, query); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.Common{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Revert email change successfully, please reset your password",
+		})
+}
+
+// @Tags         Auth
+// @Summary      Request a magic login link
+// @Description  An email with a single-use login link will be sent. Only a few links can be requested for the same email within a window.
+// @Accept       json
+// @Produce      json
+// @Param        request  body  validation.MagicLink  true  "Request body"
+// @Router       /auth/magic-link [post]
+// @Success      200  {object}  example.MagicLinkResponse
+func (a *AuthController) MagicLink(c *fiber.Ctx) error {
+	req := new(validation.MagicLink)
+
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	magicLinkToken, err := a.TokenService.GenerateMagicLinkToken(c, req)
+	if err != nil {
+		return err
+	}
+
+	if errEmail := a.EmailService.SendMagicLinkEmail(req.Email, magicLinkToken); errEmail != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to send magic link email")
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.Common{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "A login link has been sent to your email address.",
+		})
+}
+
+// @Tags         Auth
+// @Summary      Login with a magic link
+// @Description  Exchanges the token of a magic link for auth tokens, the link can only be used once. When two-factor authentication is enabled, an mfa token is returned instead of auth tokens.
+// @Produce      json
+// @Param        token   query  string  true  "The magic link token"
+// @Router       /auth/magic-link/verify [post]
+// @Success      200  {object}  example.LoginResponse
+func (a *AuthController) VerifyMagicLink(c *fiber.Ctx) error {
+	query := &validation.Token{
+		Token: c.Query("token"),
+	}
+
+	user, err := a.AuthService.LoginWithMagicLink(c, query)
+	if err != nil {
+		return err
+	}
+
+	return a.loginSuccess(c, user)
+}
+
+// @Tags         Auth
+// @Summary      Set password
+// @Description  Adds a local password to an account created through an OAuth provider.
+// @Security BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        request  body  validation.SetPassword  true  "Request body"
+// @Router       /auth/set-password [post]
+// @Success      200  {object}  example.SetPasswordResponse
+func (a *AuthController) SetPassword(c *fiber.Ctx) error {
+	req := new(validation.SetPassword)
+	user, _ := c.Locals("user").(*model.User)
+
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := a.AuthService.SetPassword(c, user, req); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.Common{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Set password successfully",
+		})
+}
+
+// @Tags         Auth
+// @Summary      Login with an OAuth provider
+// @Description  This route initiates the OAuth2 / OpenID Connect login flow of a configured provider, e.g. google, keycloak or github. Please try this in your browser.
+// @Param        provider  path  string  true  "Provider name"
+// @Router       /auth/oauth/{provider} [get]
+// @Success      303
+func (a *AuthController) OAuthLogin(c *fiber.Ctx) error {
+	return a.oauthLogin(c, c.Params("provider"))
+}
+
+// @Tags         Auth
+// @Summary      OAuth provider callback
+// @Description  The provider redirects here after the login. The state is single use, the id token is validated against the provider's JWKS and its nonce. When OAUTH_FRONTEND_REDIRECT_URL is set, the browser is redirected there with a one-time code (or an error) instead of receiving the tokens in the body.
+// @Param        provider  path   string  true  "Provider name"
+// @Param        code      query  string  true  "Authorization code"
+// @Param        state     query  string  true  "State"
+// @Router       /auth/oauth/{provider}/callback [get]
+// @Success      200  {object}  example.OAuthLoginResponse
+func (a *AuthController) OAuthCallback(c *fiber.Ctx) error {
+	return a.oauthCallback(c, c.Params("provider"))
+}
+
+// @Tags         Identities
+// @Summary      Link an OAuth provider
+// @Description  Starts the login flow of the provider for the logged in user, open the returned url in the browser. The provider account is linked on callback.
+// @Security BearerAuth
+// @Produce      json
+// @Param        provider  path  string  true  "Provider name"
+// @Router       /auth/oauth/{provider}/link [post]
+// @Success      200  {object}  example.LinkIdentityResponse
+func (a *AuthController) OAuthLink(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+
+	authURL, state, err := a.OAuthService.AuthCodeURL(c, c.Params("provider"), &user.ID)
+	if err != nil {
+		return err
+	}
+
+	a.setStateCookie(c, state)
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.OAuthRedirect{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Open the url to link your account",
+			URL:     authURL,
+		})
+}
+
+// @Tags         Auth
+// @Summary      Exchange an OAuth login code
+// @Description  When OAUTH_FRONTEND_REDIRECT_URL is set, the provider callback redirects to the frontend with a one-time code instead of returning tokens. The frontend exchanges the code here.
+// @Accept       json
+// @Produce      json
+// @Param        request  body  validation.OAuthCode  true  "Request body"
+// @Router       /auth/oauth/exchange [post]
+// @Success      200  {object}  example.LoginResponse
+func (a *AuthController) OAuthExchange(c *fiber.Ctx) error {
+	req := new(validation.OAuthCode)
+
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	user, err := a.AuthService.ExchangeOAuthCode(c, req)
+	if err != nil {
+		return err
+	}
+
+	return a.loginSuccess(c, user)
+}
+
 // @Tags         Auth
 // @Summary      Login with google
 // @Description  This route initiates the Google OAuth2 login flow. Please try this in your browser.
 // @Router       /auth/google [get]
-// @Success      200  {object}  example.GoogleLoginResponse
+// @Success      200  {object}  example.OAuthLoginResponse
 func (a *AuthController) GoogleLogin(c *fiber.Ctx) error {
-	// Generate a random state
-	state := uuid.New().String()
+	return a.oauthLogin(c, "google")
+}
 
-	c.Cookie(&fiber.Cookie{
-		Name:   "oauth_state",
-		Value:  state,
-		MaxAge: 30,
-	})
+func (a *AuthController) GoogleCallback(c *fiber.Ctx) error {
+	return a.oauthCallback(c, "google")
+}
 
-	url := config.AppConfig.GoogleLoginConfig.AuthCodeURL(state)
+func (a *AuthController) oauthLogin(c *fiber.Ctx, provider string) error {
+	authURL, state, err := a.OAuthService.AuthCodeURL(c, provider, nil)
+	if err != nil {
+		return err
+	}
 
-	return c.Status(fiber.StatusSeeOther).Redirect(url)
+	a.setStateCookie(c, state)
+
+	return c.Redirect(authURL, fiber.StatusSeeOther)
 }
 
-func (a *AuthController) GoogleCallback(c *fiber.Ctx) error {
+func (a *AuthController) oauthCallback(c *fiber.Ctx, provider string) error {
+	err := a.completeOAuth(c, provider)
+
+	var fiberErr *fiber.Error
+	if err != nil && config.OAuthFrontendURL != "" && errors.As(err, &fiberErr) {
+		return a.redirectToFrontend(c, "error", fiberErr.Message)
+	}
+
+	return err
+}
+
+func (a *AuthController) completeOAuth(c *fiber.Ctx, provider string) error {
 	state := c.Query("state")
-	storedState := c.Cookies("oauth_state")
 
-	if state != storedState {
+	// The state is also kept in a cookie, so a callback url can only be completed by the browser that started it
+	if state == "" || state != c.Cookies("oauth_state") {
 		return fiber.NewError(fiber.StatusUnauthorized, "States don't Match!")
 	}
 
-	code := c.Query("code")
-	googlecon := config.GoogleConfig()
+	c.ClearCookie("oauth_state")
 
-	token, err := googlecon.Exchange(context.Background(), code)
+	oauthUser, oauthState, err := a.OAuthService.Exchange(c, provider, state, c.Query("code"))
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequestWithContext(
-		c.Context(), http.MethodGet,
-		"https://www.googleapis.com/oauth2/v2/userinfo?access_token="+token.AccessToken,
-		nil,
-	)
-	if err != nil {
-		return err
+	if oauthState.UserID != nil {
+		return a.linkIdentity(c, oauthState.UserID.String(), oauthUser)
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	user, err := a.IdentityService.Login(c, oauthUser)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
 
-	userData, err := io.ReadAll(resp.Body)
+	// Tokens are never put in a url, the frontend exchanges the one-time code for them
+	if config.OAuthFrontendURL != "" {
+		code, errCode := a.TokenService.GenerateOAuthCode(c, user)
+		if errCode != nil {
+			return errCode
+		}
+
+		return a.redirectToFrontend(c, "code", code)
+	}
+
+	return a.loginSuccess(c, user)
+}
+
+func (a *AuthController) linkIdentity(c *fiber.Ctx, userID string, oauthUser *validation.OAuthLogin) error {
+	identity, err := a.IdentityService.Link(c, userID, oauthUser)
 	if err != nil {
 		return err
 	}
 
-	googleUser := new(validation.GoogleLogin)
-	if errJSON := json.Unmarshal(userData, googleUser); errJSON != nil {
-		return errJSON
+	if config.OAuthFrontendURL != "" {
+		return a.redirectToFrontend(c, "linked", identity.Provider)
 	}
 
-	user, err := a.UserService.CreateGoogleUser(c, googleUser)
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithIdentity{
+			Code:     fiber.StatusOK,
+			Status:   "success",
+			Message:  "Link identity successfully",
+			Identity: *identity,
+		})
+}
+
+func (a *AuthController) redirectToFrontend(c *fiber.Ctx, key, value string) error {
+	redirectURL, err := url.Parse(config.OAuthFrontendURL)
 	if err != nil {
-		return err
+		return fiber.NewError(fiber.StatusInternalServerError, "Invalid frontend redirect url")
+	}
+
+	query := redirectURL.Query()
+	query.Set(key, value)
+	redirectURL.RawQuery = query.Encode()
+
+	return c.Redirect(redirectURL.String(), fiber.StatusSeeOther)
+}
+
+func (a *AuthController) setStateCookie(c *fiber.Ctx, state string) {
+	c.Cookie(&fiber.Cookie{
+		Name:     "oauth_state",
+		Value:    state,
+		MaxAge:   config.OAuthStateExpMinutes * 60,
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+func (a *AuthController) loginSuccess(c *fiber.Ctx, user *model.User) error {
+	if user.TwoFactorEnabled {
+		return a.requireTwoFactor(c, user)
 	}
 
 	tokens, err := a.TokenService.GenerateAuthTokens(c, user)
@@ -346,10 +616,19 @@ func (a *AuthController) GoogleCallback(c *fiber.Ctx) error {
 			User:    *user,
 			Tokens:  *tokens,
 		})
+}
 
-	// TODO: replace this url with the link to the oauth google success page of your front-end app
-	// googleLoginURL := fmt.Sprintf("http://link-to-app/google/success?access_token=%s&refresh_token=%s",
-	// 	tokens.Access.Token, tokens.Refresh.Token)
+func (a *AuthController) requireTwoFactor(c *fiber.Ctx, user *model.User) error {
+	mfaToken, err := a.TokenService.GenerateMFAPendingToken(c, user)
+	if err != nil {
+		return err
+	}
 
-	// return c.Status(fiber.StatusSeeOther).Redirect(googleLoginURL)
+	return c.Status(fiber.StatusOK).
+		JSON(response.MFARequired{
+			Code:     fiber.StatusOK,
+			Status:   "success",
+			Message:  "Two-factor authentication required",
+			MFAToken: *mfaToken,
+		})
 }
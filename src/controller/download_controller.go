@@ -0,0 +1,193 @@
+package controller
+
+import (
+	"app/src/model"
+	"app/src/service"
+	"app/src/utils"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// DownloadFile godoc
+// @Summary Download file
+// @Description Streams the file from the storage with its original name, or one of its image variants. Supports Range (a single range), If-Range, If-None-Match and If-Modified-Since. Users can only download their own files, users with the manageFiles right any file.
+// @Tags Files
+// @Produce octet-stream
+// @Security BearerAuth
+// @Param fileId path string true "File id"
+// @Param variant query string false "Image variant name, e.g. thumbnail"
+// @Param Range header string false "bytes=start-end"
+// @Router /files/{fileId}/download [get]
+func (fc *FileController) DownloadFile(c *fiber.Ctx) error {
+	fileID, actor, err := fc.fileRequest(c)
+	if err != nil {
+		return err
+	}
+
+	file, err := fc.storageService.GetFile(c.Context(), fileID, *actor)
+	if errors.Is(err, service.ErrFileNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, "File not found")
+	}
+	if err != nil {
+		utils.Log.Errorf("Failed to get file: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get file")
+	}
+
+	filePath, name, contentType := file.FilePath, file.OriginalName, file.ContentType
+	if name == "" {
+		name = file.FileName
+	}
+
+	if variantName := c.Query("variant"); variantName != "" {
+		i := slices.IndexFunc(file.Variants, func(variant model.FileVariant) bool { return variant.Name == variantName })
+		if i < 0 {
+			return fiber.NewError(fiber.StatusNotFound, "Variant not found")
+		}
+
+		variant := file.Variants[i]
+		filePath, contentType = variant.FilePath, variant.ContentType
+		name = strings.TrimSuffix(name, filepath.Ext(name)) + "_" + variant.Name + filepath.Ext(variant.FilePath)
+	}
+
+	stored, err := fc.storageService.OpenFile(c.Context(), filePath)
+	if errors.Is(err, service.ErrFileNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, "File not found")
+	}
+	if err != nil {
+		utils.Log.Errorf("Failed to open file: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to download file")
+	}
+
+	return sendStoredFile(c, stored, name, contentType)
+}
+
+// sendStoredFile mengirim isi file dengan header untuk cache validation dan range request.
+// Content ditutup oleh fasthttp setelah stream selesai, atau di sini jika tidak ada body yang dikirim.
+func sendStoredFile(c *fiber.Ctx, stored *service.StoredFile, name, contentType string) error {
+	streaming := false
+	defer func() {
+		if !streaming {
+			stored.Content.Close()
+		}
+	}()
+
+	lastModified := stored.ModTime.UTC().Truncate(time.Second)
+
+	c.Set(fiber.HeaderAcceptRanges, "bytes")
+	c.Set(fiber.HeaderETag, stored.ETag)
+	c.Set(fiber.HeaderLastModified, lastModified.Format(http.TimeFormat))
+	c.Set(fiber.HeaderCacheControl, "private, no-cache")
+
+	if notModified(c, stored.ETag, lastModified) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	var byteRange *utils.ByteRange
+	if header := c.Get(fiber.HeaderRange); header != "" && ifRangeMatches(c, stored.ETag, lastModified) {
+		var err error
+		byteRange, err = utils.ParseRange(header, stored.Size)
+		if errors.Is(err, utils.ErrRangeNotSatisfiable) {
+			c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes */%d", stored.Size))
+			return fiber.NewError(fiber.StatusRequestedRangeNotSatisfiable, "Range not satisfiable")
+		}
+	}
+
+	if contentType == "" {
+		contentType = stored.ContentType
+	}
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(name))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, contentDisposition(name))
+
+	if byteRange == nil {
+		streaming = true
+		return c.Status(fiber.StatusOK).SendStream(stored.Content, int(stored.Size))
+	}
+
+	if _, err := stored.Content.Seek(byteRange.Start, io.SeekStart); err != nil {
+		utils.Log.Errorf("Failed to seek file: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to download file")
+	}
+
+	c.Set(fiber.HeaderContentRange,
+		fmt.Sprintf("bytes %d-%d/%d", byteRange.Start, byteRange.Start+byteRange.Length-1, stored.Size))
+
+	streaming = true
+	return c.Status(fiber.StatusPartialContent).SendStream(struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(stored.Content, byteRange.Length), stored.Content}, int(byteRange.Length))
+}
+
+// notModified memeriksa If-None-Match, atau If-Modified-Since jika If-None-Match tidak dikirim
+func notModified(c *fiber.Ctx, etag string, lastModified time.Time) bool {
+	if header := c.Get(fiber.HeaderIfNoneMatch); header != "" {
+		return etagMatches(header, etag)
+	}
+
+	if header := c.Get(fiber.HeaderIfModifiedSince); header != "" {
+		since, err := http.ParseTime(header)
+		return err == nil && !lastModified.After(since)
+	}
+
+	return false
+}
+
+// ifRangeMatches menentukan apakah Range dipakai, If-Range harus berisi ETag (strong) atau Last-Modified yang sama
+func ifRangeMatches(c *fiber.Ctx, etag string, lastModified time.Time) bool {
+	header := c.Get(fiber.HeaderIfRange)
+	if header == "" {
+		return true
+	}
+
+	if strings.HasPrefix(header, `"`) {
+		return header == etag
+	}
+
+	since, err := http.ParseTime(header)
+	return err == nil && lastModified.Equal(since)
+}
+
+// etagMatches perbandingan weak dari If-None-Match
+func etagMatches(header, etag string) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// contentDisposition attachment dengan nama file asli, filename* (RFC 6266) untuk nama di luar ASCII
+func contentDisposition(name string) string {
+	fallback := strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, name)
+
+	return fmt.Sprintf(`attachment; filename="%s"; filename*=UTF-8''%s`,
+		fallback, strings.ReplaceAll(url.QueryEscape(name), "+", "%20"))
+}
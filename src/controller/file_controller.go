@@ -5,6 +5,7 @@ import (
 	"app/src/response"
 	"app/src/service"
 	"app/src/utils"
+	"errors"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -13,12 +14,21 @@ import (
 // FileController struct
 type FileController struct {
 	storageService service.StorageService
+	uploadService  service.UploadService
+	imageService   service.ImageService
+	userService    service.UserService
 }
 
 // NewFileController membuat instance FileController
-func NewFileController(storageService service.StorageService) *FileController {
+func NewFileController(
+	storageService service.StorageService, uploadService service.UploadService,
+	imageService service.ImageService, userService service.UserService,
+) *FileController {
 	return &FileController{
 		storageService: storageService,
+		uploadService:  uploadService,
+		imageService:   imageService,
+		userService:    userService,
 	}
 }
 
@@ -29,7 +39,7 @@ type FileUploadRequest struct {
 
 // UploadFile godoc
 // @Summary Upload file
-// @Description Upload file ke storage (local atau MinIO)
+// @Description Upload file ke storage (local atau MinIO). File menjadi milik organization yang aktif jika ada. Tipe, ukuran dan jumlah file diperiksa terhadap policy folder, isi file harus sesuai dengan ekstensinya (415).
 // @Tags Files
 // @Accept multipart/form-data
 // @Produce json
@@ -38,13 +48,9 @@ type FileUploadRequest struct {
 // @Param folder formData string false "Folder destination"
 // @Router /files/upload [post]
 func (fc *FileController) UploadFile(c *fiber.Ctx) error {
-	// Get user from context (set by JWT middleware)
-	userClaims := c.Locals("user")
-	var userID *uuid.UUID
-	if userClaims != nil {
-		if claims, ok := userClaims.(*model.User); ok {
-			userID = &claims.ID
-		}
+	actor, err := fc.fileActor(c)
+	if err != nil {
+		return err
 	}
 
 	// Get uploaded file
@@ -57,12 +63,22 @@ func (fc *FileController) UploadFile(c *fiber.Ctx) error {
 	folder := c.FormValue("folder", "general")
 
 	// Upload file
-	result, err := fc.storageService.UploadFile(c.Context(), file, folder, userID)
+	result, err := fc.storageService.UploadFile(c.Context(), file, folder, *actor)
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return err
+	}
 	if err != nil {
 		utils.Log.Errorf("Failed to upload file: %v", err)
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to upload file")
 	}
 
+	fc.imageService.Enqueue(result.ID)
+
+	if result.FileURL, err = fc.downloadURL(c, result.FilePath); err != nil {
+		return err
+	}
+
 	return c.Status(fiber.StatusOK).JSON(response.Response{
 		Code:    fiber.StatusOK,
 		Status:  "success",
@@ -71,66 +87,77 @@ func (fc *FileController) UploadFile(c *fiber.Ctx) error {
 	})
 }
 
-// DeleteFile godoc
-// @Summary Delete file
-// @Description Delete file dari storage (local atau MinIO)
+// GetFile godoc
+// @Summary Get file info
+// @Description Get file information and URL. Users can only get their own files, users with the manageFiles right any file.
 // @Tags Files
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param file_path query string true "File path to delete"
-// @Router /files/delete [delete]
-func (fc *FileController) DeleteFile(c *fiber.Ctx) error {
-	filePath := c.Query("file_path")
-	if filePath == "" {
-		return fiber.NewError(fiber.StatusBadRequest, "File path is required")
+// @Param fileId path string true "File id"
+// @Router /files/{fileId} [get]
+func (fc *FileController) GetFile(c *fiber.Ctx) error {
+	fileID, actor, err := fc.fileRequest(c)
+	if err != nil {
+		return err
 	}
 
-	// Delete file
-	err := fc.storageService.DeleteFile(c.Context(), filePath)
+	file, err := fc.storageService.GetFile(c.Context(), fileID, *actor)
+	if errors.Is(err, service.ErrFileNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, "File not found")
+	}
 	if err != nil {
-		utils.Log.Errorf("Failed to delete file: %v", err)
-		return fiber.NewError(fiber.StatusInternalServerError, "Failed to delete file")
+		utils.Log.Errorf("Failed to get file: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get file")
 	}
 
-	return c.Status(fiber.StatusOK).JSON(response.Common{
+	if err := fc.setFileURLs(c, file); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.Response{
 		Code:    fiber.StatusOK,
 		Status:  "success",
-		Message: "File deleted successfully",
+		Message: "File info retrieved successfully",
+		Data:    file,
 	})
 }
 
-// GetFileInfo godoc
-// @Summary Get file info
-// @Description Get file information and URL
+// DeleteFile godoc
+// @Summary Delete file
+// @Description Delete file dari storage (local atau MinIO). Users can only delete their own files, users with the manageFiles right any file.
 // @Tags Files
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param file_path query string true "File path"
-// @Router /files/info [get]
-func (fc *FileController) GetFileInfo(c *fiber.Ctx) error {
-	filePath := c.Query("file_path")
-	if filePath == "" {
-		return fiber.NewError(fiber.StatusBadRequest, "File path is required")
+// @Param fileId path string true "File id"
+// @Router /files/{fileId} [delete]
+func (fc *FileController) DeleteFile(c *fiber.Ctx) error {
+	fileID, actor, err := fc.fileRequest(c)
+	if err != nil {
+		return err
 	}
 
-	fileURL := fc.storageService.GetFileURL(filePath)
+	// Delete file
+	err = fc.storageService.DeleteFile(c.Context(), fileID, *actor)
+	if errors.Is(err, service.ErrFileNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, "File not found")
+	}
+	if err != nil {
+		utils.Log.Errorf("Failed to delete file: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to delete file")
+	}
 
-	return c.Status(fiber.StatusOK).JSON(response.Response{
+	return c.Status(fiber.StatusOK).JSON(response.Common{
 		Code:    fiber.StatusOK,
 		Status:  "success",
-		Message: "File info retrieved successfully",
-		Data: map[string]string{
-			"file_path": filePath,
-			"file_url":  fileURL,
-		},
+		Message: "File deleted successfully",
 	})
 }
 
 // GetMyFiles godoc
 // @Summary Get user files
-// @Description Get files uploaded by current user
+// @Description Get files uploaded by current user in the active organization, or the personal files without one
 // @Tags Files
 // @Accept json
 // @Produce json
@@ -149,12 +176,18 @@ func (fc *FileController) GetMyFiles(c *fiber.Ctx) error {
 	}
 
 	// Get user files
-	files, err := fc.storageService.GetFilesByUser(user.ID)
+	files, err := fc.storageService.GetFilesByUser(user.ID, service.ActiveOrganizationID(c))
 	if err != nil {
 		utils.Log.Errorf("Failed to get user files: %v", err)
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get files")
 	}
 
+	for i := range files {
+		if err := fc.setFileURLs(c, &files[i]); err != nil {
+			return err
+		}
+	}
+
 	return c.Status(fiber.StatusOK).JSON(response.Response{
 		Code:    fiber.StatusOK,
 		Status:  "success",
@@ -162,3 +195,98 @@ func (fc *FileController) GetMyFiles(c *fiber.Ctx) error {
 		Data:    files,
 	})
 }
+
+// GetDownloadURL godoc
+// @Summary Get file download url
+// @Description Returns a presigned download url valid for PRESIGNED_URL_EXP_MINUTES. Users can only get their own files, users with the manageFiles right any file.
+// @Tags Files
+// @Produce json
+// @Security BearerAuth
+// @Param fileId path string true "File id"
+// @Router /files/{fileId}/download-url [get]
+func (fc *FileController) GetDownloadURL(c *fiber.Ctx) error {
+	fileID, actor, err := fc.fileRequest(c)
+	if err != nil {
+		return err
+	}
+
+	file, err := fc.storageService.GetFile(c.Context(), fileID, *actor)
+	if errors.Is(err, service.ErrFileNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, "File not found")
+	}
+	if err != nil {
+		utils.Log.Errorf("Failed to get file: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get file")
+	}
+
+	presigned, err := fc.storageService.PresignDownload(c.Context(), file.FilePath, service.PresignedURLExpiration())
+	if err != nil {
+		utils.Log.Errorf("Failed to presign download: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get download url")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.Response{
+		Code:    fiber.StatusOK,
+		Status:  "success",
+		Message: "Download url created successfully",
+		Data:    presigned,
+	})
+}
+
+// downloadURL membuat presigned URL untuk file_url pada response, URL yang tersimpan tidak dipakai
+// karena bucket MinIO tidak perlu public
+func (fc *FileController) downloadURL(c *fiber.Ctx, filePath string) (string, error) {
+	presigned, err := fc.storageService.PresignDownload(c.Context(), filePath, service.PresignedURLExpiration())
+	if err != nil {
+		utils.Log.Errorf("Failed to presign download: %v", err)
+		return "", fiber.NewError(fiber.StatusInternalServerError, "Failed to get download url")
+	}
+
+	return presigned.URL, nil
+}
+
+// setFileURLs mengisi file_url dari file dan variant-nya dengan presigned URL
+func (fc *FileController) setFileURLs(c *fiber.Ctx, file *model.File) error {
+	var err error
+	if file.FileURL, err = fc.downloadURL(c, file.FilePath); err != nil {
+		return err
+	}
+
+	for i := range file.Variants {
+		if file.Variants[i].FileURL, err = fc.downloadURL(c, file.Variants[i].FilePath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fileRequest membaca file ID dari path dan user yang sedang login beserta rights-nya
+func (fc *FileController) fileRequest(c *fiber.Ctx) (uuid.UUID, *service.FileActor, error) {
+	fileID, err := uuid.Parse(c.Params("fileId"))
+	if err != nil {
+		return uuid.Nil, nil, fiber.NewError(fiber.StatusBadRequest, "Invalid file ID")
+	}
+
+	actor, err := fc.fileActor(c)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	return fileID, actor, nil
+}
+
+// fileActor membaca user yang sedang login beserta rights-nya dan organization yang aktif
+func (fc *FileController) fileActor(c *fiber.Ctx) (*service.FileActor, error) {
+	user, ok := c.Locals("user").(*model.User)
+	if !ok {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	rights, err := fc.userService.GetRights(c, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &service.FileActor{UserID: user.ID, Rights: rights, OrganizationID: service.ActiveOrganizationID(c)}, nil
+}
@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"app/src/model"
+	"app/src/response"
+	"app/src/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type IdentityController struct {
+	IdentityService service.IdentityService
+}
+
+func NewIdentityController(identityService service.IdentityService) *IdentityController {
+	return &IdentityController{
+		IdentityService: identityService,
+	}
+}
+
+// @Tags         Identities
+// @Summary      List my linked OAuth providers
+// @Security BearerAuth
+// @Produce      json
+// @Router       /auth/identities [get]
+// @Success      200  {object}  example.GetIdentitiesResponse
+func (i *IdentityController) GetIdentities(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+
+	identities, err := i.IdentityService.GetIdentities(c, user.ID.String())
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithIdentities{
+			Code:       fiber.StatusOK,
+			Status:     "success",
+			Message:    "Get identities successfully",
+			Identities: identities,
+		})
+}
+
+// @Tags         Identities
+// @Summary      Unlink an OAuth provider
+// @Description  The last provider of an account without a password can not be unlinked, set a password first.
+// @Security BearerAuth
+// @Produce      json
+// @Param        identityId  path  string  true  "Identity id"
+// @Router       /auth/identities/{identityId} [delete]
+// @Success      200  {object}  example.UnlinkIdentityResponse
+func (i *IdentityController) UnlinkIdentity(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+
+	if err := i.IdentityService.Unlink(c, user, c.Params("identityId")); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.Common{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Unlink identity successfully",
+		})
+}
@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"app/src/model"
+	"app/src/response"
+	"app/src/service"
+	"app/src/utils"
+	"app/src/validation"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type ImpersonationController struct {
+	ImpersonationService service.ImpersonationService
+}
+
+func NewImpersonationController(impersonationService service.ImpersonationService) *ImpersonationController {
+	return &ImpersonationController{
+		ImpersonationService: impersonationService,
+	}
+}
+
+// @Tags         Impersonation
+// @Summary      Impersonate a user
+// @Description  Only admins can impersonate. Returns a short-lived access token of the user with the admin in the act claim, there is no refresh token. Impersonated requests can not change the password, email, 2FA or other credentials of the user, and users who can impersonate can not be impersonated. The impersonation is bound to the active organization of the admin, the user must be a member of it, and the token can not select another organization.
+// @Security BearerAuth
+// @Produce      json
+// @Param        userId  path  string  true  "User id"
+// @Param        request  body  validation.StartImpersonation  true  "Request body"
+// @Router       /users/{userId}/impersonate [post]
+// @Success      201  {object}  example.StartImpersonationResponse
+func (i *ImpersonationController) StartImpersonation(c *fiber.Ctx) error {
+	req := new(validation.StartImpersonation)
+	actor, _ := c.Locals("user").(*model.User)
+	userID := c.Params("userId")
+
+	if _, err := uuid.Parse(userID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	impersonation, access, err := i.ImpersonationService.StartImpersonation(c, actor, userID, req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).
+		JSON(response.SuccessWithImpersonation{
+			Code:          fiber.StatusCreated,
+			Status:        "success",
+			Message:       "Start impersonation successfully",
+			Impersonation: *impersonation,
+			Access:        *access,
+		})
+}
+
+// @Tags         Impersonation
+// @Summary      Stop impersonating
+// @Description  Must be called with the impersonation token, the token is revoked and the end of the impersonation recorded.
+// @Security BearerAuth
+// @Produce      json
+// @Router       /auth/impersonation/stop [post]
+// @Success      200  {object}  example.StopImpersonationResponse
+func (i *ImpersonationController) StopImpersonation(c *fiber.Ctx) error {
+	if err := i.ImpersonationService.StopImpersonation(c); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.Common{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Stop impersonation successfully",
+		})
+}
+
+// @Tags         Impersonation
+// @Summary      Get the impersonation audit trail
+// @Description  Only admins can list who impersonated whom, why and when.
+// @Security BearerAuth
+// @Produce      json
+// @Param        page       query     int     false   "Page number"  default(1)
+// @Param        limit      query     int     false   "Maximum number of impersonations"    default(10)
+// @Param        start_date query     string  false  "Filter by start date (YYYY-MM-DD)"
+// @Param        end_date   query     string  false  "Filter by end date (YYYY-MM-DD)"
+// @Param        sort_order query     string  false  "Sort order for results (asc or desc)"  default(ASC)  Enums(ASC, DESC)
+// @Router       /impersonations [get]
+// @Success      200  {object}  example.GetImpersonationsResponse
+func (i *ImpersonationController) GetImpersonations(c *fiber.Ctx) error {
+	paginationParams := utils.ExtractPaginationParams(c)
+
+	result, err := i.ImpersonationService.GetImpersonations(c, paginationParams)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithPaginate[model.Impersonation]{
+			Code:         fiber.StatusOK,
+			Status:       "success",
+			Message:      "Get impersonations successfully",
+			Results:      result.Results,
+			Page:         result.Page,
+			Limit:        result.Limit,
+			TotalPages:   result.TotalPages,
+			TotalResults: result.TotalResults,
+		})
+}
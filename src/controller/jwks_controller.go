@@ -0,0 +1,24 @@
+package controller
+
+import (
+	"app/src/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type JWKSController struct {
+	KeySet *utils.KeySet
+}
+
+func NewJWKSController(keySet *utils.KeySet) *JWKSController {
+	return &JWKSController{
+		KeySet: keySet,
+	}
+}
+
+// GetJWKS publishes the public keys so other services can verify our tokens without the signing key
+func (j *JWKSController) GetJWKS(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+
+	return c.Status(fiber.StatusOK).JSON(j.KeySet.JWKS())
+}
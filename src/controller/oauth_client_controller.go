@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"app/src/model"
+	"app/src/response"
+	"app/src/service"
+	"app/src/validation"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type OAuthClientController struct {
+	OAuthClientService service.OAuthClientService
+}
+
+func NewOAuthClientController(oauthClientService service.OAuthClientService) *OAuthClientController {
+	return &OAuthClientController{
+		OAuthClientService: oauthClientService,
+	}
+}
+
+// @Tags         OAuth Clients
+// @Summary      List the OAuth clients
+// @Description  Only admins can manage OAuth clients. The secrets are never returned again.
+// @Security BearerAuth
+// @Produce      json
+// @Router       /oauth/clients [get]
+// @Success      200  {object}  example.GetOAuthClientsResponse
+func (o *OAuthClientController) GetOAuthClients(c *fiber.Ctx) error {
+	clients, err := o.OAuthClientService.GetOAuthClients(c)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithOAuthClients{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Get OAuth clients successfully",
+			Clients: clients,
+		})
+}
+
+// @Tags         OAuth Clients
+// @Summary      Register an OAuth client
+// @Description  Only admins can manage OAuth clients. The secret of a confidential client is only shown in this response. The scopes must be rights the admin has, client_credentials tokens act as the admin limited to the scopes.
+// @Security BearerAuth
+// @Produce      json
+// @Param        request  body  validation.CreateOAuthClient  true  "Request body"
+// @Router       /oauth/clients [post]
+// @Success      201  {object}  example.CreateOAuthClientResponse
+func (o *OAuthClientController) CreateOAuthClient(c *fiber.Ctx) error {
+	req := new(validation.CreateOAuthClient)
+	user, _ := c.Locals("user").(*model.User)
+
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	client, secret, err := o.OAuthClientService.CreateOAuthClient(c, user, req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).
+		JSON(response.SuccessWithOAuthClient{
+			Code:         fiber.StatusCreated,
+			Status:       "success",
+			Message:      "Create OAuth client successfully",
+			ClientSecret: secret,
+			Client:       *client,
+		})
+}
+
+// @Tags         OAuth Clients
+// @Summary      Delete an OAuth client
+// @Description  Only admins can manage OAuth clients. The access tokens issued to the client stop working.
+// @Security BearerAuth
+// @Produce      json
+// @Param        clientId  path  string  true  "Client id"
+// @Router       /oauth/clients/{clientId} [delete]
+// @Success      200  {object}  example.DeleteOAuthClientResponse
+func (o *OAuthClientController) DeleteOAuthClient(c *fiber.Ctx) error {
+	if err := o.OAuthClientService.DeleteOAuthClient(c, c.Params("clientId")); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.Common{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Delete OAuth client successfully",
+		})
+}
@@ -0,0 +1,273 @@
+package controller
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/response"
+	"app/src/service"
+	"app/src/validation"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type OAuthServerController struct {
+	OAuthServerService service.OAuthServerService
+}
+
+func NewOAuthServerController(oauthServerService service.OAuthServerService) *OAuthServerController {
+	return &OAuthServerController{
+		OAuthServerService: oauthServerService,
+	}
+}
+
+// @Tags         OAuth Server
+// @Summary      Start an authorization request
+// @Description  Third-party clients send the user here with a PKCE challenge (S256 only). When OAUTH_CONSENT_URL is set the browser is redirected there with the same query, otherwise the consent details are returned. Invalid requests of a known client and redirect uri are redirected back to the client with ?error=.
+// @Produce      json
+// @Param        response_type          query  string  true   "Must be code"
+// @Param        client_id              query  string  true   "Client id"
+// @Param        redirect_uri           query  string  true   "A redirect uri registered for the client"
+// @Param        scope                  query  string  false  "Space separated scopes, defaults to every scope of the client"
+// @Param        state                  query  string  false  "Returned to the client unchanged"
+// @Param        code_challenge         query  string  true   "PKCE code challenge"
+// @Param        code_challenge_method  query  string  true   "Must be S256"
+// @Router       /oauth/authorize [get]
+// @Success      200  {object}  example.GetOAuthConsentResponse
+func (o *OAuthServerController) GetAuthorization(c *fiber.Ctx) error {
+	req := new(validation.OAuthAuthorize)
+
+	if err := c.QueryParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid query")
+	}
+
+	client, scopes, err := o.OAuthServerService.ValidateAuthorization(c, req)
+
+	var oauthErr *service.OAuthError
+	if errors.As(err, &oauthErr) {
+		return c.Redirect(service.ClientRedirectURI(req.RedirectURI, map[string]string{
+			"error":             oauthErr.Code,
+			"error_description": oauthErr.Description,
+			"state":             req.State,
+		}), fiber.StatusSeeOther)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if config.OAuthConsentURL != "" {
+		consentURL, errURL := url.Parse(config.OAuthConsentURL)
+		if errURL != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Invalid consent url")
+		}
+
+		consentURL.RawQuery = string(c.Request().URI().QueryString())
+
+		return c.Redirect(consentURL.String(), fiber.StatusSeeOther)
+	}
+
+	permissions := make([]model.Permission, 0, len(scopes))
+	for _, scope := range scopes {
+		permissions = append(permissions, model.Permission{Name: scope, Description: config.Permissions[scope]})
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithOAuthConsent{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Ask the user for consent",
+			Consent: response.OAuthConsent{
+				ClientID:    client.ID,
+				ClientName:  client.Name,
+				RedirectURI: req.RedirectURI,
+				Scopes:      permissions,
+			},
+		})
+}
+
+// @Tags         OAuth Server
+// @Summary      Answer an authorization request
+// @Description  The consent page sends the parameters of the authorization request with the decision of the logged in user. Returns the url to send the browser back to, with a one-time code valid for 1 minute or ?error=access_denied. Can not be used with an API key, an OAuth token or while impersonating.
+// @Security BearerAuth
+// @Produce      json
+// @Param        request  body  validation.OAuthAuthorize  true  "Request body"
+// @Router       /oauth/authorize [post]
+// @Success      200  {object}  example.AuthorizeOAuthClientResponse
+func (o *OAuthServerController) Authorize(c *fiber.Ctx) error {
+	req := new(validation.OAuthAuthorize)
+	user, _ := c.Locals("user").(*model.User)
+
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	redirectURI, err := o.OAuthServerService.Authorize(c, user, req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.OAuthRedirect{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Send the user back to the client",
+			URL:     redirectURI,
+		})
+}
+
+// @Tags         OAuth Server
+// @Summary      Get an access token
+// @Description  Token endpoint of RFC 6749 for the authorization_code (with code_verifier) and client_credentials grants. Confidential clients authenticate with HTTP Basic or client_id and client_secret. Errors use the OAuth2 format.
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        grant_type     formData  string  true   "authorization_code or client_credentials"
+// @Param        code           formData  string  false  "Authorization code"
+// @Param        redirect_uri   formData  string  false  "Redirect uri of the authorization request"
+// @Param        code_verifier  formData  string  false  "PKCE code verifier"
+// @Param        scope          formData  string  false  "Space separated scopes of the client_credentials grant"
+// @Param        client_id      formData  string  false  "Client id"
+// @Param        client_secret  formData  string  false  "Client secret"
+// @Router       /oauth/token [post]
+// @Success      200  {object}  example.OAuthTokenResponse
+func (o *OAuthServerController) Token(c *fiber.Ctx) error {
+	req := new(validation.OAuthToken)
+
+	if err := c.BodyParser(req); err != nil {
+		return o.oauthError(c, &service.OAuthError{
+			Status: fiber.StatusBadRequest, Code: "invalid_request", Description: "Invalid request body",
+		})
+	}
+
+	if clientID, secret, ok := basicAuth(c); ok {
+		req.ClientID, req.ClientSecret = clientID, secret
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set(fiber.HeaderPragma, "no-cache")
+
+	token, err := o.OAuthServerService.Token(c, req)
+	if err != nil {
+		return o.oauthError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(token)
+}
+
+// @Tags         OAuth Server
+// @Summary      Introspect an access token
+// @Description  RFC 7662 introspection for the client the token was issued to, other tokens are reported as inactive.
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        token            formData  string  true   "Access token"
+// @Param        token_type_hint  formData  string  false  "Ignored, only access tokens are issued"
+// @Param        client_id        formData  string  false  "Client id"
+// @Param        client_secret    formData  string  false  "Client secret"
+// @Router       /oauth/introspect [post]
+// @Success      200  {object}  example.OAuthIntrospectionResponse
+func (o *OAuthServerController) Introspect(c *fiber.Ctx) error {
+	req, err := o.tokenLookup(c)
+	if err != nil {
+		return o.oauthError(c, err)
+	}
+
+	introspection, err := o.OAuthServerService.Introspect(c, req)
+	if err != nil {
+		return o.oauthError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(introspection)
+}
+
+// @Tags         OAuth Server
+// @Summary      Revoke an access token
+// @Description  RFC 7009 revocation for the client the token was issued to. Unknown tokens are ignored, the response is the same.
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        token            formData  string  true   "Access token"
+// @Param        token_type_hint  formData  string  false  "Ignored, only access tokens are issued"
+// @Param        client_id        formData  string  false  "Client id"
+// @Param        client_secret    formData  string  false  "Client secret"
+// @Router       /oauth/revoke [post]
+// @Success      200  {object}  example.RevokeOAuthTokenResponse
+func (o *OAuthServerController) Revoke(c *fiber.Ctx) error {
+	req, err := o.tokenLookup(c)
+	if err != nil {
+		return o.oauthError(c, err)
+	}
+
+	if err := o.OAuthServerService.Revoke(c, req); err != nil {
+		return o.oauthError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.Common{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Revoke token successfully",
+		})
+}
+
+func (o *OAuthServerController) tokenLookup(c *fiber.Ctx) (*validation.OAuthTokenLookup, error) {
+	req := new(validation.OAuthTokenLookup)
+
+	if err := c.BodyParser(req); err != nil {
+		return nil, &service.OAuthError{
+			Status: fiber.StatusBadRequest, Code: "invalid_request", Description: "Invalid request body",
+		}
+	}
+
+	if clientID, secret, ok := basicAuth(c); ok {
+		req.ClientID, req.ClientSecret = clientID, secret
+	}
+
+	return req, nil
+}
+
+// oauthError answers errors of the authorization server in the format of RFC 6749, other errors are left
+// to the error handler
+func (o *OAuthServerController) oauthError(c *fiber.Ctx, err error) error {
+	var oauthErr *service.OAuthError
+	if !errors.As(err, &oauthErr) {
+		return err
+	}
+
+	if oauthErr.Status == fiber.StatusUnauthorized {
+		c.Set(fiber.HeaderWWWAuthenticate, `Basic realm="oauth"`)
+	}
+
+	return c.Status(oauthErr.Status).
+		JSON(response.OAuthError{
+			Error:            oauthErr.Code,
+			ErrorDescription: oauthErr.Description,
+		})
+}
+
+// basicAuth reads the client credentials of the Authorization header, both parts are form encoded (RFC 6749 2.3.1)
+func basicAuth(c *fiber.Ctx) (string, string, bool) {
+	header := c.Get(fiber.HeaderAuthorization)
+	if !strings.HasPrefix(header, "Basic ") {
+		return "", "", false
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(header, "Basic "))
+	if err != nil {
+		return "", "", false
+	}
+
+	parts := strings.SplitN(string(decoded), ":", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+
+	clientID, errID := url.QueryUnescape(parts[0])
+	secret, errSecret := url.QueryUnescape(parts[1])
+	if errID != nil || errSecret != nil {
+		return "", "", false
+	}
+
+	return clientID, secret, true
+}
@@ -0,0 +1,470 @@
+package controller
+
+import (
+	"app/src/model"
+	"app/src/response"
+	"app/src/service"
+	"app/src/validation"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type OrganizationController struct {
+	OrganizationService service.OrganizationService
+	TokenService        service.TokenService
+}
+
+func NewOrganizationController(
+	organizationService service.OrganizationService, tokenService service.TokenService,
+) *OrganizationController {
+	return &OrganizationController{
+		OrganizationService: organizationService,
+		TokenService:        tokenService,
+	}
+}
+
+// @Tags         Organizations
+// @Summary      Create an organization
+// @Description  The user creating the organization becomes its owner.
+// @Security BearerAuth
+// @Produce      json
+// @Param        request  body  validation.CreateOrganization  true  "Request body"
+// @Router       /organizations [post]
+// @Success      201  {object}  example.CreateOrganizationResponse
+func (o *OrganizationController) CreateOrganization(c *fiber.Ctx) error {
+	req := new(validation.CreateOrganization)
+	user, _ := c.Locals("user").(*model.User)
+
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	membership, err := o.OrganizationService.CreateOrganization(c, user, req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).
+		JSON(response.SuccessWithMembership{
+			Code:       fiber.StatusCreated,
+			Status:     "success",
+			Message:    "Create organization successfully",
+			Membership: *membership,
+		})
+}
+
+// @Tags         Organizations
+// @Summary      List my organizations
+// @Description  Returns the memberships of the user with their organization.
+// @Security BearerAuth
+// @Produce      json
+// @Router       /organizations [get]
+// @Success      200  {object}  example.GetOrganizationsResponse
+func (o *OrganizationController) GetOrganizations(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+
+	memberships, err := o.OrganizationService.GetOrganizations(c, user)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithMemberships{
+			Code:        fiber.StatusOK,
+			Status:      "success",
+			Message:     "Get organizations successfully",
+			Memberships: memberships,
+		})
+}
+
+// @Tags         Organizations
+// @Summary      Get an organization
+// @Description  Only members can fetch an organization, the membership of the user is returned with it.
+// @Security BearerAuth
+// @Produce      json
+// @Param        organizationId  path  string  true  "Organization id"
+// @Router       /organizations/{organizationId} [get]
+// @Success      200  {object}  example.GetOrganizationResponse
+func (o *OrganizationController) GetOrganization(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+
+	organizationID, err := organizationParam(c)
+	if err != nil {
+		return err
+	}
+
+	membership, err := o.OrganizationService.GetOrganization(c, user, organizationID)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithMembership{
+			Code:       fiber.StatusOK,
+			Status:     "success",
+			Message:    "Get organization successfully",
+			Membership: *membership,
+		})
+}
+
+// @Tags         Organizations
+// @Summary      Update an organization
+// @Description  Only owners can rename the organization.
+// @Security BearerAuth
+// @Produce      json
+// @Param        organizationId  path  string  true  "Organization id"
+// @Param        request  body  validation.UpdateOrganization  true  "Request body"
+// @Router       /organizations/{organizationId} [patch]
+// @Success      200  {object}  example.UpdateOrganizationResponse
+func (o *OrganizationController) UpdateOrganization(c *fiber.Ctx) error {
+	req := new(validation.UpdateOrganization)
+	user, _ := c.Locals("user").(*model.User)
+
+	organizationID, err := organizationParam(c)
+	if err != nil {
+		return err
+	}
+
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	organization, err := o.OrganizationService.UpdateOrganization(c, user, organizationID, req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithOrganization{
+			Code:         fiber.StatusOK,
+			Status:       "success",
+			Message:      "Update organization successfully",
+			Organization: *organization,
+		})
+}
+
+// @Tags         Organizations
+// @Summary      Delete an organization
+// @Description  Only owners can delete the organization. Its files have to be deleted first.
+// @Security BearerAuth
+// @Produce      json
+// @Param        organizationId  path  string  true  "Organization id"
+// @Router       /organizations/{organizationId} [delete]
+// @Success      200  {object}  example.DeleteOrganizationResponse
+func (o *OrganizationController) DeleteOrganization(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+
+	organizationID, err := organizationParam(c)
+	if err != nil {
+		return err
+	}
+
+	if err := o.OrganizationService.DeleteOrganization(c, user, organizationID); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.Common{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Delete organization successfully",
+		})
+}
+
+// @Tags         Organizations
+// @Summary      Switch to an organization
+// @Description  Issues an access token of the current session with the organization active, so the X-Organization-ID header is not needed. Refreshing the tokens drops the organization again.
+// @Security BearerAuth
+// @Produce      json
+// @Param        organizationId  path  string  true  "Organization id"
+// @Router       /organizations/{organizationId}/token [post]
+// @Success      200  {object}  example.OrganizationTokenResponse
+func (o *OrganizationController) CreateOrganizationToken(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+
+	organizationID, err := organizationParam(c)
+	if err != nil {
+		return err
+	}
+
+	membership, err := o.OrganizationService.GetMembership(c, user.ID.String(), organizationID)
+	if err != nil {
+		return err
+	}
+
+	access, err := o.TokenService.GenerateOrganizationAccessToken(c, user, membership.OrganizationID)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithAccessToken{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Create organization token successfully",
+			Access:  *access,
+		})
+}
+
+// @Tags         Organizations
+// @Summary      List the members of an organization
+// @Description  Every member can list the members.
+// @Security BearerAuth
+// @Produce      json
+// @Param        organizationId  path  string  true  "Organization id"
+// @Router       /organizations/{organizationId}/members [get]
+// @Success      200  {object}  example.GetMembersResponse
+func (o *OrganizationController) GetMembers(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+
+	organizationID, err := organizationParam(c)
+	if err != nil {
+		return err
+	}
+
+	members, err := o.OrganizationService.GetMembers(c, user, organizationID)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithMemberships{
+			Code:        fiber.StatusOK,
+			Status:      "success",
+			Message:     "Get members successfully",
+			Memberships: members,
+		})
+}
+
+// @Tags         Organizations
+// @Summary      Change the role of a member
+// @Description  Owners and admins can change roles, only owners can make or unmake owners. The last owner can not be demoted.
+// @Security BearerAuth
+// @Produce      json
+// @Param        organizationId  path  string  true  "Organization id"
+// @Param        userId  path  string  true  "User id of the member"
+// @Param        request  body  validation.UpdateMembership  true  "Request body"
+// @Router       /organizations/{organizationId}/members/{userId} [patch]
+// @Success      200  {object}  example.UpdateMemberResponse
+func (o *OrganizationController) UpdateMember(c *fiber.Ctx) error {
+	req := new(validation.UpdateMembership)
+	user, _ := c.Locals("user").(*model.User)
+
+	organizationID, err := organizationParam(c)
+	if err != nil {
+		return err
+	}
+
+	memberID := c.Params("userId")
+	if _, err := uuid.Parse(memberID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	member, err := o.OrganizationService.UpdateMember(c, user, organizationID, memberID, req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithMembership{
+			Code:       fiber.StatusOK,
+			Status:     "success",
+			Message:    "Update member successfully",
+			Membership: *member,
+		})
+}
+
+// @Tags         Organizations
+// @Summary      Remove a member
+// @Description  Owners and admins can remove members, every member can leave on its own. The last owner can not leave.
+// @Security BearerAuth
+// @Produce      json
+// @Param        organizationId  path  string  true  "Organization id"
+// @Param        userId  path  string  true  "User id of the member"
+// @Router       /organizations/{organizationId}/members/{userId} [delete]
+// @Success      200  {object}  example.RemoveMemberResponse
+func (o *OrganizationController) RemoveMember(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+
+	organizationID, err := organizationParam(c)
+	if err != nil {
+		return err
+	}
+
+	memberID := c.Params("userId")
+	if _, err := uuid.Parse(memberID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	if err := o.OrganizationService.RemoveMember(c, user, organizationID, memberID); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.Common{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Remove member successfully",
+		})
+}
+
+// @Tags         Organizations
+// @Summary      Invite a user by email
+// @Description  Owners and admins can invite, only owners can invite owners. A pending invitation of the same email is replaced.
+// @Security BearerAuth
+// @Produce      json
+// @Param        organizationId  path  string  true  "Organization id"
+// @Param        request  body  validation.CreateInvitation  true  "Request body"
+// @Router       /organizations/{organizationId}/invitations [post]
+// @Success      201  {object}  example.CreateInvitationResponse
+func (o *OrganizationController) CreateInvitation(c *fiber.Ctx) error {
+	req := new(validation.CreateInvitation)
+	user, _ := c.Locals("user").(*model.User)
+
+	organizationID, err := organizationParam(c)
+	if err != nil {
+		return err
+	}
+
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	invitation, err := o.OrganizationService.CreateInvitation(c, user, organizationID, req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).
+		JSON(response.SuccessWithInvitation{
+			Code:       fiber.StatusCreated,
+			Status:     "success",
+			Message:    "Create invitation successfully",
+			Invitation: *invitation,
+		})
+}
+
+// @Tags         Organizations
+// @Summary      List the pending invitations
+// @Description  Owners and admins can list the invitations that have not expired.
+// @Security BearerAuth
+// @Produce      json
+// @Param        organizationId  path  string  true  "Organization id"
+// @Router       /organizations/{organizationId}/invitations [get]
+// @Success      200  {object}  example.GetInvitationsResponse
+func (o *OrganizationController) GetInvitations(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+
+	organizationID, err := organizationParam(c)
+	if err != nil {
+		return err
+	}
+
+	invitations, err := o.OrganizationService.GetInvitations(c, user, organizationID)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithInvitations{
+			Code:        fiber.StatusOK,
+			Status:      "success",
+			Message:     "Get invitations successfully",
+			Invitations: invitations,
+		})
+}
+
+// @Tags         Organizations
+// @Summary      Revoke an invitation
+// @Security BearerAuth
+// @Produce      json
+// @Param        organizationId  path  string  true  "Organization id"
+// @Param        invitationId  path  string  true  "Invitation id"
+// @Router       /organizations/{organizationId}/invitations/{invitationId} [delete]
+// @Success      200  {object}  example.RevokeInvitationResponse
+func (o *OrganizationController) RevokeInvitation(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+
+	organizationID, err := organizationParam(c)
+	if err != nil {
+		return err
+	}
+
+	if err := o.OrganizationService.RevokeInvitation(c, user, organizationID, c.Params("invitationId")); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.Common{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Revoke invitation successfully",
+		})
+}
+
+// @Tags         Organizations
+// @Summary      Accept an invitation
+// @Description  The invitation must have been sent to the email of the user.
+// @Security BearerAuth
+// @Produce      json
+// @Param        token   query  string  true  "The invitation token"
+// @Router       /invitations/accept [post]
+// @Success      200  {object}  example.AcceptInvitationResponse
+func (o *OrganizationController) AcceptInvitation(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+	query := &validation.Token{
+		Token: c.Query("token"),
+	}
+
+	membership, err := o.OrganizationService.AcceptInvitation(c, user, query)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithMembership{
+			Code:       fiber.StatusOK,
+			Status:     "success",
+			Message:    "Accept invitation successfully",
+			Membership: *membership,
+		})
+}
+
+// @Tags         Organizations
+// @Summary      Decline an invitation
+// @Description  Anyone holding the invitation token can decline it, no login is needed.
+// @Produce      json
+// @Param        token   query  string  true  "The invitation token"
+// @Router       /invitations/decline [post]
+// @Success      200  {object}  example.DeclineInvitationResponse
+func (o *OrganizationController) DeclineInvitation(c *fiber.Ctx) error {
+	query := &validation.Token{
+		Token: c.Query("token"),
+	}
+
+	if err := o.OrganizationService.DeclineInvitation(c, query); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.Common{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Decline invitation successfully",
+		})
+}
+
+func organizationParam(c *fiber.Ctx) (string, error) {
+	organizationID := c.Params("organizationId")
+
+	if _, err := uuid.Parse(organizationID); err != nil {
+		return "", fiber.NewError(fiber.StatusBadRequest, "Invalid organization ID")
+	}
+
+	return organizationID, nil
+}
@@ -0,0 +1,169 @@
+package controller
+
+import (
+	"app/src/response"
+	"app/src/service"
+	"app/src/utils"
+	"app/src/validation"
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CreatePresignedUpload godoc
+// @Summary Create presigned upload
+// @Description Returns a presigned PUT url to send the file directly to the storage, the returned headers must be sent unchanged. Call the complete endpoint afterwards to register the file. The file belongs to the active organization if any.
+// @Tags Files
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body validation.CreatePresignedUpload true "Request body"
+// @Router /files/presigned-uploads [post]
+func (fc *FileController) CreatePresignedUpload(c *fiber.Ctx) error {
+	req := new(validation.CreatePresignedUpload)
+
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	actor, err := fc.fileActor(c)
+	if err != nil {
+		return err
+	}
+
+	presigned, err := fc.uploadService.CreatePresignedUpload(c.Context(), *actor, req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response.Response{
+		Code:    fiber.StatusCreated,
+		Status:  "success",
+		Message: "Upload created successfully",
+		Data:    presigned,
+	})
+}
+
+// CompletePresignedUpload godoc
+// @Summary Complete presigned upload
+// @Description Checks the file sent to the presigned url and registers it. Returns 409 if the file was not uploaded yet.
+// @Tags Files
+// @Produce json
+// @Security BearerAuth
+// @Param uploadId path string true "Upload id"
+// @Router /files/presigned-uploads/{uploadId}/complete [post]
+func (fc *FileController) CompletePresignedUpload(c *fiber.Ctx) error {
+	uploadID, actor, err := fc.uploadRequest(c)
+	if err != nil {
+		return err
+	}
+
+	file, err := fc.uploadService.CompletePresignedUpload(c.Context(), uploadID, *actor)
+	if err != nil {
+		return err
+	}
+
+	if file.FileURL, err = fc.downloadURL(c, file.FilePath); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.Response{
+		Code:    fiber.StatusOK,
+		Status:  "success",
+		Message: "File uploaded successfully",
+		Data:    file,
+	})
+}
+
+// DownloadSignedFile godoc
+// @Summary Download file with a signed url
+// @Description Serves files of the local storage for the urls returned as file_url or by download-url, with the same Range and cache validation support as the download endpoint. Not available with MinIO, which serves its presigned urls itself.
+// @Tags Files
+// @Param path path string true "File path"
+// @Param expires query int true "Unix time the url expires"
+// @Param signature query string true "Signature"
+// @Router /files/signed/{path} [get]
+func (fc *FileController) DownloadSignedFile(c *fiber.Ctx) error {
+	storage, filePath, err := fc.signedRequest(c, "", 0)
+	if err != nil {
+		return err
+	}
+
+	stored, err := storage.OpenFile(c.Context(), filePath)
+	if errors.Is(err, service.ErrFileNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, "File not found")
+	}
+	if err != nil {
+		utils.Log.Errorf("Failed to open file: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to download file")
+	}
+
+	name, contentType := filepath.Base(filePath), ""
+	if file, err := storage.GetFileByPath(filePath); err == nil {
+		name, contentType = file.OriginalName, file.ContentType
+		if name == "" {
+			name = file.FileName
+		}
+	}
+
+	return sendStoredFile(c, stored, name, contentType)
+}
+
+// UploadSignedFile godoc
+// @Summary Upload file with a signed url
+// @Description Receives the body of presigned uploads of the local storage. Content-Type and Content-Length must be the ones of the presigned upload.
+// @Tags Files
+// @Param path path string true "File path"
+// @Param expires query int true "Unix time the url expires"
+// @Param signature query string true "Signature"
+// @Router /files/signed/{path} [put]
+func (fc *FileController) UploadSignedFile(c *fiber.Ctx) error {
+	body := c.Body()
+
+	storage, filePath, err := fc.signedRequest(c, c.Get(fiber.HeaderContentType), int64(len(body)))
+	if err != nil {
+		return err
+	}
+
+	if err := storage.SaveSignedUpload(c.Context(), filePath, body); err != nil {
+		if errors.Is(err, service.ErrInvalidSignedURL) {
+			return fiber.NewError(fiber.StatusForbidden, "Invalid or expired signature")
+		}
+		utils.Log.Errorf("Failed to save signed upload: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to upload file")
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// signedRequest memeriksa signature dari URL local storage, signature adalah satu-satunya autentikasi
+func (fc *FileController) signedRequest(
+	c *fiber.Ctx, contentType string, size int64,
+) (*service.LocalStorageService, string, error) {
+	storage, ok := fc.storageService.(*service.LocalStorageService)
+	if !ok {
+		return nil, "", fiber.NewError(fiber.StatusNotFound, "Endpoint Not Found")
+	}
+
+	// Hanya path relatif yang sudah bersih, path seperti ../ tidak pernah ditandatangani
+	filePath, err := url.PathUnescape(c.Params("*"))
+	if err != nil || !filepath.IsLocal(filePath) || path.Clean(filePath) != filePath {
+		return nil, "", fiber.NewError(fiber.StatusBadRequest, "Invalid file path")
+	}
+
+	method := c.Method()
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+
+	err = storage.VerifySignedURL(method, filePath, contentType, size, c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		return nil, "", fiber.NewError(fiber.StatusForbidden, "Invalid or expired signature")
+	}
+
+	return storage, filePath, nil
+}
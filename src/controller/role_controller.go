@@ -0,0 +1,184 @@
+package controller
+
+import (
+	"app/src/response"
+	"app/src/service"
+	"app/src/validation"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type RoleController struct {
+	RoleService service.RoleService
+}
+
+func NewRoleController(roleService service.RoleService) *RoleController {
+	return &RoleController{
+		RoleService: roleService,
+	}
+}
+
+// @Tags         Roles
+// @Summary      Get all roles
+// @Description  Only admins can retrieve all roles with their permissions.
+// @Security BearerAuth
+// @Produce      json
+// @Router       /roles [get]
+// @Success      200  {object}  example.GetRolesResponse
+func (r *RoleController) GetRoles(c *fiber.Ctx) error {
+	roles, err := r.RoleService.GetRoles(c)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithRoles{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Get roles successfully",
+			Roles:   roles,
+		})
+}
+
+// @Tags         Roles
+// @Summary      Get all permissions
+// @Description  Lists the permissions that can be granted to roles. Permissions are defined by the application and can not be changed.
+// @Security BearerAuth
+// @Produce      json
+// @Router       /roles/permissions [get]
+// @Success      200  {object}  example.GetPermissionsResponse
+func (r *RoleController) GetPermissions(c *fiber.Ctx) error {
+	permissions, err := r.RoleService.GetPermissions(c)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithPermissions{
+			Code:        fiber.StatusOK,
+			Status:      "success",
+			Message:     "Get permissions successfully",
+			Permissions: permissions,
+		})
+}
+
+// @Tags         Roles
+// @Summary      Get a role
+// @Description  Only admins can fetch a role.
+// @Security BearerAuth
+// @Produce      json
+// @Param        id  path  string  true  "Role id"
+// @Router       /roles/{id} [get]
+// @Success      200  {object}  example.GetRoleResponse
+func (r *RoleController) GetRoleByID(c *fiber.Ctx) error {
+	roleID := c.Params("roleId")
+
+	if _, err := uuid.Parse(roleID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid role ID")
+	}
+
+	role, err := r.RoleService.GetRoleByID(c, roleID)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithRole{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Get role successfully",
+			Role:    *role,
+		})
+}
+
+// @Tags         Roles
+// @Summary      Create a role
+// @Description  Only admins can create roles.
+// @Security BearerAuth
+// @Produce      json
+// @Param        request  body  validation.CreateRole  true  "Request body"
+// @Router       /roles [post]
+// @Success      201  {object}  example.CreateRoleResponse
+func (r *RoleController) CreateRole(c *fiber.Ctx) error {
+	req := new(validation.CreateRole)
+
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	role, err := r.RoleService.CreateRole(c, req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).
+		JSON(response.SuccessWithRole{
+			Code:    fiber.StatusCreated,
+			Status:  "success",
+			Message: "Create role successfully",
+			Role:    *role,
+		})
+}
+
+// @Tags         Roles
+// @Summary      Update a role
+// @Description  Only admins can update roles. The given permissions replace the current ones.
+// @Security BearerAuth
+// @Produce      json
+// @Param        id  path  string  true  "Role id"
+// @Param        request  body  validation.UpdateRole  true  "Request body"
+// @Router       /roles/{id} [patch]
+// @Success      200  {object}  example.UpdateRoleResponse
+func (r *RoleController) UpdateRole(c *fiber.Ctx) error {
+	req := new(validation.UpdateRole)
+	roleID := c.Params("roleId")
+
+	if _, err := uuid.Parse(roleID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid role ID")
+	}
+
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	role, err := r.RoleService.UpdateRole(c, req, roleID)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithRole{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Update role successfully",
+			Role:    *role,
+		})
+}
+
+// @Tags         Roles
+// @Summary      Delete a role
+// @Description  Only admins can delete roles. The default role and roles still assigned to users can not be deleted.
+// @Security BearerAuth
+// @Produce      json
+// @Param        id  path  string  true  "Role id"
+// @Router       /roles/{id} [delete]
+// @Success      200  {object}  example.DeleteRoleResponse
+func (r *RoleController) DeleteRole(c *fiber.Ctx) error {
+	roleID := c.Params("roleId")
+
+	if _, err := uuid.Parse(roleID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid role ID")
+	}
+
+	if err := r.RoleService.DeleteRole(c, roleID); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.Common{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Delete role successfully",
+		})
+}
@@ -0,0 +1,180 @@
+package controller
+
+import (
+	"app/src/model"
+	"app/src/response"
+	"app/src/service"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type SessionController struct {
+	SessionService service.SessionService
+}
+
+func NewSessionController(sessionService service.SessionService) *SessionController {
+	return &SessionController{
+		SessionService: sessionService,
+	}
+}
+
+// @Tags         Sessions
+// @Summary      List my active sessions
+// @Description  Every login on a device is a session, the one used by this request is flagged as current.
+// @Security BearerAuth
+// @Produce      json
+// @Router       /auth/sessions [get]
+// @Success      200  {object}  example.GetSessionsResponse
+func (s *SessionController) GetSessions(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+	currentSessionID, _ := c.Locals("session_id").(string)
+
+	sessions, err := s.SessionService.GetSessions(c, user.ID.String(), currentSessionID)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithSessions{
+			Code:     fiber.StatusOK,
+			Status:   "success",
+			Message:  "Get sessions successfully",
+			Sessions: sessions,
+		})
+}
+
+// @Tags         Sessions
+// @Summary      Revoke one of my sessions
+// @Security BearerAuth
+// @Produce      json
+// @Param        sessionId  path  string  true  "Session id"
+// @Router       /auth/sessions/{sessionId} [delete]
+// @Success      200  {object}  example.RevokeSessionResponse
+func (s *SessionController) RevokeSession(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+
+	if err := s.SessionService.RevokeSession(c, user.ID.String(), c.Params("sessionId")); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.Common{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Revoke session successfully",
+		})
+}
+
+// @Tags         Sessions
+// @Summary      Revoke all my other sessions
+// @Description  Logs out every device except the one used by this request.
+// @Security BearerAuth
+// @Produce      json
+// @Router       /auth/sessions [delete]
+// @Success      200  {object}  example.RevokeOtherSessionsResponse
+func (s *SessionController) RevokeOtherSessions(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+	currentSessionID, _ := c.Locals("session_id").(string)
+
+	if currentSessionID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Current session could not be determined, please log in again")
+	}
+
+	if err := s.SessionService.RevokeOtherSessions(c, user.ID.String(), currentSessionID); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.Common{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Revoke other sessions successfully",
+		})
+}
+
+// @Tags         Sessions
+// @Summary      List the active sessions of a user
+// @Description  Only admins can list the sessions of other users.
+// @Security BearerAuth
+// @Produce      json
+// @Param        id  path  string  true  "User id"
+// @Router       /users/{id}/sessions [get]
+// @Success      200  {object}  example.GetSessionsResponse
+func (s *SessionController) GetUserSessions(c *fiber.Ctx) error {
+	userID := c.Params("userId")
+	currentSessionID, _ := c.Locals("session_id").(string)
+
+	if _, err := uuid.Parse(userID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	sessions, err := s.SessionService.GetSessions(c, userID, currentSessionID)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithSessions{
+			Code:     fiber.StatusOK,
+			Status:   "success",
+			Message:  "Get sessions successfully",
+			Sessions: sessions,
+		})
+}
+
+// @Tags         Sessions
+// @Summary      Revoke a session of a user
+// @Description  Only admins can revoke the sessions of other users.
+// @Security BearerAuth
+// @Produce      json
+// @Param        id  path  string  true  "User id"
+// @Param        sessionId  path  string  true  "Session id"
+// @Router       /users/{id}/sessions/{sessionId} [delete]
+// @Success      200  {object}  example.RevokeSessionResponse
+func (s *SessionController) RevokeUserSession(c *fiber.Ctx) error {
+	userID := c.Params("userId")
+
+	if _, err := uuid.Parse(userID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	if err := s.SessionService.RevokeSession(c, userID, c.Params("sessionId")); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.Common{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Revoke session successfully",
+		})
+}
+
+// @Tags         Sessions
+// @Summary      Revoke all sessions of a user
+// @Description  Only admins can revoke the sessions of other users. The session used by this request is kept.
+// @Security BearerAuth
+// @Produce      json
+// @Param        id  path  string  true  "User id"
+// @Router       /users/{id}/sessions [delete]
+// @Success      200  {object}  example.RevokeAllSessionsResponse
+func (s *SessionController) RevokeUserSessions(c *fiber.Ctx) error {
+	userID := c.Params("userId")
+	currentSessionID, _ := c.Locals("session_id").(string)
+
+	if _, err := uuid.Parse(userID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	if err := s.SessionService.RevokeOtherSessions(c, userID, currentSessionID); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.Common{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Revoke all sessions successfully",
+		})
+}
@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"app/src/model"
+	"app/src/response"
+	"app/src/service"
+	"app/src/validation"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type TwoFactorController struct {
+	TwoFactorService service.TwoFactorService
+	TokenService     service.TokenService
+}
+
+func NewTwoFactorController(
+	twoFactorService service.TwoFactorService, tokenService service.TokenService,
+) *TwoFactorController {
+	return &TwoFactorController{
+		TwoFactorService: twoFactorService,
+		TokenService:     tokenService,
+	}
+}
+
+// @Tags         Two-Factor
+// @Summary      Enroll two-factor authentication
+// @Description  Returns a TOTP secret, its provisioning URI and one-time recovery codes. Recovery codes are only shown once.
+// @Security BearerAuth
+// @Produce      json
+// @Router       /auth/2fa/enroll [post]
+// @Success      200  {object}  example.TwoFactorEnrollResponse
+func (t *TwoFactorController) Enroll(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+
+	setup, err := t.TwoFactorService.Enroll(c, user)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithTwoFactorSetup{
+			Code:      fiber.StatusOK,
+			Status:    "success",
+			Message:   "Scan the provisioning URI with your authenticator app and confirm with a code",
+			TwoFactor: *setup,
+		})
+}
+
+// @Tags         Two-Factor
+// @Summary      Confirm two-factor authentication
+// @Description  Enables two-factor authentication once a code from the authenticator app is confirmed.
+// @Security BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        request  body  validation.TwoFactorCode  true  "Request body"
+// @Router       /auth/2fa/confirm [post]
+// @Success      200  {object}  example.ConfirmTwoFactorResponse
+func (t *TwoFactorController) Confirm(c *fiber.Ctx) error {
+	req := new(validation.TwoFactorCode)
+	user, _ := c.Locals("user").(*model.User)
+
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := t.TwoFactorService.Confirm(c, user, req); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.Common{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Two-factor authentication enabled successfully",
+		})
+}
+
+// @Tags         Two-Factor
+// @Summary      Disable two-factor authentication
+// @Security BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        request  body  validation.DisableTwoFactor  true  "Request body"
+// @Router       /auth/2fa/disable [post]
+// @Success      200  {object}  example.DisableTwoFactorResponse
+func (t *TwoFactorController) Disable(c *fiber.Ctx) error {
+	req := new(validation.DisableTwoFactor)
+	user, _ := c.Locals("user").(*model.User)
+
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := t.TwoFactorService.Disable(c, user, req); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.Common{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Two-factor authentication disabled successfully",
+		})
+}
+
+// @Tags         Two-Factor
+// @Summary      Verify two-factor challenge
+// @Description  Exchanges the mfa token returned by login and a TOTP or recovery code for auth tokens.
+// @Accept       json
+// @Produce      json
+// @Param        request  body  validation.VerifyTwoFactor  true  "Request body"
+// @Router       /auth/2fa/verify [post]
+// @Success      200  {object}  example.LoginResponse
+func (t *TwoFactorController) Verify(c *fiber.Ctx) error {
+	req := new(validation.VerifyTwoFactor)
+
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	user, err := t.TwoFactorService.Verify(c, req)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := t.TokenService.GenerateAuthTokens(c, user)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithTokens{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Login successfully",
+			User:    *user,
+			Tokens:  *tokens,
+		})
+}
@@ -0,0 +1,201 @@
+package controller
+
+import (
+	"app/src/config"
+	"app/src/middleware"
+	"app/src/model"
+	"app/src/response"
+	"app/src/service"
+	"app/src/validation"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// tusExtensions adalah extension tus 1.0 yang didukung oleh route upload
+const tusExtensions = "creation,expiration,termination"
+
+// UploadOptions godoc
+// @Summary Get tus capabilities
+// @Description Returns the supported tus version and extensions in the Tus-Version, Tus-Extension and Tus-Max-Size headers
+// @Tags Files
+// @Router /files/uploads [options]
+func (fc *FileController) UploadOptions(c *fiber.Ctx) error {
+	c.Set("Tus-Version", middleware.TusVersion)
+	c.Set("Tus-Extension", tusExtensions)
+	c.Set("Tus-Max-Size", strconv.FormatInt(config.MaxUploadSize(), 10))
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// CreateUpload godoc
+// @Summary Create resumable upload
+// @Description Creates a tus upload, the Location header is the url of the upload. Upload-Metadata holds the base64 encoded filename (or name), filetype (or type) and folder (default "general"). The file belongs to the active organization if any.
+// @Tags Files
+// @Produce json
+// @Security BearerAuth
+// @Param Tus-Resumable header string true "1.0.0"
+// @Param Upload-Length header int true "Size of the file in bytes"
+// @Param Upload-Metadata header string true "Comma separated key and base64 value pairs"
+// @Router /files/uploads [post]
+func (fc *FileController) CreateUpload(c *fiber.Ctx) error {
+	actor, err := fc.fileActor(c)
+	if err != nil {
+		return err
+	}
+
+	if c.Get("Upload-Defer-Length") != "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Upload-Defer-Length is not supported")
+	}
+
+	size, err := strconv.ParseInt(c.Get("Upload-Length"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid Upload-Length")
+	}
+
+	metadata, err := service.ParseUploadMetadata(c.Get("Upload-Metadata"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid Upload-Metadata")
+	}
+
+	req := &validation.CreateUpload{
+		FileName:    firstOf(metadata["filename"], metadata["name"]),
+		ContentType: firstOf(metadata["filetype"], metadata["type"]),
+		Folder:      firstOf(metadata["folder"], "general"),
+		Size:        size,
+	}
+
+	upload, err := fc.uploadService.CreateUpload(c.Context(), *actor, req)
+	if err != nil {
+		return err
+	}
+
+	c.Location(c.BaseURL() + "/v1/files/uploads/" + upload.ID.String())
+	setUploadHeaders(c, upload)
+
+	return c.Status(fiber.StatusCreated).JSON(response.Response{
+		Code:    fiber.StatusCreated,
+		Status:  "success",
+		Message: "Upload created successfully",
+		Data:    upload,
+	})
+}
+
+// GetUploadStatus godoc
+// @Summary Get resumable upload offset
+// @Description Returns the received bytes in the Upload-Offset header. Once the upload is completed the Upload-File-Id header holds the id of the file.
+// @Tags Files
+// @Security BearerAuth
+// @Param Tus-Resumable header string true "1.0.0"
+// @Param uploadId path string true "Upload id"
+// @Router /files/uploads/{uploadId} [head]
+func (fc *FileController) GetUploadStatus(c *fiber.Ctx) error {
+	uploadID, actor, err := fc.uploadRequest(c)
+	if err != nil {
+		return err
+	}
+
+	upload, err := fc.uploadService.GetUpload(c.Context(), uploadID, *actor)
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	setUploadHeaders(c, upload)
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// WriteUploadChunk godoc
+// @Summary Upload a chunk
+// @Description Appends the body at Upload-Offset, which must be the current offset of the upload. The file is created after the last byte, its id is returned in the Upload-File-Id header.
+// @Tags Files
+// @Accept application/offset+octet-stream
+// @Security BearerAuth
+// @Param Tus-Resumable header string true "1.0.0"
+// @Param Upload-Offset header int true "Current offset of the upload"
+// @Param uploadId path string true "Upload id"
+// @Router /files/uploads/{uploadId} [patch]
+func (fc *FileController) WriteUploadChunk(c *fiber.Ctx) error {
+	uploadID, actor, err := fc.uploadRequest(c)
+	if err != nil {
+		return err
+	}
+
+	if c.Get(fiber.HeaderContentType) != "application/offset+octet-stream" {
+		return fiber.NewError(fiber.StatusUnsupportedMediaType, "Content-Type must be application/offset+octet-stream")
+	}
+
+	offset, err := strconv.ParseInt(c.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid Upload-Offset")
+	}
+
+	upload, err := fc.uploadService.WriteChunk(c.Context(), uploadID, *actor, offset, c.Body())
+	if err != nil {
+		return err
+	}
+
+	setUploadHeaders(c, upload)
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// DeleteUpload godoc
+// @Summary Terminate resumable upload
+// @Description Stops the upload and removes the received chunks. The file of a completed upload is kept.
+// @Tags Files
+// @Security BearerAuth
+// @Param Tus-Resumable header string true "1.0.0"
+// @Param uploadId path string true "Upload id"
+// @Router /files/uploads/{uploadId} [delete]
+func (fc *FileController) DeleteUpload(c *fiber.Ctx) error {
+	uploadID, actor, err := fc.uploadRequest(c)
+	if err != nil {
+		return err
+	}
+
+	if err := fc.uploadService.DeleteUpload(c.Context(), uploadID, *actor); err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// uploadRequest membaca upload ID dari path dan user yang sedang login
+func (fc *FileController) uploadRequest(c *fiber.Ctx) (uuid.UUID, *service.FileActor, error) {
+	uploadID, err := uuid.Parse(c.Params("uploadId"))
+	if err != nil {
+		return uuid.Nil, nil, fiber.NewError(fiber.StatusBadRequest, "Invalid upload ID")
+	}
+
+	actor, err := fc.fileActor(c)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	return uploadID, actor, nil
+}
+
+// setUploadHeaders mengisi Upload-Offset dan Upload-Expires, atau Upload-File-Id jika upload sudah selesai
+func setUploadHeaders(c *fiber.Ctx, upload *model.Upload) {
+	c.Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+
+	if upload.Completed() {
+		c.Set("Upload-File-Id", upload.FileID.String())
+	} else {
+		c.Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+func firstOf(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -12,14 +12,21 @@ import (
 )
 
 type UserController struct {
-	UserService  service.UserService
-	TokenService service.TokenService
+	UserService         service.UserService
+	TokenService        service.TokenService
+	LoginAttemptService service.LoginAttemptService
+	EmailService        service.EmailService
 }
 
-func NewUserController(userService service.UserService, tokenService service.TokenService) *UserController {
+func NewUserController(
+	userService service.UserService, tokenService service.TokenService,
+	loginAttemptService service.LoginAttemptService, emailService service.EmailService,
+) *UserController {
 	return &UserController{
-		UserService:  userService,
-		TokenService: tokenService,
+		UserService:         userService,
+		TokenService:        tokenService,
+		LoginAttemptService: loginAttemptService,
+		EmailService:        emailService,
 	}
 }
 
@@ -118,7 +125,7 @@ func (u *UserController) CreateUser(c *fiber.Ctx) error {
 
 // @Tags         Users
 // @Summary      Update a user
-// @Description  Logged in users can only update their own information. Only admins can update other users.
+// @Description  Logged in users can only update their own information. Only admins can update other users. A new email is stored as pending_email and a confirmation link is sent to it, the email only changes once the link is opened.
 // @Security BearerAuth
 // @Produce      json
 // @Param        id  path  string  true  "User id"
@@ -142,11 +149,26 @@ func (u *UserController) UpdateUser(c *fiber.Ctx) error {
 		return err
 	}
 
+	message := "Update user successfully"
+
+	if req.Email != "" && user.PendingEmail != nil && *user.PendingEmail == req.Email {
+		changeEmailToken, err := u.TokenService.GenerateChangeEmailToken(c, user)
+		if err != nil {
+			return err
+		}
+
+		if errEmail := u.EmailService.SendConfirmEmailChangeEmail(req.Email, changeEmailToken); errEmail != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to send confirm email change email")
+		}
+
+		message = "Update user successfully, please check the new email for a link to confirm it"
+	}
+
 	return c.Status(fiber.StatusOK).
 		JSON(response.SuccessWithUser{
 			Code:    fiber.StatusOK,
 			Status:  "success",
-			Message: "Update user successfully",
+			Message: message,
 			User:    *user,
 		})
 }
@@ -181,3 +203,32 @@ func (u *UserController) DeleteUser(c *fiber.Ctx) error {
 			Message: "Delete user successfully",
 		})
 }
+
+// @Tags         Users
+// @Summary      Unlock a user
+// @Description  Lifts the lock set after too many failed logins and clears the failed attempts of the user's email. Only admins can unlock users.
+// @Security BearerAuth
+// @Produce      json
+// @Param        id  path  string  true  "User id"
+// @Router       /users/{id}/unlock [post]
+// @Success      200  {object}  example.UnlockUserResponse
+func (u *UserController) UnlockUser(c *fiber.Ctx) error {
+	userID := c.Params("userId")
+
+	if _, err := uuid.Parse(userID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	user, err := u.LoginAttemptService.Unlock(c, userID)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithUser{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Unlock user successfully",
+			User:    *user,
+		})
+}
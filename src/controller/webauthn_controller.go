@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"app/src/model"
+	"app/src/response"
+	"app/src/service"
+	"app/src/validation"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type WebAuthnController struct {
+	WebAuthnService service.WebAuthnService
+	TokenService    service.TokenService
+}
+
+func NewWebAuthnController(webAuthnService service.WebAuthnService, tokenService service.TokenService) *WebAuthnController {
+	return &WebAuthnController{
+		WebAuthnService: webAuthnService,
+		TokenService:    tokenService,
+	}
+}
+
+// @Tags         WebAuthn
+// @Summary      Begin passkey registration
+// @Description  Returns the options for navigator.credentials.create() and a session id for the finish call.
+// @Security BearerAuth
+// @Produce      json
+// @Router       /auth/webauthn/register/begin [post]
+// @Success      200  {object}  example.WebAuthnOptionsResponse
+func (w *WebAuthnController) BeginRegistration(c *fiber.Ctx) error {
+	user, _ := c.Locals("user").(*model.User)
+
+	options, sessionID, err := w.WebAuthnService.BeginRegistration(c, user)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.WebAuthnOptions{
+			Code:      fiber.StatusOK,
+			Status:    "success",
+			SessionID: sessionID,
+			Options:   options,
+		})
+}
+
+// @Tags         WebAuthn
+// @Summary      Finish passkey registration
+// @Description  Verifies the credential returned by navigator.credentials.create() and stores the passkey.
+// @Security BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        request  body  validation.FinishWebAuthnRegistration  true  "Request body"
+// @Router       /auth/webauthn/register/finish [post]
+// @Success      201  {object}  example.RegisterPasskeyResponse
+func (w *WebAuthnController) FinishRegistration(c *fiber.Ctx) error {
+	req := new(validation.FinishWebAuthnRegistration)
+	user, _ := c.Locals("user").(*model.User)
+
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	passkey, err := w.WebAuthnService.FinishRegistration(c, user, req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).
+		JSON(response.SuccessWithPasskey{
+			Code:    fiber.StatusCreated,
+			Status:  "success",
+			Message: "Register passkey successfully",
+			Passkey: *passkey,
+		})
+}
+
+// @Tags         WebAuthn
+// @Summary      Begin passkey login
+// @Description  Returns the options for navigator.credentials.get() and a session id for the finish call. Without an email any passkey of the site can be used.
+// @Accept       json
+// @Produce      json
+// @Param        request  body  validation.BeginWebAuthnLogin  false  "Request body"
+// @Router       /auth/webauthn/login/begin [post]
+// @Success      200  {object}  example.WebAuthnOptionsResponse
+func (w *WebAuthnController) BeginLogin(c *fiber.Ctx) error {
+	req := new(validation.BeginWebAuthnLogin)
+
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+
+	options, sessionID, err := w.WebAuthnService.BeginLogin(c, req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.WebAuthnOptions{
+			Code:      fiber.StatusOK,
+			Status:    "success",
+			SessionID: sessionID,
+			Options:   options,
+		})
+}
+
+// @Tags         WebAuthn
+// @Summary      Finish passkey login
+// @Description  Verifies the assertion returned by navigator.credentials.get() and returns auth tokens like the password login. Passkeys require user verification, so they are not followed by a two-factor challenge.
+// @Accept       json
+// @Produce      json
+// @Param        request  body  validation.FinishWebAuthnLogin  true  "Request body"
+// @Router       /auth/webauthn/login/finish [post]
+// @Success      200  {object}  example.LoginResponse
+func (w *WebAuthnController) FinishLogin(c *fiber.Ctx) error {
+	req := new(validation.FinishWebAuthnLogin)
+
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	user, err := w.WebAuthnService.FinishLogin(c, req)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := w.TokenService.GenerateAuthTokens(c, user)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).
+		JSON(response.SuccessWithTokens{
+			Code:    fiber.StatusOK,
+			Status:  "success",
+			Message: "Login successfully",
+			User:    *user,
+			Tokens:  *tokens,
+		})
+}
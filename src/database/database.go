@@ -99,6 +99,7 @@ func NewSeederConfig() *SeederConfig {
 // getAllSeeders returns all available seeders
 func (sc *SeederConfig) getAllSeeders() []SeederInterface {
 	return []SeederInterface{
+		&seeders.RoleSeeder{},
 		&seeders.UserSeeder{},
 		// Add more seeders here as you create them
 		// &seeders.ProductSeeder{},
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    ADD COLUMN two_factor_enabled  BOOLEAN         DEFAULT FALSE  NOT NULL,
+    ADD COLUMN two_factor_secret   VARCHAR(255)    DEFAULT ''     NOT NULL;
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_tokens_user_id_type;
+DROP INDEX IF EXISTS idx_tokens_family_id;
+ALTER TABLE tokens
+    DROP COLUMN IF EXISTS revoked_at,
+    DROP COLUMN IF EXISTS parent_id,
+    DROP COLUMN IF EXISTS family_id,
+    ALTER COLUMN token TYPE VARCHAR(255);
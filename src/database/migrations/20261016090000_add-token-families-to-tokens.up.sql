@@ -0,0 +1,8 @@
+ALTER TABLE tokens
+    ALTER COLUMN token TYPE TEXT,
+    ADD COLUMN family_id   UUID,
+    ADD COLUMN parent_id   UUID,
+    ADD COLUMN revoked_at  TIMESTAMP;
+UPDATE tokens SET family_id = id WHERE family_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_tokens_family_id ON tokens(family_id);
+CREATE INDEX IF NOT EXISTS idx_tokens_user_id_type ON tokens(user_id, type);
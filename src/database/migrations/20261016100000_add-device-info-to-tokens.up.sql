@@ -0,0 +1,4 @@
+ALTER TABLE tokens
+    ADD COLUMN user_agent    VARCHAR(512) DEFAULT '' NOT NULL,
+    ADD COLUMN ip_address    VARCHAR(64) DEFAULT '' NOT NULL,
+    ADD COLUMN last_used_at  TIMESTAMP;
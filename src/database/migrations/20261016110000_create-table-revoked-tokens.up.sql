@@ -0,0 +1,7 @@
+CREATE TABLE revoked_tokens(
+    id              VARCHAR(64)     PRIMARY KEY,
+    expires_at      TIMESTAMP       NOT NULL,
+    created_at      TIMESTAMP       DEFAULT CURRENT_TIMESTAMP  NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens(expires_at);
+ALTER TABLE users ADD COLUMN tokens_valid_after TIMESTAMP;
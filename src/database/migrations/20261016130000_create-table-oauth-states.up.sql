@@ -0,0 +1,12 @@
+CREATE TABLE oauth_states(
+    id              VARCHAR(64)     PRIMARY KEY,
+    provider        VARCHAR(50)     NOT NULL,
+    nonce           VARCHAR(64)     NOT NULL,
+    code_verifier   VARCHAR(128)    NOT NULL,
+    user_id         UUID,
+    expires_at      TIMESTAMP       NOT NULL,
+    created_at      TIMESTAMP       DEFAULT CURRENT_TIMESTAMP  NOT NULL,
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_oauth_states_expires_at ON oauth_states(expires_at);
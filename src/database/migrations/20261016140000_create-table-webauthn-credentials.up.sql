@@ -0,0 +1,21 @@
+CREATE TABLE webauthn_credentials(
+    id                  UUID            PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id             UUID            NOT NULL,
+    credential_id       BYTEA           NOT NULL,
+    public_key          BYTEA           NOT NULL,
+    attestation_type    VARCHAR(50)     DEFAULT ''  NOT NULL,
+    aaguid              BYTEA,
+    transports          VARCHAR(255)    DEFAULT ''  NOT NULL,
+    sign_count          BIGINT          DEFAULT 0  NOT NULL,
+    backup_eligible     BOOLEAN         DEFAULT FALSE  NOT NULL,
+    backup_state        BOOLEAN         DEFAULT FALSE  NOT NULL,
+    clone_warning       BOOLEAN         DEFAULT FALSE  NOT NULL,
+    name                VARCHAR(50)     DEFAULT ''  NOT NULL,
+    last_used_at        TIMESTAMP,
+    created_at          TIMESTAMP       DEFAULT CURRENT_TIMESTAMP  NOT NULL,
+    updated_at          TIMESTAMP       DEFAULT CURRENT_TIMESTAMP  NOT NULL,
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT uq_webauthn_credentials_credential_id UNIQUE (credential_id)
+);
+CREATE INDEX IF NOT EXISTS idx_webauthn_credentials_user_id ON webauthn_credentials(user_id);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS webauthn_sessions;
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE webauthn_sessions(
+    id              VARCHAR(64)     PRIMARY KEY,
+    ceremony        VARCHAR(20)     NOT NULL,
+    user_id         UUID,
+    data            TEXT            NOT NULL,
+    expires_at      TIMESTAMP       NOT NULL,
+    created_at      TIMESTAMP       DEFAULT CURRENT_TIMESTAMP  NOT NULL,
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_webauthn_sessions_expires_at ON webauthn_sessions(expires_at);
@@ -0,0 +1,9 @@
+CREATE TABLE login_attempts(
+    id              UUID            PRIMARY KEY,
+    email           VARCHAR(50)     NOT NULL,
+    ip              VARCHAR(45)     NOT NULL,
+    created_at      TIMESTAMP       DEFAULT CURRENT_TIMESTAMP  NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_email_created_at ON login_attempts(email, created_at);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_ip_created_at ON login_attempts(ip, created_at);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_created_at ON login_attempts(created_at);
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN locked_until TIMESTAMP;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS roles;
\ No newline at end of file
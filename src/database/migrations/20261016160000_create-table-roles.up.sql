@@ -0,0 +1,10 @@
+CREATE TABLE roles(
+    id              UUID            PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name            VARCHAR(50)     NOT NULL UNIQUE,
+    description     VARCHAR(255)    DEFAULT ''  NOT NULL,
+    created_at      TIMESTAMP       DEFAULT CURRENT_TIMESTAMP  NOT NULL,
+    updated_at      TIMESTAMP       DEFAULT CURRENT_TIMESTAMP  NOT NULL
+);
+INSERT INTO roles (name, description) VALUES
+    ('user', 'Default role of new users'),
+    ('admin', 'Manages users and roles');
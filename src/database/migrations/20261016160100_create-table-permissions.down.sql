@@ -0,0 +1 @@
+DROP TABLE IF EXISTS permissions;
\ No newline at end of file
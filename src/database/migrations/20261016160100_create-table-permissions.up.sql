@@ -0,0 +1,11 @@
+CREATE TABLE permissions(
+    id              UUID            PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name            VARCHAR(50)     NOT NULL UNIQUE,
+    description     VARCHAR(255)    DEFAULT ''  NOT NULL,
+    created_at      TIMESTAMP       DEFAULT CURRENT_TIMESTAMP  NOT NULL
+);
+INSERT INTO permissions (name, description) VALUES
+    ('getUsers', 'List and read users'),
+    ('manageUsers', 'Create, update, delete and unlock users and revoke their sessions'),
+    ('getRoles', 'List and read roles and permissions'),
+    ('manageRoles', 'Create, update and delete roles');
@@ -0,0 +1,13 @@
+CREATE TABLE role_permissions(
+    role_id         UUID            NOT NULL,
+    permission_id   UUID            NOT NULL,
+    PRIMARY KEY (role_id, permission_id),
+    CONSTRAINT fk_role
+        FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE,
+    CONSTRAINT fk_permission
+        FOREIGN KEY (permission_id) REFERENCES permissions(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_role_permissions_permission_id ON role_permissions(permission_id);
+INSERT INTO role_permissions (role_id, permission_id)
+    SELECT roles.id, permissions.id FROM roles, permissions
+    WHERE roles.name = 'admin' AND permissions.name IN ('getUsers', 'manageUsers', 'getRoles', 'manageRoles');
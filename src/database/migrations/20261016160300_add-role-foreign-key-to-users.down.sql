@@ -0,0 +1 @@
+ALTER TABLE users DROP CONSTRAINT IF EXISTS fk_role;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    ADD CONSTRAINT fk_role
+        FOREIGN KEY (role) REFERENCES roles(name) ON DELETE RESTRICT;
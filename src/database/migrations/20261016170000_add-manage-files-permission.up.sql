@@ -0,0 +1,7 @@
+INSERT INTO permissions (name, description) VALUES
+    ('manageFiles', 'Read and delete the files of every user')
+    ON CONFLICT (name) DO NOTHING;
+INSERT INTO role_permissions (role_id, permission_id)
+    SELECT roles.id, permissions.id FROM roles, permissions
+    WHERE roles.name = 'admin' AND permissions.name = 'manageFiles'
+    ON CONFLICT DO NOTHING;
@@ -0,0 +1,6 @@
+CREATE TABLE organizations(
+    id              UUID            PRIMARY KEY,
+    name            VARCHAR(100)    NOT NULL,
+    created_at      TIMESTAMP       DEFAULT CURRENT_TIMESTAMP  NOT NULL,
+    updated_at      TIMESTAMP       DEFAULT CURRENT_TIMESTAMP  NOT NULL
+);
@@ -0,0 +1,14 @@
+CREATE TABLE memberships(
+    id                  UUID            PRIMARY KEY,
+    organization_id     UUID            NOT NULL,
+    user_id             UUID            NOT NULL,
+    role                VARCHAR(20)     NOT NULL,
+    created_at          TIMESTAMP       DEFAULT CURRENT_TIMESTAMP  NOT NULL,
+    updated_at          TIMESTAMP       DEFAULT CURRENT_TIMESTAMP  NOT NULL,
+    CONSTRAINT fk_organization
+        FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE CASCADE,
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT uq_memberships_organization_user UNIQUE (organization_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_memberships_user_id ON memberships(user_id);
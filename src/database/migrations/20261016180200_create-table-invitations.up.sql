@@ -0,0 +1,15 @@
+CREATE TABLE invitations(
+    id                  UUID            PRIMARY KEY,
+    organization_id     UUID            NOT NULL,
+    email               VARCHAR(50)     NOT NULL,
+    role                VARCHAR(20)     NOT NULL,
+    token               VARCHAR(64)     NOT NULL UNIQUE,
+    invited_by          UUID,
+    expires_at          TIMESTAMP       NOT NULL,
+    created_at          TIMESTAMP       DEFAULT CURRENT_TIMESTAMP  NOT NULL,
+    CONSTRAINT fk_organization
+        FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE CASCADE,
+    CONSTRAINT fk_invited_by
+        FOREIGN KEY (invited_by) REFERENCES users(id) ON DELETE SET NULL,
+    CONSTRAINT uq_invitations_organization_email UNIQUE (organization_id, email)
+);
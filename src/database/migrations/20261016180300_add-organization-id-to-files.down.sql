@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_files_organization_id;
+ALTER TABLE files DROP COLUMN IF EXISTS organization_id;
@@ -0,0 +1,2 @@
+ALTER TABLE files ADD COLUMN organization_id UUID REFERENCES organizations(id) ON DELETE RESTRICT;
+CREATE INDEX IF NOT EXISTS idx_files_organization_id ON files(organization_id);
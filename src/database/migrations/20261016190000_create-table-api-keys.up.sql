@@ -0,0 +1,16 @@
+CREATE TABLE api_keys(
+    id                  UUID            PRIMARY KEY,
+    user_id             UUID            NOT NULL,
+    name                VARCHAR(50)     NOT NULL,
+    prefix              VARCHAR(20)     NOT NULL,
+    key                 VARCHAR(64)     NOT NULL UNIQUE,
+    scopes              TEXT            DEFAULT ''  NOT NULL,
+    expires_at          TIMESTAMP,
+    last_used_at        TIMESTAMP,
+    last_used_ip        VARCHAR(45)     DEFAULT ''  NOT NULL,
+    created_at          TIMESTAMP       DEFAULT CURRENT_TIMESTAMP  NOT NULL,
+    updated_at          TIMESTAMP       DEFAULT CURRENT_TIMESTAMP  NOT NULL,
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
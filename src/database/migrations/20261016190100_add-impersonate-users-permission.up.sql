@@ -0,0 +1,7 @@
+INSERT INTO permissions (name, description) VALUES
+    ('impersonateUsers', 'Act as another user to reproduce issues, every impersonation is recorded')
+    ON CONFLICT (name) DO NOTHING;
+INSERT INTO role_permissions (role_id, permission_id)
+    SELECT roles.id, permissions.id FROM roles, permissions
+    WHERE roles.name = 'admin' AND permissions.name = 'impersonateUsers'
+    ON CONFLICT DO NOTHING;
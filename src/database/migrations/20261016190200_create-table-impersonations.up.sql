@@ -0,0 +1,17 @@
+CREATE TABLE impersonations(
+    id                  UUID            PRIMARY KEY,
+    actor_id            UUID,
+    user_id             UUID,
+    reason              VARCHAR(255)    NOT NULL,
+    ip_address          VARCHAR(45)     DEFAULT ''  NOT NULL,
+    user_agent          VARCHAR(512)    DEFAULT ''  NOT NULL,
+    expires_at          TIMESTAMP       NOT NULL,
+    ended_at            TIMESTAMP,
+    created_at          TIMESTAMP       DEFAULT CURRENT_TIMESTAMP  NOT NULL,
+    CONSTRAINT fk_actor
+        FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE SET NULL,
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS idx_impersonations_actor_id ON impersonations(actor_id);
+CREATE INDEX IF NOT EXISTS idx_impersonations_user_id ON impersonations(user_id);
@@ -0,0 +1,7 @@
+INSERT INTO permissions (name, description) VALUES
+    ('manageOAuthClients', 'Register and delete the OAuth clients of third-party apps')
+    ON CONFLICT (name) DO NOTHING;
+INSERT INTO role_permissions (role_id, permission_id)
+    SELECT roles.id, permissions.id FROM roles, permissions
+    WHERE roles.name = 'admin' AND permissions.name = 'manageOAuthClients'
+    ON CONFLICT DO NOTHING;
@@ -0,0 +1,14 @@
+CREATE TABLE oauth_clients(
+    id                  UUID            PRIMARY KEY,
+    user_id             UUID            NOT NULL,
+    name                VARCHAR(50)     NOT NULL,
+    secret              VARCHAR(64)     DEFAULT ''  NOT NULL,
+    redirect_uris       TEXT            DEFAULT ''  NOT NULL,
+    scopes              TEXT            DEFAULT ''  NOT NULL,
+    grant_types         TEXT            DEFAULT ''  NOT NULL,
+    created_at          TIMESTAMP       DEFAULT CURRENT_TIMESTAMP  NOT NULL,
+    updated_at          TIMESTAMP       DEFAULT CURRENT_TIMESTAMP  NOT NULL,
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_oauth_clients_user_id ON oauth_clients(user_id);
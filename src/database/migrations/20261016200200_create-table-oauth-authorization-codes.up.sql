@@ -0,0 +1,16 @@
+CREATE TABLE oauth_authorization_codes(
+    id                  UUID            PRIMARY KEY,
+    code                VARCHAR(64)     NOT NULL UNIQUE,
+    client_id           UUID            NOT NULL,
+    user_id             UUID            NOT NULL,
+    redirect_uri        TEXT            NOT NULL,
+    scopes              TEXT            DEFAULT ''  NOT NULL,
+    code_challenge      VARCHAR(128)    NOT NULL,
+    expires_at          TIMESTAMP       NOT NULL,
+    created_at          TIMESTAMP       DEFAULT CURRENT_TIMESTAMP  NOT NULL,
+    CONSTRAINT fk_client
+        FOREIGN KEY (client_id) REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    CONSTRAINT fk_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_oauth_authorization_codes_expires_at ON oauth_authorization_codes(expires_at);
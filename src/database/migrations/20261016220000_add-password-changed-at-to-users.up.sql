@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN password_changed_at TIMESTAMP;
+UPDATE users SET password_changed_at = updated_at WHERE password <> '';
\ No newline at end of file
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS uploads (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    file_name VARCHAR(255) NOT NULL,
+    file_path VARCHAR(500) NOT NULL UNIQUE,
+    content_type VARCHAR(100),
+    folder VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL,
+    "offset" BIGINT NOT NULL DEFAULT 0,
+    multipart_id VARCHAR(255),
+    parts INTEGER NOT NULL DEFAULT 0,
+    buffered BIGINT NOT NULL DEFAULT 0,
+    uploaded_by UUID REFERENCES users(id) ON DELETE CASCADE,
+    organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE,
+    file_id UUID REFERENCES files(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_uploads_uploaded_by ON uploads(uploaded_by);
+CREATE INDEX IF NOT EXISTS idx_uploads_expires_at ON uploads(expires_at);
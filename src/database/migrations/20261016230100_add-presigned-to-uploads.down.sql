@@ -0,0 +1 @@
+ALTER TABLE uploads DROP COLUMN IF EXISTS presigned;
\ No newline at end of file
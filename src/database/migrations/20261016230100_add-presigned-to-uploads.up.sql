@@ -0,0 +1 @@
+ALTER TABLE uploads ADD COLUMN presigned BOOLEAN NOT NULL DEFAULT false;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE uploads DROP COLUMN IF EXISTS original_name;
+ALTER TABLE files DROP COLUMN IF EXISTS original_name;
\ No newline at end of file
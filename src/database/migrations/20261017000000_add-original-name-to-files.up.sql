@@ -0,0 +1,2 @@
+ALTER TABLE files ADD COLUMN original_name VARCHAR(255);
+ALTER TABLE uploads ADD COLUMN original_name VARCHAR(255);
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS file_variants;
+ALTER TABLE files DROP COLUMN IF EXISTS processing_status;
\ No newline at end of file
@@ -0,0 +1,15 @@
+ALTER TABLE files ADD COLUMN processing_status VARCHAR(20);
+CREATE TABLE IF NOT EXISTS file_variants (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    file_id UUID NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    file_path VARCHAR(500) NOT NULL UNIQUE,
+    file_size BIGINT NOT NULL,
+    content_type VARCHAR(100),
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (file_id, name)
+);
+CREATE INDEX IF NOT EXISTS idx_files_processing_status ON files(processing_status);
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN two_factor_last_step BIGINT DEFAULT 0 NOT NULL;
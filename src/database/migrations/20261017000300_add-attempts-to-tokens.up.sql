@@ -0,0 +1 @@
+ALTER TABLE tokens ADD COLUMN attempts INTEGER DEFAULT 0 NOT NULL;
@@ -0,0 +1 @@
+ALTER TABLE impersonations DROP COLUMN IF EXISTS organization_id;
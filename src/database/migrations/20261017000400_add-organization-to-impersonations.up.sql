@@ -0,0 +1 @@
+ALTER TABLE impersonations ADD COLUMN organization_id UUID REFERENCES organizations(id) ON DELETE SET NULL;
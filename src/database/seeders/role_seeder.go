@@ -0,0 +1,98 @@
+package seeders
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/utils"
+	"sort"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RoleSeeder handles permission and default role seeding
+type RoleSeeder struct{}
+
+// Run executes the role seeder, roles changed through the API keep their permissions
+func (s *RoleSeeder) Run(db *gorm.DB) error {
+	utils.Log.Info("Seeding roles...")
+
+	// Permissions created in this run are granted to the default roles listing them
+	newPermissions := make(map[string]bool)
+	permissions := make(map[string]model.Permission)
+
+	for _, name := range sortedKeys(config.Permissions) {
+		permission := model.Permission{Name: name, Description: config.Permissions[name]}
+
+		var existingPermission model.Permission
+		if err := db.Where("name = ?", name).First(&existingPermission).Error; err != nil {
+			if err != gorm.ErrRecordNotFound {
+				utils.Log.Errorf("Error checking permission existence %s: %v", name, err)
+				return err
+			}
+
+			if err := db.Create(&permission).Error; err != nil {
+				utils.Log.Errorf("Failed to create permission %s: %v", name, err)
+				return err
+			}
+			newPermissions[name] = true
+		} else {
+			permission = existingPermission
+		}
+
+		permissions[name] = permission
+	}
+
+	createdCount := 0
+	skippedCount := 0
+
+	for _, name := range sortedKeys(config.DefaultRoles) {
+		role := model.Role{Name: name}
+		created := false
+
+		var existingRole model.Role
+		if err := db.Where("name = ?", name).First(&existingRole).Error; err != nil {
+			if err != gorm.ErrRecordNotFound {
+				utils.Log.Errorf("Error checking role existence %s: %v", name, err)
+				return err
+			}
+
+			if err := db.Omit("Permissions").Create(&role).Error; err != nil {
+				utils.Log.Errorf("Failed to create role %s: %v", name, err)
+				return err
+			}
+			created = true
+			createdCount++
+		} else {
+			role = existingRole
+			skippedCount++
+		}
+
+		for _, right := range config.DefaultRoles[name] {
+			if !created && !newPermissions[right] {
+				continue
+			}
+
+			if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.RolePermission{
+				RoleID:       role.ID,
+				PermissionID: permissions[right].ID,
+			}).Error; err != nil {
+				utils.Log.Errorf("Failed to grant permission %s to role %s: %v", right, name, err)
+				return err
+			}
+		}
+	}
+
+	utils.Log.Infof("Role seeder completed: %d created, %d skipped", createdCount, skippedCount)
+	return nil
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
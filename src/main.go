@@ -10,11 +10,11 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"syscall"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/compress"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/helmet"
 	"gorm.io/gorm"
@@ -55,17 +55,25 @@ func main() {
 func setupFiberApp() *fiber.App {
 	app := fiber.New(config.FiberConfig())
 
-	// Static files middleware for local storage
-	if config.StorageType == "local" {
+	// Static files middleware for local storage, only the public folders with STORAGE_PRIVATE_BY_DEFAULT
+	if config.StorageType == "local" && !config.StoragePrivateByDefault {
 		app.Static("/uploads", config.StorageLocalPath)
+	} else if config.StorageType == "local" {
+		for _, folder := range config.StoragePublicFolders {
+			app.Static("/uploads/"+folder, filepath.Join(config.StorageLocalPath, folder))
+		}
 	}
 
 	// Middleware setup
 	app.Use("/v1/auth", middleware.LimiterConfig())
 	app.Use(middleware.LoggerConfig())
 	app.Use(helmet.New())
-	app.Use(compress.New())
-	app.Use(cors.New())
+	app.Use(middleware.CompressConfig())
+	app.Use(cors.New(cors.Config{
+		// Header dari upload resumable (tus) harus bisa dibaca client di browser
+		ExposeHeaders: "Location, Tus-Resumable, Tus-Version, Tus-Extension, Tus-Max-Size, " +
+			"Upload-Offset, Upload-Length, Upload-Expires, Upload-File-Id",
+	}))
 	app.Use(middleware.RecoverConfig())
 
 	return app
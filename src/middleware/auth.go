@@ -2,55 +2,305 @@ package middleware
 
 import (
 	"app/src/config"
+	"app/src/model"
+	"app/src/policy"
 	"app/src/service"
 	"app/src/utils"
+	"slices"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
 )
 
-func Auth(userService service.UserService, requiredRights ...string) fiber.Handler {
+var errForbidden = fiber.NewError(fiber.StatusForbidden, "You don't have permission to access this resource")
+
+// Auth authenticates the request and requires the user to have all of the required rights
+func Auth(userService service.UserService, tokenService service.TokenService, requiredRights ...string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		authHeader := c.Get("Authorization")
-		token := strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer "))
+		user, err := authenticate(c, userService, tokenService)
+		if err != nil {
+			return err
+		}
 
-		if token == "" {
-			return fiber.NewError(fiber.StatusUnauthorized, "Please authenticate")
+		if len(requiredRights) > 0 {
+			userRights, err := userService.GetRights(c, user)
+			if err != nil {
+				return err
+			}
+
+			if !policy.HasAllRights(userRights, requiredRights) {
+				return errForbidden
+			}
 		}
 
-		userID, err := utils.VerifyToken(token, config.JWTSecret, config.TokenTypeAccess)
+		return c.Next()
+	}
+}
+
+// Authorize authenticates the request and lets it through when the rule allows the user to act on the
+// resource addressed by the request, e.g. policy.OwnerOr(policy.User("userId"), "manageUsers")
+func Authorize(userService service.UserService, tokenService service.TokenService, rule policy.Rule) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, err := authenticate(c, userService, tokenService)
 		if err != nil {
-			return fiber.NewError(fiber.StatusUnauthorized, "Please authenticate")
+			return err
 		}
 
-		user, err := userService.GetUserByID(c, userID)
-		if err != nil || user == nil {
-			return fiber.NewError(fiber.StatusUnauthorized, "Please authenticate")
+		userRights, err := userService.GetRights(c, user)
+		if err != nil {
+			return err
 		}
 
-		c.Locals("user", user)
+		// API keys and OAuth clients only act through the rights of their scopes, owning the resource is not enough
+		requestRule := rule
+		if service.ActiveAPIKey(c) != nil || service.ActiveOAuthAccess(c) != nil {
+			requestRule.AllowOwner = false
+		}
 
-		if len(requiredRights) > 0 {
-			userRights, hasRights := config.RoleRights[user.Role]
-			if (!hasRights || !hasAllRights(userRights, requiredRights)) && c.Params("userId") != userID {
-				return fiber.NewError(fiber.StatusForbidden, "You don't have permission to access this resource")
+		allowed, err := requestRule.Allows(c, user.ID, userRights)
+		if err != nil {
+			return err
+		}
+
+		if !allowed {
+			if rule.Resource.NotFound != nil {
+				return rule.Resource.NotFound
 			}
+			return errForbidden
 		}
 
 		return c.Next()
 	}
 }
 
-func hasAllRights(userRights, requiredRights []string) bool {
-	rightSet := make(map[string]struct{}, len(userRights))
-	for _, right := range userRights {
-		rightSet[right] = struct{}{}
+// authenticate verifies the access token, or the API key when the request has no Authorization header,
+// and stores the user and its session or API key in the locals
+func authenticate(c *fiber.Ctx, userService service.UserService, tokenService service.TokenService) (*model.User, error) {
+	var (
+		user   *model.User
+		claims jwt.MapClaims
+		err    error
+	)
+
+	authHeader := c.Get("Authorization")
+
+	if apiKey := c.Get(config.HeaderAPIKey); authHeader == "" && apiKey != "" {
+		user, err = authenticateAPIKey(c, userService, tokenService, apiKey)
+	} else {
+		user, claims, err = authenticateAccessToken(c, userService, tokenService, authHeader)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	c.Locals("user", user)
+
+	// The active organization is picked by the header or the org claim, the membership is checked on every request.
+	// An impersonation is bound to the organization of its org claim, the header can not switch it.
+	organizationID := c.Get(config.HeaderOrganizationID)
+	if organizationID == "" || service.Actor(c) != nil {
+		organizationID, _ = claims["org"].(string)
+	}
+
+	if organizationID != "" {
+		membership, err := userService.GetMembership(c, user, organizationID)
+		if err != nil {
+			return nil, err
+		}
+
+		c.Locals("membership", membership)
+	}
+
+	return user, nil
+}
+
+func authenticateAccessToken(
+	c *fiber.Ctx, userService service.UserService, tokenService service.TokenService, authHeader string,
+) (*model.User, jwt.MapClaims, error) {
+	token := strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer "))
+
+	if token == "" {
+		return nil, nil, fiber.NewError(fiber.StatusUnauthorized, "Please authenticate")
 	}
 
-	for _, right := range requiredRights {
-		if _, exists := rightSet[right]; !exists {
-			return false
+	claims, err := utils.ParseToken(token, config.JWTKeySet, config.TokenTypeAccess)
+	if err != nil {
+		return nil, nil, fiber.NewError(fiber.StatusUnauthorized, "Please authenticate")
+	}
+
+	userID, ok := claims["sub"].(string)
+	if !ok {
+		return nil, nil, fiber.NewError(fiber.StatusUnauthorized, "Please authenticate")
+	}
+
+	revoked, err := tokenService.IsAccessTokenRevoked(c, claims)
+	if err != nil || revoked {
+		return nil, nil, fiber.NewError(fiber.StatusUnauthorized, "Please authenticate")
+	}
+
+	user, err := userService.GetUserByID(c, userID)
+	if err != nil || user == nil {
+		return nil, nil, fiber.NewError(fiber.StatusUnauthorized, "Please authenticate")
+	}
+
+	if service.IssuedBeforeValidity(claims, user) {
+		return nil, nil, fiber.NewError(fiber.StatusUnauthorized, "Please authenticate")
+	}
+
+	// Access tokens issued by login or refresh carry the session (token family) they belong to
+	if sessionID, ok := claims["sid"].(string); ok {
+		c.Locals("session_id", sessionID)
+	}
+
+	// Impersonation tokens carry the admin in the act claim
+	if act, ok := claims["act"].(map[string]interface{}); ok {
+		if err := authenticateActor(c, userService, claims, act); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Tokens issued by the authorization server name the OAuth client and its scopes
+	if clientID, ok := claims["client_id"].(string); ok {
+		if err := authenticateOAuthClient(c, tokenService, user, claims, clientID); err != nil {
+			return nil, nil, err
 		}
 	}
-	return true
+
+	return user, claims, nil
+}
+
+// authenticateOAuthClient stores the client and the scopes of the token in the locals. Scopes the client
+// no longer has are dropped, so narrowing a client takes effect on its tokens right away.
+func authenticateOAuthClient(
+	c *fiber.Ctx, tokenService service.TokenService, user *model.User, claims jwt.MapClaims, clientID string,
+) error {
+	client, err := tokenService.VerifyOAuthClient(c, clientID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "Please authenticate")
+	}
+
+	clientScopes := utils.SplitList(client.Scopes)
+	scope, _ := claims["scope"].(string)
+
+	scopes := slices.DeleteFunc(strings.Fields(scope), func(scope string) bool {
+		return !slices.Contains(clientScopes, scope)
+	})
+
+	c.Locals("oauth_access", &service.OAuthAccess{
+		Client: client,
+		UserID: user.ID,
+		Scopes: scopes,
+	})
+
+	return nil
+}
+
+// authenticateActor stores the admin impersonating the user in the locals, the admin must still exist
+// and be allowed to impersonate
+func authenticateActor(
+	c *fiber.Ctx, userService service.UserService, claims jwt.MapClaims, act map[string]interface{},
+) error {
+	actorID, ok := act["sub"].(string)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Please authenticate")
+	}
+
+	actor, err := userService.GetUserByID(c, actorID)
+	if err != nil || actor == nil || service.IssuedBeforeValidity(claims, actor) {
+		return fiber.NewError(fiber.StatusUnauthorized, "Please authenticate")
+	}
+
+	rights, err := userService.GetRights(c, actor)
+	if err != nil {
+		return err
+	}
+
+	if !policy.HasAllRights(rights, []string{"impersonateUsers"}) {
+		return fiber.NewError(fiber.StatusUnauthorized, "Please authenticate")
+	}
+
+	jti, _ := claims["jti"].(string)
+	organizationID, _ := claims["org"].(string)
+
+	c.Locals("actor", actor)
+	c.Locals("impersonation_id", jti)
+	c.Locals("impersonation_organization_id", organizationID)
+
+	return nil
+}
+
+func authenticateAPIKey(
+	c *fiber.Ctx, userService service.UserService, tokenService service.TokenService, key string,
+) (*model.User, error) {
+	apiKey, err := tokenService.VerifyAPIKey(c, key)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Please authenticate")
+	}
+
+	user, err := userService.GetUserByID(c, apiKey.UserID.String())
+	if err != nil || user == nil {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Please authenticate")
+	}
+
+	c.Locals("api_key", apiKey)
+
+	return user, nil
+}
+
+// Scopes requires the scopes of an API key or OAuth token to hold the rights. It guards the routes that only
+// reach the user's own resources, a session of the user passes, a key or client needs to be granted the right.
+func Scopes(userService service.UserService, rights ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if service.ActiveAPIKey(c) == nil && service.ActiveOAuthAccess(c) == nil {
+			return c.Next()
+		}
+
+		user, _ := c.Locals("user").(*model.User)
+		if user == nil {
+			return fiber.NewError(fiber.StatusUnauthorized, "Please authenticate")
+		}
+
+		userRights, err := userService.GetRights(c, user)
+		if err != nil {
+			return err
+		}
+
+		if !policy.HasAllRights(userRights, rights) {
+			return errForbidden
+		}
+
+		return c.Next()
+	}
+}
+
+// NoImpersonation rejects requests made by an admin impersonating the user. It guards the routes that change
+// how the user logs in, the impersonated user keeps control over its credentials.
+func NoImpersonation(c *fiber.Ctx) error {
+	if service.Actor(c) != nil {
+		return fiber.NewError(fiber.StatusForbidden, "This route can not be used while impersonating")
+	}
+
+	return c.Next()
+}
+
+// NoOAuthClient rejects requests made with a token issued to an OAuth client. Like NoAPIKey it guards the
+// routes that change how the user logs in or that issue tokens, a third-party app only gets its scopes.
+func NoOAuthClient(c *fiber.Ctx) error {
+	if service.ActiveOAuthAccess(c) != nil {
+		return fiber.NewError(fiber.StatusForbidden, "This route can not be used by an OAuth client")
+	}
+
+	return c.Next()
+}
+
+// NoAPIKey rejects requests authenticated with an API key. It guards the routes that change how the user
+// logs in or that issue tokens, so a leaked key can not be turned into a full login.
+func NoAPIKey(c *fiber.Ctx) error {
+	if service.ActiveAPIKey(c) != nil {
+		return fiber.NewError(fiber.StatusForbidden, "This route can not be used with an API key")
+	}
+
+	return c.Next()
 }
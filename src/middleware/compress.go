@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/compress"
+)
+
+// CompressConfig skips file downloads, compressing them would break Content-Length and byte ranges
+func CompressConfig() fiber.Handler {
+	return compress.New(compress.Config{
+		Next: func(c *fiber.Ctx) bool {
+			path := c.Path()
+			return strings.HasPrefix(path, "/v1/files/") &&
+				(strings.HasSuffix(path, "/download") || strings.HasPrefix(path, "/v1/files/signed/"))
+		},
+	})
+}
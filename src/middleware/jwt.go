@@ -9,6 +9,6 @@ import (
 
 func JwtConfig() fiber.Handler {
 	return jwtware.New(jwtware.Config{
-		SigningKey: jwtware.SigningKey{Key: []byte(config.JWTSecret)},
+		KeyFunc: config.JWTKeySet.Keyfunc,
 	})
 }
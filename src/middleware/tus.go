@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// TusVersion is the only version of the tus protocol the upload routes speak
+const TusVersion = "1.0.0"
+
+// TusResumable answers every upload request with the Tus-Resumable header and rejects requests
+// of another protocol version, OPTIONS is exempt so clients can discover the supported version
+func TusResumable() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Set("Tus-Resumable", TusVersion)
+
+		if c.Method() != fiber.MethodOptions && c.Get("Tus-Resumable") != TusVersion {
+			c.Set("Tus-Version", TusVersion)
+			return fiber.NewError(fiber.StatusPreconditionFailed, "Unsupported Tus-Resumable version")
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// APIKey is a long-lived credential of a user for scripts and CI jobs. Only the hash of the key is stored,
+// Prefix keeps its first characters so the user can tell the keys apart. Scopes is a comma separated list
+// of the rights the key may use.
+type APIKey struct {
+	ID         uuid.UUID  `gorm:"primaryKey;not null"`
+	UserID     uuid.UUID  `gorm:"not null"`
+	Name       string     `gorm:"not null"`
+	Prefix     string     `gorm:"not null"`
+	Key        string     `gorm:"not null"`
+	Scopes     string     `gorm:"default:'';not null"`
+	ExpiresAt  *time.Time `gorm:"default:null"`
+	LastUsedAt *time.Time `gorm:"default:null"`
+	LastUsedIP string     `gorm:"default:'';not null"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime:milli"`
+	UpdatedAt  time.Time  `gorm:"autoCreateTime:milli;autoUpdateTime:milli"`
+	User       *User      `gorm:"foreignKey:user_id;references:id"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+func (apiKey *APIKey) BeforeCreate(_ *gorm.DB) error {
+	apiKey.ID = uuid.New()
+	return nil
+}
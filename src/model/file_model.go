@@ -7,21 +7,33 @@ import (
 	"gorm.io/gorm"
 )
 
-// File model untuk menyimpan informasi file
+const (
+	FileProcessingPending    = "pending"
+	FileProcessingProcessing = "processing"
+	FileProcessingReady      = "ready"
+	FileProcessingFailed     = "failed"
+)
+
+// File model untuk menyimpan informasi file.
+// ProcessingStatus hanya diisi untuk gambar yang variant-nya dibuat oleh ImageService.
 type File struct {
-	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
-	FileName    string     `json:"file_name" gorm:"not null"`
-	FilePath    string     `json:"file_path" gorm:"not null;unique"`
-	FileSize    int64      `json:"file_size" gorm:"not null"`
-	FileURL     string     `json:"file_url" gorm:"not null"`
-	ContentType string     `json:"content_type"`
-	Folder      string     `json:"folder" gorm:"not null"`
-	UploadedBy  *uuid.UUID `json:"uploaded_by" gorm:"type:uuid"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+	ID               uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	FileName         string     `json:"file_name" gorm:"not null"`
+	OriginalName     string     `json:"original_name"`
+	FilePath         string     `json:"file_path" gorm:"not null;unique"`
+	FileSize         int64      `json:"file_size" gorm:"not null"`
+	FileURL          string     `json:"file_url" gorm:"not null"`
+	ContentType      string     `json:"content_type"`
+	Folder           string     `json:"folder" gorm:"not null"`
+	UploadedBy       *uuid.UUID `json:"uploaded_by" gorm:"type:uuid"`
+	OrganizationID   *uuid.UUID `json:"organization_id" gorm:"type:uuid"`
+	ProcessingStatus string     `json:"processing_status,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
 
 	// Relations
-	User *User `json:"user,omitempty" gorm:"foreignKey:UploadedBy;references:ID"`
+	User     *User         `json:"user,omitempty" gorm:"foreignKey:UploadedBy;references:ID"`
+	Variants []FileVariant `json:"variants,omitempty" gorm:"foreignKey:FileID"`
 }
 
 // TableName menentukan nama tabel untuk model File
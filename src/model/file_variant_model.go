@@ -0,0 +1,36 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// FileVariant gambar turunan dari sebuah file (thumbnail, ukuran atau format lain) yang disimpan di samping aslinya
+type FileVariant struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	FileID      uuid.UUID `json:"file_id" gorm:"type:uuid;not null"`
+	Name        string    `json:"name" gorm:"not null"`
+	FilePath    string    `json:"file_path" gorm:"not null;unique"`
+	FileURL     string    `json:"file_url" gorm:"-"`
+	FileSize    int64     `json:"file_size" gorm:"not null"`
+	ContentType string    `json:"content_type"`
+	Width       int       `json:"width" gorm:"not null"`
+	Height      int       `json:"height" gorm:"not null"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName menentukan nama tabel untuk model FileVariant
+func (FileVariant) TableName() string {
+	return "file_variants"
+}
+
+// BeforeCreate hook yang dijalankan sebelum record dibuat
+func (v *FileVariant) BeforeCreate(tx *gorm.DB) error {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	return nil
+}
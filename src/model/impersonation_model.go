@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Impersonation records an admin (the actor) acting as another user. Its id is the jti of the impersonation
+// token, EndedAt is set when the actor stops before the token expires. The record outlives both users.
+// OrganizationID is the only organization the impersonation can act in, none when it is nil.
+type Impersonation struct {
+	ID             uuid.UUID  `gorm:"primaryKey;not null" json:"id"`
+	ActorID        *uuid.UUID `gorm:"type:uuid" json:"actor_id"`
+	UserID         *uuid.UUID `gorm:"type:uuid" json:"user_id"`
+	OrganizationID *uuid.UUID `gorm:"type:uuid" json:"organization_id"`
+	Reason         string     `gorm:"not null" json:"reason"`
+	IPAddress      string     `gorm:"default:'';not null" json:"ip_address"`
+	UserAgent      string     `gorm:"default:'';not null" json:"user_agent"`
+	ExpiresAt      time.Time  `gorm:"not null" json:"expires_at"`
+	EndedAt        *time.Time `gorm:"default:null" json:"ended_at"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime:milli" json:"created_at"`
+}
+
+func (impersonation *Impersonation) BeforeCreate(_ *gorm.DB) error {
+	impersonation.ID = uuid.New()
+	return nil
+}
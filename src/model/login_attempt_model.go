@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LoginAttempt is a failed password login, counted per email and per ip to throttle credential stuffing.
+// Emails that do not belong to any user are recorded too, so throttling does not reveal which accounts exist.
+type LoginAttempt struct {
+	ID        uuid.UUID `gorm:"primaryKey;not null"`
+	Email     string    `gorm:"not null"`
+	IP        string    `gorm:"column:ip;not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime:milli"`
+}
+
+func (attempt *LoginAttempt) BeforeCreate(_ *gorm.DB) error {
+	attempt.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OAuthClient is a third-party app that obtains tokens from our authorization server. Only the hash of the
+// secret is stored, public clients (e.g. mobile apps) have none and must use PKCE. RedirectURIs, Scopes and
+// GrantTypes are comma separated lists. Tokens of the client_credentials grant act as the user who registered
+// the client, limited to its scopes.
+type OAuthClient struct {
+	ID           uuid.UUID `gorm:"primaryKey;not null"`
+	UserID       uuid.UUID `gorm:"not null"`
+	Name         string    `gorm:"not null"`
+	Secret       string    `gorm:"default:'';not null"`
+	RedirectURIs string    `gorm:"column:redirect_uris;default:'';not null"`
+	Scopes       string    `gorm:"default:'';not null"`
+	GrantTypes   string    `gorm:"default:'';not null"`
+	CreatedAt    time.Time `gorm:"autoCreateTime:milli"`
+	UpdatedAt    time.Time `gorm:"autoCreateTime:milli;autoUpdateTime:milli"`
+	User         *User     `gorm:"foreignKey:user_id;references:id"`
+}
+
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+func (client *OAuthClient) BeforeCreate(_ *gorm.DB) error {
+	client.ID = uuid.New()
+	return nil
+}
+
+// Confidential reports whether the client authenticates with a secret
+func (client *OAuthClient) Confidential() bool {
+	return client.Secret != ""
+}
+
+// OAuthAuthorizationCode is issued when a user consents to a client and exchanged once for an access token.
+// Only the hash of the code is stored together with the PKCE challenge it must be redeemed with.
+type OAuthAuthorizationCode struct {
+	ID            uuid.UUID `gorm:"primaryKey;not null"`
+	Code          string    `gorm:"not null"`
+	ClientID      uuid.UUID `gorm:"not null"`
+	UserID        uuid.UUID `gorm:"not null"`
+	RedirectURI   string    `gorm:"not null"`
+	Scopes        string    `gorm:"default:'';not null"`
+	CodeChallenge string    `gorm:"not null"`
+	ExpiresAt     time.Time `gorm:"not null"`
+	CreatedAt     time.Time `gorm:"autoCreateTime:milli"`
+}
+
+func (OAuthAuthorizationCode) TableName() string {
+	return "oauth_authorization_codes"
+}
+
+func (code *OAuthAuthorizationCode) BeforeCreate(_ *gorm.DB) error {
+	code.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OAuthState is a pending provider login, the id is the hash of the state sent to the provider.
+// UserID is set when a logged in user links the provider instead of logging in with it.
+type OAuthState struct {
+	ID           string     `gorm:"primaryKey;not null"`
+	Provider     string     `gorm:"not null"`
+	Nonce        string     `gorm:"not null"`
+	CodeVerifier string     `gorm:"not null"`
+	UserID       *uuid.UUID `gorm:"type:uuid"`
+	ExpiresAt    time.Time  `gorm:"not null"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime:milli"`
+}
+
+func (OAuthState) TableName() string {
+	return "oauth_states"
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Organization struct {
+	ID        uuid.UUID `gorm:"primaryKey;not null" json:"id"`
+	Name      string    `gorm:"not null" json:"name"`
+	CreatedAt time.Time `gorm:"autoCreateTime:milli" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoCreateTime:milli;autoUpdateTime:milli" json:"updated_at"`
+}
+
+func (organization *Organization) BeforeCreate(_ *gorm.DB) error {
+	organization.ID = uuid.New()
+	return nil
+}
+
+// Membership puts a user in an organization, its role is one of config.OrganizationRoles
+type Membership struct {
+	ID             uuid.UUID     `gorm:"primaryKey;not null" json:"id"`
+	OrganizationID uuid.UUID     `gorm:"not null" json:"organization_id"`
+	UserID         uuid.UUID     `gorm:"not null" json:"user_id"`
+	Role           string        `gorm:"not null" json:"role"`
+	CreatedAt      time.Time     `gorm:"autoCreateTime:milli" json:"created_at"`
+	UpdatedAt      time.Time     `gorm:"autoCreateTime:milli;autoUpdateTime:milli" json:"updated_at"`
+	Organization   *Organization `gorm:"foreignKey:organization_id;references:id" json:"organization,omitempty"`
+	User           *User         `gorm:"foreignKey:user_id;references:id" json:"user,omitempty"`
+}
+
+func (membership *Membership) BeforeCreate(_ *gorm.DB) error {
+	membership.ID = uuid.New()
+	return nil
+}
+
+// Invitation is sent by email, only the hash of its token is stored
+type Invitation struct {
+	ID             uuid.UUID  `gorm:"primaryKey;not null" json:"id"`
+	OrganizationID uuid.UUID  `gorm:"not null" json:"organization_id"`
+	Email          string     `gorm:"not null" json:"email"`
+	Role           string     `gorm:"not null" json:"role"`
+	Token          string     `gorm:"not null" json:"-"`
+	InvitedBy      *uuid.UUID `gorm:"type:uuid" json:"invited_by"`
+	ExpiresAt      time.Time  `gorm:"not null" json:"expires_at"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime:milli" json:"created_at"`
+}
+
+func (invitation *Invitation) BeforeCreate(_ *gorm.DB) error {
+	invitation.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PasswordHistory keeps the hash of a password the user had before, so it can not be used again
+type PasswordHistory struct {
+	ID        uuid.UUID `gorm:"primaryKey;not null"`
+	UserID    uuid.UUID `gorm:"not null"`
+	Password  string    `gorm:"not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime:milli"`
+	User      *User     `gorm:"foreignKey:user_id;references:id"`
+}
+
+func (history *PasswordHistory) BeforeCreate(_ *gorm.DB) error {
+	history.ID = uuid.New()
+	return nil
+}
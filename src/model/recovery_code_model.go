@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type RecoveryCode struct {
+	ID        uuid.UUID  `gorm:"primaryKey;not null"`
+	UserID    uuid.UUID  `gorm:"not null"`
+	CodeHash  string     `gorm:"not null"`
+	UsedAt    *time.Time `gorm:"default:null"`
+	CreatedAt time.Time  `gorm:"autoCreateTime:milli"`
+	User      *User      `gorm:"foreignKey:user_id;references:id"`
+}
+
+func (code *RecoveryCode) BeforeCreate(_ *gorm.DB) error {
+	code.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// RevokedToken is a denylist entry, the id is either the jti of an access token
+// or the session id (sid claim) shared by every access token of a session
+type RevokedToken struct {
+	ID        string    `gorm:"primaryKey;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime:milli"`
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Role is referenced by name from users.role, its permissions are the rights checked by middleware.Auth
+type Role struct {
+	ID          uuid.UUID    `gorm:"primaryKey;not null" json:"id"`
+	Name        string       `gorm:"uniqueIndex;not null" json:"name"`
+	Description string       `gorm:"default:'';not null" json:"description"`
+	Permissions []Permission `gorm:"many2many:role_permissions" json:"permissions"`
+	CreatedAt   time.Time    `gorm:"autoCreateTime:milli" json:"created_at"`
+	UpdatedAt   time.Time    `gorm:"autoCreateTime:milli;autoUpdateTime:milli" json:"updated_at"`
+}
+
+func (role *Role) BeforeCreate(_ *gorm.DB) error {
+	role.ID = uuid.New()
+	return nil
+}
+
+// Rights returns the names of the permissions of the role
+func (role *Role) Rights() []string {
+	rights := make([]string, 0, len(role.Permissions))
+	for _, permission := range role.Permissions {
+		rights = append(rights, permission.Name)
+	}
+
+	return rights
+}
+
+type Permission struct {
+	ID          uuid.UUID `gorm:"primaryKey;not null" json:"-"`
+	Name        string    `gorm:"uniqueIndex;not null" json:"name"`
+	Description string    `gorm:"default:'';not null" json:"description"`
+	CreatedAt   time.Time `gorm:"autoCreateTime:milli" json:"-"`
+}
+
+func (permission *Permission) BeforeCreate(_ *gorm.DB) error {
+	permission.ID = uuid.New()
+	return nil
+}
+
+type RolePermission struct {
+	RoleID       uuid.UUID `gorm:"primaryKey"`
+	PermissionID uuid.UUID `gorm:"primaryKey"`
+}
@@ -8,17 +8,37 @@ import (
 )
 
 type Token struct {
-	ID        uuid.UUID `gorm:"primaryKey;not null"`
-	Token     string    `gorm:"not null"`
-	UserID    uuid.UUID `gorm:"not null"`
-	Type      string    `gorm:"not null"`
-	Expires   time.Time `gorm:"not null"`
-	CreatedAt time.Time `gorm:"autoCreateTime:milli"`
-	UpdatedAt time.Time `gorm:"autoCreateTime:milli;autoUpdateTime:milli"`
-	User      *User     `gorm:"foreignKey:user_id;references:id"`
+	ID         uuid.UUID  `gorm:"primaryKey;not null"`
+	Token      string     `gorm:"not null"`
+	UserID     uuid.UUID  `gorm:"not null"`
+	Type       string     `gorm:"not null"`
+	Expires    time.Time  `gorm:"not null"`
+	FamilyID   *uuid.UUID `gorm:"type:uuid"`
+	ParentID   *uuid.UUID `gorm:"type:uuid"`
+	RevokedAt  *time.Time `gorm:"default:null"`
+	UserAgent  string     `gorm:"default:'';not null"`
+	IPAddress  string     `gorm:"default:'';not null"`
+	LastUsedAt *time.Time `gorm:"default:null"`
+	Attempts   int        `gorm:"default:0;not null"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime:milli"`
+	UpdatedAt  time.Time  `gorm:"autoCreateTime:milli;autoUpdateTime:milli"`
+	User       *User      `gorm:"foreignKey:user_id;references:id"`
 }
 
 func (token *Token) BeforeCreate(_ *gorm.DB) error {
 	token.ID = uuid.New()
+
+	// The first token of a login starts a new family
+	if token.FamilyID == nil {
+		token.FamilyID = &token.ID
+	}
 	return nil
 }
+
+// Family returns the id shared by every token rotated from the same login
+func (token *Token) Family() uuid.UUID {
+	if token.FamilyID != nil {
+		return *token.FamilyID
+	}
+	return token.ID
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Upload model untuk upload resumable (tus) dan upload langsung ke storage melalui presigned URL,
+// yang belum maupun sudah selesai.
+// MultipartID, Parts dan Buffered hanya dipakai oleh MinIO multipart upload.
+type Upload struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	FileName       string     `json:"file_name" gorm:"not null"`
+	OriginalName   string     `json:"original_name"`
+	FilePath       string     `json:"file_path" gorm:"not null;unique"`
+	ContentType    string     `json:"content_type"`
+	Folder         string     `json:"folder" gorm:"not null"`
+	Size           int64      `json:"size" gorm:"not null"`
+	Offset         int64      `json:"offset" gorm:"not null"`
+	Presigned      bool       `json:"presigned" gorm:"not null"`
+	MultipartID    string     `json:"-"`
+	Parts          int        `json:"-" gorm:"not null"`
+	Buffered       int64      `json:"-" gorm:"not null"`
+	UploadedBy     *uuid.UUID `json:"uploaded_by" gorm:"type:uuid"`
+	OrganizationID *uuid.UUID `json:"organization_id" gorm:"type:uuid"`
+	FileID         *uuid.UUID `json:"file_id" gorm:"type:uuid"`
+	ExpiresAt      time.Time  `json:"expires_at" gorm:"not null"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// TableName menentukan nama tabel untuk model Upload
+func (Upload) TableName() string {
+	return "uploads"
+}
+
+// BeforeCreate hook yang dijalankan sebelum record dibuat
+func (u *Upload) BeforeCreate(tx *gorm.DB) error {
+	if u.ID == uuid.Nil {
+		u.ID = uuid.New()
+	}
+	return nil
+}
+
+// Completed menandakan semua byte sudah diterima dan record File sudah dibuat
+func (u *Upload) Completed() bool {
+	return u.FileID != nil
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UserIdentity links a user to an account at an external login provider, identified by the provider's subject
+type UserIdentity struct {
+	ID        uuid.UUID `gorm:"primaryKey;not null" json:"id"`
+	UserID    uuid.UUID `gorm:"not null" json:"-"`
+	Provider  string    `gorm:"not null" json:"provider"`
+	Subject   string    `gorm:"not null" json:"-"`
+	Email     string    `gorm:"default:'';not null" json:"email"`
+	LinkedAt  time.Time `gorm:"autoCreateTime:milli" json:"linked_at"`
+	UpdatedAt time.Time `gorm:"autoCreateTime:milli;autoUpdateTime:milli" json:"-"`
+	User      *User     `gorm:"foreignKey:user_id;references:id" json:"-"`
+}
+
+func (identity *UserIdentity) BeforeCreate(_ *gorm.DB) error {
+	identity.ID = uuid.New()
+	return nil
+}
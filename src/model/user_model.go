@@ -8,18 +8,40 @@ import (
 )
 
 type User struct {
-	ID            uuid.UUID `gorm:"primaryKey;not null" json:"id"`
-	Name          string    `gorm:"not null" json:"name"`
-	Email         string    `gorm:"uniqueIndex;not null" json:"email"`
-	Password      string    `gorm:"not null" json:"-"`
-	Role          string    `gorm:"default:user;not null" json:"role"`
-	VerifiedEmail bool      `gorm:"default:false;not null" json:"verified_email"`
-	CreatedAt     time.Time `gorm:"autoCreateTime:milli" json:"-"`
-	UpdatedAt     time.Time `gorm:"autoCreateTime:milli;autoUpdateTime:milli" json:"-"`
-	Token         []Token   `gorm:"foreignKey:user_id;references:id" json:"-"`
+	ID                uuid.UUID            `gorm:"primaryKey;not null" json:"id"`
+	Name              string               `gorm:"not null" json:"name"`
+	Email             string               `gorm:"uniqueIndex;not null" json:"email"`
+	PendingEmail      *string              `gorm:"default:null" json:"pending_email,omitempty"`
+	Password          string               `gorm:"not null" json:"-"`
+	Role              string               `gorm:"default:user;not null" json:"role"`
+	VerifiedEmail     bool                 `gorm:"default:false;not null" json:"verified_email"`
+	TwoFactorEnabled  bool                 `gorm:"default:false;not null" json:"two_factor_enabled"`
+	LocalLogin        bool                 `gorm:"-" json:"local_login"`
+	TwoFactorSecret   string               `gorm:"default:'';not null" json:"-"`
+	TwoFactorLastStep int64                `gorm:"default:0;not null" json:"-"`
+	TokensValidAfter  *time.Time           `gorm:"default:null" json:"-"`
+	PasswordChangedAt *time.Time           `gorm:"default:null" json:"-"`
+	LockedUntil       *time.Time           `gorm:"default:null" json:"locked_until,omitempty"`
+	CreatedAt         time.Time            `gorm:"autoCreateTime:milli" json:"-"`
+	UpdatedAt         time.Time            `gorm:"autoCreateTime:milli;autoUpdateTime:milli" json:"-"`
+	Token             []Token              `gorm:"foreignKey:user_id;references:id" json:"-"`
+	RecoveryCodes     []RecoveryCode       `gorm:"foreignKey:user_id;references:id" json:"-"`
+	Identities        []UserIdentity       `gorm:"foreignKey:user_id;references:id" json:"-"`
+	Passkeys          []WebAuthnCredential `gorm:"foreignKey:user_id;references:id" json:"-"`
 }
 
 func (user *User) BeforeCreate(_ *gorm.DB) error {
 	user.ID = uuid.New() // Generate UUID before create
 	return nil
 }
+
+// Users signing up with an external provider have no local password until they set one
+func (user *User) AfterFind(_ *gorm.DB) error {
+	user.LocalLogin = user.Password != ""
+	return nil
+}
+
+func (user *User) AfterSave(_ *gorm.DB) error {
+	user.LocalLogin = user.Password != ""
+	return nil
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WebAuthnCredential is a passkey registered by a user. SignCount is the last counter reported by the
+// authenticator, a counter that does not increase means the key may have been cloned.
+type WebAuthnCredential struct {
+	ID              uuid.UUID  `gorm:"primaryKey;not null" json:"id"`
+	UserID          uuid.UUID  `gorm:"not null" json:"-"`
+	CredentialID    []byte     `gorm:"not null" json:"-"`
+	PublicKey       []byte     `gorm:"not null" json:"-"`
+	AttestationType string     `gorm:"default:'';not null" json:"-"`
+	AAGUID          []byte     `gorm:"column:aaguid" json:"-"`
+	Transports      string     `gorm:"default:'';not null" json:"-"`
+	SignCount       uint32     `gorm:"default:0;not null" json:"-"`
+	BackupEligible  bool       `gorm:"default:false;not null" json:"backup_eligible"`
+	BackupState     bool       `gorm:"default:false;not null" json:"backup_state"`
+	CloneWarning    bool       `gorm:"default:false;not null" json:"clone_warning"`
+	Name            string     `gorm:"default:'';not null" json:"name"`
+	LastUsedAt      *time.Time `gorm:"default:null" json:"last_used_at"`
+	CreatedAt       time.Time  `gorm:"autoCreateTime:milli" json:"created_at"`
+	UpdatedAt       time.Time  `gorm:"autoCreateTime:milli;autoUpdateTime:milli" json:"-"`
+	User            *User      `gorm:"foreignKey:user_id;references:id" json:"-"`
+}
+
+func (WebAuthnCredential) TableName() string {
+	return "webauthn_credentials"
+}
+
+func (credential *WebAuthnCredential) BeforeCreate(_ *gorm.DB) error {
+	credential.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebAuthnSession is a pending passkey registration or login, the id is the hash of the session id
+// handed to the client and Data holds the challenge the authenticator has to sign
+type WebAuthnSession struct {
+	ID        string     `gorm:"primaryKey;not null"`
+	Ceremony  string     `gorm:"not null"`
+	UserID    *uuid.UUID `gorm:"type:uuid"`
+	Data      string     `gorm:"not null"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	CreatedAt time.Time  `gorm:"autoCreateTime:milli"`
+}
+
+func (WebAuthnSession) TableName() string {
+	return "webauthn_sessions"
+}
@@ -0,0 +1,66 @@
+// Package policy decides whether a user may act on a resource. A route declares a Rule: the rights that
+// grant access to every resource of a type and whether the owner of the addressed resource is let in
+// without them. Resources know how to find their owner from the request.
+package policy
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// OwnerResolver returns the id of the user owning the resource addressed by the request, uuid.Nil when the
+// resource has no owner. Errors are returned to the client as they are, so fiber errors like 404 pass through.
+type OwnerResolver func(c *fiber.Ctx) (uuid.UUID, error)
+
+// Resource is a type of resource and the way to find the owner of one of them. NotFound, when set, is
+// returned instead of 403 to users who may not act on the resource, so they can not tell whether it exists.
+type Resource struct {
+	Type     string
+	Owner    OwnerResolver
+	NotFound error
+}
+
+// Rule grants access to users having all of Rights, and to the owner of the resource when AllowOwner is set
+type Rule struct {
+	Resource   Resource
+	Rights     []string
+	AllowOwner bool
+}
+
+// OwnerOr is the usual rule: the owner may act on its own resource, users with the rights on any of them
+func OwnerOr(resource Resource, rights ...string) Rule {
+	return Rule{Resource: resource, Rights: rights, AllowOwner: true}
+}
+
+// Allows reports whether the user with the given rights may act on the resource addressed by the request.
+// The owner is only resolved when the rights are not enough, so privileged users skip the lookup.
+func (r Rule) Allows(c *fiber.Ctx, userID uuid.UUID, rights []string) (bool, error) {
+	if len(r.Rights) > 0 && HasAllRights(rights, r.Rights) {
+		return true, nil
+	}
+
+	if !r.AllowOwner || r.Resource.Owner == nil {
+		return false, nil
+	}
+
+	owner, err := r.Resource.Owner(c)
+	if err != nil {
+		return false, err
+	}
+
+	return owner != uuid.Nil && owner == userID, nil
+}
+
+func HasAllRights(userRights, requiredRights []string) bool {
+	rightSet := make(map[string]struct{}, len(userRights))
+	for _, right := range userRights {
+		rightSet[right] = struct{}{}
+	}
+
+	for _, right := range requiredRights {
+		if _, exists := rightSet[right]; !exists {
+			return false
+		}
+	}
+	return true
+}
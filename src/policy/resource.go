@@ -0,0 +1,68 @@
+package policy
+
+import (
+	"app/src/model"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	ResourceUser = "user"
+	ResourceFile = "file"
+)
+
+// User addresses a user by the id in the route param, every user owns its own account.
+// An invalid id has no owner, the handler rejects it afterwards.
+func User(param string) Resource {
+	return Resource{
+		Type: ResourceUser,
+		Owner: func(c *fiber.Ctx) (uuid.UUID, error) {
+			id, err := uuid.Parse(c.Params(param))
+			if err != nil {
+				return uuid.Nil, nil
+			}
+
+			return id, nil
+		},
+	}
+}
+
+// FileFinder looks up the stored record of a file, service.StorageService implements it
+type FileFinder interface {
+	GetFileByID(fileID uuid.UUID) (*model.File, error)
+}
+
+var errFileNotFound = fiber.NewError(fiber.StatusNotFound, "File not found")
+
+// File addresses a file by the id in the route param, files are owned by the user who uploaded them.
+// Files the user may not access are reported as not found, so the files of other users stay hidden.
+func File(files FileFinder, param string) Resource {
+	return Resource{
+		Type:     ResourceFile,
+		NotFound: errFileNotFound,
+		Owner: func(c *fiber.Ctx) (uuid.UUID, error) {
+			id, err := uuid.Parse(c.Params(param))
+			if err != nil {
+				return uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "Invalid file ID")
+			}
+
+			file, err := files.GetFileByID(id)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return uuid.Nil, errFileNotFound
+			}
+
+			if err != nil {
+				return uuid.Nil, err
+			}
+
+			if file.UploadedBy == nil {
+				return uuid.Nil, nil
+			}
+
+			return *file.UploadedBy, nil
+		},
+	}
+}
@@ -17,3 +17,23 @@ type RefreshToken struct {
 	Status string `json:"status"`
 	Tokens Tokens `json:"tokens"`
 }
+
+type MFARequired struct {
+	Code     int          `json:"code"`
+	Status   string       `json:"status"`
+	Message  string       `json:"message"`
+	MFAToken TokenExpires `json:"mfa_token"`
+}
+
+type TwoFactorSetup struct {
+	Secret          string   `json:"secret"`
+	ProvisioningURI string   `json:"provisioning_uri"`
+	RecoveryCodes   []string `json:"recovery_codes"`
+}
+
+type SuccessWithTwoFactorSetup struct {
+	Code      int            `json:"code"`
+	Status    string         `json:"status"`
+	Message   string         `json:"message"`
+	TwoFactor TwoFactorSetup `json:"two_factor"`
+}
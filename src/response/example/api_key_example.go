@@ -0,0 +1,18 @@
+package example
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type APIKey struct {
+	ID         uuid.UUID `json:"id" example:"4e3d2c1b-0a9f-4e8d-b7c6-5a4b3c2d1e0f"`
+	Name       string    `json:"name" example:"CI deploy"`
+	Prefix     string    `json:"prefix" example:"fgk_k3xqj7w2"`
+	Scopes     []string  `json:"scopes" example:"getUsers"`
+	ExpiresAt  time.Time `json:"expires_at" example:"2025-10-07T00:00:00Z"`
+	LastUsedAt time.Time `json:"last_used_at" example:"2024-10-07T11:56:46.618Z"`
+	LastUsedIP string    `json:"last_used_ip" example:"203.0.113.7"`
+	CreatedAt  time.Time `json:"created_at" example:"2024-10-01T08:12:03.512Z"`
+}
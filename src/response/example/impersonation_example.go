@@ -0,0 +1,20 @@
+package example
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Impersonation struct {
+	ID             uuid.UUID `json:"id" example:"2c4e6a8b-1d3f-4a5b-9c7d-8e0f1a2b3c4d"`
+	ActorID        uuid.UUID `json:"actor_id" example:"9a1b2c3d-4e5f-4a6b-8c7d-0e1f2a3b4c5d"`
+	UserID         uuid.UUID `json:"user_id" example:"e088d183-9eea-4a11-8d5d-74d7ec91bdf5"`
+	OrganizationID uuid.UUID `json:"organization_id" example:"5b7d9f1a-3c5e-4f7a-9b1c-3d5e7f9a1b3c"`
+	Reason         string    `json:"reason" example:"Reproducing ticket #1234"`
+	IPAddress      string    `json:"ip_address" example:"203.0.113.7"`
+	UserAgent      string    `json:"user_agent" example:"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36"`
+	ExpiresAt      time.Time `json:"expires_at" example:"2024-10-07T12:11:46.618Z"`
+	EndedAt        time.Time `json:"ended_at" example:"2024-10-07T12:03:12.104Z"`
+	CreatedAt      time.Time `json:"created_at" example:"2024-10-07T11:56:46.618Z"`
+}
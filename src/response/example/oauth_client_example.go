@@ -0,0 +1,24 @@
+package example
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OAuthClient struct {
+	ID           uuid.UUID `json:"id" example:"e088d183-9eea-4a11-8d5d-74d7ec91bdf5"`
+	Name         string    `json:"name" example:"Partner CRM"`
+	Confidential bool      `json:"confidential" example:"true"`
+	RedirectURIs []string  `json:"redirect_uris" example:"https://partner.example.com/callback"`
+	Scopes       []string  `json:"scopes" example:"getUsers"`
+	GrantTypes   []string  `json:"grant_types" example:"authorization_code"`
+	CreatedAt    time.Time `json:"created_at" example:"2024-10-01T08:12:03.512Z"`
+}
+
+type OAuthConsent struct {
+	ClientID    uuid.UUID    `json:"client_id" example:"e088d183-9eea-4a11-8d5d-74d7ec91bdf5"`
+	ClientName  string       `json:"client_name" example:"Partner CRM"`
+	RedirectURI string       `json:"redirect_uri" example:"https://partner.example.com/callback"`
+	Scopes      []Permission `json:"scopes"`
+}
@@ -0,0 +1,44 @@
+package example
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Organization struct {
+	ID        uuid.UUID `json:"id" example:"7c1e4b2a-9d3f-4e5a-8b6c-0d1e2f3a4b5c"`
+	Name      string    `json:"name" example:"Acme Inc"`
+	CreatedAt time.Time `json:"created_at" example:"2024-10-01T08:12:03.512Z"`
+	UpdatedAt time.Time `json:"updated_at" example:"2024-10-07T11:56:46.618Z"`
+}
+
+type Membership struct {
+	ID             uuid.UUID     `json:"id" example:"5a6b7c8d-1e2f-4a3b-9c4d-5e6f7a8b9c0d"`
+	OrganizationID uuid.UUID     `json:"organization_id" example:"7c1e4b2a-9d3f-4e5a-8b6c-0d1e2f3a4b5c"`
+	UserID         uuid.UUID     `json:"user_id" example:"e088d183-9eea-4a11-8d5d-74d7ec91bdf5"`
+	Role           string        `json:"role" example:"owner"`
+	CreatedAt      time.Time     `json:"created_at" example:"2024-10-01T08:12:03.512Z"`
+	UpdatedAt      time.Time     `json:"updated_at" example:"2024-10-07T11:56:46.618Z"`
+	Organization   *Organization `json:"organization,omitempty"`
+}
+
+type Member struct {
+	ID             uuid.UUID `json:"id" example:"5a6b7c8d-1e2f-4a3b-9c4d-5e6f7a8b9c0d"`
+	OrganizationID uuid.UUID `json:"organization_id" example:"7c1e4b2a-9d3f-4e5a-8b6c-0d1e2f3a4b5c"`
+	UserID         uuid.UUID `json:"user_id" example:"e088d183-9eea-4a11-8d5d-74d7ec91bdf5"`
+	Role           string    `json:"role" example:"member"`
+	CreatedAt      time.Time `json:"created_at" example:"2024-10-01T08:12:03.512Z"`
+	UpdatedAt      time.Time `json:"updated_at" example:"2024-10-07T11:56:46.618Z"`
+	User           *User     `json:"user,omitempty"`
+}
+
+type Invitation struct {
+	ID             uuid.UUID `json:"id" example:"9f8e7d6c-5b4a-4c3d-8e2f-1a0b9c8d7e6f"`
+	OrganizationID uuid.UUID `json:"organization_id" example:"7c1e4b2a-9d3f-4e5a-8b6c-0d1e2f3a4b5c"`
+	Email          string    `json:"email" example:"fake@example.com"`
+	Role           string    `json:"role" example:"member"`
+	InvitedBy      uuid.UUID `json:"invited_by" example:"e088d183-9eea-4a11-8d5d-74d7ec91bdf5"`
+	ExpiresAt      time.Time `json:"expires_at" example:"2024-10-10T08:12:03.512Z"`
+	CreatedAt      time.Time `json:"created_at" example:"2024-10-07T08:12:03.512Z"`
+}
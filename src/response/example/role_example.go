@@ -0,0 +1,21 @@
+package example
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Role struct {
+	ID          uuid.UUID    `json:"id" example:"3d9c1b7e-2f4a-4c6d-8e0f-1a2b3c4d5e6f"`
+	Name        string       `json:"name" example:"admin"`
+	Description string       `json:"description" example:"Manages users and roles"`
+	Permissions []Permission `json:"permissions"`
+	CreatedAt   time.Time    `json:"created_at" example:"2024-10-01T08:12:03.512Z"`
+	UpdatedAt   time.Time    `json:"updated_at" example:"2024-10-07T11:56:46.618Z"`
+}
+
+type Permission struct {
+	Name        string `json:"name" example:"manageUsers"`
+	Description string `json:"description" example:"Create, update, delete and unlock users and revoke their sessions"`
+}
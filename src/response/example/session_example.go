@@ -0,0 +1,17 @@
+package example
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Session struct {
+	ID         uuid.UUID `json:"id" example:"0b5ad2a6-0c4f-4a8a-9f58-0f2b1c5d8e11"`
+	UserAgent  string    `json:"user_agent" example:"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36"`
+	IPAddress  string    `json:"ip_address" example:"203.0.113.7"`
+	CreatedAt  time.Time `json:"created_at" example:"2024-10-01T08:12:03.512Z"`
+	LastUsedAt time.Time `json:"last_used_at" example:"2024-10-07T11:56:46.618Z"`
+	ExpiresAt  time.Time `json:"expires_at" example:"2024-11-06T11:56:46.618Z"`
+	Current    bool      `json:"current" example:"true"`
+}
@@ -1,19 +1,44 @@
 package example
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type User struct {
-	ID            uuid.UUID `json:"id" example:"e088d183-9eea-4a11-8d5d-74d7ec91bdf5"`
-	Name          string    `json:"name" example:"fake name"`
-	Email         string    `json:"email" example:"fake@example.com"`
-	Role          string    `json:"role" example:"user"`
-	VerifiedEmail bool      `json:"verified_email" example:"false"`
+	ID               uuid.UUID `json:"id" example:"e088d183-9eea-4a11-8d5d-74d7ec91bdf5"`
+	Name             string    `json:"name" example:"fake name"`
+	Email            string    `json:"email" example:"fake@example.com"`
+	Role             string    `json:"role" example:"user"`
+	VerifiedEmail    bool      `json:"verified_email" example:"false"`
+	TwoFactorEnabled bool      `json:"two_factor_enabled" example:"false"`
+	LocalLogin       bool      `json:"local_login" example:"true"`
+}
+
+type OAuthUser struct {
+	ID               uuid.UUID `json:"id" example:"e088d183-9eea-4a11-8d5d-74d7ec91bdf5"`
+	Name             string    `json:"name" example:"fake name"`
+	Email            string    `json:"email" example:"fake@example.com"`
+	Role             string    `json:"role" example:"user"`
+	VerifiedEmail    bool      `json:"verified_email" example:"true"`
+	TwoFactorEnabled bool      `json:"two_factor_enabled" example:"false"`
+	LocalLogin       bool      `json:"local_login" example:"false"`
+}
+
+type Identity struct {
+	ID       uuid.UUID `json:"id" example:"6f1c2f0e-8a5b-4c1e-9d7a-2b3c4d5e6f70"`
+	Provider string    `json:"provider" example:"google"`
+	Email    string    `json:"email" example:"fake@example.com"`
+	LinkedAt time.Time `json:"linked_at" example:"2024-10-01T08:12:03.512Z"`
 }
 
-type GoogleUser struct {
-	ID            uuid.UUID `json:"id" example:"e088d183-9eea-4a11-8d5d-74d7ec91bdf5"`
-	Name          string    `json:"name" example:"fake name"`
-	Email         string    `json:"email" example:"fake@example.com"`
-	Role          string    `json:"role" example:"user"`
-	VerifiedEmail bool      `json:"verified_email" example:"true"`
+type Passkey struct {
+	ID             uuid.UUID  `json:"id" example:"c6b1d3f2-4a5e-4f6b-9c7d-8e9f0a1b2c3d"`
+	Name           string     `json:"name" example:"MacBook Touch ID"`
+	BackupEligible bool       `json:"backup_eligible" example:"true"`
+	BackupState    bool       `json:"backup_state" example:"true"`
+	CloneWarning   bool       `json:"clone_warning" example:"false"`
+	LastUsedAt     *time.Time `json:"last_used_at" example:"2024-10-06T10:12:34.567Z"`
+	CreatedAt      time.Time  `json:"created_at" example:"2024-10-06T10:12:34.567Z"`
 }
@@ -0,0 +1,20 @@
+package response
+
+import (
+	"app/src/model"
+	"time"
+)
+
+// PresignedURL adalah request yang dapat dikirim langsung ke storage sampai ExpiresAt.
+// Headers wajib dikirim persis seperti yang tertulis karena ikut ditandatangani.
+type PresignedURL struct {
+	URL       string            `json:"url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+type PresignedUpload struct {
+	Upload *model.Upload `json:"upload"`
+	PresignedURL
+}
@@ -0,0 +1,24 @@
+package response
+
+import "app/src/model"
+
+type SuccessWithIdentity struct {
+	Code     int                `json:"code"`
+	Status   string             `json:"status"`
+	Message  string             `json:"message"`
+	Identity model.UserIdentity `json:"identity"`
+}
+
+type SuccessWithIdentities struct {
+	Code       int                  `json:"code"`
+	Status     string               `json:"status"`
+	Message    string               `json:"message"`
+	Identities []model.UserIdentity `json:"identities"`
+}
+
+type OAuthRedirect struct {
+	Code    int    `json:"code"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	URL     string `json:"url"`
+}
@@ -0,0 +1,11 @@
+package response
+
+import "app/src/model"
+
+type SuccessWithImpersonation struct {
+	Code          int                 `json:"code"`
+	Status        string              `json:"status"`
+	Message       string              `json:"message"`
+	Impersonation model.Impersonation `json:"impersonation"`
+	Access        TokenExpires        `json:"access"`
+}
@@ -0,0 +1,72 @@
+package response
+
+import (
+	"app/src/model"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OAuthClient struct {
+	ID           uuid.UUID `json:"id"`
+	Name         string    `json:"name"`
+	Confidential bool      `json:"confidential"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	GrantTypes   []string  `json:"grant_types"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type SuccessWithOAuthClient struct {
+	Code         int         `json:"code"`
+	Status       string      `json:"status"`
+	Message      string      `json:"message"`
+	ClientSecret string      `json:"client_secret,omitempty"`
+	Client       OAuthClient `json:"client"`
+}
+
+type SuccessWithOAuthClients struct {
+	Code    int           `json:"code"`
+	Status  string        `json:"status"`
+	Message string        `json:"message"`
+	Clients []OAuthClient `json:"clients"`
+}
+
+// OAuthConsent is what the user is asked to agree to before a client gets a token
+type OAuthConsent struct {
+	ClientID    uuid.UUID          `json:"client_id"`
+	ClientName  string             `json:"client_name"`
+	RedirectURI string             `json:"redirect_uri"`
+	Scopes      []model.Permission `json:"scopes"`
+}
+
+type SuccessWithOAuthConsent struct {
+	Code    int          `json:"code"`
+	Status  string       `json:"status"`
+	Message string       `json:"message"`
+	Consent OAuthConsent `json:"consent"`
+}
+
+// OAuthToken and the types below are the bodies defined by RFC 6749, 7662 and 7009 so clients can use
+// their usual OAuth2 libraries
+type OAuthToken struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+type OAuthIntrospection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+}
+
+type OAuthError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
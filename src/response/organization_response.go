@@ -0,0 +1,45 @@
+package response
+
+import "app/src/model"
+
+type SuccessWithOrganization struct {
+	Code         int                `json:"code"`
+	Status       string             `json:"status"`
+	Message      string             `json:"message"`
+	Organization model.Organization `json:"organization"`
+}
+
+type SuccessWithMembership struct {
+	Code       int              `json:"code"`
+	Status     string           `json:"status"`
+	Message    string           `json:"message"`
+	Membership model.Membership `json:"membership"`
+}
+
+type SuccessWithMemberships struct {
+	Code        int                `json:"code"`
+	Status      string             `json:"status"`
+	Message     string             `json:"message"`
+	Memberships []model.Membership `json:"memberships"`
+}
+
+type SuccessWithInvitation struct {
+	Code       int              `json:"code"`
+	Status     string           `json:"status"`
+	Message    string           `json:"message"`
+	Invitation model.Invitation `json:"invitation"`
+}
+
+type SuccessWithInvitations struct {
+	Code        int                `json:"code"`
+	Status      string             `json:"status"`
+	Message     string             `json:"message"`
+	Invitations []model.Invitation `json:"invitations"`
+}
+
+type SuccessWithAccessToken struct {
+	Code    int          `json:"code"`
+	Status  string       `json:"status"`
+	Message string       `json:"message"`
+	Access  TokenExpires `json:"access"`
+}
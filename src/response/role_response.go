@@ -0,0 +1,24 @@
+package response
+
+import "app/src/model"
+
+type SuccessWithRole struct {
+	Code    int        `json:"code"`
+	Status  string     `json:"status"`
+	Message string     `json:"message"`
+	Role    model.Role `json:"role"`
+}
+
+type SuccessWithRoles struct {
+	Code    int          `json:"code"`
+	Status  string       `json:"status"`
+	Message string       `json:"message"`
+	Roles   []model.Role `json:"roles"`
+}
+
+type SuccessWithPermissions struct {
+	Code        int                `json:"code"`
+	Status      string             `json:"status"`
+	Message     string             `json:"message"`
+	Permissions []model.Permission `json:"permissions"`
+}
@@ -0,0 +1,19 @@
+package response
+
+import "app/src/model"
+
+// WebAuthnOptions carries the options for navigator.credentials.create or .get,
+// the session id has to be sent back with the authenticator response
+type WebAuthnOptions struct {
+	Code      int         `json:"code"`
+	Status    string      `json:"status"`
+	SessionID string      `json:"session_id"`
+	Options   interface{} `json:"options"`
+}
+
+type SuccessWithPasskey struct {
+	Code    int                      `json:"code"`
+	Status  string                   `json:"status"`
+	Message string                   `json:"message"`
+	Passkey model.WebAuthnCredential `json:"passkey"`
+}
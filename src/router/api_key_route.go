@@ -0,0 +1,22 @@
+package router
+
+import (
+	"app/src/controller"
+	m "app/src/middleware"
+	"app/src/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func APIKeyRoutes(v1 fiber.Router, a service.APIKeyService, u service.UserService, t service.TokenService) {
+	apiKeyController := controller.NewAPIKeyController(a)
+
+	apiKey := v1.Group("/auth/api-keys")
+
+	apiKey.Get("/", m.Auth(u, t), m.NoAPIKey, m.NoImpersonation, m.NoOAuthClient,
+		apiKeyController.GetAPIKeys)
+	apiKey.Post("/", m.Auth(u, t), m.NoAPIKey, m.NoImpersonation, m.NoOAuthClient,
+		apiKeyController.CreateAPIKey)
+	apiKey.Delete("/:apiKeyId", m.Auth(u, t), m.NoAPIKey, m.NoImpersonation, m.NoOAuthClient,
+		apiKeyController.RevokeAPIKey)
+}
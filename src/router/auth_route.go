@@ -1,7 +1,6 @@
 package router
 
 import (
-	"app/src/config"
 	"app/src/controller"
 	m "app/src/middleware"
 	"app/src/service"
@@ -11,10 +10,9 @@ import (
 
 func AuthRoutes(
 	v1 fiber.Router, a service.AuthService, u service.UserService,
-	t service.TokenService, e service.EmailService,
+	t service.TokenService, e service.EmailService, o service.OAuthService, i service.IdentityService,
 ) {
-	authController := controller.NewAuthController(a, u, t, e)
-	config.GoogleConfig()
+	authController := controller.NewAuthController(a, u, t, e, o, i)
 
 	auth := v1.Group("/auth")
 
@@ -24,8 +22,19 @@ func AuthRoutes(
 	auth.Post("/refresh-tokens", authController.RefreshTokens)
 	auth.Post("/forgot-password", authController.ForgotPassword)
 	auth.Post("/reset-password", authController.ResetPassword)
-	auth.Post("/send-verification-email", m.Auth(u), authController.SendVerificationEmail)
+	auth.Post("/send-verification-email", m.Auth(u, t), authController.SendVerificationEmail)
 	auth.Post("/verify-email", authController.VerifyEmail)
+	auth.Post("/confirm-email-change", authController.ConfirmEmailChange)
+	auth.Post("/revert-email-change", authController.RevertEmailChange)
+	auth.Post("/magic-link", authController.MagicLink)
+	auth.Post("/magic-link/verify", authController.VerifyMagicLink)
+	auth.Post("/set-password", m.Auth(u, t), m.NoAPIKey, m.NoImpersonation, m.NoOAuthClient,
+		authController.SetPassword)
 	auth.Get("/google", authController.GoogleLogin)
 	auth.Get("/google-callback", authController.GoogleCallback)
+	auth.Get("/oauth/:provider", authController.OAuthLogin)
+	auth.Get("/oauth/:provider/callback", authController.OAuthCallback)
+	auth.Post("/oauth/exchange", authController.OAuthExchange)
+	auth.Post("/oauth/:provider/link", m.Auth(u, t), m.NoAPIKey, m.NoImpersonation, m.NoOAuthClient,
+		authController.OAuthLink)
 }
@@ -3,31 +3,53 @@ package router
 import (
 	"app/src/controller"
 	"app/src/middleware"
+	"app/src/policy"
 	"app/src/service"
-	"app/src/validation"
 
 	"github.com/gofiber/fiber/v2"
-	"gorm.io/gorm"
 )
 
 // FileRoutes setup routes untuk file operations
-func FileRoutes(api fiber.Router, db *gorm.DB) {
-	// Initialize validator
-	validate := validation.Validator()
-
-	// Initialize services
-	storageService := service.NewStorageService(db)
-	userService := service.NewUserService(db, validate)
-
+func FileRoutes(
+	api fiber.Router, storageService service.StorageService, uploadService service.UploadService,
+	imageService service.ImageService, userService service.UserService, tokenService service.TokenService,
+) {
 	// Initialize controllers
-	fileController := controller.NewFileController(storageService)
+	fileController := controller.NewFileController(storageService, uploadService, imageService, userService)
 
 	// File routes
 	files := api.Group("/files")
 
-	// Protected routes (require authentication)
-	files.Post("/upload", middleware.Auth(userService), fileController.UploadFile)
-	files.Delete("/delete", middleware.Auth(userService), fileController.DeleteFile)
-	files.Get("/info", middleware.Auth(userService), fileController.GetFileInfo)
-	files.Get("/my-files", middleware.Auth(userService), fileController.GetMyFiles)
+	// Protected routes (require authentication). API key dan OAuth client butuh scope manageFiles
+	// untuk mengakses file milik user sendiri.
+	ownFiles := middleware.Scopes(userService, "manageFiles")
+	files.Post("/upload", middleware.Auth(userService, tokenService), ownFiles, fileController.UploadFile)
+	files.Get("/my-files", middleware.Auth(userService, tokenService), ownFiles, fileController.GetMyFiles)
+
+	// Upload resumable (tus 1.0), upload hanya bisa dilanjutkan oleh user yang membuatnya
+	uploads := files.Group("/uploads", middleware.TusResumable())
+	uploads.Options("/", fileController.UploadOptions)
+	uploads.Post("/", middleware.Auth(userService, tokenService), ownFiles, fileController.CreateUpload)
+	uploads.Head("/:uploadId", middleware.Auth(userService, tokenService), ownFiles, fileController.GetUploadStatus)
+	uploads.Patch("/:uploadId", middleware.Auth(userService, tokenService), ownFiles, fileController.WriteUploadChunk)
+	uploads.Delete("/:uploadId", middleware.Auth(userService, tokenService), ownFiles, fileController.DeleteUpload)
+
+	// Upload langsung ke storage melalui presigned URL
+	files.Post("/presigned-uploads", middleware.Auth(userService, tokenService), ownFiles,
+		fileController.CreatePresignedUpload)
+	files.Post("/presigned-uploads/:uploadId/complete", middleware.Auth(userService, tokenService), ownFiles,
+		fileController.CompletePresignedUpload)
+
+	// Signed URL dari local storage, signature menggantikan autentikasi
+	files.Get("/signed/*", fileController.DownloadSignedFile)
+	files.Put("/signed/*", fileController.UploadSignedFile)
+
+	// File diakses berdasarkan ID, user hanya boleh mengakses file miliknya sendiri, admin semua file.
+	// StorageService membatasi lagi file yang terlihat pada organization yang aktif.
+	ownFile := policy.OwnerOr(policy.File(storageService, "fileId"), "manageFiles")
+	files.Get("/:fileId", middleware.Authorize(userService, tokenService, ownFile), fileController.GetFile)
+	files.Get("/:fileId/download", middleware.Authorize(userService, tokenService, ownFile), fileController.DownloadFile)
+	files.Get("/:fileId/download-url", middleware.Authorize(userService, tokenService, ownFile),
+		fileController.GetDownloadURL)
+	files.Delete("/:fileId", middleware.Authorize(userService, tokenService, ownFile), fileController.DeleteFile)
 }
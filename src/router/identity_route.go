@@ -0,0 +1,20 @@
+package router
+
+import (
+	"app/src/controller"
+	m "app/src/middleware"
+	"app/src/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func IdentityRoutes(v1 fiber.Router, i service.IdentityService, u service.UserService, t service.TokenService) {
+	identityController := controller.NewIdentityController(i)
+
+	identity := v1.Group("/auth/identities")
+
+	identity.Get("/", m.Auth(u, t), m.NoAPIKey, m.NoImpersonation, m.NoOAuthClient,
+		identityController.GetIdentities)
+	identity.Delete("/:identityId", m.Auth(u, t), m.NoAPIKey, m.NoImpersonation, m.NoOAuthClient,
+		identityController.UnlinkIdentity)
+}
@@ -0,0 +1,18 @@
+package router
+
+import (
+	"app/src/controller"
+	m "app/src/middleware"
+	"app/src/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func ImpersonationRoutes(v1 fiber.Router, i service.ImpersonationService, u service.UserService, t service.TokenService) {
+	impersonationController := controller.NewImpersonationController(i)
+
+	v1.Post("/users/:userId/impersonate", m.Auth(u, t, "impersonateUsers"),
+		m.NoAPIKey, m.NoImpersonation, m.NoOAuthClient, impersonationController.StartImpersonation)
+	v1.Post("/auth/impersonation/stop", m.Auth(u, t), impersonationController.StopImpersonation)
+	v1.Get("/impersonations", m.Auth(u, t, "impersonateUsers"), impersonationController.GetImpersonations)
+}
@@ -0,0 +1,31 @@
+package router
+
+import (
+	"app/src/controller"
+	m "app/src/middleware"
+	"app/src/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func OAuthServerRoutes(
+	v1 fiber.Router, o service.OAuthServerService, oc service.OAuthClientService,
+	u service.UserService, t service.TokenService,
+) {
+	oauthServerController := controller.NewOAuthServerController(o)
+	oauthClientController := controller.NewOAuthClientController(oc)
+
+	oauth := v1.Group("/oauth")
+
+	oauth.Get("/authorize", oauthServerController.GetAuthorization)
+	oauth.Post("/authorize", m.Auth(u, t), m.NoAPIKey, m.NoImpersonation, m.NoOAuthClient,
+		oauthServerController.Authorize)
+	oauth.Post("/token", oauthServerController.Token)
+	oauth.Post("/introspect", oauthServerController.Introspect)
+	oauth.Post("/revoke", oauthServerController.Revoke)
+
+	oauth.Get("/clients", m.Auth(u, t, "manageOAuthClients"), oauthClientController.GetOAuthClients)
+	oauth.Post("/clients", m.Auth(u, t, "manageOAuthClients"), m.NoAPIKey, m.NoImpersonation, m.NoOAuthClient,
+		oauthClientController.CreateOAuthClient)
+	oauth.Delete("/clients/:clientId", m.Auth(u, t, "manageOAuthClients"), oauthClientController.DeleteOAuthClient)
+}
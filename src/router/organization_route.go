@@ -0,0 +1,40 @@
+package router
+
+import (
+	"app/src/controller"
+	m "app/src/middleware"
+	"app/src/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func OrganizationRoutes(
+	v1 fiber.Router, o service.OrganizationService, u service.UserService, t service.TokenService,
+) {
+	organizationController := controller.NewOrganizationController(o, t)
+
+	// The organization routes check the membership themselves, the role in the organization decides the rights
+	// and the scopes of an API key or OAuth token limit them. Creating and joining organizations needs a session.
+	organization := v1.Group("/organizations")
+
+	organization.Post("/", m.Auth(u, t), m.NoAPIKey, m.NoOAuthClient, organizationController.CreateOrganization)
+	organization.Get("/", m.Auth(u, t), organizationController.GetOrganizations)
+	organization.Get("/:organizationId", m.Auth(u, t), organizationController.GetOrganization)
+	organization.Patch("/:organizationId", m.Auth(u, t), organizationController.UpdateOrganization)
+	organization.Delete("/:organizationId", m.Auth(u, t), organizationController.DeleteOrganization)
+	organization.Post("/:organizationId/token", m.Auth(u, t), m.NoAPIKey, m.NoImpersonation, m.NoOAuthClient,
+		organizationController.CreateOrganizationToken)
+
+	organization.Get("/:organizationId/members", m.Auth(u, t), organizationController.GetMembers)
+	organization.Patch("/:organizationId/members/:userId", m.Auth(u, t), organizationController.UpdateMember)
+	organization.Delete("/:organizationId/members/:userId", m.Auth(u, t), organizationController.RemoveMember)
+
+	organization.Post("/:organizationId/invitations", m.Auth(u, t), organizationController.CreateInvitation)
+	organization.Get("/:organizationId/invitations", m.Auth(u, t), organizationController.GetInvitations)
+	organization.Delete("/:organizationId/invitations/:invitationId", m.Auth(u, t), organizationController.RevokeInvitation)
+
+	invitation := v1.Group("/invitations")
+
+	invitation.Post("/accept", m.Auth(u, t), m.NoAPIKey, m.NoOAuthClient, organizationController.AcceptInvitation)
+	invitation.Post("/decline", organizationController.DeclineInvitation)
+}
@@ -0,0 +1,22 @@
+package router
+
+import (
+	"app/src/controller"
+	m "app/src/middleware"
+	"app/src/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func RoleRoutes(v1 fiber.Router, r service.RoleService, u service.UserService, t service.TokenService) {
+	roleController := controller.NewRoleController(r)
+
+	role := v1.Group("/roles")
+
+	role.Get("/", m.Auth(u, t, "getRoles"), roleController.GetRoles)
+	role.Get("/permissions", m.Auth(u, t, "getRoles"), roleController.GetPermissions)
+	role.Post("/", m.Auth(u, t, "manageRoles"), roleController.CreateRole)
+	role.Get("/:roleId", m.Auth(u, t, "getRoles"), roleController.GetRoleByID)
+	role.Patch("/:roleId", m.Auth(u, t, "manageRoles"), roleController.UpdateRole)
+	role.Delete("/:roleId", m.Auth(u, t, "manageRoles"), roleController.DeleteRole)
+}
@@ -4,6 +4,7 @@ import (
 	"app/src/config"
 	"app/src/service"
 	"app/src/validation"
+	"context"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
@@ -14,18 +15,49 @@ func Routes(app *fiber.App, db *gorm.DB) {
 
 	healthCheckService := service.NewHealthCheckService(db)
 	emailService := service.NewEmailService()
-	userService := service.NewUserService(db, validate)
-	tokenService := service.NewTokenService(db, validate, userService)
-	authService := service.NewAuthService(db, validate, userService, tokenService)
+	oauthService := service.NewOAuthService(service.NewDatabaseOAuthStateStore(db))
+	roleService := service.NewRoleService(db, validate)
+	organizationService := service.NewOrganizationService(db, validate, emailService)
+	userService := service.NewUserService(db, validate, roleService, organizationService)
+	revocationStore := service.NewRevocationStore(db)
+	tokenService := service.NewTokenService(db, validate, userService, revocationStore)
+	loginAttemptService := service.NewLoginAttemptService(db, emailService)
+	authService := service.NewAuthService(db, validate, userService, tokenService, loginAttemptService)
+	twoFactorService := service.NewTwoFactorService(db, validate, userService, tokenService)
+	sessionService := service.NewSessionService(db, validate, tokenService)
+	identityService := service.NewIdentityService(db, validate, userService)
+	webAuthnService := service.NewWebAuthnService(db, validate, userService)
+	apiKeyService := service.NewAPIKeyService(db, validate, userService)
+	impersonationService := service.NewImpersonationService(db, validate, userService, tokenService)
+	oauthClientService := service.NewOAuthClientService(db, validate, userService)
+	oauthServerService := service.NewOAuthServerService(db, validate, userService, tokenService)
+	storageService := service.NewStorageService(db)
+	imageService := service.NewImageService(db, storageService)
+	uploadService := service.NewUploadService(db, validate, storageService, imageService)
+
+	WellKnownRoutes(app)
 
 	v1 := app.Group("/v1")
 
 	HealthCheckRoutes(v1, healthCheckService)
-	AuthRoutes(v1, authService, userService, tokenService, emailService)
-	UserRoutes(v1, userService, tokenService)
-	FileRoutes(v1, db)
+	AuthRoutes(v1, authService, userService, tokenService, emailService, oauthService, identityService)
+	TwoFactorRoutes(v1, twoFactorService, userService, tokenService)
+	SessionRoutes(v1, sessionService, userService, tokenService)
+	APIKeyRoutes(v1, apiKeyService, userService, tokenService)
+	IdentityRoutes(v1, identityService, userService, tokenService)
+	WebAuthnRoutes(v1, webAuthnService, userService, tokenService)
+	UserRoutes(v1, userService, tokenService, loginAttemptService, emailService)
+	ImpersonationRoutes(v1, impersonationService, userService, tokenService)
+	OAuthServerRoutes(v1, oauthServerService, oauthClientService, userService, tokenService)
+	RoleRoutes(v1, roleService, userService, tokenService)
+	OrganizationRoutes(v1, organizationService, userService, tokenService)
+	FileRoutes(v1, storageService, uploadService, imageService, userService, tokenService)
 	// TODO: add another routes here...
 
+	go service.PruneRevocations(context.Background(), revocationStore)
+	go service.PruneUploads(context.Background(), uploadService)
+	go service.ProcessImages(context.Background(), imageService)
+
 	if !config.IsProd {
 		DocsRoutes(v1)
 	}
@@ -0,0 +1,33 @@
+package router
+
+import (
+	"app/src/controller"
+	m "app/src/middleware"
+	"app/src/policy"
+	"app/src/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SessionRoutes(v1 fiber.Router, s service.SessionService, u service.UserService, t service.TokenService) {
+	sessionController := controller.NewSessionController(s)
+
+	session := v1.Group("/auth/sessions")
+
+	session.Get("/", m.Auth(u, t), m.NoAPIKey, m.NoImpersonation, m.NoOAuthClient,
+		sessionController.GetSessions)
+	session.Delete("/", m.Auth(u, t), m.NoAPIKey, m.NoImpersonation, m.NoOAuthClient,
+		sessionController.RevokeOtherSessions)
+	session.Delete("/:sessionId", m.Auth(u, t), m.NoAPIKey, m.NoImpersonation, m.NoOAuthClient,
+		sessionController.RevokeSession)
+
+	user := v1.Group("/users/:userId/sessions")
+	self := policy.User("userId")
+
+	user.Get("/", m.Authorize(u, t, policy.OwnerOr(self, "manageUsers")), m.NoImpersonation,
+		sessionController.GetUserSessions)
+	user.Delete("/", m.Authorize(u, t, policy.OwnerOr(self, "manageUsers")), m.NoImpersonation,
+		sessionController.RevokeUserSessions)
+	user.Delete("/:sessionId", m.Authorize(u, t, policy.OwnerOr(self, "manageUsers")), m.NoImpersonation,
+		sessionController.RevokeUserSession)
+}
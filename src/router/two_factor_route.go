@@ -0,0 +1,23 @@
+package router
+
+import (
+	"app/src/controller"
+	m "app/src/middleware"
+	"app/src/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TwoFactorRoutes(v1 fiber.Router, tf service.TwoFactorService, u service.UserService, t service.TokenService) {
+	twoFactorController := controller.NewTwoFactorController(tf, t)
+
+	twoFactor := v1.Group("/auth/2fa")
+
+	twoFactor.Post("/enroll", m.Auth(u, t), m.NoAPIKey, m.NoImpersonation, m.NoOAuthClient,
+		twoFactorController.Enroll)
+	twoFactor.Post("/confirm", m.Auth(u, t), m.NoAPIKey, m.NoImpersonation, m.NoOAuthClient,
+		twoFactorController.Confirm)
+	twoFactor.Post("/disable", m.Auth(u, t), m.NoAPIKey, m.NoImpersonation, m.NoOAuthClient,
+		twoFactorController.Disable)
+	twoFactor.Post("/verify", twoFactorController.Verify)
+}
@@ -3,19 +3,26 @@ package router
 import (
 	"app/src/controller"
 	m "app/src/middleware"
+	"app/src/policy"
 	"app/src/service"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-func UserRoutes(v1 fiber.Router, u service.UserService, t service.TokenService) {
-	userController := controller.NewUserController(u, t)
+func UserRoutes(
+	v1 fiber.Router, u service.UserService, t service.TokenService, l service.LoginAttemptService, e service.EmailService,
+) {
+	userController := controller.NewUserController(u, t, l, e)
 
 	user := v1.Group("/users")
 
-	user.Get("/paginated", m.Auth(u, "getUsers"), userController.GetUsersWithPagination)
-	user.Post("/", m.Auth(u, "manageUsers"), userController.CreateUser)
-	user.Get("/:userId", m.Auth(u, "getUsers"), userController.GetUserByID)
-	user.Patch("/:userId", m.Auth(u, "manageUsers"), userController.UpdateUser)
-	user.Delete("/:userId", m.Auth(u, "manageUsers"), userController.DeleteUser)
+	// Users may read and change their own account, admins any account
+	self := policy.User("userId")
+
+	user.Get("/paginated", m.Auth(u, t, "getUsers"), userController.GetUsersWithPagination)
+	user.Post("/", m.Auth(u, t, "manageUsers"), userController.CreateUser)
+	user.Get("/:userId", m.Authorize(u, t, policy.OwnerOr(self, "getUsers")), userController.GetUserByID)
+	user.Patch("/:userId", m.Authorize(u, t, policy.OwnerOr(self, "manageUsers")), userController.UpdateUser)
+	user.Delete("/:userId", m.Authorize(u, t, policy.OwnerOr(self, "manageUsers")), userController.DeleteUser)
+	user.Post("/:userId/unlock", m.Auth(u, t, "manageUsers"), userController.UnlockUser)
 }
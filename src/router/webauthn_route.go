@@ -0,0 +1,22 @@
+package router
+
+import (
+	"app/src/controller"
+	m "app/src/middleware"
+	"app/src/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func WebAuthnRoutes(v1 fiber.Router, w service.WebAuthnService, u service.UserService, t service.TokenService) {
+	webAuthnController := controller.NewWebAuthnController(w, t)
+
+	webAuthn := v1.Group("/auth/webauthn")
+
+	webAuthn.Post("/register/begin", m.Auth(u, t), m.NoAPIKey, m.NoImpersonation, m.NoOAuthClient,
+		webAuthnController.BeginRegistration)
+	webAuthn.Post("/register/finish", m.Auth(u, t), m.NoAPIKey, m.NoImpersonation, m.NoOAuthClient,
+		webAuthnController.FinishRegistration)
+	webAuthn.Post("/login/begin", webAuthnController.BeginLogin)
+	webAuthn.Post("/login/finish", webAuthnController.FinishLogin)
+}
@@ -0,0 +1,15 @@
+package router
+
+import (
+	"app/src/config"
+	"app/src/controller"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func WellKnownRoutes(app *fiber.App) {
+	jwksController := controller.NewJWKSController(config.JWTKeySet)
+
+	wellKnown := app.Group("/.well-known")
+	wellKnown.Get("/jwks.json", jwksController.GetJWKS)
+}
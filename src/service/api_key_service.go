@@ -0,0 +1,157 @@
+package service
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/policy"
+	"app/src/response"
+	"app/src/utils"
+	"app/src/validation"
+	"slices"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// apiKeyPrefixLength is the number of random characters kept in model.APIKey.Prefix
+const apiKeyPrefixLength = 8
+
+// APIKeyService manages the personal API keys of a user. The keys themselves are verified by
+// TokenService.VerifyAPIKey when middleware.Auth gets the X-API-Key header.
+type APIKeyService interface {
+	CreateAPIKey(c *fiber.Ctx, user *model.User, req *validation.CreateAPIKey) (*response.APIKey, string, error)
+	GetAPIKeys(c *fiber.Ctx, userID string) ([]response.APIKey, error)
+	RevokeAPIKey(c *fiber.Ctx, userID, apiKeyID string) error
+}
+
+type apiKeyService struct {
+	Log         *logrus.Logger
+	DB          *gorm.DB
+	Validate    *validator.Validate
+	UserService UserService
+}
+
+func NewAPIKeyService(db *gorm.DB, validate *validator.Validate, userService UserService) APIKeyService {
+	return &apiKeyService{
+		Log:         utils.Log,
+		DB:          db,
+		Validate:    validate,
+		UserService: userService,
+	}
+}
+
+// ActiveAPIKey returns the API key the request was authenticated with, nil for access tokens
+func ActiveAPIKey(c *fiber.Ctx) *model.APIKey {
+	apiKey, _ := c.Locals("api_key").(*model.APIKey)
+	return apiKey
+}
+
+// CreateAPIKey returns the new key together with its plain value, which is not stored and can not be shown again.
+// The scopes must be rights the user currently has.
+func (s *apiKeyService) CreateAPIKey(
+	c *fiber.Ctx, user *model.User, req *validation.CreateAPIKey,
+) (*response.APIKey, string, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, "", err
+	}
+
+	rights, err := s.UserService.GetRights(c, user)
+	if err != nil {
+		return nil, "", err
+	}
+
+	scopes := slices.Compact(slices.Sorted(slices.Values(req.Scopes)))
+	for _, scope := range scopes {
+		if !policy.HasAllRights(rights, []string{scope}) {
+			return nil, "", fiber.NewError(fiber.StatusBadRequest, "Unknown or not granted scope: "+scope)
+		}
+	}
+
+	secret, err := utils.GenerateRandomString(32)
+	if err != nil {
+		s.Log.Errorf("Failed to generate api key: %+v", err)
+		return nil, "", err
+	}
+
+	key := config.APIKeyPrefix + secret
+
+	apiKey := &model.APIKey{
+		UserID:    user.ID,
+		Name:      req.Name,
+		Prefix:    key[:len(config.APIKeyPrefix)+apiKeyPrefixLength],
+		Key:       utils.HashToken(key),
+		Scopes:    strings.Join(scopes, ","),
+		ExpiresAt: req.ExpiresAt,
+	}
+
+	if err := s.DB.WithContext(c.Context()).Omit("User").Create(apiKey).Error; err != nil {
+		s.Log.Errorf("Failed to create api key: %+v", err)
+		return nil, "", err
+	}
+
+	result := toAPIKeyResponse(apiKey)
+
+	return &result, key, nil
+}
+
+func (s *apiKeyService) GetAPIKeys(c *fiber.Ctx, userID string) ([]response.APIKey, error) {
+	var apiKeys []model.APIKey
+
+	if err := s.DB.WithContext(c.Context()).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&apiKeys).Error; err != nil {
+		s.Log.Errorf("Failed to get api keys: %+v", err)
+		return nil, err
+	}
+
+	result := make([]response.APIKey, 0, len(apiKeys))
+	for i := range apiKeys {
+		result = append(result, toAPIKeyResponse(&apiKeys[i]))
+	}
+
+	return result, nil
+}
+
+func (s *apiKeyService) RevokeAPIKey(c *fiber.Ctx, userID, apiKeyID string) error {
+	if _, err := uuid.Parse(apiKeyID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid API key ID")
+	}
+
+	result := s.DB.WithContext(c.Context()).
+		Where("id = ? AND user_id = ?", apiKeyID, userID).
+		Delete(&model.APIKey{})
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed to revoke api key: %+v", result.Error)
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return fiber.NewError(fiber.StatusNotFound, "API key not found")
+	}
+
+	return nil
+}
+
+func toAPIKeyResponse(apiKey *model.APIKey) response.APIKey {
+	scopes := utils.SplitList(apiKey.Scopes)
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	return response.APIKey{
+		ID:         apiKey.ID,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		Scopes:     scopes,
+		ExpiresAt:  apiKey.ExpiresAt,
+		LastUsedAt: apiKey.LastUsedAt,
+		LastUsedIP: apiKey.LastUsedIP,
+		CreatedAt:  apiKey.CreatedAt,
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"app/src/utils"
 	"app/src/validation"
 	"errors"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
@@ -14,6 +15,11 @@ import (
 	"gorm.io/gorm"
 )
 
+// ErrPasswordExpired is returned for password logins with a password older than PASSWORD_MAX_AGE_DAYS,
+// even when the password is right
+var ErrPasswordExpired = fiber.NewError(fiber.StatusForbidden,
+	"Your password has expired, a link to reset it has been sent to your email address")
+
 type AuthService interface {
 	Register(c *fiber.Ctx, req *validation.Register) (*model.User, error)
 	Login(c *fiber.Ctx, req *validation.Login) (*model.User, error)
@@ -21,25 +27,33 @@ type AuthService interface {
 	RefreshAuth(c *fiber.Ctx, req *validation.RefreshToken) (*response.Tokens, error)
 	ResetPassword(c *fiber.Ctx, query *validation.Token, req *validation.UpdatePassOrVerify) error
 	VerifyEmail(c *fiber.Ctx, query *validation.Token) error
+	ConfirmEmailChange(c *fiber.Ctx, query *validation.Token) (*model.User, string, error)
+	RevertEmailChange(c *fiber.Ctx, query *validation.Token) error
+	SetPassword(c *fiber.Ctx, user *model.User, req *validation.SetPassword) error
+	ExchangeOAuthCode(c *fiber.Ctx, req *validation.OAuthCode) (*model.User, error)
+	LoginWithMagicLink(c *fiber.Ctx, query *validation.Token) (*model.User, error)
 }
 
 type authService struct {
-	Log          *logrus.Logger
-	DB           *gorm.DB
-	Validate     *validator.Validate
-	UserService  UserService
-	TokenService TokenService
+	Log                 *logrus.Logger
+	DB                  *gorm.DB
+	Validate            *validator.Validate
+	UserService         UserService
+	TokenService        TokenService
+	LoginAttemptService LoginAttemptService
 }
 
 func NewAuthService(
 	db *gorm.DB, validate *validator.Validate, userService UserService, tokenService TokenService,
+	loginAttemptService LoginAttemptService,
 ) AuthService {
 	return &authService{
-		Log:          utils.Log,
-		DB:           db,
-		Validate:     validate,
-		UserService:  userService,
-		TokenService: tokenService,
+		Log:                 utils.Log,
+		DB:                  db,
+		Validate:            validate,
+		UserService:         userService,
+		TokenService:        tokenService,
+		LoginAttemptService: loginAttemptService,
 	}
 }
 
@@ -54,10 +68,12 @@ func (s *authService) Register(c *fiber.Ctx, req *validation.Register) (*model.U
 		return nil, err
 	}
 
+	now := time.Now().UTC()
 	user := &model.User{
-		Name:     req.Name,
-		Email:    req.Email,
-		Password: hashedPassword,
+		Name:              req.Name,
+		Email:             req.Email,
+		Password:          hashedPassword,
+		PasswordChangedAt: &now,
 	}
 
 	result := s.DB.WithContext(c.Context()).Create(user)
@@ -77,15 +93,40 @@ func (s *authService) Login(c *fiber.Ctx, req *validation.Login) (*model.User, e
 		return nil, err
 	}
 
+	if err := s.LoginAttemptService.Check(c, req.Email); err != nil {
+		return nil, err
+	}
+
 	user, err := s.UserService.GetUserByEmail(c, req.Email)
 	if err != nil {
+		if errRecord := s.LoginAttemptService.RecordFailure(c, req.Email, nil); errRecord != nil {
+			return nil, errRecord
+		}
+
 		return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid email or password")
 	}
 
+	if accountLocked(user) {
+		return nil, ErrAccountLocked
+	}
+
 	if !utils.CheckPasswordHash(req.Password, user.Password) {
+		if errRecord := s.LoginAttemptService.RecordFailure(c, req.Email, user); errRecord != nil {
+			return nil, errRecord
+		}
+
 		return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid email or password")
 	}
 
+	if err := s.LoginAttemptService.RecordSuccess(c, req.Email); err != nil {
+		return nil, err
+	}
+
+	if config.PasswordMaxAge > 0 && user.PasswordChangedAt != nil &&
+		user.PasswordChangedAt.Before(time.Now().UTC().AddDate(0, 0, -config.PasswordMaxAge)) {
+		return nil, ErrPasswordExpired
+	}
+
 	return user, nil
 }
 
@@ -99,9 +140,11 @@ func (s *authService) Logout(c *fiber.Ctx, req *validation.Logout) error {
 		return fiber.NewError(fiber.StatusNotFound, "Token not found")
 	}
 
-	err = s.TokenService.DeleteToken(c, config.TokenTypeRefresh, token.UserID.String())
+	if err = s.TokenService.DeleteTokenFamily(c, token); err != nil {
+		return err
+	}
 
-	return err
+	return s.TokenService.RevokeSession(c, token.Family())
 }
 
 func (s *authService) RefreshAuth(c *fiber.Ctx, req *validation.RefreshToken) (*response.Tokens, error) {
@@ -119,8 +162,21 @@ func (s *authService) RefreshAuth(c *fiber.Ctx, req *validation.RefreshToken) (*
 		return nil, fiber.NewError(fiber.StatusUnauthorized, "Please authenticate")
 	}
 
-	newTokens, err := s.TokenService.GenerateAuthTokens(c, user)
+	// A password or email change logs out the sessions started before it, not only their access tokens
+	claims, err := utils.ParseToken(req.RefreshToken, config.JWTKeySet, config.TokenTypeRefresh)
+	if err != nil || IssuedBeforeValidity(claims, user) {
+		if errDelete := s.TokenService.DeleteTokenFamily(c, token); errDelete != nil {
+			return nil, errDelete
+		}
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Please authenticate")
+	}
+
+	newTokens, err := s.TokenService.RotateAuthTokens(c, token, user)
 	if err != nil {
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			return nil, err
+		}
 		return nil, fiber.ErrInternalServerError
 	}
 
@@ -132,9 +188,10 @@ func (s *authService) ResetPassword(c *fiber.Ctx, query *validation.Token, req *
 		return err
 	}
 
-	userID, err := utils.VerifyToken(query.Token, config.JWTSecret, config.TokenTypeResetPassword)
+	// The link is deleted only after the reset, so a password rejected by the policy can be retried
+	userID, err := s.TokenService.VerifyStoredToken(c, query.Token, config.TokenTypeResetPassword)
 	if err != nil {
-		return fiber.NewError(fiber.StatusUnauthorized, "Invalid Token")
+		return err
 	}
 
 	user, err := s.UserService.GetUserByID(c, userID)
@@ -150,6 +207,16 @@ func (s *authService) ResetPassword(c *fiber.Ctx, query *validation.Token, req *
 		return errToken
 	}
 
+	// A reset usually means the password leaked, so every session is logged out
+	if errToken := s.TokenService.DeleteToken(c, config.TokenTypeRefresh, user.ID.String()); errToken != nil {
+		return errToken
+	}
+
+	// Opening the reset email proves the owner is back in control, a lock from failed logins is lifted
+	if _, errUnlock := s.LoginAttemptService.Unlock(c, user.ID.String()); errUnlock != nil {
+		return errUnlock
+	}
+
 	return nil
 }
 
@@ -158,9 +225,9 @@ func (s *authService) VerifyEmail(c *fiber.Ctx, query *validation.Token) error {
 		return err
 	}
 
-	userID, err := utils.VerifyToken(query.Token, config.JWTSecret, config.TokenTypeVerifyEmail)
+	userID, err := s.TokenService.VerifyStoredToken(c, query.Token, config.TokenTypeVerifyEmail)
 	if err != nil {
-		return fiber.NewError(fiber.StatusUnauthorized, "Invalid Token")
+		return err
 	}
 
 	user, err := s.UserService.GetUserByID(c, userID)
@@ -182,3 +249,155 @@ func (s *authService) VerifyEmail(c *fiber.Ctx, query *validation.Token) error {
 
 	return nil
 }
+
+// ConfirmEmailChange applies the pending email of the user the confirmation link was sent to and returns
+// the user with the previous email. Links sent to the previous email can no longer reset the password.
+func (s *authService) ConfirmEmailChange(c *fiber.Ctx, query *validation.Token) (*model.User, string, error) {
+	if err := s.Validate.Struct(query); err != nil {
+		return nil, "", err
+	}
+
+	userID, err := s.TokenService.ConsumeChangeEmailToken(c, query.Token)
+	if err != nil {
+		return nil, "", err
+	}
+
+	user, previousEmail, err := s.UserService.ConfirmPendingEmail(c, userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, tokenType := range []string{config.TokenTypeResetPassword, config.TokenTypeVerifyEmail} {
+		if errToken := s.TokenService.DeleteToken(c, tokenType, userID); errToken != nil {
+			return nil, "", errToken
+		}
+	}
+
+	s.Log.WithFields(logrus.Fields{
+		"event":   "email_changed",
+		"user_id": userID,
+		"ip":      c.IP(),
+	}).Info("User changed their email")
+
+	return user, previousEmail, nil
+}
+
+// RevertEmailChange restores the previous email from the link sent to it after a change. Whoever changed
+// the email may have taken over the account, so every session is logged out as for a password reset.
+func (s *authService) RevertEmailChange(c *fiber.Ctx, query *validation.Token) error {
+	if err := s.Validate.Struct(query); err != nil {
+		return err
+	}
+
+	userID, email, err := s.TokenService.ConsumeRevertEmailToken(c, query.Token)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.UserService.RestoreEmail(c, userID, email); err != nil {
+		return err
+	}
+
+	tokenTypes := []string{
+		config.TokenTypeRefresh, config.TokenTypeChangeEmail, config.TokenTypeResetPassword, config.TokenTypeVerifyEmail,
+	}
+
+	for _, tokenType := range tokenTypes {
+		if errToken := s.TokenService.DeleteToken(c, tokenType, userID); errToken != nil {
+			return errToken
+		}
+	}
+
+	s.Log.WithFields(logrus.Fields{
+		"event":   "email_change_reverted",
+		"user_id": userID,
+		"ip":      c.IP(),
+	}).Warn("User reverted an email change from the previous address")
+
+	return nil
+}
+
+// SetPassword adds a local password to an account that was created through an OAuth provider
+func (s *authService) SetPassword(c *fiber.Ctx, user *model.User, req *validation.SetPassword) error {
+	if err := s.Validate.Struct(req); err != nil {
+		return err
+	}
+
+	if user.LocalLogin {
+		return fiber.NewError(fiber.StatusConflict, "Password is already set")
+	}
+
+	hashedPassword, err := utils.HashPassword(req.Password)
+	if err != nil {
+		s.Log.Errorf("Failed hash password: %+v", err)
+		return err
+	}
+
+	// The empty password condition keeps a concurrent request from overwriting a password set in the meantime
+	result := s.DB.WithContext(c.Context()).Model(&model.User{}).
+		Where("id = ? AND password = ''", user.ID).
+		Updates(map[string]interface{}{
+			"password":            hashedPassword,
+			"password_changed_at": time.Now().UTC(),
+		})
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed to set password: %+v", result.Error)
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return fiber.NewError(fiber.StatusConflict, "Password is already set")
+	}
+
+	return nil
+}
+
+func (s *authService) ExchangeOAuthCode(c *fiber.Ctx, req *validation.OAuthCode) (*model.User, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	userID, err := s.TokenService.ConsumeOAuthCode(c, req.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.UserService.GetUserByID(c, userID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired code")
+	}
+
+	return user, nil
+}
+
+// LoginWithMagicLink consumes the link, opening it proves the user owns the email
+func (s *authService) LoginWithMagicLink(c *fiber.Ctx, query *validation.Token) (*model.User, error) {
+	if err := s.Validate.Struct(query); err != nil {
+		return nil, err
+	}
+
+	userID, err := s.TokenService.ConsumeMagicLinkToken(c, query.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.UserService.GetUserByID(c, userID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid Token")
+	}
+
+	if !user.VerifiedEmail {
+		updateBody := &validation.UpdatePassOrVerify{
+			VerifiedEmail: true,
+		}
+
+		if errUpdate := s.UserService.UpdatePassOrVerify(c, updateBody, user.ID.String()); errUpdate != nil {
+			return nil, errUpdate
+		}
+
+		user.VerifiedEmail = true
+	}
+
+	return user, nil
+}
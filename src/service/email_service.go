@@ -4,6 +4,7 @@ import (
 	"app/src/config"
 	"app/src/utils"
 	"fmt"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"gopkg.in/gomail.v2"
@@ -13,6 +14,11 @@ type EmailService interface {
 	SendEmail(to, subject, body string) error
 	SendResetPasswordEmail(to, token string) error
 	SendVerificationEmail(to, token string) error
+	SendMagicLinkEmail(to, token string) error
+	SendAccountLockedEmail(to, ip string, until time.Time) error
+	SendInvitationEmail(to, organization, token string) error
+	SendConfirmEmailChangeEmail(to, token string) error
+	SendEmailChangedEmail(to, token string) error
 }
 
 type emailService struct {
@@ -78,3 +84,71 @@ To verify your email, click on this link: %s
 If you did not create an account, then ignore this email.`, verificationEmailURL)
 	return s.SendEmail(to, subject, body)
 }
+
+func (s *emailService) SendMagicLinkEmail(to, token string) error {
+	subject := "Your login link"
+
+	// TODO: replace this url with the link to the magic link login page of your front-end app
+	magicLinkURL := fmt.Sprintf("http://link-to-app/magic-link?token=%s", token)
+	body := fmt.Sprintf(`Dear user,
+
+To log in, click on this link: %s
+
+The link can only be used once. If you did not request it, then ignore this email.`, magicLinkURL)
+	return s.SendEmail(to, subject, body)
+}
+
+func (s *emailService) SendAccountLockedEmail(to, ip string, until time.Time) error {
+	subject := "Your account has been locked"
+
+	// TODO: replace this url with the link to the forgot password page of your front-end app
+	forgotPasswordURL := "http://link-to-app/forgot-password"
+	body := fmt.Sprintf(`Dear user,
+
+We locked your account after too many failed login attempts, the last one came from %s.
+Password login is disabled until %s.
+
+If this was not you, reset your password now: %s
+Resetting the password also unlocks your account.`, ip, until.UTC().Format(time.RFC1123), forgotPasswordURL)
+	return s.SendEmail(to, subject, body)
+}
+
+func (s *emailService) SendInvitationEmail(to, organization, token string) error {
+	subject := fmt.Sprintf("You have been invited to join %s", organization)
+
+	// TODO: replace this url with the link to the invitation page of your front-end app
+	invitationURL := fmt.Sprintf("http://link-to-app/invitation?token=%s", token)
+	body := fmt.Sprintf(`Dear user,
+
+You have been invited to join %s. To accept or decline the invitation, click on this link: %s
+
+If you did not expect this invitation, then ignore this email.`, organization, invitationURL)
+	return s.SendEmail(to, subject, body)
+}
+
+func (s *emailService) SendConfirmEmailChangeEmail(to, token string) error {
+	subject := "Confirm your new email"
+
+	// TODO: replace this url with the link to the confirm email change page of your front-end app
+	confirmEmailChangeURL := fmt.Sprintf("http://link-to-app/confirm-email-change?token=%s", token)
+	body := fmt.Sprintf(`Dear user,
+
+To use this address for your account, click on this link: %s
+
+Your email will not change until you do. If you did not request this change, then ignore this email.`, confirmEmailChangeURL)
+	return s.SendEmail(to, subject, body)
+}
+
+func (s *emailService) SendEmailChangedEmail(to, token string) error {
+	subject := "Your email has been changed"
+
+	// TODO: replace this url with the link to the revert email change page of your front-end app
+	revertEmailChangeURL := fmt.Sprintf("http://link-to-app/revert-email-change?token=%s", token)
+	body := fmt.Sprintf(`Dear user,
+
+The email of your account has been changed, we will no longer send messages to this address.
+
+If this was not you, click on this link to restore this address and log out every session: %s
+Then reset your password.`, revertEmailChangeURL)
+	return s.SendEmail(to, subject, body)
+}
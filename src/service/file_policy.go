@@ -0,0 +1,144 @@
+package service
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/utils"
+	"context"
+	"fmt"
+	"io/fs"
+	"mime"
+	"mime/multipart"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// cleanFolder menolak folder yang bukan path relatif yang bersih, seperti general/../avatars, /avatars atau
+// avatars//2024, sehingga policy selalu diperiksa dan disimpan untuk folder tempat file benar-benar ditulis
+func cleanFolder(folder string) (string, error) {
+	if path.Clean(folder) != folder || !fs.ValidPath(folder) || folder == "." || strings.Contains(folder, `\`) {
+		return "", fiber.NewError(fiber.StatusBadRequest, "Invalid folder")
+	}
+
+	return folder, nil
+}
+
+// checkFileName memeriksa ukuran dan tipe file terhadap policy folder sebelum isinya diterima,
+// lalu mengembalikan MIME type dari ekstensinya yang disimpan sebagai content type file.
+// declaredType dari client (boleh kosong) harus sesuai dengan ekstensi.
+func checkFileName(policy config.FilePolicy, fileName, declaredType string, size int64) (string, error) {
+	if size > policy.MaxSize {
+		return "", fiber.NewError(fiber.StatusRequestEntityTooLarge,
+			fmt.Sprintf("File size exceeds maximum limit of %d bytes", policy.MaxSize))
+	}
+
+	ext := strings.ToLower(filepath.Ext(fileName))
+	mimeType := utils.ExtensionType(ext)
+	if ext == "" || mimeType == "" || !policy.Allows(ext, mimeType) {
+		return "", fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("File type %s is not allowed", ext))
+	}
+
+	if declaredType != "" {
+		mediaType, _, err := mime.ParseMediaType(declaredType)
+		if err != nil || (mediaType != mimeType && mediaType != "application/octet-stream") {
+			return "", fiber.NewError(fiber.StatusBadRequest,
+				fmt.Sprintf("Content type %s does not match the file extension %s", declaredType, ext))
+		}
+	}
+
+	return mimeType, nil
+}
+
+// checkFileContent memeriksa magic bytes dari awal isi file, isi file harus sesuai dengan ekstensinya
+func checkFileContent(fileName string, head []byte) error {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	if !utils.ContentMatchesType(head, utils.ExtensionType(ext)) {
+		return fiber.NewError(fiber.StatusUnsupportedMediaType,
+			fmt.Sprintf("File content (%s) does not match the file extension %s", utils.DetectFileType(head), ext))
+	}
+
+	return nil
+}
+
+// checkFileCount memeriksa jumlah file milik user di folder, di dalam organization yang aktif atau file pribadi
+func checkFileCount(
+	db *gorm.DB, policy config.FilePolicy, folder string, uploadedBy *uuid.UUID, organizationID *uuid.UUID,
+) error {
+	if policy.MaxCount <= 0 || uploadedBy == nil {
+		return nil
+	}
+
+	query := db.Model(&model.File{}).Where("uploaded_by = ? AND folder = ?", *uploadedBy, folder)
+	if organizationID != nil {
+		query = query.Where("organization_id = ?", *organizationID)
+	} else {
+		query = query.Where("organization_id IS NULL")
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to count files: %w", err)
+	}
+
+	if count >= int64(policy.MaxCount) {
+		return fiber.NewError(fiber.StatusConflict,
+			fmt.Sprintf("Folder %s already has the maximum of %d files", folder, policy.MaxCount))
+	}
+
+	return nil
+}
+
+// checkStoredFile memeriksa file dari upload resumable atau presigned upload yang sudah tersimpan di storage
+func checkStoredFile(ctx context.Context, db *gorm.DB, storage StorageService, upload *model.Upload) error {
+	stored, err := storage.OpenFile(ctx, upload.FilePath)
+	if err != nil {
+		return fmt.Errorf("failed to open uploaded file: %w", err)
+	}
+	head, err := utils.ReadHead(stored.Content)
+	stored.Content.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+
+	if err := checkFileContent(upload.FileName, head); err != nil {
+		return err
+	}
+
+	return checkFileCount(db, config.FolderPolicy(upload.Folder), upload.Folder, upload.UploadedBy, upload.OrganizationID)
+}
+
+// validateUpload memeriksa file dari upload multipart terhadap policy folder beserta isinya sebelum disimpan
+// oleh backend mana pun. Content-Type dari client diabaikan, content type file ditentukan dari ekstensinya.
+func validateUpload(db *gorm.DB, file *multipart.FileHeader, folder string, actor FileActor) (string, error) {
+	policy := config.FolderPolicy(folder)
+
+	contentType, err := checkFileName(policy, file.Filename, "", file.Size)
+	if err != nil {
+		return "", err
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open uploaded file: %w", err)
+	}
+	head, err := utils.ReadHead(src)
+	src.Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+
+	if err := checkFileContent(file.Filename, head); err != nil {
+		return "", err
+	}
+
+	if err := checkFileCount(db, policy, folder, &actor.UserID, actor.OrganizationID); err != nil {
+		return "", err
+	}
+
+	return contentType, nil
+}
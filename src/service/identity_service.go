@@ -0,0 +1,290 @@
+package service
+
+import (
+	"app/src/model"
+	"app/src/utils"
+	"app/src/validation"
+	"errors"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IdentityService signs users in by the subject of their external provider account, never by email alone
+type IdentityService interface {
+	Login(c *fiber.Ctx, req *validation.OAuthLogin) (*model.User, error)
+	Link(c *fiber.Ctx, userID string, req *validation.OAuthLogin) (*model.UserIdentity, error)
+	GetIdentities(c *fiber.Ctx, userID string) ([]model.UserIdentity, error)
+	Unlink(c *fiber.Ctx, user *model.User, identityID string) error
+}
+
+type identityService struct {
+	Log         *logrus.Logger
+	DB          *gorm.DB
+	Validate    *validator.Validate
+	UserService UserService
+}
+
+func NewIdentityService(db *gorm.DB, validate *validator.Validate, userService UserService) IdentityService {
+	return &identityService{
+		Log:         utils.Log,
+		DB:          db,
+		Validate:    validate,
+		UserService: userService,
+	}
+}
+
+func (s *identityService) Login(c *fiber.Ctx, req *validation.OAuthLogin) (*model.User, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	identity, err := s.getIdentity(c, req.Provider, req.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	if identity != nil {
+		return s.loginLinkedUser(c, identity, req)
+	}
+
+	// A new provider account is only matched to an email its provider has verified, otherwise anyone could
+	// sign up at a provider that does not verify emails and claim the address of someone else
+	if !req.VerifiedEmail {
+		return nil, fiber.NewError(fiber.StatusForbidden,
+			"The provider has not verified this email, verify it with the provider and try again")
+	}
+
+	user, err := s.UserService.GetUserByEmail(c, req.Email)
+	if err != nil {
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) && fiberErr.Code == fiber.StatusNotFound {
+			return s.createUser(c, req)
+		}
+
+		return nil, err
+	}
+
+	// Accounts created by the OAuth login before identities were stored have neither a password nor an
+	// identity, they are linked on their first login
+	if !user.LocalLogin {
+		count, errCount := s.countIdentities(c, user.ID.String())
+		if errCount != nil {
+			return nil, errCount
+		}
+
+		if count == 0 {
+			if _, errLink := s.Link(c, user.ID.String(), req); errLink != nil {
+				return nil, errLink
+			}
+
+			return s.markEmailVerified(c, user)
+		}
+	}
+
+	return nil, fiber.NewError(fiber.StatusConflict,
+		"An account with this email already exists, log in and link the provider from your account")
+}
+
+func (s *identityService) Link(c *fiber.Ctx, userID string, req *validation.OAuthLogin) (*model.UserIdentity, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.getIdentity(c, req.Provider, req.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil {
+		if existing.UserID.String() == userID {
+			return existing, nil
+		}
+
+		return nil, fiber.NewError(fiber.StatusConflict, "This provider account is already linked to another user")
+	}
+
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	identity := &model.UserIdentity{
+		UserID:   id,
+		Provider: req.Provider,
+		Subject:  req.Subject,
+		Email:    req.Email,
+	}
+
+	result := s.DB.WithContext(c.Context()).Create(identity)
+
+	if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+		return nil, fiber.NewError(fiber.StatusConflict, "Another account of this provider is already linked")
+	}
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed to link identity: %+v", result.Error)
+		return nil, result.Error
+	}
+
+	return identity, nil
+}
+
+func (s *identityService) GetIdentities(c *fiber.Ctx, userID string) ([]model.UserIdentity, error) {
+	identities := []model.UserIdentity{}
+
+	result := s.DB.WithContext(c.Context()).
+		Where("user_id = ?", userID).
+		Order("linked_at ASC").
+		Find(&identities)
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed to get identities: %+v", result.Error)
+		return nil, result.Error
+	}
+
+	return identities, nil
+}
+
+func (s *identityService) Unlink(c *fiber.Ctx, user *model.User, identityID string) error {
+	if _, err := uuid.Parse(identityID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid identity ID")
+	}
+
+	return s.DB.WithContext(c.Context()).Transaction(func(tx *gorm.DB) error {
+		// Lock the user's identities so two concurrent unlinks can not remove the last login method
+		var identities []model.UserIdentity
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ?", user.ID).Find(&identities).Error; err != nil {
+			s.Log.Errorf("Failed to get identities: %+v", err)
+			return err
+		}
+
+		found := false
+		for _, identity := range identities {
+			if identity.ID.String() == identityID {
+				found = true
+			}
+		}
+
+		if !found {
+			return fiber.NewError(fiber.StatusNotFound, "Identity not found")
+		}
+
+		if !user.LocalLogin && len(identities) == 1 {
+			return fiber.NewError(fiber.StatusBadRequest, "Set a password before unlinking your last login provider")
+		}
+
+		if err := tx.Where("id = ? AND user_id = ?", identityID, user.ID).Delete(&model.UserIdentity{}).Error; err != nil {
+			s.Log.Errorf("Failed to unlink identity: %+v", err)
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (s *identityService) loginLinkedUser(
+	c *fiber.Ctx, identity *model.UserIdentity, req *validation.OAuthLogin,
+) (*model.User, error) {
+	user, err := s.UserService.GetUserByID(c, identity.UserID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if identity.Email != req.Email {
+		if errUpdate := s.DB.WithContext(c.Context()).Model(identity).
+			Update("email", req.Email).Error; errUpdate != nil {
+			s.Log.Errorf("Failed to update identity email: %+v", errUpdate)
+			return nil, errUpdate
+		}
+	}
+
+	if req.VerifiedEmail && req.Email == user.Email {
+		return s.markEmailVerified(c, user)
+	}
+
+	return user, nil
+}
+
+func (s *identityService) createUser(c *fiber.Ctx, req *validation.OAuthLogin) (*model.User, error) {
+	user := &model.User{
+		Name:          req.Name,
+		Email:         req.Email,
+		VerifiedEmail: true,
+	}
+
+	err := s.DB.WithContext(c.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&model.UserIdentity{
+			UserID:   user.ID,
+			Provider: req.Provider,
+			Subject:  req.Subject,
+			Email:    req.Email,
+		}).Error
+	})
+
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, fiber.NewError(fiber.StatusConflict, "Email is already in use")
+	}
+
+	if err != nil {
+		s.Log.Errorf("Failed to create user: %+v", err)
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *identityService) markEmailVerified(c *fiber.Ctx, user *model.User) (*model.User, error) {
+	if user.VerifiedEmail {
+		return user, nil
+	}
+
+	if err := s.DB.WithContext(c.Context()).Model(user).Update("verified_email", true).Error; err != nil {
+		s.Log.Errorf("Failed to update user: %+v", err)
+		return nil, err
+	}
+
+	user.VerifiedEmail = true
+
+	return user, nil
+}
+
+func (s *identityService) getIdentity(c *fiber.Ctx, provider, subject string) (*model.UserIdentity, error) {
+	identity := new(model.UserIdentity)
+
+	result := s.DB.WithContext(c.Context()).
+		Where("provider = ? AND subject = ?", provider, subject).
+		Take(identity)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed to get identity: %+v", result.Error)
+		return nil, result.Error
+	}
+
+	return identity, nil
+}
+
+func (s *identityService) countIdentities(c *fiber.Ctx, userID string) (int64, error) {
+	var count int64
+
+	if err := s.DB.WithContext(c.Context()).Model(&model.UserIdentity{}).
+		Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		s.Log.Errorf("Failed to count identities: %+v", err)
+		return 0, err
+	}
+
+	return count, nil
+}
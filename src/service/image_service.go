@@ -0,0 +1,383 @@
+package service
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/utils"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultImageWorkers    = 2
+	imageQueueSize         = 100
+	imageMaxPixels         = 50_000_000
+	imageOriginalQuality   = 92
+	imageProcessingTimeout = 10 * time.Minute
+)
+
+// imageExtensions ekstensi file yang diproses oleh ImageService
+var imageExtensions = []string{".jpg", ".jpeg", ".png", ".gif"}
+
+// ImageService membuat variant dari gambar yang diupload (config.ImageVariants) di background.
+// File yang belum diproses memiliki ProcessingStatus pending. Metadata EXIF file asli sudah dibuang
+// oleh stripOriginal saat upload, sebelum file dapat diakses.
+type ImageService interface {
+	Enqueue(fileID uuid.UUID)
+	EnqueuePending(ctx context.Context) error
+	ProcessFile(ctx context.Context, fileID uuid.UUID) error
+	Queue() <-chan uuid.UUID
+}
+
+type imageService struct {
+	Log            *logrus.Logger
+	DB             *gorm.DB
+	StorageService StorageService
+	queue          chan uuid.UUID
+}
+
+func NewImageService(db *gorm.DB, storageService StorageService) ImageService {
+	return &imageService{
+		Log:            utils.Log,
+		DB:             db,
+		StorageService: storageService,
+		queue:          make(chan uuid.UUID, imageQueueSize),
+	}
+}
+
+// ProcessImages menjalankan worker yang memproses gambar dari antrian sampai ctx selesai.
+// Gambar yang masih pending dari proses sebelumnya ikut dimasukkan ke antrian.
+func ProcessImages(ctx context.Context, imageService ImageService) {
+	workers := config.ImageWorkers
+	if workers <= 0 {
+		workers = defaultImageWorkers
+	}
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case fileID := <-imageService.Queue():
+					if err := imageService.ProcessFile(ctx, fileID); err != nil {
+						utils.Log.Errorf("Failed to process image %s: %+v", fileID, err)
+					}
+				}
+			}
+		}()
+	}
+
+	if err := imageService.EnqueuePending(ctx); err != nil {
+		utils.Log.Errorf("Failed to enqueue pending images: %+v", err)
+	}
+
+	wg.Wait()
+}
+
+// Enqueue memasukkan file ke antrian tanpa menunggu. Jika antrian penuh file tetap pending
+// dan diproses oleh EnqueuePending saat aplikasi dijalankan lagi.
+func (s *imageService) Enqueue(fileID uuid.UUID) {
+	select {
+	case s.queue <- fileID:
+	default:
+		s.Log.Warnf("Image queue is full, file %s stays pending", fileID)
+	}
+}
+
+// EnqueuePending memasukkan semua file yang masih pending ke antrian, menunggu jika antrian penuh.
+// File yang terlalu lama processing ditinggalkan oleh instance yang berhenti dan dijadikan pending lagi.
+func (s *imageService) EnqueuePending(ctx context.Context) error {
+	err := s.DB.WithContext(ctx).Model(&model.File{}).
+		Where("processing_status = ? AND updated_at < ?", model.FileProcessingProcessing,
+			time.Now().Add(-imageProcessingTimeout)).
+		Update("processing_status", model.FileProcessingPending).Error
+	if err != nil {
+		return err
+	}
+
+	var fileIDs []uuid.UUID
+	err = s.DB.WithContext(ctx).Model(&model.File{}).
+		Where("processing_status = ?", model.FileProcessingPending).Order("created_at").Pluck("id", &fileIDs).Error
+	if err != nil {
+		return err
+	}
+
+	for _, fileID := range fileIDs {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case s.queue <- fileID:
+		}
+	}
+
+	return nil
+}
+
+// Queue antrian file yang dibaca oleh worker dari ProcessImages
+func (s *imageService) Queue() <-chan uuid.UUID {
+	return s.queue
+}
+
+// ProcessFile memproses file yang masih pending. Status diubah ke processing lebih dulu
+// sehingga file yang sama tidak diproses dua kali oleh beberapa instance aplikasi.
+func (s *imageService) ProcessFile(ctx context.Context, fileID uuid.UUID) error {
+	result := s.DB.WithContext(ctx).Model(&model.File{}).
+		Where("id = ? AND processing_status = ?", fileID, model.FileProcessingPending).
+		Update("processing_status", model.FileProcessingProcessing)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	file := new(model.File)
+	if err := s.DB.WithContext(ctx).Preload("Variants").First(file, "id = ?", fileID).Error; err != nil {
+		return err
+	}
+
+	status := model.FileProcessingReady
+	processErr := s.processFile(ctx, file)
+	if ctx.Err() != nil {
+		// Aplikasi berhenti selama file diproses, file diproses lagi saat aplikasi dijalankan
+		status = model.FileProcessingPending
+	} else if processErr != nil {
+		status = model.FileProcessingFailed
+	}
+
+	// Status tetap ditulis setelah ctx dibatalkan agar file tidak tertinggal processing
+	err := s.DB.WithContext(context.WithoutCancel(ctx)).Model(file).Update("processing_status", status).Error
+	return errors.Join(processErr, err)
+}
+
+func (s *imageService) processFile(ctx context.Context, file *model.File) error {
+	stored, err := s.StorageService.OpenFile(ctx, file.FilePath)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(stored.Content)
+	stored.Content.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	img, _, err := decodeImage(data)
+	if err != nil {
+		return err
+	}
+	img = utils.OrientImage(img, utils.ImageOrientation(data))
+
+	variants := make([]model.FileVariant, 0, len(config.ImageVariants))
+	for _, imageVariant := range config.ImageVariants {
+		variant, err := s.createVariant(ctx, file, img, imageVariant)
+		if err != nil {
+			s.removeVariants(ctx, variants, nil)
+			return err
+		}
+		variants = append(variants, *variant)
+	}
+
+	err = s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("file_id = ?", file.ID).Delete(&model.FileVariant{}).Error; err != nil {
+			return err
+		}
+		if len(variants) == 0 {
+			return nil
+		}
+		return tx.Create(&variants).Error
+	})
+	if err != nil {
+		// File dihapus selama diproses atau database gagal, variant yang sudah tersimpan tidak dipakai
+		s.removeVariants(ctx, variants, nil)
+		return fmt.Errorf("failed to save variants: %w", err)
+	}
+
+	// Variant lama dengan format yang berbeda tidak tertimpa
+	s.removeVariants(ctx, file.Variants, variants)
+
+	return nil
+}
+
+// stripOriginal menyimpan ulang gambar yang baru diupload tanpa metadata EXIF (termasuk lokasi GPS), XMP dan IPTC
+// sebelum record File dibuat, sehingga file asli tidak pernah tersaji dengan metadata-nya. Gambar dengan orientasi
+// EXIF di-encode ulang dalam posisi tegak, selain itu segment metadata dibuang tanpa mengubah kualitas gambar.
+// FileSize diubah ke ukuran file yang disimpan.
+func stripOriginal(ctx context.Context, storage StorageService, file *model.File) error {
+	ext := strings.ToLower(filepath.Ext(file.FileName))
+	if !config.ImageStripMetadata || !slices.Contains(imageExtensions, ext) || ext == ".gif" {
+		return nil
+	}
+
+	stored, err := storage.OpenFile(ctx, file.FilePath)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(stored.Content)
+	stored.Content.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var original []byte
+	if orientation := utils.ImageOrientation(data); orientation != 1 {
+		img, format, err := decodeImage(data)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "The image can not be read")
+		}
+
+		var buf bytes.Buffer
+		if err := encodeImage(&buf, utils.OrientImage(img, orientation), format, imageOriginalQuality); err != nil {
+			return err
+		}
+		original = buf.Bytes()
+	} else if original, err = utils.StripImageMetadata(data); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "The image can not be read")
+	}
+
+	if bytes.Equal(original, data) {
+		return nil
+	}
+
+	if err := storage.PutFile(ctx, file.FilePath, original, file.ContentType); err != nil {
+		return err
+	}
+
+	file.FileSize = int64(len(original))
+	return nil
+}
+
+// decodeImage membaca gambar yang ukurannya tidak lebih dari imageMaxPixels
+func decodeImage(data []byte) (image.Image, string, error) {
+	imageConfig, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode image: %w", err)
+	}
+	if imageConfig.Width*imageConfig.Height > imageMaxPixels {
+		return nil, "", fmt.Errorf("image of %dx%d pixels is too large", imageConfig.Width, imageConfig.Height)
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	return img, format, nil
+}
+
+func (s *imageService) createVariant(
+	ctx context.Context, file *model.File, img image.Image, imageVariant config.ImageVariant,
+) (*model.FileVariant, error) {
+	resized := utils.ResizeImage(img, imageVariant.Width, imageVariant.Height, imageVariant.Crop)
+
+	var buf bytes.Buffer
+	if err := encodeImage(&buf, resized, imageVariant.Format, imageVariant.Quality); err != nil {
+		return nil, err
+	}
+
+	variant := &model.FileVariant{
+		FileID:      file.ID,
+		Name:        imageVariant.Name,
+		FilePath:    variantPath(file.FilePath, imageVariant),
+		FileSize:    int64(buf.Len()),
+		ContentType: "image/" + imageVariant.Format,
+		Width:       resized.Bounds().Dx(),
+		Height:      resized.Bounds().Dy(),
+	}
+
+	if err := s.StorageService.PutFile(ctx, variant.FilePath, buf.Bytes(), variant.ContentType); err != nil {
+		return nil, err
+	}
+
+	return variant, nil
+}
+
+// removeVariants menghapus file variant yang tidak ada di keep
+func (s *imageService) removeVariants(ctx context.Context, variants, keep []model.FileVariant) {
+	for _, variant := range variants {
+		if slices.ContainsFunc(keep, func(kept model.FileVariant) bool { return kept.FilePath == variant.FilePath }) {
+			continue
+		}
+
+		if err := s.StorageService.RemoveFile(ctx, variant.FilePath); err != nil {
+			s.Log.Errorf("Failed to remove variant %s: %+v", variant.FilePath, err)
+		}
+	}
+}
+
+// imageProcessingStatus status awal file baru, pending untuk gambar yang perlu diproses oleh ImageService
+func imageProcessingStatus(fileName string) string {
+	if !slices.Contains(imageExtensions, strings.ToLower(filepath.Ext(fileName))) {
+		return ""
+	}
+	if len(config.ImageVariants) == 0 {
+		return ""
+	}
+	return model.FileProcessingPending
+}
+
+// variantPath menyimpan variant di subfolder variants dari folder file asli
+func variantPath(filePath string, imageVariant config.ImageVariant) string {
+	filePath = filepath.ToSlash(filePath)
+	name := strings.TrimSuffix(path.Base(filePath), path.Ext(filePath))
+	ext := imageVariant.Format
+	if ext == config.ImageFormatJPEG {
+		ext = "jpg"
+	}
+
+	return path.Join(path.Dir(filePath), "variants", fmt.Sprintf("%s_%s.%s", name, imageVariant.Name, ext))
+}
+
+// encodeImage menulis gambar dalam format jpeg, png, gif atau webp. JPEG tidak mendukung transparansi
+// sehingga gambar diletakkan di atas latar putih.
+func encodeImage(w io.Writer, img image.Image, format string, quality int) error {
+	var err error
+
+	switch format {
+	case config.ImageFormatJPEG:
+		if quality <= 0 {
+			quality = jpeg.DefaultQuality
+		}
+		opaque := image.NewRGBA(img.Bounds())
+		draw.Draw(opaque, opaque.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.Draw(opaque, opaque.Bounds(), img, img.Bounds().Min, draw.Over)
+		err = jpeg.Encode(w, opaque, &jpeg.Options{Quality: quality})
+	case config.ImageFormatPNG:
+		err = png.Encode(w, img)
+	case config.ImageFormatWebP:
+		err = nativewebp.Encode(w, img, nil)
+	case "gif":
+		err = gif.Encode(w, img, nil)
+	default:
+		return fmt.Errorf("unsupported image format %s", format)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to encode %s image: %w", format, err)
+	}
+	return nil
+}
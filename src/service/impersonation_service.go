@@ -0,0 +1,213 @@
+package service
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/policy"
+	"app/src/response"
+	"app/src/utils"
+	"app/src/validation"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const defaultImpersonationExp = 15
+
+// ImpersonationService lets admins act as another user. Every impersonation is stored with the actor,
+// the user, the reason and when it started, ended or expired, and logged as a security event.
+type ImpersonationService interface {
+	StartImpersonation(
+		c *fiber.Ctx, actor *model.User, userID string, req *validation.StartImpersonation,
+	) (*model.Impersonation, *response.TokenExpires, error)
+	StopImpersonation(c *fiber.Ctx) error
+	GetImpersonations(c *fiber.Ctx, params *utils.PaginationParams) (*utils.PaginationResult[model.Impersonation], error)
+}
+
+type impersonationService struct {
+	Log          *logrus.Logger
+	DB           *gorm.DB
+	Validate     *validator.Validate
+	UserService  UserService
+	TokenService TokenService
+}
+
+func NewImpersonationService(
+	db *gorm.DB, validate *validator.Validate, userService UserService, tokenService TokenService,
+) ImpersonationService {
+	return &impersonationService{
+		Log:          utils.Log,
+		DB:           db,
+		Validate:     validate,
+		UserService:  userService,
+		TokenService: tokenService,
+	}
+}
+
+// Actor returns the admin impersonating the user of the request, nil when the request is not impersonated
+func Actor(c *fiber.Ctx) *model.User {
+	actor, _ := c.Locals("actor").(*model.User)
+	return actor
+}
+
+// impersonatedOrganizationID returns the organization the impersonation of the request is bound to, empty when none
+func impersonatedOrganizationID(c *fiber.Ctx) string {
+	organizationID, _ := c.Locals("impersonation_organization_id").(string)
+	return organizationID
+}
+
+// StartImpersonation issues a short-lived access token of the user for the actor. Users who may impersonate
+// can not be impersonated themselves and the user may only have rights the actor has as well,
+// so an impersonation never grants more rights than the actor has. The impersonation is bound to the
+// active organization of the actor, the user's rights in other organizations are never compared and never reached.
+func (s *impersonationService) StartImpersonation(
+	c *fiber.Ctx, actor *model.User, userID string, req *validation.StartImpersonation,
+) (*model.Impersonation, *response.TokenExpires, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, nil, err
+	}
+
+	if actor.ID.String() == userID {
+		return nil, nil, fiber.NewError(fiber.StatusBadRequest, "You can not impersonate yourself")
+	}
+
+	user, err := s.UserService.GetUserByID(c, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rights, err := s.UserService.GetRights(c, user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if policy.HasAllRights(rights, []string{"impersonateUsers"}) {
+		return nil, nil, fiber.NewError(fiber.StatusForbidden, "Users who can impersonate can not be impersonated")
+	}
+
+	// The active membership is the actor's, the user has to be a member of the same organization
+	organizationID := ActiveOrganizationID(c)
+	if organizationID != nil {
+		membership, err := s.UserService.GetMembership(c, user, organizationID.String())
+		if errors.Is(err, errOrganizationNotFound) {
+			return nil, nil, fiber.NewError(fiber.StatusBadRequest, "The user is not a member of the active organization")
+		}
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		rights = append(slices.Clone(rights), config.OrganizationRoles[membership.Role]...)
+	}
+
+	actorRights, err := s.UserService.GetRights(c, actor)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !policy.HasAllRights(actorRights, rights) {
+		return nil, nil, fiber.NewError(fiber.StatusForbidden, "You can not impersonate a user with rights you do not have")
+	}
+
+	expMinutes := config.JWTImpersonationExp
+	if expMinutes <= 0 {
+		expMinutes = defaultImpersonationExp
+	}
+
+	impersonation := &model.Impersonation{
+		ActorID:        &actor.ID,
+		UserID:         &user.ID,
+		OrganizationID: organizationID,
+		Reason:         req.Reason,
+		IPAddress:      truncate(c.IP(), 45),
+		UserAgent:      truncate(c.Get(fiber.HeaderUserAgent), 512),
+		ExpiresAt:      time.Now().UTC().Add(time.Minute * time.Duration(expMinutes)),
+	}
+
+	if err := s.DB.WithContext(c.Context()).Create(impersonation).Error; err != nil {
+		s.Log.Errorf("Failed to create impersonation: %+v", err)
+		return nil, nil, err
+	}
+
+	token, err := s.TokenService.GenerateImpersonationToken(c, impersonation)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.Log.WithFields(logrus.Fields{
+		"event":            "impersonation_started",
+		"impersonation_id": impersonation.ID.String(),
+		"actor_id":         actor.ID.String(),
+		"user_id":          user.ID.String(),
+		"ip":               c.IP(),
+		"reason":           req.Reason,
+	}).Warn("Admin started impersonating a user")
+
+	return impersonation, token, nil
+}
+
+// StopImpersonation ends the impersonation the request was made with and revokes its token
+func (s *impersonationService) StopImpersonation(c *fiber.Ctx) error {
+	actor := Actor(c)
+	impersonationID, _ := c.Locals("impersonation_id").(string)
+
+	if actor == nil || impersonationID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "The request is not impersonated")
+	}
+
+	impersonation := new(model.Impersonation)
+
+	result := s.DB.WithContext(c.Context()).First(impersonation, "id = ?", impersonationID)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, "Impersonation not found")
+	}
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed to get impersonation: %+v", result.Error)
+		return result.Error
+	}
+
+	now := time.Now().UTC()
+	if err := s.DB.WithContext(c.Context()).Model(impersonation).
+		Where("ended_at IS NULL").
+		Update("ended_at", now).Error; err != nil {
+		s.Log.Errorf("Failed to stop impersonation: %+v", err)
+		return err
+	}
+
+	if err := s.TokenService.RevokeImpersonation(c, impersonation); err != nil {
+		return err
+	}
+
+	s.Log.WithFields(logrus.Fields{
+		"event":            "impersonation_stopped",
+		"impersonation_id": impersonation.ID.String(),
+		"actor_id":         actor.ID.String(),
+		"user_id":          impersonation.UserID,
+		"ip":               c.IP(),
+	}).Warn("Admin stopped impersonating a user")
+
+	return nil
+}
+
+func (s *impersonationService) GetImpersonations(
+	c *fiber.Ctx, params *utils.PaginationParams,
+) (*utils.PaginationResult[model.Impersonation], error) {
+	if err := s.Validate.Struct(params); err != nil {
+		return nil, err
+	}
+
+	result, err := utils.ApplyPagination[model.Impersonation](s.DB.WithContext(c.Context()), params, "created_at")
+	if err != nil {
+		s.Log.Errorf("Failed to get impersonations: %+v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/utils"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultLoginMaxAttempts   = 10
+	defaultLoginLockout       = 30
+	defaultLoginAttemptWindow = 15
+	defaultLoginBackoffBase   = 1
+	defaultLoginIPMaxAttempts = 50
+
+	// loginBackoffFreeAttempts failed logins of an email are not delayed, they cover the usual typos
+	loginBackoffFreeAttempts = 3
+)
+
+// ErrAccountLocked is returned by every login of a locked account, even when the password is right
+var ErrAccountLocked = fiber.NewError(fiber.StatusLocked,
+	"Account is locked after too many failed login attempts, reset your password or try again later")
+
+// LoginAttemptService throttles password logins. Failed attempts are stored per email and ip: every failure
+// of an email past the free attempts doubles the delay before the next try, an account is locked once its
+// email reaches the maximum, and an ip failing too often is blocked for every email until the window passes.
+// The counters live in the database so they are shared by every instance and prefork process.
+type LoginAttemptService interface {
+	Check(c *fiber.Ctx, email string) error
+	RecordFailure(c *fiber.Ctx, email string, user *model.User) error
+	RecordSuccess(c *fiber.Ctx, email string) error
+	Unlock(c *fiber.Ctx, userID string) (*model.User, error)
+}
+
+type loginAttemptService struct {
+	Log          *logrus.Logger
+	DB           *gorm.DB
+	EmailService EmailService
+}
+
+func NewLoginAttemptService(db *gorm.DB, emailService EmailService) LoginAttemptService {
+	return &loginAttemptService{
+		Log:          utils.Log,
+		DB:           db,
+		EmailService: emailService,
+	}
+}
+
+// accountLocked reports whether the user is locked out after too many failed password logins
+func accountLocked(user *model.User) bool {
+	return user.LockedUntil != nil && user.LockedUntil.After(time.Now())
+}
+
+// loginFailures sums up the failed attempts of an email or ip inside the window
+type loginFailures struct {
+	Failures      int64
+	FirstFailedAt *time.Time
+	LastFailedAt  *time.Time
+}
+
+// Check returns 429 with a Retry-After header while the email is backing off or the ip is blocked
+func (s *loginAttemptService) Check(c *fiber.Ctx, email string) error {
+	now := time.Now().UTC()
+	window := loginAttemptWindow()
+
+	byIP, err := s.failures(c, "ip", c.IP(), now.Add(-window))
+	if err != nil {
+		return err
+	}
+
+	if byIP.Failures >= int64(loginIPMaxAttempts()) && byIP.FirstFailedAt != nil {
+		return s.throttled(c, byIP.FirstFailedAt.Add(window).Sub(now))
+	}
+
+	byEmail, err := s.failures(c, "email", normalizeLoginEmail(email), now.Add(-window))
+	if err != nil {
+		return err
+	}
+
+	if delay := loginBackoff(byEmail.Failures); delay > 0 && byEmail.LastFailedAt != nil {
+		if retryAfter := byEmail.LastFailedAt.Add(delay).Sub(now); retryAfter > 0 {
+			return s.throttled(c, retryAfter)
+		}
+	}
+
+	return nil
+}
+
+// RecordFailure stores a failed attempt and locks the user once the email reached the maximum,
+// user is nil when the email does not belong to any account
+func (s *loginAttemptService) RecordFailure(c *fiber.Ctx, email string, user *model.User) error {
+	now := time.Now().UTC()
+	window := loginAttemptWindow()
+
+	// Attempts older than the window no longer count, they are removed when new ones are stored
+	if err := s.DB.WithContext(c.Context()).
+		Where("created_at <= ?", now.Add(-window)).
+		Delete(&model.LoginAttempt{}).Error; err != nil {
+		s.Log.Errorf("Failed to prune login attempts: %+v", err)
+		return err
+	}
+
+	if err := s.DB.WithContext(c.Context()).Create(&model.LoginAttempt{
+		Email: normalizeLoginEmail(email),
+		IP:    c.IP(),
+	}).Error; err != nil {
+		s.Log.Errorf("Failed to save login attempt: %+v", err)
+		return err
+	}
+
+	if user == nil {
+		return nil
+	}
+
+	byEmail, err := s.failures(c, "email", normalizeLoginEmail(email), now.Add(-window))
+	if err != nil {
+		return err
+	}
+
+	if byEmail.Failures < int64(loginMaxAttempts()) {
+		return nil
+	}
+
+	return s.lock(c, user)
+}
+
+func (s *loginAttemptService) RecordSuccess(c *fiber.Ctx, email string) error {
+	if err := s.DB.WithContext(c.Context()).
+		Where("email = ?", normalizeLoginEmail(email)).
+		Delete(&model.LoginAttempt{}).Error; err != nil {
+		s.Log.Errorf("Failed to clear login attempts: %+v", err)
+		return err
+	}
+
+	return nil
+}
+
+// Unlock lifts the lock of the user and forgets the failed attempts of its email
+func (s *loginAttemptService) Unlock(c *fiber.Ctx, userID string) (*model.User, error) {
+	user := new(model.User)
+
+	result := s.DB.WithContext(c.Context()).First(user, "id = ?", userID)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, fiber.NewError(fiber.StatusNotFound, "User not found")
+	}
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed get user by id: %+v", result.Error)
+		return nil, result.Error
+	}
+
+	if err := s.DB.WithContext(c.Context()).Model(user).Update("locked_until", nil).Error; err != nil {
+		s.Log.Errorf("Failed to unlock user: %+v", err)
+		return nil, err
+	}
+
+	if err := s.RecordSuccess(c, user.Email); err != nil {
+		return nil, err
+	}
+
+	user.LockedUntil = nil
+
+	return user, nil
+}
+
+func (s *loginAttemptService) lock(c *fiber.Ctx, user *model.User) error {
+	now := time.Now().UTC()
+	until := now.Add(time.Minute * time.Duration(loginLockoutDuration()))
+
+	// Only the request that actually locks the account notifies the owner
+	result := s.DB.WithContext(c.Context()).Model(&model.User{}).
+		Where("id = ? AND (locked_until IS NULL OR locked_until <= ?)", user.ID, now).
+		Update("locked_until", until)
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed to lock user: %+v", result.Error)
+		return result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		s.Log.WithFields(logrus.Fields{
+			"event":        "account_locked",
+			"user_id":      user.ID.String(),
+			"ip":           c.IP(),
+			"locked_until": until,
+		}).Warn("Too many failed login attempts, locking the account")
+
+		if err := s.EmailService.SendAccountLockedEmail(user.Email, c.IP(), until); err != nil {
+			s.Log.Errorf("Failed to send account locked email: %+v", err)
+		}
+	}
+
+	user.LockedUntil = &until
+
+	return ErrAccountLocked
+}
+
+func (s *loginAttemptService) failures(c *fiber.Ctx, column, value string, since time.Time) (*loginFailures, error) {
+	failures := new(loginFailures)
+
+	if err := s.DB.WithContext(c.Context()).Model(&model.LoginAttempt{}).
+		Select("COUNT(*) AS failures, MIN(created_at) AS first_failed_at, MAX(created_at) AS last_failed_at").
+		Where(column+" = ? AND created_at > ?", value, since).
+		Scan(failures).Error; err != nil {
+		s.Log.Errorf("Failed to count login attempts: %+v", err)
+		return nil, err
+	}
+
+	return failures, nil
+}
+
+func (s *loginAttemptService) throttled(c *fiber.Ctx, retryAfter time.Duration) error {
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+
+	return fiber.NewError(fiber.StatusTooManyRequests, "Too many failed login attempts, please try again later")
+}
+
+// loginBackoff is the delay after the last failure, it starts at LOGIN_BACKOFF_SECONDS and doubles
+// with every failure past the free attempts, never longer than the window
+func loginBackoff(failures int64) time.Duration {
+	if failures < loginBackoffFreeAttempts {
+		return 0
+	}
+
+	base := config.LoginBackoffBase
+	if base <= 0 {
+		base = defaultLoginBackoffBase
+	}
+
+	window := loginAttemptWindow()
+
+	delay := time.Second * time.Duration(base)
+	for i := int64(loginBackoffFreeAttempts); i < failures && delay < window; i++ {
+		delay *= 2
+	}
+
+	return min(delay, window)
+}
+
+func loginAttemptWindow() time.Duration {
+	window := config.LoginAttemptWindow
+	if window <= 0 {
+		window = defaultLoginAttemptWindow
+	}
+
+	return time.Minute * time.Duration(window)
+}
+
+func loginMaxAttempts() int {
+	if config.LoginMaxAttempts <= 0 {
+		return defaultLoginMaxAttempts
+	}
+
+	return config.LoginMaxAttempts
+}
+
+func loginLockoutDuration() int {
+	if config.LoginLockoutDuration <= 0 {
+		return defaultLoginLockout
+	}
+
+	return config.LoginLockoutDuration
+}
+
+func loginIPMaxAttempts() int {
+	if config.LoginIPMaxAttempts <= 0 {
+		return defaultLoginIPMaxAttempts
+	}
+
+	return config.LoginIPMaxAttempts
+}
+
+// normalizeLoginEmail keeps the counter of an email from being split by changing its case
+func normalizeLoginEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"app/src/utils"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -30,3 +31,28 @@ func (s *MockEmailService) SendVerificationEmail(to, token string) error {
 	s.Log.Infof("Mock verification email sent to %s", to)
 	return nil
 }
+
+func (s *MockEmailService) SendMagicLinkEmail(to, token string) error {
+	s.Log.Infof("Mock magic link email sent to %s", to)
+	return nil
+}
+
+func (s *MockEmailService) SendAccountLockedEmail(to, ip string, until time.Time) error {
+	s.Log.Infof("Mock account locked email sent to %s", to)
+	return nil
+}
+
+func (s *MockEmailService) SendInvitationEmail(to, organization, token string) error {
+	s.Log.Infof("Mock invitation email sent to %s", to)
+	return nil
+}
+
+func (s *MockEmailService) SendConfirmEmailChangeEmail(to, token string) error {
+	s.Log.Infof("Mock confirm email change email sent to %s", to)
+	return nil
+}
+
+func (s *MockEmailService) SendEmailChangedEmail(to, token string) error {
+	s.Log.Infof("Mock email changed email sent to %s", to)
+	return nil
+}
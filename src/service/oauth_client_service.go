@@ -0,0 +1,168 @@
+package service
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/policy"
+	"app/src/response"
+	"app/src/utils"
+	"app/src/validation"
+	"slices"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// OAuthClientService manages the registry of third-party apps that may obtain tokens from the authorization server
+type OAuthClientService interface {
+	CreateOAuthClient(
+		c *fiber.Ctx, user *model.User, req *validation.CreateOAuthClient,
+	) (*response.OAuthClient, string, error)
+	GetOAuthClients(c *fiber.Ctx) ([]response.OAuthClient, error)
+	DeleteOAuthClient(c *fiber.Ctx, clientID string) error
+}
+
+type oauthClientService struct {
+	Log         *logrus.Logger
+	DB          *gorm.DB
+	Validate    *validator.Validate
+	UserService UserService
+}
+
+func NewOAuthClientService(db *gorm.DB, validate *validator.Validate, userService UserService) OAuthClientService {
+	return &oauthClientService{
+		Log:         utils.Log,
+		DB:          db,
+		Validate:    validate,
+		UserService: userService,
+	}
+}
+
+// CreateOAuthClient registers a client and returns its secret, which is only shown once. Public clients get
+// no secret and can only use the authorization code grant. The scopes must be rights the user has, since
+// client_credentials tokens act as the user who registered the client.
+func (s *oauthClientService) CreateOAuthClient(
+	c *fiber.Ctx, user *model.User, req *validation.CreateOAuthClient,
+) (*response.OAuthClient, string, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, "", err
+	}
+
+	grantTypes := slices.Compact(slices.Sorted(slices.Values(req.GrantTypes)))
+
+	if slices.Contains(grantTypes, config.GrantTypeAuthorizationCode) && len(req.RedirectURIs) == 0 {
+		return nil, "", fiber.NewError(fiber.StatusBadRequest, "The authorization_code grant needs a redirect uri")
+	}
+
+	if slices.Contains(grantTypes, config.GrantTypeClientCredentials) && !req.Confidential {
+		return nil, "", fiber.NewError(fiber.StatusBadRequest, "The client_credentials grant needs a confidential client")
+	}
+
+	for _, redirectURI := range req.RedirectURIs {
+		if strings.ContainsAny(redirectURI, "#,") {
+			return nil, "", fiber.NewError(fiber.StatusBadRequest, "Invalid redirect uri: "+redirectURI)
+		}
+	}
+
+	rights, err := s.UserService.GetRights(c, user)
+	if err != nil {
+		return nil, "", err
+	}
+
+	scopes := slices.Compact(slices.Sorted(slices.Values(req.Scopes)))
+	for _, scope := range scopes {
+		if !policy.HasAllRights(rights, []string{scope}) {
+			return nil, "", fiber.NewError(fiber.StatusBadRequest, "Unknown or not granted scope: "+scope)
+		}
+	}
+
+	client := &model.OAuthClient{
+		UserID:       user.ID,
+		Name:         req.Name,
+		RedirectURIs: strings.Join(req.RedirectURIs, ","),
+		Scopes:       strings.Join(scopes, ","),
+		GrantTypes:   strings.Join(grantTypes, ","),
+	}
+
+	var secret string
+	if req.Confidential {
+		random, errRandom := utils.GenerateRandomString(32)
+		if errRandom != nil {
+			s.Log.Errorf("Failed to generate client secret: %+v", errRandom)
+			return nil, "", errRandom
+		}
+
+		secret = config.OAuthClientSecretPrefix + random
+		client.Secret = utils.HashToken(secret)
+	}
+
+	if err := s.DB.WithContext(c.Context()).Omit("User").Create(client).Error; err != nil {
+		s.Log.Errorf("Failed to create oauth client: %+v", err)
+		return nil, "", err
+	}
+
+	result := toOAuthClientResponse(client)
+
+	return &result, secret, nil
+}
+
+func (s *oauthClientService) GetOAuthClients(c *fiber.Ctx) ([]response.OAuthClient, error) {
+	var clients []model.OAuthClient
+
+	if err := s.DB.WithContext(c.Context()).Order("created_at DESC").Find(&clients).Error; err != nil {
+		s.Log.Errorf("Failed to get oauth clients: %+v", err)
+		return nil, err
+	}
+
+	result := make([]response.OAuthClient, 0, len(clients))
+	for i := range clients {
+		result = append(result, toOAuthClientResponse(&clients[i]))
+	}
+
+	return result, nil
+}
+
+// DeleteOAuthClient removes the client, its pending authorization codes and, through the middleware,
+// every access token issued to it
+func (s *oauthClientService) DeleteOAuthClient(c *fiber.Ctx, clientID string) error {
+	if _, err := uuid.Parse(clientID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid client ID")
+	}
+
+	result := s.DB.WithContext(c.Context()).Where("id = ?", clientID).Delete(&model.OAuthClient{})
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed to delete oauth client: %+v", result.Error)
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return fiber.NewError(fiber.StatusNotFound, "OAuth client not found")
+	}
+
+	return nil
+}
+
+func toOAuthClientResponse(client *model.OAuthClient) response.OAuthClient {
+	return response.OAuthClient{
+		ID:           client.ID,
+		Name:         client.Name,
+		Confidential: client.Confidential(),
+		RedirectURIs: listOrEmpty(client.RedirectURIs),
+		Scopes:       listOrEmpty(client.Scopes),
+		GrantTypes:   listOrEmpty(client.GrantTypes),
+		CreatedAt:    client.CreatedAt,
+	}
+}
+
+func listOrEmpty(list string) []string {
+	values := utils.SplitList(list)
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
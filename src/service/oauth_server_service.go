@@ -0,0 +1,435 @@
+package service
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/response"
+	"app/src/utils"
+	"app/src/validation"
+	"crypto/subtle"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+// OAuthServerService is the authorization server for third-party clients: the authorization code grant
+// with PKCE for acting on behalf of a user and the client_credentials grant. Access tokens are signed by
+// TokenService like our own and carry the client and its scopes.
+type OAuthServerService interface {
+	ValidateAuthorization(c *fiber.Ctx, req *validation.OAuthAuthorize) (*model.OAuthClient, []string, error)
+	Authorize(c *fiber.Ctx, user *model.User, req *validation.OAuthAuthorize) (string, error)
+	Token(c *fiber.Ctx, req *validation.OAuthToken) (*response.OAuthToken, error)
+	Introspect(c *fiber.Ctx, req *validation.OAuthTokenLookup) (*response.OAuthIntrospection, error)
+	Revoke(c *fiber.Ctx, req *validation.OAuthTokenLookup) error
+}
+
+// OAuthAccess is the client and the scopes of a request made with a token issued to an OAuth client
+type OAuthAccess struct {
+	Client *model.OAuthClient
+	UserID uuid.UUID
+	Scopes []string
+}
+
+// OAuthError is an error of the authorization server with its RFC 6749 error code. It unwraps to a
+// fiber error, so routes that do not answer in the OAuth format still return the usual error body.
+type OAuthError struct {
+	Status      int
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func (e *OAuthError) Unwrap() error {
+	return fiber.NewError(e.Status, e.Description)
+}
+
+func oauthError(status int, code, description string) *OAuthError {
+	return &OAuthError{Status: status, Code: code, Description: description}
+}
+
+type oauthServerService struct {
+	Log          *logrus.Logger
+	DB           *gorm.DB
+	Validate     *validator.Validate
+	UserService  UserService
+	TokenService TokenService
+}
+
+func NewOAuthServerService(
+	db *gorm.DB, validate *validator.Validate, userService UserService, tokenService TokenService,
+) OAuthServerService {
+	return &oauthServerService{
+		Log:          utils.Log,
+		DB:           db,
+		Validate:     validate,
+		UserService:  userService,
+		TokenService: tokenService,
+	}
+}
+
+// ActiveOAuthAccess returns the OAuth client and scopes of the request, nil when the token was not issued to a client
+func ActiveOAuthAccess(c *fiber.Ctx) *OAuthAccess {
+	access, _ := c.Locals("oauth_access").(*OAuthAccess)
+	return access
+}
+
+// ValidateAuthorization checks an authorization request and returns the client with the scopes it asks for.
+// An unknown client or redirect uri is returned as a fiber error and must not be redirected to, every later
+// error is an *OAuthError that can be sent back to the redirect uri.
+func (s *oauthServerService) ValidateAuthorization(
+	c *fiber.Ctx, req *validation.OAuthAuthorize,
+) (*model.OAuthClient, []string, error) {
+	client, err := s.getClient(c, req.ClientID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !slices.Contains(utils.SplitList(client.GrantTypes), config.GrantTypeAuthorizationCode) {
+		return nil, nil, fiber.NewError(fiber.StatusBadRequest, "The client can not use the authorization code grant")
+	}
+
+	if !slices.Contains(utils.SplitList(client.RedirectURIs), req.RedirectURI) {
+		return nil, nil, fiber.NewError(fiber.StatusBadRequest, "Invalid redirect uri")
+	}
+
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, nil, oauthError(fiber.StatusBadRequest, "invalid_request",
+			"response_type code and a S256 code_challenge are required")
+	}
+
+	scopes, err := requestedScopes(client, req.Scope)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return client, scopes, nil
+}
+
+// Authorize records the decision of the user and returns the redirect uri of the client with either
+// a one-time authorization code or the access_denied error
+func (s *oauthServerService) Authorize(c *fiber.Ctx, user *model.User, req *validation.OAuthAuthorize) (string, error) {
+	client, scopes, err := s.ValidateAuthorization(c, req)
+	if err != nil {
+		return "", err
+	}
+
+	if !req.Approve {
+		return ClientRedirectURI(req.RedirectURI, map[string]string{"error": "access_denied", "state": req.State}), nil
+	}
+
+	code, err := utils.GenerateRandomString(32)
+	if err != nil {
+		s.Log.Errorf("Failed to generate authorization code: %+v", err)
+		return "", err
+	}
+
+	now := time.Now().UTC()
+
+	// Expired codes are never exchanged, they are cleaned up whenever a new one is issued
+	if err := s.DB.WithContext(c.Context()).
+		Where("expires_at < ?", now).
+		Delete(&model.OAuthAuthorizationCode{}).Error; err != nil {
+		s.Log.Errorf("Failed to delete expired authorization codes: %+v", err)
+	}
+
+	authorizationCode := &model.OAuthAuthorizationCode{
+		Code:          utils.HashToken(code),
+		ClientID:      client.ID,
+		UserID:        user.ID,
+		RedirectURI:   req.RedirectURI,
+		Scopes:        strings.Join(scopes, ","),
+		CodeChallenge: req.CodeChallenge,
+		ExpiresAt:     now.Add(time.Minute * config.OAuthAuthorizationCodeExpMinutes),
+	}
+
+	if err := s.DB.WithContext(c.Context()).Create(authorizationCode).Error; err != nil {
+		s.Log.Errorf("Failed to save authorization code: %+v", err)
+		return "", err
+	}
+
+	return ClientRedirectURI(req.RedirectURI, map[string]string{"code": code, "state": req.State}), nil
+}
+
+func (s *oauthServerService) Token(c *fiber.Ctx, req *validation.OAuthToken) (*response.OAuthToken, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, oauthError(fiber.StatusBadRequest, "invalid_request", "The token request is missing parameters")
+	}
+
+	client, err := s.authenticateClient(c, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.GrantType != config.GrantTypeAuthorizationCode && req.GrantType != config.GrantTypeClientCredentials {
+		return nil, oauthError(fiber.StatusBadRequest, "unsupported_grant_type", "Unsupported grant type")
+	}
+
+	if !slices.Contains(utils.SplitList(client.GrantTypes), req.GrantType) {
+		return nil, oauthError(fiber.StatusBadRequest, "unauthorized_client", "The client can not use this grant type")
+	}
+
+	var (
+		userID uuid.UUID
+		scopes []string
+	)
+
+	if req.GrantType == config.GrantTypeAuthorizationCode {
+		userID, scopes, err = s.exchangeCode(c, client, req)
+	} else {
+		userID, scopes, err = s.clientCredentials(client, req)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.TokenService.GenerateOAuthAccessToken(c, client, userID, scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response.OAuthToken{
+		AccessToken: token.Token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(time.Until(token.Expires).Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
+
+// exchangeCode redeems an authorization code, every code can be used once and only with the verifier of its challenge
+func (s *oauthServerService) exchangeCode(
+	c *fiber.Ctx, client *model.OAuthClient, req *validation.OAuthToken,
+) (uuid.UUID, []string, error) {
+	invalidGrant := oauthError(fiber.StatusBadRequest, "invalid_grant", "Invalid or expired authorization code")
+	authorizationCode := new(model.OAuthAuthorizationCode)
+
+	err := s.DB.WithContext(c.Context()).
+		Where("code = ? AND client_id = ? AND expires_at > ?", utils.HashToken(req.Code), client.ID, time.Now().UTC()).
+		Take(authorizationCode).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return uuid.Nil, nil, invalidGrant
+	}
+
+	if err != nil {
+		s.Log.Errorf("Failed get authorization code: %+v", err)
+		return uuid.Nil, nil, err
+	}
+
+	result := s.DB.WithContext(c.Context()).Where("id = ?", authorizationCode.ID).Delete(&model.OAuthAuthorizationCode{})
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed delete authorization code: %+v", result.Error)
+		return uuid.Nil, nil, result.Error
+	}
+
+	// A concurrent request redeemed the code first
+	if result.RowsAffected == 0 {
+		return uuid.Nil, nil, invalidGrant
+	}
+
+	if req.RedirectURI != authorizationCode.RedirectURI {
+		return uuid.Nil, nil, invalidGrant
+	}
+
+	challenge := oauth2.S256ChallengeFromVerifier(req.CodeVerifier)
+	if subtle.ConstantTimeCompare([]byte(challenge), []byte(authorizationCode.CodeChallenge)) != 1 {
+		return uuid.Nil, nil, oauthError(fiber.StatusBadRequest, "invalid_grant", "Invalid code verifier")
+	}
+
+	return authorizationCode.UserID, utils.SplitList(authorizationCode.Scopes), nil
+}
+
+// clientCredentials issues a token of the user who registered the client, limited to the requested scopes
+func (s *oauthServerService) clientCredentials(
+	client *model.OAuthClient, req *validation.OAuthToken,
+) (uuid.UUID, []string, error) {
+	if !client.Confidential() {
+		return uuid.Nil, nil, oauthError(fiber.StatusBadRequest, "unauthorized_client",
+			"Public clients can not use the client_credentials grant")
+	}
+
+	scopes, err := requestedScopes(client, req.Scope)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	return client.UserID, scopes, nil
+}
+
+// Introspect reports whether an access token issued to the calling client can still be used (RFC 7662).
+// Tokens of other clients and our own tokens are reported as inactive.
+func (s *oauthServerService) Introspect(
+	c *fiber.Ctx, req *validation.OAuthTokenLookup,
+) (*response.OAuthIntrospection, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, oauthError(fiber.StatusBadRequest, "invalid_request", "The token parameter is required")
+	}
+
+	client, err := s.authenticateClient(c, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	inactive := &response.OAuthIntrospection{Active: false}
+
+	claims, err := utils.ParseToken(req.Token, config.JWTKeySet, config.TokenTypeAccess)
+	if err != nil {
+		return inactive, nil
+	}
+
+	if clientID, _ := claims["client_id"].(string); clientID != client.ID.String() {
+		return inactive, nil
+	}
+
+	revoked, err := s.TokenService.IsAccessTokenRevoked(c, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, _ := claims["sub"].(string)
+	user, err := s.UserService.GetUserByID(c, userID)
+	if revoked || err != nil || IssuedBeforeValidity(claims, user) {
+		return inactive, nil
+	}
+
+	clientScopes := utils.SplitList(client.Scopes)
+	scope, _ := claims["scope"].(string)
+	scopes := slices.DeleteFunc(strings.Fields(scope), func(scope string) bool {
+		return !slices.Contains(clientScopes, scope)
+	})
+
+	introspection := &response.OAuthIntrospection{
+		Active:    true,
+		Scope:     strings.Join(scopes, " "),
+		ClientID:  client.ID.String(),
+		Subject:   userID,
+		TokenType: "Bearer",
+	}
+
+	if expires, errExp := claims.GetExpirationTime(); errExp == nil && expires != nil {
+		introspection.ExpiresAt = expires.Unix()
+	}
+
+	if issuedAt, errIat := claims.GetIssuedAt(); errIat == nil && issuedAt != nil {
+		introspection.IssuedAt = issuedAt.Unix()
+	}
+
+	return introspection, nil
+}
+
+// Revoke denies an access token issued to the calling client (RFC 7009). Unknown tokens are not an error,
+// so the response does not tell whether a token was valid.
+func (s *oauthServerService) Revoke(c *fiber.Ctx, req *validation.OAuthTokenLookup) error {
+	if err := s.Validate.Struct(req); err != nil {
+		return oauthError(fiber.StatusBadRequest, "invalid_request", "The token parameter is required")
+	}
+
+	client, err := s.authenticateClient(c, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return err
+	}
+
+	claims, err := utils.ParseToken(req.Token, config.JWTKeySet, config.TokenTypeAccess)
+	if err != nil {
+		return nil
+	}
+
+	if clientID, _ := claims["client_id"].(string); clientID != client.ID.String() {
+		return nil
+	}
+
+	return s.TokenService.RevokeAccessToken(c, claims)
+}
+
+// authenticateClient identifies the client of a token endpoint request. Confidential clients must send their
+// secret, public clients are identified by their id and protected by PKCE instead.
+func (s *oauthServerService) authenticateClient(c *fiber.Ctx, clientID, secret string) (*model.OAuthClient, error) {
+	invalidClient := oauthError(fiber.StatusUnauthorized, "invalid_client", "Client authentication failed")
+
+	client, err := s.getClient(c, clientID)
+	if err != nil {
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			return nil, invalidClient
+		}
+		return nil, err
+	}
+
+	if client.Confidential() &&
+		subtle.ConstantTimeCompare([]byte(utils.HashToken(secret)), []byte(client.Secret)) != 1 {
+		return nil, invalidClient
+	}
+
+	return client, nil
+}
+
+func (s *oauthServerService) getClient(c *fiber.Ctx, clientID string) (*model.OAuthClient, error) {
+	if _, err := uuid.Parse(clientID); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Unknown OAuth client")
+	}
+
+	client := new(model.OAuthClient)
+
+	result := s.DB.WithContext(c.Context()).First(client, "id = ?", clientID)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Unknown OAuth client")
+	}
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed to get oauth client: %+v", result.Error)
+		return nil, result.Error
+	}
+
+	return client, nil
+}
+
+// requestedScopes returns the space separated scopes of a request, or every scope of the client when none
+// are requested. Scopes the client was not registered with are rejected.
+func requestedScopes(client *model.OAuthClient, scope string) ([]string, error) {
+	clientScopes := utils.SplitList(client.Scopes)
+
+	requested := strings.Fields(scope)
+	if len(requested) == 0 {
+		return clientScopes, nil
+	}
+
+	for _, name := range requested {
+		if !slices.Contains(clientScopes, name) {
+			return nil, oauthError(fiber.StatusBadRequest, "invalid_scope", "Unknown or not granted scope: "+name)
+		}
+	}
+
+	return slices.Compact(slices.Sorted(slices.Values(requested))), nil
+}
+
+// ClientRedirectURI adds the values to the query of a registered redirect uri, empty values are left out
+func ClientRedirectURI(redirectURI string, values map[string]string) string {
+	redirectURL, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+
+	query := redirectURL.Query()
+	for key, value := range values {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	redirectURL.RawQuery = query.Encode()
+
+	return redirectURL.String()
+}
@@ -0,0 +1,374 @@
+package service
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/utils"
+	"app/src/validation"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MicahParks/keyfunc/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+)
+
+// idTokenAlgorithms are the asymmetric algorithms accepted for provider id tokens,
+// HMAC is excluded so a public JWKS key can never be used as a shared secret
+var idTokenAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+type OAuthService interface {
+	AuthCodeURL(c *fiber.Ctx, provider string, userID *uuid.UUID) (string, string, error)
+	Exchange(c *fiber.Ctx, provider, state, code string) (*validation.OAuthLogin, *model.OAuthState, error)
+}
+
+type oauthService struct {
+	Log        *logrus.Logger
+	Client     *http.Client
+	StateStore OAuthStateStore
+
+	mu      sync.Mutex
+	issuers map[string]*oidcIssuer
+}
+
+// oidcIssuer is the discovered metadata and signing keys of an OpenID Connect issuer
+type oidcIssuer struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+
+	keys *keyfunc.JWKS
+}
+
+func NewOAuthService(stateStore OAuthStateStore) OAuthService {
+	return &oauthService{
+		Log:        utils.Log,
+		Client:     &http.Client{Timeout: 10 * time.Second},
+		StateStore: stateStore,
+		issuers:    make(map[string]*oidcIssuer),
+	}
+}
+
+// AuthCodeURL starts a login, or a link for the given user, and returns the provider url and the state.
+// The nonce and PKCE verifier never leave the server, they are kept with the hashed state until the callback.
+func (s *oauthService) AuthCodeURL(c *fiber.Ctx, provider string, userID *uuid.UUID) (string, string, error) {
+	oauthConfig, _, err := s.oauthConfig(c, provider)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := utils.GenerateRandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	nonce, err := utils.GenerateRandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	verifier := oauth2.GenerateVerifier()
+
+	if err := s.StateStore.Save(c.Context(), &model.OAuthState{
+		ID:           utils.HashToken(state),
+		Provider:     strings.Clone(provider), // route params are only valid during the request
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		UserID:       userID,
+		ExpiresAt:    time.Now().UTC().Add(time.Minute * config.OAuthStateExpMinutes),
+	}); err != nil {
+		s.Log.Errorf("Failed to save oauth state: %+v", err)
+		return "", "", err
+	}
+
+	url := oauthConfig.AuthCodeURL(state,
+		oauth2.S256ChallengeOption(verifier),
+		oauth2.SetAuthURLParam("nonce", nonce),
+	)
+
+	return url, state, nil
+}
+
+// Exchange consumes the state of the login and exchanges the code for the identity of the user
+func (s *oauthService) Exchange(
+	c *fiber.Ctx, provider, state, code string,
+) (*validation.OAuthLogin, *model.OAuthState, error) {
+	if code == "" {
+		return nil, nil, fiber.NewError(fiber.StatusBadRequest, "Authorization code is required")
+	}
+
+	oauthConfig, issuer, err := s.oauthConfig(c, provider)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	oauthState, err := s.StateStore.Consume(c.Context(), utils.HashToken(state))
+	if errors.Is(err, ErrOAuthStateNotFound) || (err == nil && oauthState.Provider != provider) {
+		return nil, nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired state")
+	}
+
+	if err != nil {
+		s.Log.Errorf("Failed to consume oauth state: %+v", err)
+		return nil, nil, err
+	}
+
+	ctx := context.WithValue(c.Context(), oauth2.HTTPClient, s.Client)
+
+	token, err := oauthConfig.Exchange(ctx, code, oauth2.VerifierOption(oauthState.CodeVerifier))
+	if err != nil {
+		s.Log.Errorf("Failed to exchange %s authorization code: %+v", provider, err)
+		return nil, nil, fiber.NewError(fiber.StatusUnauthorized, "Failed to exchange authorization code")
+	}
+
+	claims := map[string]interface{}{}
+
+	if issuer != nil {
+		if claims, err = s.verifyIDToken(token, issuer, oauthConfig.ClientID, oauthState.Nonce); err != nil {
+			s.Log.Errorf("Failed to verify %s id token: %+v", provider, err)
+			return nil, nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid id token")
+		}
+	}
+
+	// Plain OAuth2 providers have no id token, and some OIDC providers leave the email out of it
+	if claimString(claims, "email") == "" {
+		if userInfoURL := s.userInfoURL(provider, issuer); userInfoURL != "" {
+			userInfo, errUserInfo := s.userInfo(ctx, userInfoURL, token)
+			if errUserInfo != nil {
+				s.Log.Errorf("Failed to get %s user info: %+v", provider, errUserInfo)
+				return nil, nil, fiber.NewError(fiber.StatusBadGateway, "Failed to get user info from the OAuth provider")
+			}
+
+			if subject := claimString(claims, "sub"); subject != "" && subject != claimString(userInfo, "sub", "id") {
+				return nil, nil, fiber.NewError(fiber.StatusUnauthorized, "User info does not match the id token")
+			}
+
+			for key, value := range userInfo {
+				if _, ok := claims[key]; !ok {
+					claims[key] = value
+				}
+			}
+		}
+	}
+
+	login := &validation.OAuthLogin{
+		Provider:      provider,
+		Subject:       claimString(claims, "sub", "id"),
+		Name:          claimString(claims, "name", "preferred_username", "login"),
+		Email:         strings.ToLower(claimString(claims, "email")),
+		VerifiedEmail: claimBool(claims, "email_verified", "verified_email"),
+	}
+
+	if login.Name == "" {
+		login.Name = login.Email
+	}
+
+	if len(login.Name) > 50 {
+		login.Name = login.Name[:50]
+	}
+
+	return login, oauthState, nil
+}
+
+// oauthConfig builds the client config of the provider, discovering the endpoints of OIDC issuers.
+// Providers are looked up on every request so the registry can be changed at runtime, e.g. in tests.
+func (s *oauthService) oauthConfig(c *fiber.Ctx, name string) (*oauth2.Config, *oidcIssuer, error) {
+	provider, ok := config.OAuthProviders[name]
+	if !ok {
+		return nil, nil, fiber.NewError(fiber.StatusNotFound, "OAuth provider not found")
+	}
+
+	oauthConfig := &oauth2.Config{
+		ClientID:     provider.ClientID,
+		ClientSecret: provider.ClientSecret,
+		Scopes:       provider.Scopes,
+		RedirectURL:  provider.RedirectURL,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  provider.AuthURL,
+			TokenURL: provider.TokenURL,
+		},
+	}
+
+	if provider.Issuer == "" {
+		return oauthConfig, nil, nil
+	}
+
+	issuer, err := s.discover(c.Context(), provider.Issuer)
+	if err != nil {
+		s.Log.Errorf("Failed to discover %s issuer: %+v", name, err)
+		return nil, nil, fiber.NewError(fiber.StatusBadGateway, "Failed to reach the OAuth provider")
+	}
+
+	// Explicitly configured endpoints take precedence over the discovered ones
+	if oauthConfig.Endpoint.AuthURL == "" {
+		oauthConfig.Endpoint.AuthURL = issuer.AuthorizationEndpoint
+	}
+
+	if oauthConfig.Endpoint.TokenURL == "" {
+		oauthConfig.Endpoint.TokenURL = issuer.TokenEndpoint
+	}
+
+	return oauthConfig, issuer, nil
+}
+
+// discover loads the OpenID configuration and JWKS of the issuer once and caches them,
+// the JWKS refreshes itself when a token is signed by an unknown key
+func (s *oauthService) discover(ctx context.Context, issuerURL string) (*oidcIssuer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if issuer, ok := s.issuers[issuerURL]; ok {
+		return issuer, nil
+	}
+
+	issuer := new(oidcIssuer)
+	if err := s.getJSON(ctx, issuerURL+"/.well-known/openid-configuration", "", issuer); err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSuffix(issuer.Issuer, "/") != issuerURL {
+		return nil, fmt.Errorf("discovered issuer %q does not match %q", issuer.Issuer, issuerURL)
+	}
+
+	if issuer.AuthorizationEndpoint == "" || issuer.TokenEndpoint == "" || issuer.JWKSURI == "" {
+		return nil, errors.New("openid configuration is missing required endpoints")
+	}
+
+	keys, err := keyfunc.Get(issuer.JWKSURI, keyfunc.Options{
+		Client:            s.Client,
+		RefreshUnknownKID: true,
+		RefreshRateLimit:  5 * time.Minute,
+		RefreshTimeout:    10 * time.Second,
+		RefreshErrorHandler: func(err error) {
+			s.Log.Errorf("Failed to refresh jwks of %s: %+v", issuerURL, err)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	issuer.keys = keys
+	s.issuers[issuerURL] = issuer
+
+	return issuer, nil
+}
+
+func (s *oauthService) verifyIDToken(
+	token *oauth2.Token, issuer *oidcIssuer, clientID, nonce string,
+) (jwt.MapClaims, error) {
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+
+	claims := jwt.MapClaims{}
+
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, issuer.keys.Keyfunc,
+		jwt.WithValidMethods(idTokenAlgorithms),
+		jwt.WithIssuer(issuer.Issuer),
+		jwt.WithAudience(clientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// The nonce binds the id token to this login, a token replayed from another login carries another nonce
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return nil, errors.New("id token nonce does not match")
+	}
+
+	return claims, nil
+}
+
+func (s *oauthService) userInfoURL(name string, issuer *oidcIssuer) string {
+	if url := config.OAuthProviders[name].UserInfoURL; url != "" {
+		return url
+	}
+
+	if issuer != nil {
+		return issuer.UserinfoEndpoint
+	}
+
+	return ""
+}
+
+func (s *oauthService) userInfo(ctx context.Context, url string, token *oauth2.Token) (map[string]interface{}, error) {
+	userInfo := map[string]interface{}{}
+	if err := s.getJSON(ctx, url, token.AccessToken, &userInfo); err != nil {
+		return nil, err
+	}
+
+	return userInfo, nil
+}
+
+func (s *oauthService) getJSON(ctx context.Context, url string, accessToken string, dst interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+
+	decoder := json.NewDecoder(io.LimitReader(resp.Body, 1<<20))
+	decoder.UseNumber()
+
+	return decoder.Decode(dst)
+}
+
+// claimString returns the first non empty claim, numeric ids like GitHub's are returned as strings
+func claimString(claims map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		switch value := claims[key].(type) {
+		case string:
+			if value != "" {
+				return value
+			}
+		case json.Number:
+			return value.String()
+		case float64:
+			return fmt.Sprintf("%.0f", value)
+		}
+	}
+
+	return ""
+}
+
+// claimBool returns the first boolean claim, some providers send booleans as strings
+func claimBool(claims map[string]interface{}, keys ...string) bool {
+	for _, key := range keys {
+		switch value := claims[key].(type) {
+		case bool:
+			return value
+		case string:
+			return value == "true"
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"app/src/model"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrOAuthStateNotFound is returned for unknown, expired or already used states
+var ErrOAuthStateNotFound = errors.New("oauth state not found")
+
+// OAuthStateStore keeps pending provider logins until their callback, every state can be consumed once
+type OAuthStateStore interface {
+	Save(ctx context.Context, state *model.OAuthState) error
+	Consume(ctx context.Context, id string) (*model.OAuthState, error)
+}
+
+// DatabaseOAuthStateStore keeps the states in the oauth_states table so the callback can reach any instance
+type DatabaseOAuthStateStore struct {
+	db *gorm.DB
+}
+
+func NewDatabaseOAuthStateStore(db *gorm.DB) *DatabaseOAuthStateStore {
+	return &DatabaseOAuthStateStore{db: db}
+}
+
+func (s *DatabaseOAuthStateStore) Save(ctx context.Context, state *model.OAuthState) error {
+	// Abandoned logins never reach the callback, their states are removed when new ones are created
+	if err := s.db.WithContext(ctx).
+		Where("expires_at <= ?", time.Now().UTC()).
+		Delete(&model.OAuthState{}).Error; err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Create(state).Error
+}
+
+func (s *DatabaseOAuthStateStore) Consume(ctx context.Context, id string) (*model.OAuthState, error) {
+	state := new(model.OAuthState)
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND expires_at > ?", id, time.Now().UTC()).Take(state).Error; err != nil {
+			return err
+		}
+
+		// Only the request deleting the row may use the state, a concurrent callback gets no rows
+		result := tx.Where("id = ?", id).Delete(&model.OAuthState{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return nil
+	})
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrOAuthStateNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
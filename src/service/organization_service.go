@@ -0,0 +1,583 @@
+package service
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/policy"
+	"app/src/utils"
+	"app/src/validation"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const defaultInvitationExp = 72
+
+var (
+	errOrganizationNotFound = fiber.NewError(fiber.StatusNotFound, "Organization not found")
+	errInvitationNotFound   = fiber.NewError(fiber.StatusNotFound, "Invitation not found")
+	errLastOwner            = fiber.NewError(fiber.StatusBadRequest, "An organization needs at least one owner")
+)
+
+// OrganizationService manages organizations, their members and invitations. Every operation takes the acting
+// user: organizations the user is not a member of are reported as not found, the role of the membership
+// decides what the user may change (see config.OrganizationRoles).
+type OrganizationService interface {
+	CreateOrganization(c *fiber.Ctx, user *model.User, req *validation.CreateOrganization) (*model.Membership, error)
+	GetOrganizations(c *fiber.Ctx, user *model.User) ([]model.Membership, error)
+	GetOrganization(c *fiber.Ctx, user *model.User, organizationID string) (*model.Membership, error)
+	UpdateOrganization(c *fiber.Ctx, user *model.User, organizationID string, req *validation.UpdateOrganization) (*model.Organization, error)
+	DeleteOrganization(c *fiber.Ctx, user *model.User, organizationID string) error
+	GetMembers(c *fiber.Ctx, user *model.User, organizationID string) ([]model.Membership, error)
+	UpdateMember(c *fiber.Ctx, user *model.User, organizationID, memberID string, req *validation.UpdateMembership) (*model.Membership, error)
+	RemoveMember(c *fiber.Ctx, user *model.User, organizationID, memberID string) error
+	CreateInvitation(c *fiber.Ctx, user *model.User, organizationID string, req *validation.CreateInvitation) (*model.Invitation, error)
+	GetInvitations(c *fiber.Ctx, user *model.User, organizationID string) ([]model.Invitation, error)
+	RevokeInvitation(c *fiber.Ctx, user *model.User, organizationID, invitationID string) error
+	AcceptInvitation(c *fiber.Ctx, user *model.User, query *validation.Token) (*model.Membership, error)
+	DeclineInvitation(c *fiber.Ctx, query *validation.Token) error
+	GetMembership(c *fiber.Ctx, userID, organizationID string) (*model.Membership, error)
+}
+
+type organizationService struct {
+	Log          *logrus.Logger
+	DB           *gorm.DB
+	Validate     *validator.Validate
+	EmailService EmailService
+}
+
+func NewOrganizationService(db *gorm.DB, validate *validator.Validate, emailService EmailService) OrganizationService {
+	return &organizationService{
+		Log:          utils.Log,
+		DB:           db,
+		Validate:     validate,
+		EmailService: emailService,
+	}
+}
+
+// ActiveMembership returns the membership of the caller in the organization selected for the request by
+// middleware.Auth, nil when no organization is active
+func ActiveMembership(c *fiber.Ctx) *model.Membership {
+	membership, _ := c.Locals("membership").(*model.Membership)
+	return membership
+}
+
+// ActiveOrganizationID returns the id of the active organization, nil when no organization is active
+func ActiveOrganizationID(c *fiber.Ctx) *uuid.UUID {
+	if membership := ActiveMembership(c); membership != nil {
+		return &membership.OrganizationID
+	}
+
+	return nil
+}
+
+func (s *organizationService) CreateOrganization(
+	c *fiber.Ctx, user *model.User, req *validation.CreateOrganization,
+) (*model.Membership, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	membership := &model.Membership{
+		UserID:       user.ID,
+		Role:         config.OrganizationRoleOwner,
+		Organization: &model.Organization{Name: req.Name},
+	}
+
+	err := s.DB.WithContext(c.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(membership.Organization).Error; err != nil {
+			return err
+		}
+
+		membership.OrganizationID = membership.Organization.ID
+
+		return tx.Omit("Organization", "User").Create(membership).Error
+	})
+
+	if err != nil {
+		s.Log.Errorf("Failed to create organization: %+v", err)
+		return nil, err
+	}
+
+	return membership, nil
+}
+
+func (s *organizationService) GetOrganizations(c *fiber.Ctx, user *model.User) ([]model.Membership, error) {
+	// Like authorize with an empty right, an API key or OAuth token can not list the memberships
+	if _, scoped := scopeRights(c, user.ID, nil); scoped {
+		return nil, fiber.NewError(fiber.StatusForbidden, "You don't have permission to access this resource")
+	}
+
+	memberships := []model.Membership{}
+
+	if err := s.DB.WithContext(c.Context()).
+		Preload("Organization").
+		Where("user_id = ?", user.ID).
+		Order("created_at ASC").
+		Find(&memberships).Error; err != nil {
+		s.Log.Errorf("Failed to get organizations: %+v", err)
+		return nil, err
+	}
+
+	return memberships, nil
+}
+
+func (s *organizationService) GetOrganization(c *fiber.Ctx, user *model.User, organizationID string) (*model.Membership, error) {
+	membership, err := s.GetMembership(c, user.ID.String(), organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	membership.Organization = new(model.Organization)
+	if err := s.DB.WithContext(c.Context()).First(membership.Organization, "id = ?", membership.OrganizationID).Error; err != nil {
+		s.Log.Errorf("Failed to get organization: %+v", err)
+		return nil, err
+	}
+
+	return membership, nil
+}
+
+func (s *organizationService) UpdateOrganization(
+	c *fiber.Ctx, user *model.User, organizationID string, req *validation.UpdateOrganization,
+) (*model.Organization, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	membership, err := s.authorize(c, user, organizationID, "manageOrganization")
+	if err != nil {
+		return nil, err
+	}
+
+	organization := &model.Organization{ID: membership.OrganizationID}
+	if err := s.DB.WithContext(c.Context()).Model(organization).Update("name", req.Name).Error; err != nil {
+		s.Log.Errorf("Failed to update organization: %+v", err)
+		return nil, err
+	}
+
+	if err := s.DB.WithContext(c.Context()).First(organization, "id = ?", organization.ID).Error; err != nil {
+		s.Log.Errorf("Failed to get organization: %+v", err)
+		return nil, err
+	}
+
+	return organization, nil
+}
+
+func (s *organizationService) DeleteOrganization(c *fiber.Ctx, user *model.User, organizationID string) error {
+	membership, err := s.authorize(c, user, organizationID, "manageOrganization")
+	if err != nil {
+		return err
+	}
+
+	result := s.DB.WithContext(c.Context()).Delete(&model.Organization{}, "id = ?", membership.OrganizationID)
+
+	// Files are not cascaded, their objects in the storage have to be deleted through the file endpoints
+	if errors.Is(result.Error, gorm.ErrForeignKeyViolated) {
+		return fiber.NewError(fiber.StatusConflict, "The files of the organization have to be deleted first")
+	}
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed to delete organization: %+v", result.Error)
+		return result.Error
+	}
+
+	return nil
+}
+
+func (s *organizationService) GetMembers(c *fiber.Ctx, user *model.User, organizationID string) ([]model.Membership, error) {
+	membership, err := s.GetMembership(c, user.ID.String(), organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	members := []model.Membership{}
+
+	if err := s.DB.WithContext(c.Context()).
+		Preload("User").
+		Where("organization_id = ?", membership.OrganizationID).
+		Order("created_at ASC").
+		Find(&members).Error; err != nil {
+		s.Log.Errorf("Failed to get members: %+v", err)
+		return nil, err
+	}
+
+	return members, nil
+}
+
+func (s *organizationService) UpdateMember(
+	c *fiber.Ctx, user *model.User, organizationID, memberID string, req *validation.UpdateMembership,
+) (*model.Membership, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	actor, err := s.authorize(c, user, organizationID, "manageMembers")
+	if err != nil {
+		return nil, err
+	}
+
+	member := new(model.Membership)
+
+	err = s.DB.WithContext(c.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := s.lockMember(tx, actor.OrganizationID, memberID, member); err != nil {
+			return err
+		}
+
+		// Owners are the only ones who can make or unmake owners
+		if (member.Role == config.OrganizationRoleOwner || req.Role == config.OrganizationRoleOwner) &&
+			actor.Role != config.OrganizationRoleOwner {
+			return fiber.NewError(fiber.StatusForbidden, "Only owners can manage owners")
+		}
+
+		if member.Role == config.OrganizationRoleOwner && req.Role != config.OrganizationRoleOwner {
+			if err := s.keepOwner(tx, actor.OrganizationID); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(member).Update("role", req.Role).Error; err != nil {
+			return err
+		}
+
+		member.Role = req.Role
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, s.organizationError("Failed to update member", err)
+	}
+
+	return member, nil
+}
+
+// RemoveMember removes a member from the organization, members can always leave on their own
+func (s *organizationService) RemoveMember(c *fiber.Ctx, user *model.User, organizationID, memberID string) error {
+	right := "manageMembers"
+	if memberID == user.ID.String() {
+		right = ""
+	}
+
+	actor, err := s.authorize(c, user, organizationID, right)
+	if err != nil {
+		return err
+	}
+
+	err = s.DB.WithContext(c.Context()).Transaction(func(tx *gorm.DB) error {
+		member := new(model.Membership)
+		if err := s.lockMember(tx, actor.OrganizationID, memberID, member); err != nil {
+			return err
+		}
+
+		if member.Role == config.OrganizationRoleOwner {
+			if member.UserID != actor.UserID && actor.Role != config.OrganizationRoleOwner {
+				return fiber.NewError(fiber.StatusForbidden, "Only owners can manage owners")
+			}
+
+			if err := s.keepOwner(tx, actor.OrganizationID); err != nil {
+				return err
+			}
+		}
+
+		return tx.Delete(member).Error
+	})
+
+	if err != nil {
+		return s.organizationError("Failed to remove member", err)
+	}
+
+	return nil
+}
+
+// CreateInvitation sends an invitation to the email, a pending invitation of the same email is replaced
+func (s *organizationService) CreateInvitation(
+	c *fiber.Ctx, user *model.User, organizationID string, req *validation.CreateInvitation,
+) (*model.Invitation, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	actor, err := s.authorize(c, user, organizationID, "manageMembers")
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Role == config.OrganizationRoleOwner && actor.Role != config.OrganizationRoleOwner {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Only owners can manage owners")
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	var members int64
+	if err := s.DB.WithContext(c.Context()).Model(&model.Membership{}).
+		Joins("JOIN users ON users.id = memberships.user_id").
+		Where("memberships.organization_id = ? AND LOWER(users.email) = ?", actor.OrganizationID, email).
+		Count(&members).Error; err != nil {
+		s.Log.Errorf("Failed to check membership: %+v", err)
+		return nil, err
+	}
+
+	if members > 0 {
+		return nil, fiber.NewError(fiber.StatusConflict, "User is already a member")
+	}
+
+	token, err := utils.GenerateRandomString(32)
+	if err != nil {
+		s.Log.Errorf("Failed to generate invitation token: %+v", err)
+		return nil, err
+	}
+
+	expHours := config.InvitationExp
+	if expHours <= 0 {
+		expHours = defaultInvitationExp
+	}
+
+	invitation := &model.Invitation{
+		OrganizationID: actor.OrganizationID,
+		Email:          email,
+		Role:           req.Role,
+		Token:          utils.HashToken(token),
+		InvitedBy:      &user.ID,
+		ExpiresAt:      time.Now().UTC().Add(time.Hour * time.Duration(expHours)),
+	}
+
+	err = s.DB.WithContext(c.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("organization_id = ? AND email = ?", actor.OrganizationID, email).
+			Delete(&model.Invitation{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(invitation).Error
+	})
+
+	if err != nil {
+		s.Log.Errorf("Failed to create invitation: %+v", err)
+		return nil, err
+	}
+
+	organization := new(model.Organization)
+	if err := s.DB.WithContext(c.Context()).First(organization, "id = ?", actor.OrganizationID).Error; err != nil {
+		s.Log.Errorf("Failed to get organization: %+v", err)
+		return nil, err
+	}
+
+	if err := s.EmailService.SendInvitationEmail(email, organization.Name, token); err != nil {
+		s.Log.Errorf("Failed to send invitation email: %+v", err)
+	}
+
+	return invitation, nil
+}
+
+func (s *organizationService) GetInvitations(c *fiber.Ctx, user *model.User, organizationID string) ([]model.Invitation, error) {
+	actor, err := s.authorize(c, user, organizationID, "manageMembers")
+	if err != nil {
+		return nil, err
+	}
+
+	invitations := []model.Invitation{}
+
+	if err := s.DB.WithContext(c.Context()).
+		Where("organization_id = ? AND expires_at > ?", actor.OrganizationID, time.Now().UTC()).
+		Order("created_at ASC").
+		Find(&invitations).Error; err != nil {
+		s.Log.Errorf("Failed to get invitations: %+v", err)
+		return nil, err
+	}
+
+	return invitations, nil
+}
+
+func (s *organizationService) RevokeInvitation(c *fiber.Ctx, user *model.User, organizationID, invitationID string) error {
+	actor, err := s.authorize(c, user, organizationID, "manageMembers")
+	if err != nil {
+		return err
+	}
+
+	if _, err := uuid.Parse(invitationID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid invitation ID")
+	}
+
+	result := s.DB.WithContext(c.Context()).
+		Where("id = ? AND organization_id = ?", invitationID, actor.OrganizationID).
+		Delete(&model.Invitation{})
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed to revoke invitation: %+v", result.Error)
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errInvitationNotFound
+	}
+
+	return nil
+}
+
+// AcceptInvitation adds the user to the organization, the invitation must have been sent to the email of the user
+func (s *organizationService) AcceptInvitation(c *fiber.Ctx, user *model.User, query *validation.Token) (*model.Membership, error) {
+	if err := s.Validate.Struct(query); err != nil {
+		return nil, err
+	}
+
+	membership := new(model.Membership)
+
+	err := s.DB.WithContext(c.Context()).Transaction(func(tx *gorm.DB) error {
+		invitation, err := s.consumeInvitation(tx, query.Token)
+		if err != nil {
+			return err
+		}
+
+		if !strings.EqualFold(invitation.Email, user.Email) {
+			return fiber.NewError(fiber.StatusForbidden, "The invitation was sent to another email")
+		}
+
+		membership.OrganizationID = invitation.OrganizationID
+		membership.UserID = user.ID
+		membership.Role = invitation.Role
+
+		return tx.Omit("Organization", "User").Create(membership).Error
+	})
+
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, fiber.NewError(fiber.StatusConflict, "User is already a member")
+	}
+
+	if err != nil {
+		return nil, s.organizationError("Failed to accept invitation", err)
+	}
+
+	return membership, nil
+}
+
+// DeclineInvitation deletes the invitation, anyone holding the token can decline it
+func (s *organizationService) DeclineInvitation(c *fiber.Ctx, query *validation.Token) error {
+	if err := s.Validate.Struct(query); err != nil {
+		return err
+	}
+
+	err := s.DB.WithContext(c.Context()).Transaction(func(tx *gorm.DB) error {
+		_, err := s.consumeInvitation(tx, query.Token)
+		return err
+	})
+
+	if err != nil {
+		return s.organizationError("Failed to decline invitation", err)
+	}
+
+	return nil
+}
+
+// GetMembership returns the membership of the user, organizations the user is not a member of are not found
+func (s *organizationService) GetMembership(c *fiber.Ctx, userID, organizationID string) (*model.Membership, error) {
+	if _, err := uuid.Parse(organizationID); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid organization ID")
+	}
+
+	// An impersonated request only reaches the organization the impersonation was started in
+	if Actor(c) != nil && organizationID != impersonatedOrganizationID(c) {
+		return nil, errOrganizationNotFound
+	}
+
+	membership := new(model.Membership)
+
+	result := s.DB.WithContext(c.Context()).
+		First(membership, "organization_id = ? AND user_id = ?", organizationID, userID)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, errOrganizationNotFound
+	}
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed to get membership: %+v", result.Error)
+		return nil, result.Error
+	}
+
+	return membership, nil
+}
+
+// authorize returns the membership of the user when its role grants the right, an empty right only requires a membership.
+// The rights of the role are limited to the scopes of an API key or OAuth token, which can never use an empty right.
+func (s *organizationService) authorize(c *fiber.Ctx, user *model.User, organizationID, right string) (*model.Membership, error) {
+	membership, err := s.GetMembership(c, user.ID.String(), organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	rights, scoped := scopeRights(c, user.ID, config.OrganizationRoles[membership.Role])
+	if (right == "" && scoped) || (right != "" && !policy.HasAllRights(rights, []string{right})) {
+		return nil, fiber.NewError(fiber.StatusForbidden, "You don't have permission to access this resource")
+	}
+
+	return membership, nil
+}
+
+func (s *organizationService) lockMember(tx *gorm.DB, organizationID uuid.UUID, memberID string, member *model.Membership) error {
+	if _, err := uuid.Parse(memberID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(member, "organization_id = ? AND user_id = ?", organizationID, memberID).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, "Member not found")
+	}
+
+	return err
+}
+
+// keepOwner fails when the organization would be left without an owner. The owners are locked,
+// so two owners demoting each other at the same time can not both succeed.
+func (s *organizationService) keepOwner(tx *gorm.DB, organizationID uuid.UUID) error {
+	var owners []model.Membership
+
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("organization_id = ? AND role = ?", organizationID, config.OrganizationRoleOwner).
+		Find(&owners).Error; err != nil {
+		return err
+	}
+
+	if len(owners) <= 1 {
+		return errLastOwner
+	}
+
+	return nil
+}
+
+// consumeInvitation deletes the invitation of the token and returns it, expired invitations are not found
+func (s *organizationService) consumeInvitation(tx *gorm.DB, token string) (*model.Invitation, error) {
+	invitation := new(model.Invitation)
+
+	result := tx.Clauses(clause.Returning{}).
+		Where("token = ? AND expires_at > ?", utils.HashToken(token), time.Now().UTC()).
+		Delete(invitation)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, errInvitationNotFound
+	}
+
+	return invitation, nil
+}
+
+// organizationError keeps errors meant for the client and logs the others
+func (s *organizationService) organizationError(message string, err error) error {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return err
+	}
+
+	s.Log.Errorf("%s: %+v", message, err)
+
+	return err
+}
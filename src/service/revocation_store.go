@@ -0,0 +1,226 @@
+package service
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/utils"
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultRevocationCacheSize     = 10000
+	defaultRevocationPruneInterval = 60
+)
+
+// RevocationStore keeps the ids of revoked access tokens until the tokens expire
+type RevocationStore interface {
+	Revoke(ctx context.Context, id string, expires time.Time) error
+	IsRevoked(ctx context.Context, id string) (bool, error)
+	Prune(ctx context.Context) error
+}
+
+// NewRevocationStore creates the store selected by REVOCATION_STORE
+func NewRevocationStore(db *gorm.DB) RevocationStore {
+	cacheSize := config.RevocationCacheSize
+	if cacheSize <= 0 {
+		cacheSize = defaultRevocationCacheSize
+	}
+
+	switch config.RevocationStore {
+	case "memory":
+		return NewMemoryRevocationStore(cacheSize)
+	default:
+		return NewCachedRevocationStore(NewMemoryRevocationStore(cacheSize), NewDatabaseRevocationStore(db))
+	}
+}
+
+// PruneRevocations removes expired entries from the store periodically until ctx is done
+func PruneRevocations(ctx context.Context, store RevocationStore) {
+	interval := config.RevocationPruneInterval
+	if interval <= 0 {
+		interval = defaultRevocationPruneInterval
+	}
+
+	ticker := time.NewTicker(time.Minute * time.Duration(interval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := store.Prune(ctx); err != nil {
+				utils.Log.Errorf("Failed to prune revoked tokens: %+v", err)
+			}
+		}
+	}
+}
+
+type revocationEntry struct {
+	id      string
+	expires time.Time
+}
+
+// MemoryRevocationStore is a bounded LRU, the least recently used entries are evicted when it is full
+type MemoryRevocationStore struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+func NewMemoryRevocationStore(size int) *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		size:    size,
+		entries: make(map[string]*list.Element, size),
+		order:   list.New(),
+	}
+}
+
+func (s *MemoryRevocationStore) Revoke(_ context.Context, id string, expires time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, ok := s.entries[id]; ok {
+		element.Value.(*revocationEntry).expires = expires
+		s.order.MoveToFront(element)
+		return nil
+	}
+
+	s.entries[id] = s.order.PushFront(&revocationEntry{id: id, expires: expires})
+
+	for s.order.Len() > s.size {
+		s.remove(s.order.Back())
+	}
+
+	return nil
+}
+
+func (s *MemoryRevocationStore) IsRevoked(_ context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, ok := s.entries[id]
+	if !ok {
+		return false, nil
+	}
+
+	if time.Now().After(element.Value.(*revocationEntry).expires) {
+		s.remove(element)
+		return false, nil
+	}
+
+	s.order.MoveToFront(element)
+	return true, nil
+}
+
+func (s *MemoryRevocationStore) Prune(_ context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for element := s.order.Back(); element != nil; {
+		previous := element.Prev()
+		if now.After(element.Value.(*revocationEntry).expires) {
+			s.remove(element)
+		}
+		element = previous
+	}
+
+	return nil
+}
+
+func (s *MemoryRevocationStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.order.Len()
+}
+
+func (s *MemoryRevocationStore) remove(element *list.Element) {
+	s.order.Remove(element)
+	delete(s.entries, element.Value.(*revocationEntry).id)
+}
+
+// DatabaseRevocationStore keeps the denylist in the revoked_tokens table so every instance sees it
+type DatabaseRevocationStore struct {
+	db *gorm.DB
+}
+
+func NewDatabaseRevocationStore(db *gorm.DB) *DatabaseRevocationStore {
+	return &DatabaseRevocationStore{db: db}
+}
+
+func (s *DatabaseRevocationStore) Revoke(ctx context.Context, id string, expires time.Time) error {
+	return s.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"expires_at"}),
+		}).
+		Create(&model.RevokedToken{ID: id, ExpiresAt: expires.UTC()}).Error
+}
+
+func (s *DatabaseRevocationStore) IsRevoked(ctx context.Context, id string) (bool, error) {
+	revoked := new(model.RevokedToken)
+
+	err := s.db.WithContext(ctx).
+		Where("id = ? AND expires_at > ?", id, time.Now().UTC()).
+		Take(revoked).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (s *DatabaseRevocationStore) Prune(ctx context.Context) error {
+	return s.db.WithContext(ctx).
+		Where("expires_at <= ?", time.Now().UTC()).
+		Delete(&model.RevokedToken{}).Error
+}
+
+// CachedRevocationStore answers from the in-memory LRU first and falls back to the backing store
+type CachedRevocationStore struct {
+	cache   *MemoryRevocationStore
+	backing RevocationStore
+}
+
+func NewCachedRevocationStore(cache *MemoryRevocationStore, backing RevocationStore) *CachedRevocationStore {
+	return &CachedRevocationStore{
+		cache:   cache,
+		backing: backing,
+	}
+}
+
+func (s *CachedRevocationStore) Revoke(ctx context.Context, id string, expires time.Time) error {
+	if err := s.backing.Revoke(ctx, id, expires); err != nil {
+		return err
+	}
+
+	return s.cache.Revoke(ctx, id, expires)
+}
+
+func (s *CachedRevocationStore) IsRevoked(ctx context.Context, id string) (bool, error) {
+	if revoked, _ := s.cache.IsRevoked(ctx, id); revoked {
+		return true, nil
+	}
+
+	// Only revocations made by this instance are cached, the others are always read from the backing store
+	return s.backing.IsRevoked(ctx, id)
+}
+
+func (s *CachedRevocationStore) Prune(ctx context.Context) error {
+	if err := s.cache.Prune(ctx); err != nil {
+		return err
+	}
+
+	return s.backing.Prune(ctx)
+}
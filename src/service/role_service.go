@@ -0,0 +1,302 @@
+package service
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/utils"
+	"app/src/validation"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const defaultRoleCacheTTL = 60
+
+// RoleService manages the roles of users and resolves the rights of a role for middleware.Auth.
+// Rights are cached per role for ROLE_CACHE_TTL_SECONDS; changes made through the service clear the
+// cache of this instance right away, other instances pick them up when their entries expire.
+type RoleService interface {
+	GetRoles(c *fiber.Ctx) ([]model.Role, error)
+	GetRoleByID(c *fiber.Ctx, id string) (*model.Role, error)
+	GetPermissions(c *fiber.Ctx) ([]model.Permission, error)
+	CreateRole(c *fiber.Ctx, req *validation.CreateRole) (*model.Role, error)
+	UpdateRole(c *fiber.Ctx, req *validation.UpdateRole, id string) (*model.Role, error)
+	DeleteRole(c *fiber.Ctx, id string) error
+	GetRights(c *fiber.Ctx, role string) ([]string, error)
+	InvalidateRights()
+}
+
+type roleService struct {
+	Log      *logrus.Logger
+	DB       *gorm.DB
+	Validate *validator.Validate
+
+	mu     sync.RWMutex
+	rights map[string]cachedRights
+}
+
+type cachedRights struct {
+	rights    []string
+	expiresAt time.Time
+}
+
+func NewRoleService(db *gorm.DB, validate *validator.Validate) RoleService {
+	return &roleService{
+		Log:      utils.Log,
+		DB:       db,
+		Validate: validate,
+		rights:   make(map[string]cachedRights),
+	}
+}
+
+func (s *roleService) GetRoles(c *fiber.Ctx) ([]model.Role, error) {
+	roles := []model.Role{}
+
+	result := s.DB.WithContext(c.Context()).
+		Preload("Permissions", orderPermissions).
+		Order("name ASC").
+		Find(&roles)
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed to get roles: %+v", result.Error)
+		return nil, result.Error
+	}
+
+	return roles, nil
+}
+
+func (s *roleService) GetRoleByID(c *fiber.Ctx, id string) (*model.Role, error) {
+	role := new(model.Role)
+
+	result := s.DB.WithContext(c.Context()).
+		Preload("Permissions", orderPermissions).
+		First(role, "id = ?", id)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Role not found")
+	}
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed to get role by id: %+v", result.Error)
+		return nil, result.Error
+	}
+
+	return role, nil
+}
+
+func (s *roleService) GetPermissions(c *fiber.Ctx) ([]model.Permission, error) {
+	permissions := []model.Permission{}
+
+	if err := s.DB.WithContext(c.Context()).Order("name ASC").Find(&permissions).Error; err != nil {
+		s.Log.Errorf("Failed to get permissions: %+v", err)
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+func (s *roleService) CreateRole(c *fiber.Ctx, req *validation.CreateRole) (*model.Role, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	role := &model.Role{
+		Name:        req.Name,
+		Description: req.Description,
+	}
+
+	err := s.DB.WithContext(c.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Permissions").Create(role).Error; err != nil {
+			return err
+		}
+
+		return s.replacePermissions(tx, role, req.Permissions)
+	})
+
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, fiber.NewError(fiber.StatusConflict, "Role already exists")
+	}
+
+	if err != nil {
+		return nil, s.roleError("Failed to create role", err)
+	}
+
+	s.InvalidateRights()
+
+	return s.GetRoleByID(c, role.ID.String())
+}
+
+func (s *roleService) UpdateRole(c *fiber.Ctx, req *validation.UpdateRole, id string) (*model.Role, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	if req.Description == nil && req.Permissions == nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid Request")
+	}
+
+	err := s.DB.WithContext(c.Context()).Transaction(func(tx *gorm.DB) error {
+		role := new(model.Role)
+
+		// The lock keeps two concurrent updates from mixing their permissions
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(role, "id = ?", id).Error; err != nil {
+			return err
+		}
+
+		if req.Description != nil {
+			if err := tx.Model(role).Update("description", *req.Description).Error; err != nil {
+				return err
+			}
+		}
+
+		if req.Permissions != nil {
+			if err := s.replacePermissions(tx, role, *req.Permissions); err != nil {
+				return err
+			}
+
+			if err := tx.Model(role).Update("updated_at", time.Now()).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Role not found")
+	}
+
+	if err != nil {
+		return nil, s.roleError("Failed to update role", err)
+	}
+
+	s.InvalidateRights()
+
+	return s.GetRoleByID(c, id)
+}
+
+func (s *roleService) DeleteRole(c *fiber.Ctx, id string) error {
+	role, err := s.GetRoleByID(c, id)
+	if err != nil {
+		return err
+	}
+
+	if role.Name == config.DefaultRole {
+		return fiber.NewError(fiber.StatusBadRequest, "The default role can not be deleted")
+	}
+
+	result := s.DB.WithContext(c.Context()).Delete(role)
+
+	// users.role references the role by name and is not cascaded
+	if errors.Is(result.Error, gorm.ErrForeignKeyViolated) {
+		return fiber.NewError(fiber.StatusConflict, "Role is still assigned to users")
+	}
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed to delete role: %+v", result.Error)
+		return result.Error
+	}
+
+	s.InvalidateRights()
+
+	return nil
+}
+
+// GetRights returns the permission names of the role, unknown roles have no rights
+func (s *roleService) GetRights(c *fiber.Ctx, role string) ([]string, error) {
+	now := time.Now()
+
+	s.mu.RLock()
+	cached, ok := s.rights[role]
+	s.mu.RUnlock()
+
+	if ok && now.Before(cached.expiresAt) {
+		return cached.rights, nil
+	}
+
+	var rights []string
+
+	if err := s.DB.WithContext(c.Context()).Model(&model.Permission{}).
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Joins("JOIN roles ON roles.id = role_permissions.role_id").
+		Where("roles.name = ?", role).
+		Pluck("permissions.name", &rights).Error; err != nil {
+		s.Log.Errorf("Failed to get role rights: %+v", err)
+		return nil, err
+	}
+
+	ttl := config.RoleCacheTTL
+	if ttl <= 0 {
+		ttl = defaultRoleCacheTTL
+	}
+
+	s.mu.Lock()
+	s.rights[role] = cachedRights{rights: rights, expiresAt: now.Add(time.Second * time.Duration(ttl))}
+	s.mu.Unlock()
+
+	return rights, nil
+}
+
+func (s *roleService) InvalidateRights() {
+	s.mu.Lock()
+	s.rights = make(map[string]cachedRights)
+	s.mu.Unlock()
+}
+
+// replacePermissions sets the permissions of the role to the given names, every name must be a known permission
+func (s *roleService) replacePermissions(tx *gorm.DB, role *model.Role, names []string) error {
+	var permissions []model.Permission
+	if len(names) > 0 {
+		if err := tx.Where("name IN ?", names).Find(&permissions).Error; err != nil {
+			return err
+		}
+	}
+
+	known := make(map[string]struct{}, len(permissions))
+	for _, permission := range permissions {
+		known[permission.Name] = struct{}{}
+	}
+
+	for _, name := range names {
+		if _, ok := known[name]; !ok {
+			return fiber.NewError(fiber.StatusBadRequest, "Unknown permission: "+name)
+		}
+	}
+
+	if err := tx.Where("role_id = ?", role.ID).Delete(&model.RolePermission{}).Error; err != nil {
+		return err
+	}
+
+	if len(permissions) == 0 {
+		return nil
+	}
+
+	rolePermissions := make([]model.RolePermission, 0, len(permissions))
+	for _, permission := range permissions {
+		rolePermissions = append(rolePermissions, model.RolePermission{RoleID: role.ID, PermissionID: permission.ID})
+	}
+
+	return tx.Create(&rolePermissions).Error
+}
+
+// roleError keeps errors meant for the client and logs the others
+func (s *roleService) roleError(message string, err error) error {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return err
+	}
+
+	s.Log.Errorf("%s: %+v", message, err)
+
+	return err
+}
+
+func orderPermissions(db *gorm.DB) *gorm.DB {
+	return db.Order("permissions.name ASC")
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/response"
+	"app/src/utils"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// A session is a refresh token family: it starts at login and survives every rotation
+type SessionService interface {
+	GetSessions(c *fiber.Ctx, userID, currentSessionID string) ([]response.Session, error)
+	RevokeSession(c *fiber.Ctx, userID, sessionID string) error
+	RevokeOtherSessions(c *fiber.Ctx, userID, currentSessionID string) error
+}
+
+type sessionService struct {
+	Log          *logrus.Logger
+	DB           *gorm.DB
+	Validate     *validator.Validate
+	TokenService TokenService
+}
+
+func NewSessionService(db *gorm.DB, validate *validator.Validate, tokenService TokenService) SessionService {
+	return &sessionService{
+		Log:          utils.Log,
+		DB:           db,
+		Validate:     validate,
+		TokenService: tokenService,
+	}
+}
+
+func (s *sessionService) GetSessions(c *fiber.Ctx, userID, currentSessionID string) ([]response.Session, error) {
+	sessions := []response.Session{}
+
+	// The active refresh token of a family holds the latest device info, the family's first token marks the login
+	result := s.DB.WithContext(c.Context()).Model(&model.Token{}).
+		Select(`tokens.family_id AS id, tokens.user_agent, tokens.ip_address, tokens.last_used_at,
+			tokens.expires AS expires_at,
+			(SELECT MIN(f.created_at) FROM tokens f WHERE f.family_id = tokens.family_id) AS created_at`).
+		Where("tokens.user_id = ? AND tokens.type = ?", userID, config.TokenTypeRefresh).
+		Where("tokens.revoked_at IS NULL AND tokens.expires > ?", time.Now().UTC()).
+		Order("tokens.last_used_at DESC").
+		Scan(&sessions)
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed to get sessions: %+v", result.Error)
+		return nil, result.Error
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID.String() == currentSessionID
+	}
+
+	return sessions, nil
+}
+
+func (s *sessionService) RevokeSession(c *fiber.Ctx, userID, sessionID string) error {
+	id, err := uuid.Parse(sessionID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid session ID")
+	}
+
+	result := s.DB.WithContext(c.Context()).
+		Where("user_id = ? AND type = ? AND family_id = ?", userID, config.TokenTypeRefresh, sessionID).
+		Delete(&model.Token{})
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed to revoke session: %+v", result.Error)
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return fiber.NewError(fiber.StatusNotFound, "Session not found")
+	}
+
+	return s.TokenService.RevokeSession(c, id)
+}
+
+func (s *sessionService) RevokeOtherSessions(c *fiber.Ctx, userID, currentSessionID string) error {
+	var sessionIDs []uuid.UUID
+
+	err := s.DB.WithContext(c.Context()).Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&model.Token{}).
+			Where("user_id = ? AND type = ? AND family_id IS NOT NULL", userID, config.TokenTypeRefresh)
+
+		if currentSessionID != "" {
+			query = query.Where("family_id <> ?", currentSessionID)
+		}
+
+		if err := query.Distinct().Pluck("family_id", &sessionIDs).Error; err != nil {
+			return err
+		}
+
+		if len(sessionIDs) == 0 {
+			return nil
+		}
+
+		return tx.Where("user_id = ? AND family_id IN ?", userID, sessionIDs).Delete(&model.Token{}).Error
+	})
+	if err != nil {
+		s.Log.Errorf("Failed to revoke other sessions: %+v", err)
+		return err
+	}
+
+	for _, sessionID := range sessionIDs {
+		if err := s.TokenService.RevokeSession(c, sessionID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// StoredFile isi file yang dibuka dari backend storage beserta informasi untuk conditional dan range request
+type StoredFile struct {
+	Content     io.ReadSeekCloser
+	Size        int64
+	ModTime     time.Time
+	ETag        string
+	ContentType string
+}
+
+// OpenFile membuka file dari local storage, ETag dibuat dari ukuran dan waktu perubahan file
+func (s *LocalStorageService) OpenFile(ctx context.Context, filePath string) (*StoredFile, error) {
+	file, err := os.Open(s.LocalPath(filePath))
+	if os.IsNotExist(err) {
+		return nil, ErrFileNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	return &StoredFile{
+		Content: file,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		ETag:    fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()),
+	}, nil
+}
+
+// OpenFile membuka object dari MinIO, isi object baru diambil saat dibaca
+// sehingga Seek hanya mengambil range yang diminta
+func (s *MinIOStorageService) OpenFile(ctx context.Context, filePath string) (*StoredFile, error) {
+	object, err := s.client.GetObject(ctx, s.bucketName, filePath, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object: %w", err)
+	}
+
+	info, err := object.Stat()
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		object.Close()
+		return nil, ErrFileNotFound
+	}
+	if err != nil {
+		object.Close()
+		return nil, fmt.Errorf("failed to stat object: %w", err)
+	}
+
+	return &StoredFile{
+		Content:     object,
+		Size:        info.Size,
+		ModTime:     info.LastModified,
+		ETag:        `"` + strings.Trim(info.ETag, `"`) + `"`,
+		ContentType: info.ContentType,
+	}, nil
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// PutFile menulis file ke local storage, file yang sudah ada diganti melalui file sementara
+// agar download yang sedang berjalan tidak membaca file setengah jadi
+func (s *LocalStorageService) PutFile(ctx context.Context, filePath string, data []byte, contentType string) error {
+	fullPath := s.LocalPath(filePath)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(fullPath), ".put-*")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Chmod(temp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	if err := os.Rename(temp.Name(), fullPath); err != nil {
+		return fmt.Errorf("failed to save file: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveFile menghapus file dari local storage tanpa record-nya, file yang tidak ada diabaikan
+func (s *LocalStorageService) RemoveFile(ctx context.Context, filePath string) error {
+	if err := os.Remove(s.LocalPath(filePath)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+
+// PutFile menulis object ke MinIO, object yang sudah ada diganti
+func (s *MinIOStorageService) PutFile(ctx context.Context, filePath string, data []byte, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucketName, filePath, bytes.NewReader(data), int64(len(data)),
+		minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		return fmt.Errorf("failed to upload file to MinIO: %w", err)
+	}
+	return nil
+}
+
+// RemoveFile menghapus object dari MinIO tanpa record-nya
+func (s *MinIOStorageService) RemoveFile(ctx context.Context, filePath string) error {
+	if err := s.client.RemoveObject(ctx, s.bucketName, filePath, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to delete file from MinIO: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/response"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+const defaultPresignedURLExp = 15
+
+// ErrInvalidSignedURL dikembalikan untuk URL local storage yang signature-nya salah atau sudah kadaluarsa
+var ErrInvalidSignedURL = errors.New("invalid or expired signed url")
+
+// PresignedURLExpiration lama presigned URL dapat dipakai
+func PresignedURLExpiration() time.Duration {
+	minutes := config.PresignedURLExp
+	if minutes <= 0 {
+		minutes = defaultPresignedURLExp
+	}
+	return time.Minute * time.Duration(minutes)
+}
+
+// PresignUpload membuat URL PUT ke route /v1/files/signed yang dilayani oleh aplikasi.
+// Content-Type dan ukuran file ikut ditandatangani.
+func (s *LocalStorageService) PresignUpload(
+	ctx context.Context, upload *model.Upload, expires time.Duration,
+) (*response.PresignedURL, error) {
+	presigned := s.signedURL(http.MethodPut, upload.FilePath, upload.ContentType, upload.Size, expires)
+	presigned.Headers = map[string]string{
+		"Content-Type":   upload.ContentType,
+		"Content-Length": strconv.FormatInt(upload.Size, 10),
+	}
+
+	return presigned, nil
+}
+
+// PresignDownload membuat URL GET ke route /v1/files/signed yang dilayani oleh aplikasi
+func (s *LocalStorageService) PresignDownload(
+	ctx context.Context, filePath string, expires time.Duration,
+) (*response.PresignedURL, error) {
+	return s.signedURL(http.MethodGet, filePath, "", 0, expires), nil
+}
+
+// StatFile mendapatkan ukuran file yang tersimpan
+func (s *LocalStorageService) StatFile(ctx context.Context, filePath string) (int64, error) {
+	info, err := os.Stat(s.LocalPath(filePath))
+	if os.IsNotExist(err) {
+		return 0, ErrFileNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	return info.Size(), nil
+}
+
+// LocalPath lokasi file di file system
+func (s *LocalStorageService) LocalPath(filePath string) string {
+	return filepath.Join(s.basePath, filePath)
+}
+
+// VerifySignedURL memeriksa signature dari URL yang dibuat oleh PresignUpload atau PresignDownload.
+// Untuk GET contentType kosong dan size 0.
+func (s *LocalStorageService) VerifySignedURL(
+	method, filePath, contentType string, size int64, expires, signature string,
+) error {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return ErrInvalidSignedURL
+	}
+
+	expected := signStoragePath(method, filePath, contentType, size, expiresAt)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignedURL
+	}
+
+	return nil
+}
+
+// SaveSignedUpload menyimpan body dari PUT presigned URL, file yang sudah terdaftar tidak dapat ditimpa
+func (s *LocalStorageService) SaveSignedUpload(ctx context.Context, filePath string, data []byte) error {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&model.File{}).Where("file_path = ?", filePath).Count(&count).Error
+	if err != nil {
+		return fmt.Errorf("failed to get file: %w", err)
+	}
+	if count > 0 {
+		return ErrInvalidSignedURL
+	}
+
+	fullPath := s.LocalPath(filePath)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	if err := os.WriteFile(fullPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to save file: %w", err)
+	}
+
+	return nil
+}
+
+func (s *LocalStorageService) signedURL(
+	method, filePath, contentType string, size int64, expires time.Duration,
+) *response.PresignedURL {
+	expiresAt := time.Now().Add(expires)
+
+	segments := strings.Split(filepath.ToSlash(filePath), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expiresAt.Unix(), 10))
+	query.Set("signature", signStoragePath(method, filePath, contentType, size, expiresAt.Unix()))
+
+	return &response.PresignedURL{
+		URL: fmt.Sprintf("%s/v1/files/signed/%s?%s",
+			strings.TrimSuffix(config.AppURL, "/"), strings.Join(segments, "/"), query.Encode()),
+		Method:    method,
+		ExpiresAt: time.Unix(expiresAt.Unix(), 0),
+	}
+}
+
+// signStoragePath HMAC-SHA256 dari method, path, content type, ukuran dan waktu kadaluarsa.
+// Key-nya hanya STORAGE_SIGNING_KEY, NewStorageService menolak local storage tanpa key.
+func signStoragePath(method, filePath, contentType string, size int64, expiresAt int64) string {
+	mac := hmac.New(sha256.New, []byte(config.StorageSigningKey))
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%d\n%d", method, filepath.ToSlash(filePath), contentType, size, expiresAt)
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// PresignUpload membuat presigned PUT URL MinIO, Content-Type dan Content-Length ikut ditandatangani
+func (s *MinIOStorageService) PresignUpload(
+	ctx context.Context, upload *model.Upload, expires time.Duration,
+) (*response.PresignedURL, error) {
+	headers := map[string]string{
+		"Content-Type":   upload.ContentType,
+		"Content-Length": strconv.FormatInt(upload.Size, 10),
+	}
+
+	signedHeaders := http.Header{}
+	for key, value := range headers {
+		signedHeaders.Set(key, value)
+	}
+
+	presignedURL, err := s.client.PresignHeader(
+		ctx, http.MethodPut, s.bucketName, upload.FilePath, expires, nil, signedHeaders)
+	if err != nil {
+		return nil, fmt.Errorf("failed to presign upload: %w", err)
+	}
+
+	return &response.PresignedURL{
+		URL:       presignedURL.String(),
+		Method:    http.MethodPut,
+		Headers:   headers,
+		ExpiresAt: time.Now().Add(expires),
+	}, nil
+}
+
+// PresignDownload membuat presigned GET URL MinIO, sehingga bucket tidak perlu public
+func (s *MinIOStorageService) PresignDownload(
+	ctx context.Context, filePath string, expires time.Duration,
+) (*response.PresignedURL, error) {
+	presignedURL, err := s.client.PresignedGetObject(ctx, s.bucketName, filePath, expires, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to presign download: %w", err)
+	}
+
+	return &response.PresignedURL{
+		URL:       presignedURL.String(),
+		Method:    http.MethodGet,
+		ExpiresAt: time.Now().Add(expires),
+	}, nil
+}
+
+// StatFile mendapatkan ukuran object yang tersimpan
+func (s *MinIOStorageService) StatFile(ctx context.Context, filePath string) (int64, error) {
+	info, err := s.client.StatObject(ctx, s.bucketName, filePath, minio.StatObjectOptions{})
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return 0, ErrFileNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat object: %w", err)
+	}
+
+	return info.Size, nil
+}
@@ -3,7 +3,11 @@ package service
 import (
 	"app/src/config"
 	"app/src/model"
+	"app/src/policy"
+	"app/src/response"
+	"app/src/utils"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -20,20 +24,69 @@ import (
 
 // StorageService interface untuk file storage
 type StorageService interface {
-	UploadFile(ctx context.Context, file *multipart.FileHeader, folder string, userID *uuid.UUID) (*FileUploadResult, error)
-	DeleteFile(ctx context.Context, filePath string) error
+	UploadFile(ctx context.Context, file *multipart.FileHeader, folder string, actor FileActor) (*FileUploadResult, error)
+	GetFile(ctx context.Context, fileID uuid.UUID, actor FileActor) (*model.File, error)
+	DeleteFile(ctx context.Context, fileID uuid.UUID, actor FileActor) error
 	GetFileURL(filePath string) string
-	ValidateFile(file *multipart.FileHeader) error
+	GetFileByID(fileID uuid.UUID) (*model.File, error)
 	GetFileByPath(filePath string) (*model.File, error)
-	GetFilesByUser(userID uuid.UUID) ([]model.File, error)
+	GetFilesByUser(userID uuid.UUID, organizationID *uuid.UUID) ([]model.File, error)
+	BeginUpload(ctx context.Context, upload *model.Upload) error
+	WriteChunk(ctx context.Context, upload *model.Upload, chunk []byte) error
+	FinishUpload(ctx context.Context, upload *model.Upload) error
+	CommitUpload(ctx context.Context, upload *model.Upload) error
+	RevertUpload(ctx context.Context, upload *model.Upload) error
+	AbortUpload(ctx context.Context, upload *model.Upload) error
+	PresignUpload(ctx context.Context, upload *model.Upload, expires time.Duration) (*response.PresignedURL, error)
+	PresignDownload(ctx context.Context, filePath string, expires time.Duration) (*response.PresignedURL, error)
+	StatFile(ctx context.Context, filePath string) (int64, error)
+	OpenFile(ctx context.Context, filePath string) (*StoredFile, error)
+	PutFile(ctx context.Context, filePath string, data []byte, contentType string) error
+	RemoveFile(ctx context.Context, filePath string) error
+}
+
+// ErrFileNotFound dikembalikan untuk file yang tidak ada maupun file milik user lain,
+// sehingga user tidak bisa mengetahui keberadaan file orang lain
+var ErrFileNotFound = errors.New("file not found")
+
+// FileActor adalah user yang mengakses file beserta rights dari role-nya
+// dan organization yang sedang aktif (nil jika tidak ada)
+type FileActor struct {
+	UserID         uuid.UUID
+	Rights         []string
+	OrganizationID *uuid.UUID
+}
+
+// CanAccess menentukan apakah actor boleh melihat dan menghapus file,
+// yaitu pemilik file atau user dengan right manageFiles.
+// Saat organization aktif hanya file organization tersebut yang terlihat, tanpa organization
+// aktif file organization hanya terlihat oleh user dengan right manageFiles dari role-nya.
+func (a FileActor) CanAccess(file *model.File) bool {
+	manageFiles := policy.HasAllRights(a.Rights, []string{"manageFiles"})
+
+	if a.OrganizationID != nil {
+		if file.OrganizationID == nil || *file.OrganizationID != *a.OrganizationID {
+			return false
+		}
+	} else if file.OrganizationID != nil && !manageFiles {
+		return false
+	}
+
+	if manageFiles {
+		return true
+	}
+
+	return file.UploadedBy != nil && *file.UploadedBy == a.UserID
 }
 
 // FileUploadResult result dari upload file
 type FileUploadResult struct {
-	FileName string `json:"file_name"`
-	FilePath string `json:"file_path"`
-	FileSize int64  `json:"file_size"`
-	FileURL  string `json:"file_url"`
+	ID               uuid.UUID `json:"id"`
+	FileName         string    `json:"file_name"`
+	FilePath         string    `json:"file_path"`
+	FileSize         int64     `json:"file_size"`
+	FileURL          string    `json:"file_url"`
+	ProcessingStatus string    `json:"processing_status,omitempty"`
 }
 
 // NewStorageService membuat instance StorageService berdasarkan konfigurasi
@@ -42,6 +95,10 @@ func NewStorageService(db *gorm.DB) StorageService {
 	case "minio":
 		return NewMinIOStorageService(db)
 	default:
+		// Tanpa key siapa pun dapat membuat signed URL untuk membaca dan menulis file
+		if config.StorageSigningKey == "" {
+			utils.Log.Fatal("STORAGE_SIGNING_KEY is required to sign the urls of the local storage")
+		}
 		return NewLocalStorageService(db)
 	}
 }
@@ -61,8 +118,14 @@ func NewLocalStorageService(db *gorm.DB) *LocalStorageService {
 }
 
 // UploadFile upload file ke local storage
-func (s *LocalStorageService) UploadFile(ctx context.Context, file *multipart.FileHeader, folder string, userID *uuid.UUID) (*FileUploadResult, error) {
-	if err := s.ValidateFile(file); err != nil {
+func (s *LocalStorageService) UploadFile(ctx context.Context, file *multipart.FileHeader, folder string, actor FileActor) (*FileUploadResult, error) {
+	folder, err := cleanFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType, err := validateUpload(s.db, file, folder, actor)
+	if err != nil {
 		return nil, err
 	}
 
@@ -96,13 +159,21 @@ func (s *LocalStorageService) UploadFile(ctx context.Context, file *multipart.Fi
 	// Save file info to database
 	relativePath := filepath.Join(folder, fileName)
 	fileRecord := &model.File{
-		FileName:    fileName,
-		FilePath:    relativePath,
-		FileSize:    file.Size,
-		FileURL:     s.GetFileURL(relativePath),
-		ContentType: file.Header.Get("Content-Type"),
-		Folder:      folder,
-		UploadedBy:  userID,
+		FileName:         fileName,
+		OriginalName:     filepath.Base(file.Filename),
+		FilePath:         relativePath,
+		FileSize:         file.Size,
+		FileURL:          s.GetFileURL(relativePath),
+		ContentType:      contentType,
+		Folder:           folder,
+		UploadedBy:       &actor.UserID,
+		OrganizationID:   actor.OrganizationID,
+		ProcessingStatus: imageProcessingStatus(file.Filename),
+	}
+
+	if err := stripOriginal(ctx, s, fileRecord); err != nil {
+		os.Remove(filePath)
+		return nil, err
 	}
 
 	if err := s.db.Create(fileRecord).Error; err != nil {
@@ -113,27 +184,40 @@ func (s *LocalStorageService) UploadFile(ctx context.Context, file *multipart.Fi
 
 	// Return result
 	return &FileUploadResult{
-		FileName: fileName,
-		FilePath: relativePath,
-		FileSize: file.Size,
-		FileURL:  s.GetFileURL(relativePath),
+		ID:               fileRecord.ID,
+		FileName:         fileName,
+		FilePath:         relativePath,
+		FileSize:         fileRecord.FileSize,
+		FileURL:          s.GetFileURL(relativePath),
+		ProcessingStatus: fileRecord.ProcessingStatus,
 	}, nil
 }
 
+// GetFile mendapatkan file yang boleh diakses oleh actor
+func (s *LocalStorageService) GetFile(ctx context.Context, fileID uuid.UUID, actor FileActor) (*model.File, error) {
+	return findAccessibleFile(ctx, s.db, fileID, actor)
+}
+
 // DeleteFile menghapus file dari local storage
-func (s *LocalStorageService) DeleteFile(ctx context.Context, filePath string) error {
+func (s *LocalStorageService) DeleteFile(ctx context.Context, fileID uuid.UUID, actor FileActor) error {
 	// Get file record from database
-	fileRecord, err := s.GetFileByPath(filePath)
+	fileRecord, err := findAccessibleFile(ctx, s.db, fileID, actor)
 	if err != nil {
-		return fmt.Errorf("file not found: %w", err)
+		return err
 	}
 
-	// Delete physical file
-	fullPath := filepath.Join(s.basePath, filePath)
+	// Delete physical file and its variants
+	fullPath := filepath.Join(s.basePath, fileRecord.FilePath)
 	if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to delete file: %w", err)
 	}
 
+	for _, variant := range fileRecord.Variants {
+		if err := s.RemoveFile(ctx, variant.FilePath); err != nil {
+			return err
+		}
+	}
+
 	// Delete file record from database
 	if err := s.db.Delete(fileRecord).Error; err != nil {
 		return fmt.Errorf("failed to delete file record: %w", err)
@@ -147,32 +231,18 @@ func (s *LocalStorageService) GetFileURL(filePath string) string {
 	return fmt.Sprintf("/uploads/%s", strings.ReplaceAll(filePath, "\\", "/"))
 }
 
-// ValidateFile validasi file yang diupload
-func (s *LocalStorageService) ValidateFile(file *multipart.FileHeader) error {
-	if file.Size > config.StorageMaxFileSize {
-		return fmt.Errorf("file size exceeds maximum limit of %d bytes", config.StorageMaxFileSize)
-	}
-
-	// Validate file extension
-	allowedExtensions := []string{".jpg", ".jpeg", ".png", ".gif", ".pdf", ".doc", ".docx", ".txt"}
-	ext := strings.ToLower(filepath.Ext(file.Filename))
-
-	for _, allowedExt := range allowedExtensions {
-		if ext == allowedExt {
-			return nil
-		}
-	}
-
-	return fmt.Errorf("file extension %s is not allowed", ext)
-}
-
 // generateFileName generate nama file yang unik
 func (s *LocalStorageService) generateFileName(originalName string) string {
-	ext := filepath.Ext(originalName)
-	nameWithoutExt := strings.TrimSuffix(originalName, ext)
-	timestamp := time.Now().Format("20060102150405")
-	uuid := uuid.New().String()[:8]
-	return fmt.Sprintf("%s_%s_%s%s", nameWithoutExt, timestamp, uuid, ext)
+	return generateFileName(originalName)
+}
+
+// GetFileByID mendapatkan file berdasarkan ID tanpa memeriksa akses, dipakai oleh policy file
+func (s *LocalStorageService) GetFileByID(fileID uuid.UUID) (*model.File, error) {
+	var file model.File
+	if err := s.db.First(&file, "id = ?", fileID).Error; err != nil {
+		return nil, err
+	}
+	return &file, nil
 }
 
 // GetFileByPath mendapatkan file berdasarkan path
@@ -184,13 +254,9 @@ func (s *LocalStorageService) GetFileByPath(filePath string) (*model.File, error
 	return &file, nil
 }
 
-// GetFilesByUser mendapatkan files berdasarkan user ID
-func (s *LocalStorageService) GetFilesByUser(userID uuid.UUID) ([]model.File, error) {
-	var files []model.File
-	if err := s.db.Where("uploaded_by = ?", userID).Find(&files).Error; err != nil {
-		return nil, err
-	}
-	return files, nil
+// GetFilesByUser mendapatkan files berdasarkan user ID di dalam organization yang aktif
+func (s *LocalStorageService) GetFilesByUser(userID uuid.UUID, organizationID *uuid.UUID) ([]model.File, error) {
+	return findFilesByUser(s.db, userID, organizationID)
 }
 
 // MinIOStorageService implementasi storage untuk MinIO
@@ -235,8 +301,14 @@ func NewMinIOStorageService(db *gorm.DB) *MinIOStorageService {
 }
 
 // UploadFile upload file ke MinIO
-func (s *MinIOStorageService) UploadFile(ctx context.Context, file *multipart.FileHeader, folder string, userID *uuid.UUID) (*FileUploadResult, error) {
-	if err := s.ValidateFile(file); err != nil {
+func (s *MinIOStorageService) UploadFile(ctx context.Context, file *multipart.FileHeader, folder string, actor FileActor) (*FileUploadResult, error) {
+	folder, err := cleanFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType, err := validateUpload(s.db, file, folder, actor)
+	if err != nil {
 		return nil, err
 	}
 
@@ -252,11 +324,6 @@ func (s *MinIOStorageService) UploadFile(ctx context.Context, file *multipart.Fi
 	defer src.Close()
 
 	// Upload to MinIO
-	contentType := file.Header.Get("Content-Type")
-	if contentType == "" {
-		contentType = "application/octet-stream"
-	}
-
 	_, err = s.client.PutObject(ctx, s.bucketName, objectName, src, file.Size, minio.PutObjectOptions{
 		ContentType: contentType,
 	})
@@ -266,13 +333,21 @@ func (s *MinIOStorageService) UploadFile(ctx context.Context, file *multipart.Fi
 
 	// Save file info to database
 	fileRecord := &model.File{
-		FileName:    fileName,
-		FilePath:    objectName,
-		FileSize:    file.Size,
-		FileURL:     s.GetFileURL(objectName),
-		ContentType: contentType,
-		Folder:      folder,
-		UploadedBy:  userID,
+		FileName:         fileName,
+		OriginalName:     filepath.Base(file.Filename),
+		FilePath:         objectName,
+		FileSize:         file.Size,
+		FileURL:          s.GetFileURL(objectName),
+		ContentType:      contentType,
+		Folder:           folder,
+		UploadedBy:       &actor.UserID,
+		OrganizationID:   actor.OrganizationID,
+		ProcessingStatus: imageProcessingStatus(file.Filename),
+	}
+
+	if err := stripOriginal(ctx, s, fileRecord); err != nil {
+		s.client.RemoveObject(ctx, s.bucketName, objectName, minio.RemoveObjectOptions{})
+		return nil, err
 	}
 
 	if err := s.db.Create(fileRecord).Error; err != nil {
@@ -282,27 +357,40 @@ func (s *MinIOStorageService) UploadFile(ctx context.Context, file *multipart.Fi
 	}
 
 	return &FileUploadResult{
-		FileName: fileName,
-		FilePath: objectName,
-		FileSize: file.Size,
-		FileURL:  s.GetFileURL(objectName),
+		ID:               fileRecord.ID,
+		FileName:         fileName,
+		FilePath:         objectName,
+		FileSize:         fileRecord.FileSize,
+		FileURL:          s.GetFileURL(objectName),
+		ProcessingStatus: fileRecord.ProcessingStatus,
 	}, nil
 }
 
+// GetFile mendapatkan file yang boleh diakses oleh actor
+func (s *MinIOStorageService) GetFile(ctx context.Context, fileID uuid.UUID, actor FileActor) (*model.File, error) {
+	return findAccessibleFile(ctx, s.db, fileID, actor)
+}
+
 // DeleteFile menghapus file dari MinIO
-func (s *MinIOStorageService) DeleteFile(ctx context.Context, filePath string) error {
+func (s *MinIOStorageService) DeleteFile(ctx context.Context, fileID uuid.UUID, actor FileActor) error {
 	// Get file record from database
-	fileRecord, err := s.GetFileByPath(filePath)
+	fileRecord, err := findAccessibleFile(ctx, s.db, fileID, actor)
 	if err != nil {
-		return fmt.Errorf("file not found: %w", err)
+		return err
 	}
 
-	// Delete file from MinIO
-	err = s.client.RemoveObject(ctx, s.bucketName, filePath, minio.RemoveObjectOptions{})
+	// Delete file and its variants from MinIO
+	err = s.client.RemoveObject(ctx, s.bucketName, fileRecord.FilePath, minio.RemoveObjectOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to delete file from MinIO: %w", err)
 	}
 
+	for _, variant := range fileRecord.Variants {
+		if err := s.RemoveFile(ctx, variant.FilePath); err != nil {
+			return err
+		}
+	}
+
 	// Delete file record from database
 	if err := s.db.Delete(fileRecord).Error; err != nil {
 		return fmt.Errorf("failed to delete file record: %w", err)
@@ -320,27 +408,36 @@ func (s *MinIOStorageService) GetFileURL(filePath string) string {
 	return fmt.Sprintf("%s://%s/%s/%s", protocol, config.MinIOEndpoint, s.bucketName, filePath)
 }
 
-// ValidateFile validasi file yang diupload untuk MinIO
-func (s *MinIOStorageService) ValidateFile(file *multipart.FileHeader) error {
-	if file.Size > config.StorageMaxFileSize {
-		return fmt.Errorf("file size exceeds maximum limit of %d bytes", config.StorageMaxFileSize)
-	}
+// generateFileName generate nama file yang unik untuk MinIO
+func (s *MinIOStorageService) generateFileName(originalName string) string {
+	return generateFileName(originalName)
+}
 
-	// Validate file extension
-	allowedExtensions := []string{".jpg", ".jpeg", ".png", ".gif", ".pdf", ".doc", ".docx", ".txt"}
-	ext := strings.ToLower(filepath.Ext(file.Filename))
+// GetFileByID mendapatkan file berdasarkan ID tanpa memeriksa akses, dipakai oleh policy file
+func (s *MinIOStorageService) GetFileByID(fileID uuid.UUID) (*model.File, error) {
+	var file model.File
+	if err := s.db.First(&file, "id = ?", fileID).Error; err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
 
-	for _, allowedExt := range allowedExtensions {
-		if ext == allowedExt {
-			return nil
-		}
+// GetFileByPath mendapatkan file berdasarkan path
+func (s *MinIOStorageService) GetFileByPath(filePath string) (*model.File, error) {
+	var file model.File
+	if err := s.db.Where("file_path = ?", filePath).First(&file).Error; err != nil {
+		return nil, err
 	}
+	return &file, nil
+}
 
-	return fmt.Errorf("file extension %s is not allowed", ext)
+// GetFilesByUser mendapatkan files berdasarkan user ID di dalam organization yang aktif
+func (s *MinIOStorageService) GetFilesByUser(userID uuid.UUID, organizationID *uuid.UUID) ([]model.File, error) {
+	return findFilesByUser(s.db, userID, organizationID)
 }
 
-// generateFileName generate nama file yang unik untuk MinIO
-func (s *MinIOStorageService) generateFileName(originalName string) string {
+// generateFileName generate nama file yang unik
+func generateFileName(originalName string) string {
 	ext := filepath.Ext(originalName)
 	nameWithoutExt := strings.TrimSuffix(originalName, ext)
 	timestamp := time.Now().Format("20060102150405")
@@ -348,19 +445,34 @@ func (s *MinIOStorageService) generateFileName(originalName string) string {
 	return fmt.Sprintf("%s_%s_%s%s", nameWithoutExt, timestamp, uuid, ext)
 }
 
-// GetFileByPath mendapatkan file berdasarkan path
-func (s *MinIOStorageService) GetFileByPath(filePath string) (*model.File, error) {
+// findAccessibleFile mencari file berdasarkan ID, file milik user lain diperlakukan seperti file yang tidak ada
+func findAccessibleFile(ctx context.Context, db *gorm.DB, fileID uuid.UUID, actor FileActor) (*model.File, error) {
 	var file model.File
-	if err := s.db.Where("file_path = ?", filePath).First(&file).Error; err != nil {
-		return nil, err
+	if err := db.WithContext(ctx).Preload("Variants").First(&file, "id = ?", fileID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFileNotFound
+		}
+		return nil, fmt.Errorf("failed to get file: %w", err)
 	}
+
+	if !actor.CanAccess(&file) {
+		return nil, ErrFileNotFound
+	}
+
 	return &file, nil
 }
 
-// GetFilesByUser mendapatkan files berdasarkan user ID
-func (s *MinIOStorageService) GetFilesByUser(userID uuid.UUID) ([]model.File, error) {
+// findFilesByUser mencari file yang diupload user, tanpa organization aktif hanya file pribadi yang dikembalikan
+func findFilesByUser(db *gorm.DB, userID uuid.UUID, organizationID *uuid.UUID) ([]model.File, error) {
+	query := db.Preload("Variants").Where("uploaded_by = ?", userID)
+	if organizationID != nil {
+		query = query.Where("organization_id = ?", *organizationID)
+	} else {
+		query = query.Where("organization_id IS NULL")
+	}
+
 	var files []model.File
-	if err := s.db.Where("uploaded_by = ?", userID).Find(&files).Error; err != nil {
+	if err := query.Find(&files).Error; err != nil {
 		return nil, err
 	}
 	return files, nil
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/minio/minio-go/v7"
+)
+
+const (
+	defaultUploadTempPath = "./tmp/uploads"
+	// minPartSize adalah ukuran minimal part S3 kecuali part terakhir
+	minPartSize = 5 << 20
+)
+
+// BeginUpload menyiapkan file sementara untuk upload resumable
+func (s *LocalStorageService) BeginUpload(ctx context.Context, upload *model.Upload) error {
+	if err := os.MkdirAll(uploadTempPath(), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	file, err := os.Create(s.partialPath(upload))
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+
+	return file.Close()
+}
+
+// WriteChunk menulis chunk pada offset upload, sisa dari penulisan yang gagal sebelumnya ditimpa
+func (s *LocalStorageService) WriteChunk(ctx context.Context, upload *model.Upload, chunk []byte) error {
+	file, err := os.OpenFile(s.partialPath(upload), os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteAt(chunk, upload.Offset); err != nil {
+		return fmt.Errorf("failed to write chunk: %w", err)
+	}
+
+	if err := file.Truncate(upload.Offset + int64(len(chunk))); err != nil {
+		return fmt.Errorf("failed to write chunk: %w", err)
+	}
+
+	return nil
+}
+
+// FinishUpload menyalin file sementara ke folder tujuan. File sementara baru dihapus oleh CommitUpload
+// setelah upload tercatat, sehingga chunk terakhir masih dapat dikirim ulang jika pencatatan gagal.
+func (s *LocalStorageService) FinishUpload(ctx context.Context, upload *model.Upload) error {
+	fullPath := filepath.Join(s.basePath, upload.FilePath)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	if err := linkFile(s.partialPath(upload), fullPath); err != nil {
+		return fmt.Errorf("failed to save file: %w", err)
+	}
+
+	return nil
+}
+
+// CommitUpload menghapus file sementara dari upload yang sudah tercatat
+func (s *LocalStorageService) CommitUpload(ctx context.Context, upload *model.Upload) error {
+	if err := os.Remove(s.partialPath(upload)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+
+	return nil
+}
+
+// RevertUpload menghapus file tujuan dari upload yang gagal dicatat, file sementara tetap utuh
+func (s *LocalStorageService) RevertUpload(ctx context.Context, upload *model.Upload) error {
+	if err := os.Remove(s.LocalPath(upload.FilePath)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+
+	return nil
+}
+
+// AbortUpload menghapus file sementara dan file tujuan dari upload yang dibatalkan atau kadaluarsa
+func (s *LocalStorageService) AbortUpload(ctx context.Context, upload *model.Upload) error {
+	for _, path := range []string{s.partialPath(upload), s.LocalPath(upload.FilePath)} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// partialPath lokasi file sementara, di luar STORAGE_LOCAL_PATH agar tidak ikut tersaji di /uploads
+func (s *LocalStorageService) partialPath(upload *model.Upload) string {
+	return filepath.Join(uploadTempPath(), upload.ID.String())
+}
+
+func uploadTempPath() string {
+	if config.StorageUploadTempPath == "" {
+		return defaultUploadTempPath
+	}
+	return config.StorageUploadTempPath
+}
+
+// linkFile membuat hard link ke file, dengan copy jika link gagal karena berbeda file system.
+// File tujuan yang tersisa dari percobaan sebelumnya ditimpa.
+func linkFile(src, dst string) error {
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+
+	return nil
+}
+
+// BeginUpload memulai MinIO multipart upload ke object tujuan
+func (s *MinIOStorageService) BeginUpload(ctx context.Context, upload *model.Upload) error {
+	contentType := upload.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	uploadID, err := s.core().NewMultipartUpload(ctx, s.bucketName, upload.FilePath, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start multipart upload: %w", err)
+	}
+
+	upload.MultipartID = uploadID
+	return nil
+}
+
+// WriteChunk mengirim chunk sebagai part. Part selain yang terakhir minimal 5MB, chunk yang lebih kecil
+// dikumpulkan dulu di object sementara sampai cukup untuk satu part.
+func (s *MinIOStorageService) WriteChunk(ctx context.Context, upload *model.Upload, chunk []byte) error {
+	data := chunk
+
+	if upload.Buffered > 0 {
+		object, err := s.client.GetObject(ctx, s.bucketName, s.bufferObject(upload), minio.GetObjectOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to read buffered chunk: %w", err)
+		}
+		defer object.Close()
+
+		// Object sementara bisa lebih panjang dari Buffered jika penyimpanan offset sebelumnya gagal
+		buffered, err := io.ReadAll(io.LimitReader(object, upload.Buffered))
+		if err != nil {
+			return fmt.Errorf("failed to read buffered chunk: %w", err)
+		}
+		if int64(len(buffered)) != upload.Buffered {
+			return fmt.Errorf("buffered chunk has %d bytes instead of %d", len(buffered), upload.Buffered)
+		}
+
+		data = append(buffered, chunk...)
+	}
+
+	last := upload.Offset+int64(len(chunk)) == upload.Size
+
+	if len(data) < minPartSize && !last {
+		_, err := s.client.PutObject(ctx, s.bucketName, s.bufferObject(upload), bytes.NewReader(data), int64(len(data)),
+			minio.PutObjectOptions{ContentType: "application/octet-stream"})
+		if err != nil {
+			return fmt.Errorf("failed to buffer chunk: %w", err)
+		}
+
+		upload.Buffered = int64(len(data))
+		return nil
+	}
+
+	// Part dengan nomor yang sama ditimpa, sehingga chunk yang offset-nya gagal disimpan dapat dikirim ulang
+	_, err := s.core().PutObjectPart(ctx, s.bucketName, upload.FilePath, upload.MultipartID, upload.Parts+1,
+		bytes.NewReader(data), int64(len(data)), minio.PutObjectPartOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to upload part to MinIO: %w", err)
+	}
+
+	upload.Parts++
+	upload.Buffered = 0
+	return nil
+}
+
+// FinishUpload menggabungkan semua part menjadi object tujuan
+func (s *MinIOStorageService) FinishUpload(ctx context.Context, upload *model.Upload) error {
+	core := s.core()
+	parts := make([]minio.CompletePart, 0, upload.Parts)
+
+	marker := 0
+	for {
+		result, err := core.ListObjectParts(ctx, s.bucketName, upload.FilePath, upload.MultipartID, marker, 1000)
+		if err != nil {
+			return fmt.Errorf("failed to list parts: %w", err)
+		}
+
+		for _, part := range result.ObjectParts {
+			if part.PartNumber <= upload.Parts {
+				parts = append(parts, minio.CompletePart{PartNumber: part.PartNumber, ETag: part.ETag})
+			}
+		}
+
+		if !result.IsTruncated {
+			break
+		}
+		marker = result.NextPartNumberMarker
+	}
+
+	if _, err := core.CompleteMultipartUpload(ctx, s.bucketName, upload.FilePath, upload.MultipartID, parts,
+		minio.PutObjectOptions{ContentType: upload.ContentType}); err != nil {
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+
+	return nil
+}
+
+// CommitUpload menghapus object sementara dari upload yang sudah tercatat
+func (s *MinIOStorageService) CommitUpload(ctx context.Context, upload *model.Upload) error {
+	if err := s.client.RemoveObject(ctx, s.bucketName, s.bufferObject(upload), minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+
+	return nil
+}
+
+// RevertUpload selalu gagal karena multipart upload yang sudah digabungkan tidak dapat dibuka lagi,
+// upload tersebut harus dibatalkan
+func (s *MinIOStorageService) RevertUpload(ctx context.Context, upload *model.Upload) error {
+	return errors.New("completed multipart upload can not be reopened")
+}
+
+// AbortUpload membatalkan multipart upload, menghapus chunk yang belum dikirim dan object tujuan
+// yang mungkin sudah diupload melalui presigned URL
+func (s *MinIOStorageService) AbortUpload(ctx context.Context, upload *model.Upload) error {
+	if upload.MultipartID != "" {
+		err := s.core().AbortMultipartUpload(ctx, s.bucketName, upload.FilePath, upload.MultipartID)
+		if err != nil && minio.ToErrorResponse(err).Code != "NoSuchUpload" {
+			return fmt.Errorf("failed to abort multipart upload: %w", err)
+		}
+	}
+
+	for _, objectName := range []string{s.bufferObject(upload), upload.FilePath} {
+		if err := s.client.RemoveObject(ctx, s.bucketName, objectName, minio.RemoveObjectOptions{}); err != nil {
+			return fmt.Errorf("failed to delete object: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *MinIOStorageService) core() minio.Core {
+	return minio.Core{Client: s.client}
+}
+
+// bufferObject object sementara untuk chunk yang belum cukup besar untuk satu part
+func (s *MinIOStorageService) bufferObject(upload *model.Upload) string {
+	return fmt.Sprintf(".uploads/%s", upload.ID.String())
+}
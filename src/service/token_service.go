@@ -6,6 +6,9 @@ import (
 	res "app/src/response"
 	"app/src/utils"
 	"app/src/validation"
+	"errors"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -16,43 +19,96 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	defaultMagicLinkExp         = 15
+	defaultMagicLinkMaxRequests = 3
+	defaultMagicLinkWindow      = 15
+	defaultRevertEmailExp       = 7
+
+	// apiKeyUsageInterval keeps every request made with an API key from writing its last use
+	apiKeyUsageInterval = time.Minute
+)
+
 type TokenService interface {
 	GenerateToken(userID string, expires time.Time, tokenType string) (string, error)
 	SaveToken(c *fiber.Ctx, token, userID, tokenType string, expires time.Time) error
+	SaveRefreshToken(c *fiber.Ctx, tokenDoc *model.Token) error
 	DeleteToken(c *fiber.Ctx, tokenType string, userID string) error
 	DeleteAllToken(c *fiber.Ctx, userID string) error
+	DeleteTokenFamily(c *fiber.Ctx, token *model.Token) error
+	RevokeTokenFamily(c *fiber.Ctx, token *model.Token) error
 	GetTokenByUserID(c *fiber.Ctx, tokenStr string) (*model.Token, error)
 	GenerateAuthTokens(c *fiber.Ctx, user *model.User) (*res.Tokens, error)
+	RotateAuthTokens(c *fiber.Ctx, token *model.Token, user *model.User) (*res.Tokens, error)
 	GenerateResetPasswordToken(c *fiber.Ctx, req *validation.ForgotPassword) (string, error)
 	GenerateVerifyEmailToken(c *fiber.Ctx, user *model.User) (*string, error)
+	GenerateMagicLinkToken(c *fiber.Ctx, req *validation.MagicLink) (string, error)
+	ConsumeMagicLinkToken(c *fiber.Ctx, token string) (string, error)
+	VerifyStoredToken(c *fiber.Ctx, token, tokenType string) (string, error)
+	GenerateChangeEmailToken(c *fiber.Ctx, user *model.User) (string, error)
+	ConsumeChangeEmailToken(c *fiber.Ctx, token string) (string, error)
+	GenerateRevertEmailToken(c *fiber.Ctx, user *model.User, email string) (string, error)
+	ConsumeRevertEmailToken(c *fiber.Ctx, token string) (string, string, error)
+	GenerateMFAPendingToken(c *fiber.Ctx, user *model.User) (*res.TokenExpires, error)
+	GenerateOrganizationAccessToken(c *fiber.Ctx, user *model.User, organizationID uuid.UUID) (*res.TokenExpires, error)
+	GenerateImpersonationToken(c *fiber.Ctx, impersonation *model.Impersonation) (*res.TokenExpires, error)
+	RevokeImpersonation(c *fiber.Ctx, impersonation *model.Impersonation) error
+	GenerateOAuthCode(c *fiber.Ctx, user *model.User) (string, error)
+	ConsumeOAuthCode(c *fiber.Ctx, code string) (string, error)
+	RevokeAccessToken(c *fiber.Ctx, claims jwt.MapClaims) error
+	RevokeSession(c *fiber.Ctx, sessionID uuid.UUID) error
+	IsAccessTokenRevoked(c *fiber.Ctx, claims jwt.MapClaims) (bool, error)
+	VerifyAPIKey(c *fiber.Ctx, key string) (*model.APIKey, error)
+	GenerateOAuthAccessToken(
+		c *fiber.Ctx, client *model.OAuthClient, userID uuid.UUID, scopes []string,
+	) (*res.TokenExpires, error)
+	VerifyOAuthClient(c *fiber.Ctx, clientID string) (*model.OAuthClient, error)
 }
 
 type tokenService struct {
-	Log         *logrus.Logger
-	DB          *gorm.DB
-	Validate    *validator.Validate
-	UserService UserService
+	Log             *logrus.Logger
+	DB              *gorm.DB
+	Validate        *validator.Validate
+	UserService     UserService
+	RevocationStore RevocationStore
 }
 
-func NewTokenService(db *gorm.DB, validate *validator.Validate, userService UserService) TokenService {
+func NewTokenService(
+	db *gorm.DB, validate *validator.Validate, userService UserService, revocationStore RevocationStore,
+) TokenService {
 	return &tokenService{
-		Log:         utils.Log,
-		DB:          db,
-		Validate:    validate,
-		UserService: userService,
+		Log:             utils.Log,
+		DB:              db,
+		Validate:        validate,
+		UserService:     userService,
+		RevocationStore: revocationStore,
 	}
 }
 
 func (s *tokenService) GenerateToken(userID string, expires time.Time, tokenType string) (string, error) {
-	claims := jwt.MapClaims{
+	return s.signToken(jwt.MapClaims{
 		"sub":  userID,
-		"iat":  time.Now().Unix(),
+		"iat":  issuedAtNow(),
 		"exp":  expires.Unix(),
 		"type": tokenType,
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		"jti":  uuid.New().String(),
+	})
+}
+
+// generateAccessToken adds the session id claim so the session of a request can be identified
+func (s *tokenService) generateAccessToken(userID string, sessionID uuid.UUID, expires time.Time) (string, error) {
+	return s.signToken(jwt.MapClaims{
+		"sub":  userID,
+		"iat":  issuedAtNow(),
+		"exp":  expires.Unix(),
+		"type": config.TokenTypeAccess,
+		"jti":  uuid.New().String(),
+		"sid":  sessionID.String(),
+	})
+}
 
-	return token.SignedString([]byte(config.JWTSecret))
+func (s *tokenService) signToken(claims jwt.MapClaims) (string, error) {
+	return config.JWTKeySet.Sign(claims)
 }
 
 func (s *tokenService) SaveToken(c *fiber.Ctx, token, userID, tokenType string, expires time.Time) error {
@@ -60,6 +116,11 @@ func (s *tokenService) SaveToken(c *fiber.Ctx, token, userID, tokenType string,
 		return err
 	}
 
+	return s.insertToken(c, token, userID, tokenType, expires)
+}
+
+// insertToken stores the token next to the other tokens of the same type
+func (s *tokenService) insertToken(c *fiber.Ctx, token, userID, tokenType string, expires time.Time) error {
 	tokenDoc := &model.Token{
 		Token:   token,
 		UserID:  uuid.MustParse(userID),
@@ -76,6 +137,23 @@ func (s *tokenService) SaveToken(c *fiber.Ctx, token, userID, tokenType string,
 	return result.Error
 }
 
+func (s *tokenService) SaveRefreshToken(c *fiber.Ctx, tokenDoc *model.Token) error {
+	now := time.Now().UTC()
+
+	tokenDoc.Type = config.TokenTypeRefresh
+	tokenDoc.UserAgent = truncate(c.Get(fiber.HeaderUserAgent), 512)
+	tokenDoc.IPAddress = c.IP()
+	tokenDoc.LastUsedAt = &now
+
+	result := s.DB.WithContext(c.Context()).Create(tokenDoc)
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed save refresh token: %+v", result.Error)
+	}
+
+	return result.Error
+}
+
 func (s *tokenService) DeleteToken(c *fiber.Ctx, tokenType string, userID string) error {
 	tokenDoc := new(model.Token)
 
@@ -102,8 +180,36 @@ func (s *tokenService) DeleteAllToken(c *fiber.Ctx, userID string) error {
 	return result.Error
 }
 
+func (s *tokenService) DeleteTokenFamily(c *fiber.Ctx, token *model.Token) error {
+	familyID := token.Family()
+
+	result := s.DB.WithContext(c.Context()).
+		Where("family_id = ? OR id = ?", familyID, familyID).
+		Delete(new(model.Token))
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed to delete token family: %+v", result.Error)
+	}
+
+	return result.Error
+}
+
+func (s *tokenService) RevokeTokenFamily(c *fiber.Ctx, token *model.Token) error {
+	familyID := token.Family()
+
+	result := s.DB.WithContext(c.Context()).Model(&model.Token{}).
+		Where("(family_id = ? OR id = ?) AND revoked_at IS NULL", familyID, familyID).
+		Update("revoked_at", time.Now().UTC())
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed to revoke token family: %+v", result.Error)
+	}
+
+	return result.Error
+}
+
 func (s *tokenService) GetTokenByUserID(c *fiber.Ctx, tokenStr string) (*model.Token, error) {
-	userID, err := utils.VerifyToken(tokenStr, config.JWTSecret, config.TokenTypeRefresh)
+	userID, err := utils.VerifyToken(tokenStr, config.JWTKeySet, config.TokenTypeRefresh)
 	if err != nil {
 		return nil, err
 	}
@@ -111,20 +217,82 @@ func (s *tokenService) GetTokenByUserID(c *fiber.Ctx, tokenStr string) (*model.T
 	tokenDoc := new(model.Token)
 
 	result := s.DB.WithContext(c.Context()).
-		Where("token = ? AND user_id = ?", tokenStr, userID).
+		Where("token = ? AND user_id = ? AND type = ?", tokenStr, userID, config.TokenTypeRefresh).
 		First(tokenDoc)
 
 	if result.Error != nil {
-		s.Log.Errorf("Failed get token by user id: %+v", err)
+		s.Log.Errorf("Failed get token by user id: %+v", result.Error)
 		return nil, result.Error
 	}
 
+	if tokenDoc.RevokedAt != nil {
+		s.reportTokenReuse(c, tokenDoc)
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Please authenticate")
+	}
+
 	return tokenDoc, nil
 }
 
+// GenerateAuthTokens starts a new session. Every login method ends here, so a locked account can not
+// get around the lock with a magic link, a passkey or an OAuth provider.
 func (s *tokenService) GenerateAuthTokens(c *fiber.Ctx, user *model.User) (*res.Tokens, error) {
+	if accountLocked(user) {
+		return nil, ErrAccountLocked
+	}
+
+	return s.generateAuthTokens(c, user, nil)
+}
+
+func (s *tokenService) RotateAuthTokens(c *fiber.Ctx, token *model.Token, user *model.User) (*res.Tokens, error) {
+	// Only one request can rotate a given token, a concurrent or later attempt counts as reuse
+	result := s.DB.WithContext(c.Context()).Model(&model.Token{}).
+		Where("id = ? AND revoked_at IS NULL", token.ID).
+		Update("revoked_at", time.Now().UTC())
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed to rotate refresh token: %+v", result.Error)
+		return nil, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		s.reportTokenReuse(c, token)
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Please authenticate")
+	}
+
+	return s.generateAuthTokens(c, user, token)
+}
+
+// reportTokenReuse revokes the whole family of a refresh token that was already rotated,
+// since either the legitimate client or an attacker is holding a stolen copy
+func (s *tokenService) reportTokenReuse(c *fiber.Ctx, token *model.Token) {
+	s.Log.WithFields(logrus.Fields{
+		"event":     "refresh_token_reuse",
+		"user_id":   token.UserID.String(),
+		"family_id": token.Family().String(),
+		"token_id":  token.ID.String(),
+		"ip":        c.IP(),
+	}).Warn("Refresh token reuse detected, revoking token family")
+
+	if err := s.RevokeTokenFamily(c, token); err != nil {
+		s.Log.Errorf("Failed to revoke reused token family: %+v", err)
+	}
+
+	if err := s.RevokeSession(c, token.Family()); err != nil {
+		s.Log.Errorf("Failed to revoke reused token session: %+v", err)
+	}
+}
+
+func (s *tokenService) generateAuthTokens(c *fiber.Ctx, user *model.User, parent *model.Token) (*res.Tokens, error) {
+	// Rotated tokens stay in the family of the login they descend from, the family is the session
+	sessionID := uuid.New()
+	var parentID *uuid.UUID
+	if parent != nil {
+		sessionID = parent.Family()
+		parentID = &parent.ID
+	}
+
 	accessTokenExpires := time.Now().UTC().Add(time.Minute * time.Duration(config.JWTAccessExp))
-	accessToken, err := s.GenerateToken(user.ID.String(), accessTokenExpires, config.TokenTypeAccess)
+	accessToken, err := s.generateAccessToken(user.ID.String(), sessionID, accessTokenExpires)
 	if err != nil {
 		s.Log.Errorf("Failed generate token: %+v", err)
 		return nil, err
@@ -137,7 +305,15 @@ func (s *tokenService) GenerateAuthTokens(c *fiber.Ctx, user *model.User) (*res.
 		return nil, err
 	}
 
-	if err = s.SaveToken(c, refreshToken, user.ID.String(), config.TokenTypeRefresh, refreshTokenExpires); err != nil {
+	tokenDoc := &model.Token{
+		Token:    refreshToken,
+		UserID:   user.ID,
+		Expires:  refreshTokenExpires,
+		FamilyID: &sessionID,
+		ParentID: parentID,
+	}
+
+	if err = s.SaveRefreshToken(c, tokenDoc); err != nil {
 		return nil, err
 	}
 
@@ -191,3 +367,513 @@ func (s *tokenService) GenerateVerifyEmailToken(c *fiber.Ctx, user *model.User)
 
 	return &verifyEmailToken, nil
 }
+
+// GenerateMagicLinkToken issues a login link for the email. Every email can only request
+// MAGIC_LINK_MAX_REQUESTS links per window, so the endpoint can not be used to flood an inbox.
+func (s *tokenService) GenerateMagicLinkToken(c *fiber.Ctx, req *validation.MagicLink) (string, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return "", err
+	}
+
+	user, err := s.UserService.GetUserByEmail(c, req.Email)
+	if err != nil {
+		return "", err
+	}
+
+	maxRequests := config.MagicLinkMaxRequests
+	if maxRequests <= 0 {
+		maxRequests = defaultMagicLinkMaxRequests
+	}
+
+	window := config.MagicLinkWindow
+	if window <= 0 {
+		window = defaultMagicLinkWindow
+	}
+
+	var count int64
+	if err := s.DB.WithContext(c.Context()).Model(&model.Token{}).
+		Where("user_id = ? AND type = ? AND created_at > ?", user.ID, config.TokenTypeMagicLink,
+			time.Now().UTC().Add(-time.Minute*time.Duration(window))).
+		Count(&count).Error; err != nil {
+		s.Log.Errorf("Failed count magic link tokens: %+v", err)
+		return "", err
+	}
+
+	if count >= int64(maxRequests) {
+		return "", fiber.NewError(fiber.StatusTooManyRequests, "Too many login links requested, please try again later")
+	}
+
+	expMinutes := config.JWTMagicLinkExp
+	if expMinutes <= 0 {
+		expMinutes = defaultMagicLinkExp
+	}
+
+	expires := time.Now().UTC().Add(time.Minute * time.Duration(expMinutes))
+	magicLinkToken, err := s.GenerateToken(user.ID.String(), expires, config.TokenTypeMagicLink)
+	if err != nil {
+		s.Log.Errorf("Failed generate token: %+v", err)
+		return "", err
+	}
+
+	// Older links are kept, they are counted by the limit and stop working once a link is used
+	if err = s.insertToken(c, magicLinkToken, user.ID.String(), config.TokenTypeMagicLink, expires); err != nil {
+		return "", err
+	}
+
+	return magicLinkToken, nil
+}
+
+// ConsumeMagicLinkToken returns the id of the user the link was sent to. The first use deletes
+// every magic link of the user, so a link can not be replayed and older links stop working.
+func (s *tokenService) ConsumeMagicLinkToken(c *fiber.Ctx, token string) (string, error) {
+	userID, err := utils.VerifyToken(token, config.JWTKeySet, config.TokenTypeMagicLink)
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Invalid Token")
+	}
+
+	if err := s.consumeStoredToken(c, token, config.TokenTypeMagicLink, userID); err != nil {
+		return "", err
+	}
+
+	if err := s.DeleteToken(c, config.TokenTypeMagicLink, userID); err != nil {
+		return "", err
+	}
+
+	return userID, nil
+}
+
+// VerifyStoredToken returns the id of the user a link saved with SaveToken was sent to. A link that was
+// used or deleted since, e.g. after an email change, is rejected even though its signature is still valid.
+func (s *tokenService) VerifyStoredToken(c *fiber.Ctx, token, tokenType string) (string, error) {
+	userID, err := utils.VerifyToken(token, config.JWTKeySet, tokenType)
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Invalid Token")
+	}
+
+	var count int64
+	if err := s.DB.WithContext(c.Context()).Model(&model.Token{}).
+		Where("token = ? AND type = ? AND user_id = ?", token, tokenType, userID).
+		Count(&count).Error; err != nil {
+		s.Log.Errorf("Failed get %s token: %+v", tokenType, err)
+		return "", err
+	}
+
+	if count == 0 {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Invalid Token")
+	}
+
+	return userID, nil
+}
+
+// consumeStoredToken deletes a token that was saved with SaveToken, so a link can only be used once
+func (s *tokenService) consumeStoredToken(c *fiber.Ctx, token, tokenType, userID string) error {
+	result := s.DB.WithContext(c.Context()).
+		Where("token = ? AND type = ? AND user_id = ?", token, tokenType, userID).
+		Delete(&model.Token{})
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed delete %s token: %+v", tokenType, result.Error)
+		return result.Error
+	}
+
+	// The link was already used, replaced by a newer one, or a concurrent request used it first
+	if result.RowsAffected == 0 {
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid Token")
+	}
+
+	return nil
+}
+
+// GenerateChangeEmailToken issues the confirmation link sent to the pending email of the user.
+// Requesting another change replaces the link, so only the latest pending email can be confirmed.
+func (s *tokenService) GenerateChangeEmailToken(c *fiber.Ctx, user *model.User) (string, error) {
+	expires := time.Now().UTC().Add(time.Minute * time.Duration(config.JWTVerifyEmailExp))
+	changeEmailToken, err := s.GenerateToken(user.ID.String(), expires, config.TokenTypeChangeEmail)
+	if err != nil {
+		s.Log.Errorf("Failed generate token: %+v", err)
+		return "", err
+	}
+
+	if err = s.SaveToken(c, changeEmailToken, user.ID.String(), config.TokenTypeChangeEmail, expires); err != nil {
+		return "", err
+	}
+
+	return changeEmailToken, nil
+}
+
+// ConsumeChangeEmailToken returns the id of the user whose pending email was confirmed, every link works once
+func (s *tokenService) ConsumeChangeEmailToken(c *fiber.Ctx, token string) (string, error) {
+	userID, err := utils.VerifyToken(token, config.JWTKeySet, config.TokenTypeChangeEmail)
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Invalid Token")
+	}
+
+	if err := s.consumeStoredToken(c, token, config.TokenTypeChangeEmail, userID); err != nil {
+		return "", err
+	}
+
+	return userID, nil
+}
+
+// GenerateRevertEmailToken issues the link sent to the previous email after a change. The previous
+// email is kept in the signed token, so it can be restored without being stored on the user.
+func (s *tokenService) GenerateRevertEmailToken(c *fiber.Ctx, user *model.User, email string) (string, error) {
+	expDays := config.JWTRevertEmailExp
+	if expDays <= 0 {
+		expDays = defaultRevertEmailExp
+	}
+
+	expires := time.Now().UTC().Add(time.Hour * 24 * time.Duration(expDays))
+	revertEmailToken, err := s.signToken(jwt.MapClaims{
+		"sub":   user.ID.String(),
+		"iat":   issuedAtNow(),
+		"exp":   expires.Unix(),
+		"type":  config.TokenTypeRevertEmail,
+		"jti":   uuid.New().String(),
+		"email": email,
+	})
+	if err != nil {
+		s.Log.Errorf("Failed generate token: %+v", err)
+		return "", err
+	}
+
+	if err = s.SaveToken(c, revertEmailToken, user.ID.String(), config.TokenTypeRevertEmail, expires); err != nil {
+		return "", err
+	}
+
+	return revertEmailToken, nil
+}
+
+// ConsumeRevertEmailToken returns the id of the user and the email to restore, every link works once
+func (s *tokenService) ConsumeRevertEmailToken(c *fiber.Ctx, token string) (string, string, error) {
+	claims, err := utils.ParseToken(token, config.JWTKeySet, config.TokenTypeRevertEmail)
+	if err != nil {
+		return "", "", fiber.NewError(fiber.StatusUnauthorized, "Invalid Token")
+	}
+
+	userID, _ := claims["sub"].(string)
+	email, _ := claims["email"].(string)
+	if userID == "" || email == "" {
+		return "", "", fiber.NewError(fiber.StatusUnauthorized, "Invalid Token")
+	}
+
+	if err := s.consumeStoredToken(c, token, config.TokenTypeRevertEmail, userID); err != nil {
+		return "", "", err
+	}
+
+	return userID, email, nil
+}
+
+func (s *tokenService) GenerateMFAPendingToken(c *fiber.Ctx, user *model.User) (*res.TokenExpires, error) {
+	expires := time.Now().UTC().Add(time.Minute * time.Duration(config.JWTMFAPendingExp))
+	mfaPendingToken, err := s.GenerateToken(user.ID.String(), expires, config.TokenTypeMFAPending)
+	if err != nil {
+		s.Log.Errorf("Failed generate token: %+v", err)
+		return nil, err
+	}
+
+	if err = s.SaveToken(c, mfaPendingToken, user.ID.String(), config.TokenTypeMFAPending, expires); err != nil {
+		return nil, err
+	}
+
+	return &res.TokenExpires{
+		Token:   mfaPendingToken,
+		Expires: expires,
+	}, nil
+}
+
+// GenerateOrganizationAccessToken issues an access token of the current session with the organization as org claim,
+// so the organization stays active without sending the header. Refreshing the session drops the claim again.
+func (s *tokenService) GenerateOrganizationAccessToken(
+	c *fiber.Ctx, user *model.User, organizationID uuid.UUID,
+) (*res.TokenExpires, error) {
+	expires := time.Now().UTC().Add(time.Minute * time.Duration(config.JWTAccessExp))
+
+	claims := jwt.MapClaims{
+		"sub":  user.ID.String(),
+		"iat":  issuedAtNow(),
+		"exp":  expires.Unix(),
+		"type": config.TokenTypeAccess,
+		"jti":  uuid.New().String(),
+		"org":  organizationID.String(),
+	}
+
+	if sessionID, ok := c.Locals("session_id").(string); ok {
+		claims["sid"] = sessionID
+	}
+
+	token, err := s.signToken(claims)
+	if err != nil {
+		s.Log.Errorf("Failed generate token: %+v", err)
+		return nil, err
+	}
+
+	return &res.TokenExpires{
+		Token:   token,
+		Expires: expires,
+	}, nil
+}
+
+// GenerateImpersonationToken issues an access token of the impersonated user with the admin in the act claim
+// (RFC 8693). The id of the impersonation is the jti, there is no refresh token and no session. The organization
+// of the impersonation is the org claim.
+func (s *tokenService) GenerateImpersonationToken(
+	c *fiber.Ctx, impersonation *model.Impersonation,
+) (*res.TokenExpires, error) {
+	if impersonation.UserID == nil || impersonation.ActorID == nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid impersonation")
+	}
+
+	claims := jwt.MapClaims{
+		"sub":  impersonation.UserID.String(),
+		"iat":  issuedAtNow(),
+		"exp":  impersonation.ExpiresAt.Unix(),
+		"type": config.TokenTypeAccess,
+		"jti":  impersonation.ID.String(),
+		"act":  map[string]interface{}{"sub": impersonation.ActorID.String()},
+	}
+
+	if impersonation.OrganizationID != nil {
+		claims["org"] = impersonation.OrganizationID.String()
+	}
+
+	token, err := s.signToken(claims)
+	if err != nil {
+		s.Log.Errorf("Failed generate token: %+v", err)
+		return nil, err
+	}
+
+	return &res.TokenExpires{
+		Token:   token,
+		Expires: impersonation.ExpiresAt,
+	}, nil
+}
+
+// RevokeImpersonation denies the impersonation token before it expires
+func (s *tokenService) RevokeImpersonation(c *fiber.Ctx, impersonation *model.Impersonation) error {
+	if err := s.RevocationStore.Revoke(c.Context(), impersonation.ID.String(), impersonation.ExpiresAt); err != nil {
+		s.Log.Errorf("Failed to revoke impersonation: %+v", err)
+		return err
+	}
+
+	return nil
+}
+
+// GenerateOAuthCode issues the one-time code handed to the frontend after a provider login,
+// only its hash is stored so a leaked tokens table can not be used to log in
+func (s *tokenService) GenerateOAuthCode(c *fiber.Ctx, user *model.User) (string, error) {
+	code, err := utils.GenerateRandomString(32)
+	if err != nil {
+		return "", err
+	}
+
+	expires := time.Now().UTC().Add(time.Minute * config.OAuthCodeExpMinutes)
+
+	tokenDoc := &model.Token{
+		Token:   utils.HashToken(code),
+		UserID:  user.ID,
+		Type:    config.TokenTypeOAuthCode,
+		Expires: expires,
+	}
+
+	if err := s.DB.WithContext(c.Context()).Create(tokenDoc).Error; err != nil {
+		s.Log.Errorf("Failed save oauth code: %+v", err)
+		return "", err
+	}
+
+	return code, nil
+}
+
+// ConsumeOAuthCode returns the id of the user the code was issued to, every code can be used once
+func (s *tokenService) ConsumeOAuthCode(c *fiber.Ctx, code string) (string, error) {
+	tokenDoc := new(model.Token)
+
+	err := s.DB.WithContext(c.Context()).
+		Where("token = ? AND type = ? AND expires > ?", utils.HashToken(code), config.TokenTypeOAuthCode, time.Now().UTC()).
+		Take(tokenDoc).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired code")
+	}
+
+	if err != nil {
+		s.Log.Errorf("Failed get oauth code: %+v", err)
+		return "", err
+	}
+
+	result := s.DB.WithContext(c.Context()).Where("id = ?", tokenDoc.ID).Delete(&model.Token{})
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed delete oauth code: %+v", result.Error)
+		return "", result.Error
+	}
+
+	// A concurrent request deleted the code first
+	if result.RowsAffected == 0 {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired code")
+	}
+
+	return tokenDoc.UserID.String(), nil
+}
+
+func (s *tokenService) RevokeAccessToken(c *fiber.Ctx, claims jwt.MapClaims) error {
+	jti, ok := claims["jti"].(string)
+	if !ok {
+		return fiber.NewError(fiber.StatusBadRequest, "Token can not be revoked")
+	}
+
+	expires, err := claims.GetExpirationTime()
+	if err != nil || expires == nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Token can not be revoked")
+	}
+
+	if err := s.RevocationStore.Revoke(c.Context(), jti, expires.Time); err != nil {
+		s.Log.Errorf("Failed to revoke access token: %+v", err)
+		return err
+	}
+
+	return nil
+}
+
+// RevokeSession denies every access token carrying the session id, they all expire within JWTAccessExp
+func (s *tokenService) RevokeSession(c *fiber.Ctx, sessionID uuid.UUID) error {
+	expires := time.Now().UTC().Add(time.Minute * time.Duration(config.JWTAccessExp))
+
+	if err := s.RevocationStore.Revoke(c.Context(), sessionID.String(), expires); err != nil {
+		s.Log.Errorf("Failed to revoke session: %+v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *tokenService) IsAccessTokenRevoked(c *fiber.Ctx, claims jwt.MapClaims) (bool, error) {
+	for _, claim := range []string{"jti", "sid"} {
+		id, ok := claims[claim].(string)
+		if !ok || id == "" {
+			continue
+		}
+
+		revoked, err := s.RevocationStore.IsRevoked(c.Context(), id)
+		if err != nil {
+			s.Log.Errorf("Failed to check token revocation: %+v", err)
+			return false, err
+		}
+
+		if revoked {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// VerifyAPIKey returns the unexpired API key of the given value and records when and from where it was last used
+func (s *tokenService) VerifyAPIKey(c *fiber.Ctx, key string) (*model.APIKey, error) {
+	if !strings.HasPrefix(key, config.APIKeyPrefix) {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid API key")
+	}
+
+	now := time.Now().UTC()
+	apiKey := new(model.APIKey)
+
+	result := s.DB.WithContext(c.Context()).
+		Where("key = ? AND (expires_at IS NULL OR expires_at > ?)", utils.HashToken(key), now).
+		First(apiKey)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid API key")
+	}
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed to get api key: %+v", result.Error)
+		return nil, result.Error
+	}
+
+	ip := truncate(c.IP(), 45)
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyUsageInterval || apiKey.LastUsedIP != ip {
+		if err := s.DB.WithContext(c.Context()).Model(apiKey).
+			UpdateColumns(map[string]interface{}{"last_used_at": now, "last_used_ip": ip}).Error; err != nil {
+			s.Log.Errorf("Failed to record api key usage: %+v", err)
+		}
+	}
+
+	return apiKey, nil
+}
+
+// GenerateOAuthAccessToken issues an access token of the user to an OAuth client. The token names the client
+// and its scopes (RFC 9068), it has no session and no refresh token.
+func (s *tokenService) GenerateOAuthAccessToken(
+	c *fiber.Ctx, client *model.OAuthClient, userID uuid.UUID, scopes []string,
+) (*res.TokenExpires, error) {
+	expires := time.Now().UTC().Add(time.Minute * time.Duration(config.JWTAccessExp))
+
+	token, err := s.signToken(jwt.MapClaims{
+		"sub":       userID.String(),
+		"iat":       issuedAtNow(),
+		"exp":       expires.Unix(),
+		"type":      config.TokenTypeAccess,
+		"jti":       uuid.New().String(),
+		"client_id": client.ID.String(),
+		"scope":     strings.Join(scopes, " "),
+	})
+	if err != nil {
+		s.Log.Errorf("Failed generate token: %+v", err)
+		return nil, err
+	}
+
+	return &res.TokenExpires{
+		Token:   token,
+		Expires: expires,
+	}, nil
+}
+
+// VerifyOAuthClient returns the client an access token was issued to, tokens of deleted clients stop working
+func (s *tokenService) VerifyOAuthClient(c *fiber.Ctx, clientID string) (*model.OAuthClient, error) {
+	if _, err := uuid.Parse(clientID); err != nil {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid OAuth client")
+	}
+
+	client := new(model.OAuthClient)
+
+	result := s.DB.WithContext(c.Context()).First(client, "id = ?", clientID)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid OAuth client")
+	}
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed to get oauth client: %+v", result.Error)
+		return nil, result.Error
+	}
+
+	return client, nil
+}
+
+// IssuedBeforeValidity reports whether the token predates the last password reset or role change of the user.
+// The iat is read from the raw claim, GetIssuedAt would round it down to the second
+func IssuedBeforeValidity(claims jwt.MapClaims, user *model.User) bool {
+	if user.TokensValidAfter == nil {
+		return false
+	}
+
+	issuedAt, ok := claims["iat"].(float64)
+	if !ok {
+		return true
+	}
+
+	return int64(math.Round(issuedAt*1e6)) <= user.TokensValidAfter.UnixMicro()
+}
+
+// issuedAtNow is the iat of a new token in microseconds, the precision tokens_valid_after is stored with,
+// so a token issued right after a password change is told apart from the ones issued before
+func issuedAtNow() float64 {
+	return float64(time.Now().UnixMicro()) / 1e6
+}
+
+func truncate(value string, size int) string {
+	if len(value) > size {
+		return value[:size]
+	}
+	return value
+}
@@ -0,0 +1,318 @@
+package service
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/response"
+	"app/src/utils"
+	"app/src/validation"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	recoveryCodeCount = 10
+	// mfaMaxAttempts codes may be tried with one mfa token, after that the user has to log in again
+	mfaMaxAttempts = 5
+)
+
+type TwoFactorService interface {
+	Enroll(c *fiber.Ctx, user *model.User) (*response.TwoFactorSetup, error)
+	Confirm(c *fiber.Ctx, user *model.User, req *validation.TwoFactorCode) error
+	Disable(c *fiber.Ctx, user *model.User, req *validation.DisableTwoFactor) error
+	Verify(c *fiber.Ctx, req *validation.VerifyTwoFactor) (*model.User, error)
+}
+
+type twoFactorService struct {
+	Log          *logrus.Logger
+	DB           *gorm.DB
+	Validate     *validator.Validate
+	UserService  UserService
+	TokenService TokenService
+}
+
+func NewTwoFactorService(
+	db *gorm.DB, validate *validator.Validate, userService UserService, tokenService TokenService,
+) TwoFactorService {
+	return &twoFactorService{
+		Log:          utils.Log,
+		DB:           db,
+		Validate:     validate,
+		UserService:  userService,
+		TokenService: tokenService,
+	}
+}
+
+func (s *twoFactorService) Enroll(c *fiber.Ctx, user *model.User) (*response.TwoFactorSetup, error) {
+	if user.TwoFactorEnabled {
+		return nil, fiber.NewError(fiber.StatusConflict, "Two-factor authentication is already enabled")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		s.Log.Errorf("Failed generate totp secret: %+v", err)
+		return nil, err
+	}
+
+	codes, recoveryCodes, err := s.generateRecoveryCodes(user)
+	if err != nil {
+		s.Log.Errorf("Failed generate recovery codes: %+v", err)
+		return nil, err
+	}
+
+	err = s.DB.WithContext(c.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.User{}).Where("id = ?", user.ID).
+			Update("two_factor_secret", secret).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", user.ID).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&recoveryCodes).Error
+	})
+	if err != nil {
+		s.Log.Errorf("Failed enroll two-factor authentication: %+v", err)
+		return nil, err
+	}
+
+	return &response.TwoFactorSetup{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(config.TOTPIssuer, user.Email, secret),
+		RecoveryCodes:   codes,
+	}, nil
+}
+
+func (s *twoFactorService) Confirm(c *fiber.Ctx, user *model.User, req *validation.TwoFactorCode) error {
+	if err := s.Validate.Struct(req); err != nil {
+		return err
+	}
+
+	if user.TwoFactorEnabled {
+		return fiber.NewError(fiber.StatusConflict, "Two-factor authentication is already enabled")
+	}
+
+	if user.TwoFactorSecret == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Two-factor authentication has not been enrolled")
+	}
+
+	step, ok := utils.MatchTOTPCode(user.TwoFactorSecret, req.Code, time.Now())
+	if !ok {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid two-factor code")
+	}
+
+	// The code used to confirm can not be used to log in afterwards
+	result := s.DB.WithContext(c.Context()).Model(&model.User{}).
+		Where("id = ?", user.ID).
+		Updates(map[string]interface{}{
+			"two_factor_enabled":   true,
+			"two_factor_last_step": gorm.Expr("GREATEST(two_factor_last_step, ?)", step),
+		})
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed enable two-factor authentication: %+v", result.Error)
+	}
+
+	return result.Error
+}
+
+func (s *twoFactorService) Disable(c *fiber.Ctx, user *model.User, req *validation.DisableTwoFactor) error {
+	if err := s.Validate.Struct(req); err != nil {
+		return err
+	}
+
+	if !user.TwoFactorEnabled {
+		return fiber.NewError(fiber.StatusBadRequest, "Two-factor authentication is not enabled")
+	}
+
+	if err := s.confirmDisable(c, user, req); err != nil {
+		return err
+	}
+
+	err := s.DB.WithContext(c.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.User{}).Where("id = ?", user.ID).
+			Updates(map[string]interface{}{
+				"two_factor_enabled": false,
+				"two_factor_secret":  "",
+			}).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("user_id = ?", user.ID).Delete(&model.RecoveryCode{}).Error
+	})
+	if err != nil {
+		s.Log.Errorf("Failed disable two-factor authentication: %+v", err)
+	}
+
+	return err
+}
+
+// confirmDisable checks the password of the user. Users who log in with a magic link, a passkey or a provider
+// have no password, they prove they hold the second factor with a current code or a recovery code.
+func (s *twoFactorService) confirmDisable(c *fiber.Ctx, user *model.User, req *validation.DisableTwoFactor) error {
+	if user.Password != "" {
+		if !utils.CheckPasswordHash(req.Password, user.Password) {
+			return fiber.NewError(fiber.StatusUnauthorized, "Invalid password")
+		}
+		return nil
+	}
+
+	if req.Code != "" {
+		return s.useTOTPCode(c, user, req.Code)
+	}
+
+	if req.RecoveryCode != "" {
+		return s.useRecoveryCode(c, user, req.RecoveryCode)
+	}
+
+	return fiber.NewError(fiber.StatusBadRequest, "A two-factor code or a recovery code is required")
+}
+
+func (s *twoFactorService) Verify(c *fiber.Ctx, req *validation.VerifyTwoFactor) (*model.User, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	userID, err := utils.VerifyToken(req.MFAToken, config.JWTKeySet, config.TokenTypeMFAPending)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid Token")
+	}
+
+	tokenDoc := new(model.Token)
+	result := s.DB.WithContext(c.Context()).
+		Where("token = ? AND user_id = ? AND type = ?", req.MFAToken, userID, config.TokenTypeMFAPending).
+		First(tokenDoc)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid Token")
+	}
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed get mfa pending token: %+v", result.Error)
+		return nil, result.Error
+	}
+
+	user, err := s.UserService.GetUserByID(c, userID)
+	if err != nil || !user.TwoFactorEnabled {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid Token")
+	}
+
+	if errAttempt := s.countAttempt(c, tokenDoc); errAttempt != nil {
+		return nil, errAttempt
+	}
+
+	if req.Code != "" {
+		if errCode := s.useTOTPCode(c, user, req.Code); errCode != nil {
+			return nil, errCode
+		}
+	} else if errCode := s.useRecoveryCode(c, user, req.RecoveryCode); errCode != nil {
+		return nil, errCode
+	}
+
+	if errToken := s.TokenService.DeleteToken(c, config.TokenTypeMFAPending, userID); errToken != nil {
+		return nil, errToken
+	}
+
+	return user, nil
+}
+
+// countAttempt counts the attempt before the code is checked, so concurrent requests can not try more codes
+// than allowed. The token is deleted once it has no attempts left.
+func (s *twoFactorService) countAttempt(c *fiber.Ctx, tokenDoc *model.Token) error {
+	result := s.DB.WithContext(c.Context()).Model(&model.Token{}).
+		Where("id = ? AND attempts < ?", tokenDoc.ID, mfaMaxAttempts).
+		UpdateColumn("attempts", gorm.Expr("attempts + 1"))
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed count mfa attempt: %+v", result.Error)
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		if err := s.DB.WithContext(c.Context()).Delete(tokenDoc).Error; err != nil {
+			s.Log.Errorf("Failed delete mfa pending token: %+v", err)
+			return err
+		}
+
+		return fiber.NewError(fiber.StatusUnauthorized, "Too many invalid codes, please log in again")
+	}
+
+	return nil
+}
+
+// useTOTPCode accepts a code only for a later time step than the last accepted one, so a code that was
+// already used can not be replayed while it is still valid
+func (s *twoFactorService) useTOTPCode(c *fiber.Ctx, user *model.User, code string) error {
+	step, ok := utils.MatchTOTPCode(user.TwoFactorSecret, code, time.Now())
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid two-factor code")
+	}
+
+	// The step condition makes the update atomic, so concurrent requests can not use the same code
+	result := s.DB.WithContext(c.Context()).Model(&model.User{}).
+		Where("id = ? AND two_factor_last_step < ?", user.ID, step).
+		Update("two_factor_last_step", step)
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed use two-factor code: %+v", result.Error)
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid two-factor code")
+	}
+
+	return nil
+}
+
+func (s *twoFactorService) useRecoveryCode(c *fiber.Ctx, user *model.User, code string) error {
+	codeHash := utils.HashToken(normalizeRecoveryCode(code))
+
+	// The used_at condition makes the update atomic, so a code can never be redeemed twice
+	result := s.DB.WithContext(c.Context()).Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, codeHash).
+		Update("used_at", time.Now().UTC())
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed use recovery code: %+v", result.Error)
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid recovery code")
+	}
+
+	return nil
+}
+
+func (s *twoFactorService) generateRecoveryCodes(user *model.User) ([]string, []model.RecoveryCode, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	recoveryCodes := make([]model.RecoveryCode, 0, recoveryCodeCount)
+
+	for range recoveryCodeCount {
+		random, err := utils.GenerateRandomString(10)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		code := random[:8] + "-" + random[8:16]
+		codes = append(codes, code)
+		recoveryCodes = append(recoveryCodes, model.RecoveryCode{
+			UserID:   user.ID,
+			CodeHash: utils.HashToken(code),
+		})
+	}
+
+	return codes, recoveryCodes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.TrimSpace(code))
+}
@@ -0,0 +1,519 @@
+package service
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/response"
+	"app/src/utils"
+	"app/src/validation"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultUploadExpiration    = 24
+	defaultUploadPruneInterval = 60
+)
+
+// UploadService mengelola upload resumable sesuai protokol tus 1.0 dan upload langsung ke storage melalui
+// presigned URL. Chunk ditulis ke backend dari StorageService dan record File dibuat setelah byte terakhir diterima.
+type UploadService interface {
+	CreateUpload(ctx context.Context, actor FileActor, req *validation.CreateUpload) (*model.Upload, error)
+	GetUpload(ctx context.Context, id uuid.UUID, actor FileActor) (*model.Upload, error)
+	WriteChunk(ctx context.Context, id uuid.UUID, actor FileActor, offset int64, chunk []byte) (*model.Upload, error)
+	DeleteUpload(ctx context.Context, id uuid.UUID, actor FileActor) error
+	CreatePresignedUpload(
+		ctx context.Context, actor FileActor, req *validation.CreatePresignedUpload,
+	) (*response.PresignedUpload, error)
+	CompletePresignedUpload(ctx context.Context, id uuid.UUID, actor FileActor) (*model.File, error)
+	PruneExpired(ctx context.Context) error
+}
+
+type uploadService struct {
+	Log            *logrus.Logger
+	DB             *gorm.DB
+	Validate       *validator.Validate
+	StorageService StorageService
+	ImageService   ImageService
+}
+
+func NewUploadService(
+	db *gorm.DB, validate *validator.Validate, storageService StorageService, imageService ImageService,
+) UploadService {
+	return &uploadService{
+		Log:            utils.Log,
+		DB:             db,
+		Validate:       validate,
+		StorageService: storageService,
+		ImageService:   imageService,
+	}
+}
+
+// PruneUploads menghapus upload yang kadaluarsa secara berkala sampai ctx selesai
+func PruneUploads(ctx context.Context, uploadService UploadService) {
+	ticker := time.NewTicker(time.Minute * defaultUploadPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := uploadService.PruneExpired(ctx); err != nil {
+				utils.Log.Errorf("Failed to prune expired uploads: %+v", err)
+			}
+		}
+	}
+}
+
+// ParseUploadMetadata membaca header Upload-Metadata, pasangan key dan value base64 yang dipisah koma.
+// Value boleh kosong.
+func ParseUploadMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+
+	for _, pair := range strings.Split(header, ",") {
+		parts := strings.Fields(pair)
+		if len(parts) == 0 || len(parts) > 2 {
+			return nil, fmt.Errorf("invalid metadata pair %q", pair)
+		}
+
+		value := ""
+		if len(parts) == 2 {
+			decoded, err := base64.StdEncoding.DecodeString(parts[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid metadata value of %s: %w", parts[0], err)
+			}
+			value = string(decoded)
+		}
+
+		if _, ok := metadata[parts[0]]; ok {
+			return nil, fmt.Errorf("duplicated metadata key %s", parts[0])
+		}
+		metadata[parts[0]] = value
+	}
+
+	return metadata, nil
+}
+
+// UploadExpiration lama upload yang belum selesai disimpan sejak perubahan terakhir
+func UploadExpiration() time.Duration {
+	hours := config.UploadExpiration
+	if hours <= 0 {
+		hours = defaultUploadExpiration
+	}
+	return time.Hour * time.Duration(hours)
+}
+
+func (s *uploadService) CreateUpload(
+	ctx context.Context, actor FileActor, req *validation.CreateUpload,
+) (*model.Upload, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	upload, err := s.newUpload(ctx, actor, req.FileName, req.ContentType, req.Folder, req.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.StorageService.BeginUpload(ctx, upload); err != nil {
+		s.Log.Errorf("Failed to begin upload: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to create upload")
+	}
+
+	if err := s.DB.WithContext(ctx).Create(upload).Error; err != nil {
+		s.StorageService.AbortUpload(ctx, upload)
+		s.Log.Errorf("Failed to create upload: %+v", err)
+		return nil, err
+	}
+
+	return upload, nil
+}
+
+func (s *uploadService) GetUpload(ctx context.Context, id uuid.UUID, actor FileActor) (*model.Upload, error) {
+	return s.findUpload(s.DB.WithContext(ctx), id, actor, false)
+}
+
+// WriteChunk menulis chunk pada offset yang dikirim client, offset harus sama dengan offset upload.
+// Row upload dikunci selama chunk ditulis sehingga PATCH yang bersamaan tidak saling menimpa.
+func (s *uploadService) WriteChunk(
+	ctx context.Context, id uuid.UUID, actor FileActor, offset int64, chunk []byte,
+) (*model.Upload, error) {
+	var upload *model.Upload
+	var rejected error
+	finished, completed := false, false
+
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		upload, err = s.findUpload(tx.Clauses(clause.Locking{Strength: "UPDATE"}), id, actor, false)
+		if err != nil {
+			return err
+		}
+
+		if offset != upload.Offset {
+			return fiber.NewError(fiber.StatusConflict, "Upload-Offset does not match the current offset of the upload")
+		}
+
+		if upload.Offset+int64(len(chunk)) > upload.Size {
+			return fiber.NewError(fiber.StatusRequestEntityTooLarge, "Chunk exceeds the length of the upload")
+		}
+
+		if len(chunk) == 0 {
+			return nil
+		}
+
+		if err := s.StorageService.WriteChunk(ctx, upload, chunk); err != nil {
+			s.Log.Errorf("Failed to write chunk: %+v", err)
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to write chunk")
+		}
+
+		upload.Offset += int64(len(chunk))
+		upload.ExpiresAt = time.Now().Add(UploadExpiration())
+
+		if upload.Offset == upload.Size {
+			if err := s.StorageService.FinishUpload(ctx, upload); err != nil {
+				s.Log.Errorf("Failed to finish upload: %+v", err)
+				return fiber.NewError(fiber.StatusInternalServerError, "Failed to finish upload")
+			}
+			finished = true
+
+			file := s.newFile(upload)
+			if rejected, err = s.checkUpload(ctx, tx, upload, file); rejected != nil || err != nil {
+				return err
+			}
+
+			if err := s.createFile(tx, upload, file); err != nil {
+				return err
+			}
+			completed = true
+		}
+
+		return tx.Save(upload).Error
+	})
+
+	if err != nil {
+		var fiberErr *fiber.Error
+		if !errors.As(err, &fiberErr) {
+			s.Log.Errorf("Failed to save upload: %+v", err)
+		}
+		if finished {
+			s.revertUpload(ctx, upload)
+		}
+		return nil, err
+	}
+
+	if finished {
+		if err := s.StorageService.CommitUpload(ctx, upload); err != nil {
+			s.Log.Errorf("Failed to commit upload: %+v", err)
+		}
+	}
+
+	if rejected != nil {
+		return nil, rejected
+	}
+
+	if completed {
+		s.ImageService.Enqueue(*upload.FileID)
+	}
+
+	return upload, nil
+}
+
+// revertUpload menghapus hasil FinishUpload dari upload yang gagal dicatat agar chunk terakhir dapat
+// dikirim ulang. Upload yang tidak dapat dikembalikan dibatalkan dan dihapus.
+func (s *uploadService) revertUpload(ctx context.Context, upload *model.Upload) {
+	ctx = context.WithoutCancel(ctx)
+
+	err := s.StorageService.RevertUpload(ctx, upload)
+	if err == nil {
+		return
+	}
+	s.Log.Errorf("Failed to revert upload: %+v", err)
+
+	if err := s.StorageService.AbortUpload(ctx, upload); err != nil {
+		s.Log.Errorf("Failed to abort upload: %+v", err)
+	}
+	if err := s.DB.WithContext(ctx).Delete(upload).Error; err != nil {
+		s.Log.Errorf("Failed to delete upload: %+v", err)
+	}
+}
+
+// DeleteUpload menghentikan upload (tus termination), file dari upload yang sudah selesai tetap disimpan
+func (s *uploadService) DeleteUpload(ctx context.Context, id uuid.UUID, actor FileActor) error {
+	upload, err := s.findUpload(s.DB.WithContext(ctx), id, actor, false)
+	if err != nil {
+		return err
+	}
+
+	if !upload.Completed() {
+		if err := s.StorageService.AbortUpload(ctx, upload); err != nil {
+			s.Log.Errorf("Failed to abort upload: %+v", err)
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to delete upload")
+		}
+	}
+
+	if err := s.DB.WithContext(ctx).Delete(upload).Error; err != nil {
+		s.Log.Errorf("Failed to delete upload: %+v", err)
+		return err
+	}
+
+	return nil
+}
+
+// CreatePresignedUpload mencatat upload dan membuat presigned PUT URL ke storage. Setelah file dikirim,
+// client memanggil CompletePresignedUpload agar record File dibuat.
+func (s *uploadService) CreatePresignedUpload(
+	ctx context.Context, actor FileActor, req *validation.CreatePresignedUpload,
+) (*response.PresignedUpload, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	folder := req.Folder
+	if folder == "" {
+		folder = "general"
+	}
+
+	upload, err := s.newUpload(ctx, actor, req.FileName, req.ContentType, folder, req.Size)
+	if err != nil {
+		return nil, err
+	}
+	upload.Presigned = true
+
+	presigned, err := s.StorageService.PresignUpload(ctx, upload, PresignedURLExpiration())
+	if err != nil {
+		s.Log.Errorf("Failed to presign upload: %+v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to create upload")
+	}
+
+	if err := s.DB.WithContext(ctx).Create(upload).Error; err != nil {
+		s.Log.Errorf("Failed to create upload: %+v", err)
+		return nil, err
+	}
+
+	return &response.PresignedUpload{Upload: upload, PresignedURL: *presigned}, nil
+}
+
+// CompletePresignedUpload memeriksa file yang dikirim ke presigned URL dan membuat record File.
+// Memanggilnya lagi setelah upload selesai mengembalikan file yang sama.
+func (s *uploadService) CompletePresignedUpload(
+	ctx context.Context, id uuid.UUID, actor FileActor,
+) (*model.File, error) {
+	file := new(model.File)
+	var rejected error
+	completed := false
+
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		upload, err := s.findUpload(tx.Clauses(clause.Locking{Strength: "UPDATE"}), id, actor, true)
+		if err != nil {
+			return err
+		}
+
+		if !upload.Completed() {
+			size, err := s.StorageService.StatFile(ctx, upload.FilePath)
+			if errors.Is(err, ErrFileNotFound) {
+				return fiber.NewError(fiber.StatusConflict, "The file has not been uploaded yet")
+			}
+			if err != nil {
+				s.Log.Errorf("Failed to stat uploaded file: %+v", err)
+				return fiber.NewError(fiber.StatusInternalServerError, "Failed to complete upload")
+			}
+
+			if size != upload.Size {
+				return fiber.NewError(fiber.StatusBadRequest, "The uploaded file does not have the declared size")
+			}
+
+			upload.Offset = size
+			record := s.newFile(upload)
+			if rejected, err = s.checkUpload(ctx, tx, upload, record); rejected != nil || err != nil {
+				return err
+			}
+
+			if err := s.createFile(tx, upload, record); err != nil {
+				return err
+			}
+
+			if err := tx.Save(upload).Error; err != nil {
+				return err
+			}
+			completed = true
+		}
+
+		return tx.First(file, "id = ?", upload.FileID).Error
+	})
+
+	if err != nil {
+		var fiberErr *fiber.Error
+		if !errors.As(err, &fiberErr) {
+			s.Log.Errorf("Failed to complete upload: %+v", err)
+		}
+		return nil, err
+	}
+
+	if rejected != nil {
+		return nil, rejected
+	}
+
+	if completed {
+		s.ImageService.Enqueue(file.ID)
+	}
+
+	return file, nil
+}
+
+// PruneExpired menghapus upload yang kadaluarsa beserta chunk yang sudah ditulis
+func (s *uploadService) PruneExpired(ctx context.Context) error {
+	var uploads []model.Upload
+	if err := s.DB.WithContext(ctx).Where("expires_at < ?", time.Now()).Find(&uploads).Error; err != nil {
+		return err
+	}
+
+	for i := range uploads {
+		upload := &uploads[i]
+
+		if !upload.Completed() {
+			if err := s.StorageService.AbortUpload(ctx, upload); err != nil {
+				s.Log.Errorf("Failed to abort expired upload %s: %+v", upload.ID, err)
+				continue
+			}
+		}
+
+		if err := s.DB.WithContext(ctx).Delete(upload).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkUpload memeriksa isi file dan jumlah file di folder setelah semua byte diterima, lalu membuang metadata
+// gambar dari file. File yang ditolak dihapus dari storage bersama upload-nya, sehingga client harus memulai
+// upload baru.
+func (s *uploadService) checkUpload(
+	ctx context.Context, tx *gorm.DB, upload *model.Upload, file *model.File,
+) (rejected error, err error) {
+	checkErr := checkStoredFile(ctx, tx, s.StorageService, upload)
+	if checkErr == nil {
+		checkErr = stripOriginal(ctx, s.StorageService, file)
+	}
+
+	var fiberErr *fiber.Error
+	if !errors.As(checkErr, &fiberErr) {
+		if checkErr != nil {
+			s.Log.Errorf("Failed to check uploaded file: %+v", checkErr)
+			return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to complete upload")
+		}
+		return nil, nil
+	}
+
+	if err := s.StorageService.RemoveFile(ctx, upload.FilePath); err != nil {
+		s.Log.Errorf("Failed to remove rejected file: %+v", err)
+	}
+
+	return checkErr, tx.Delete(upload).Error
+}
+
+// newFile menyiapkan record File milik pengupload dari upload yang sudah lengkap
+func (s *uploadService) newFile(upload *model.Upload) *model.File {
+	return &model.File{
+		FileName:         upload.FileName,
+		OriginalName:     upload.OriginalName,
+		FilePath:         upload.FilePath,
+		FileSize:         upload.Size,
+		FileURL:          s.StorageService.GetFileURL(upload.FilePath),
+		ContentType:      upload.ContentType,
+		Folder:           upload.Folder,
+		UploadedBy:       upload.UploadedBy,
+		OrganizationID:   upload.OrganizationID,
+		ProcessingStatus: imageProcessingStatus(upload.FileName),
+	}
+}
+
+// createFile menyimpan record File dari upload
+func (s *uploadService) createFile(tx *gorm.DB, upload *model.Upload, file *model.File) error {
+	if err := tx.Create(file).Error; err != nil {
+		return err
+	}
+
+	upload.FileID = &file.ID
+	return nil
+}
+
+// newUpload memeriksa ukuran, tipe dan jumlah file terhadap policy folder lalu menentukan path tujuan upload.
+// Isi file baru diperiksa setelah semua byte diterima.
+func (s *uploadService) newUpload(
+	ctx context.Context, actor FileActor, fileName, declaredType, folder string, size int64,
+) (*model.Upload, error) {
+	folder, err := cleanFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := config.FolderPolicy(folder)
+
+	contentType, err := checkFileName(policy, fileName, declaredType, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkFileCount(s.DB.WithContext(ctx), policy, folder, &actor.UserID, actor.OrganizationID); err != nil {
+		return nil, err
+	}
+
+	originalName := path.Base(fileName)
+	fileName = generateFileName(originalName)
+	return &model.Upload{
+		ID:             uuid.New(),
+		FileName:       fileName,
+		OriginalName:   originalName,
+		FilePath:       path.Join(folder, fileName),
+		ContentType:    contentType,
+		Folder:         folder,
+		Size:           size,
+		UploadedBy:     &actor.UserID,
+		OrganizationID: actor.OrganizationID,
+		ExpiresAt:      time.Now().Add(UploadExpiration()),
+	}, nil
+}
+
+// findUpload mencari upload milik actor di organization yang aktif, upload lain diperlakukan seperti tidak ada.
+// Upload tus dan upload presigned tidak dapat dipakai di route satu sama lain.
+func (s *uploadService) findUpload(db *gorm.DB, id uuid.UUID, actor FileActor, presigned bool) (*model.Upload, error) {
+	upload := new(model.Upload)
+
+	result := db.Where("id = ? AND uploaded_by = ? AND presigned = ?", id, actor.UserID, presigned)
+	if actor.OrganizationID != nil {
+		result = result.Where("organization_id = ?", *actor.OrganizationID)
+	} else {
+		result = result.Where("organization_id IS NULL")
+	}
+
+	if err := result.First(upload).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, "Upload not found")
+		}
+		s.Log.Errorf("Failed to get upload: %+v", err)
+		return nil, err
+	}
+
+	if !upload.Completed() && upload.ExpiresAt.Before(time.Now()) {
+		return nil, fiber.NewError(fiber.StatusGone, "Upload has expired")
+	}
+
+	return upload, nil
+}
@@ -1,13 +1,18 @@
 package service
 
 import (
+	"app/src/config"
 	"app/src/model"
 	"app/src/utils"
 	"app/src/validation"
 	"errors"
+	"fmt"
+	"slices"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
@@ -19,21 +24,30 @@ type UserService interface {
 	CreateUser(c *fiber.Ctx, req *validation.CreateUser) (*model.User, error)
 	UpdatePassOrVerify(c *fiber.Ctx, req *validation.UpdatePassOrVerify, id string) error
 	UpdateUser(c *fiber.Ctx, req *validation.UpdateUser, id string) (*model.User, error)
+	ConfirmPendingEmail(c *fiber.Ctx, id string) (*model.User, string, error)
+	RestoreEmail(c *fiber.Ctx, id, email string) (*model.User, error)
 	DeleteUser(c *fiber.Ctx, id string) error
-	CreateGoogleUser(c *fiber.Ctx, req *validation.GoogleLogin) (*model.User, error)
+	GetRights(c *fiber.Ctx, user *model.User) ([]string, error)
+	GetMembership(c *fiber.Ctx, user *model.User, organizationID string) (*model.Membership, error)
 }
 
 type userService struct {
-	Log      *logrus.Logger
-	DB       *gorm.DB
-	Validate *validator.Validate
+	Log                 *logrus.Logger
+	DB                  *gorm.DB
+	Validate            *validator.Validate
+	RoleService         RoleService
+	OrganizationService OrganizationService
 }
 
-func NewUserService(db *gorm.DB, validate *validator.Validate) UserService {
+func NewUserService(
+	db *gorm.DB, validate *validator.Validate, roleService RoleService, organizationService OrganizationService,
+) UserService {
 	return &userService{
-		Log:      utils.Log,
-		DB:       db,
-		Validate: validate,
+		Log:                 utils.Log,
+		DB:                  db,
+		Validate:            validate,
+		RoleService:         roleService,
+		OrganizationService: organizationService,
 	}
 }
 
@@ -51,7 +65,7 @@ func (s *userService) GetUsersWithPagination(c *fiber.Ctx, params *utils.Paginat
 
 	// Use the pagination utility with search
 	result, err := utils.ApplyPaginationWithSearch[model.User](
-		s.DB.WithContext(c.Context()),
+		s.scopeToActiveOrganization(c, s.DB.WithContext(c.Context())),
 		params,
 		"created_at",
 		userSearchCallback,
@@ -67,7 +81,7 @@ func (s *userService) GetUsersWithPagination(c *fiber.Ctx, params *utils.Paginat
 func (s *userService) GetUserByID(c *fiber.Ctx, id string) (*model.User, error) {
 	user := new(model.User)
 
-	result := s.DB.WithContext(c.Context()).First(user, "id = ?", id)
+	result := s.scopeToActiveOrganization(c, s.DB.WithContext(c.Context())).First(user, "id = ?", id)
 
 	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 		return nil, fiber.NewError(fiber.StatusNotFound, "User not found")
@@ -107,11 +121,13 @@ func (s *userService) CreateUser(c *fiber.Ctx, req *validation.CreateUser) (*mod
 		return nil, err
 	}
 
+	now := time.Now().UTC()
 	user := &model.User{
-		Name:     req.Name,
-		Email:    req.Email,
-		Password: hashedPassword,
-		Role:     req.Role,
+		Name:              req.Name,
+		Email:             req.Email,
+		Password:          hashedPassword,
+		Role:              req.Role,
+		PasswordChangedAt: &now,
 	}
 
 	result := s.DB.WithContext(c.Context()).Create(user)
@@ -120,6 +136,11 @@ func (s *userService) CreateUser(c *fiber.Ctx, req *validation.CreateUser) (*mod
 		return nil, fiber.NewError(fiber.StatusConflict, "Email is already in use")
 	}
 
+	// users.role references roles.name, the foreign key rejects roles that do not exist
+	if errors.Is(result.Error, gorm.ErrForeignKeyViolated) {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Role not found")
+	}
+
 	if result.Error != nil {
 		s.Log.Errorf("Failed to create user: %+v", result.Error)
 	}
@@ -136,7 +157,21 @@ func (s *userService) UpdateUser(c *fiber.Ctx, req *validation.UpdateUser, id st
 		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid Request")
 	}
 
+	// The email is where password resets are sent, so it is a credential as well
+	if (req.Password != "" || req.Email != "") && Actor(c) != nil {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Password and email can not be changed while impersonating")
+	}
+
+	user, err := s.GetUserByID(c, id)
+	if err != nil {
+		return nil, err
+	}
+
 	if req.Password != "" {
+		if err := s.checkPasswordHistory(c, user, req.Password); err != nil {
+			return nil, err
+		}
+
 		hashedPassword, err := utils.HashPassword(req.Password)
 		if err != nil {
 			return nil, err
@@ -147,15 +182,24 @@ func (s *userService) UpdateUser(c *fiber.Ctx, req *validation.UpdateUser, id st
 	updateBody := &model.User{
 		Name:     req.Name,
 		Password: req.Password,
-		Email:    req.Email,
 	}
 
-	result := s.DB.WithContext(c.Context()).Where("id = ?", id).Updates(updateBody)
+	// A new email is only pending until it is confirmed from the new address, see ConfirmPendingEmail
+	if req.Email != "" && req.Email != user.Email {
+		if err := s.checkEmailAvailable(c, req.Email); err != nil {
+			return nil, err
+		}
 
-	if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
-		return nil, fiber.NewError(fiber.StatusConflict, "Email is already in use")
+		updateBody.PendingEmail = &req.Email
+	}
+
+	if req.Password != "" {
+		updateBody.TokensValidAfter = tokensValidAfterNow()
+		updateBody.PasswordChangedAt = updateBody.TokensValidAfter
 	}
 
+	result := s.DB.WithContext(c.Context()).Where("id = ?", id).Updates(updateBody)
+
 	if result.RowsAffected == 0 {
 		return nil, fiber.NewError(fiber.StatusNotFound, "User not found")
 	}
@@ -164,7 +208,13 @@ func (s *userService) UpdateUser(c *fiber.Ctx, req *validation.UpdateUser, id st
 		s.Log.Errorf("Failed to update user: %+v", result.Error)
 	}
 
-	user, err := s.GetUserByID(c, id)
+	if result.Error == nil && req.Password != "" {
+		if err := s.recordPassword(c, user); err != nil {
+			return nil, err
+		}
+	}
+
+	user, err = s.GetUserByID(c, id)
 	if err != nil {
 		return nil, err
 	}
@@ -172,6 +222,93 @@ func (s *userService) UpdateUser(c *fiber.Ctx, req *validation.UpdateUser, id st
 	return user, result.Error
 }
 
+func (s *userService) checkEmailAvailable(c *fiber.Ctx, email string) error {
+	var count int64
+
+	if err := s.DB.WithContext(c.Context()).Model(&model.User{}).Where("email = ?", email).Count(&count).Error; err != nil {
+		s.Log.Errorf("Failed to count users by email: %+v", err)
+		return err
+	}
+
+	if count > 0 {
+		return fiber.NewError(fiber.StatusConflict, "Email is already in use")
+	}
+
+	return nil
+}
+
+// ConfirmPendingEmail makes the pending email the email of the user. The link was opened from the new
+// address, so the email is verified. Returns the user and the previous email.
+func (s *userService) ConfirmPendingEmail(c *fiber.Ctx, id string) (*model.User, string, error) {
+	user, err := s.GetUserByID(c, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if user.PendingEmail == nil {
+		return nil, "", fiber.NewError(fiber.StatusBadRequest, "There is no pending email change")
+	}
+
+	previousEmail := user.Email
+
+	result := s.DB.WithContext(c.Context()).Model(user).
+		Where("email = ?", previousEmail).
+		Updates(map[string]interface{}{
+			"email":          *user.PendingEmail,
+			"pending_email":  nil,
+			"verified_email": true,
+		})
+
+	if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+		return nil, "", fiber.NewError(fiber.StatusConflict, "Email is already in use")
+	}
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed to confirm pending email: %+v", result.Error)
+		return nil, "", result.Error
+	}
+
+	// A concurrent request changed the email first
+	if result.RowsAffected == 0 {
+		return nil, "", fiber.NewError(fiber.StatusConflict, "The email has been changed in the meantime")
+	}
+
+	user, err = s.GetUserByID(c, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return user, previousEmail, nil
+}
+
+// RestoreEmail undoes an email change from the previous address. The change may have been made by someone
+// who took over the account, so a pending change is dropped and every token issued before is invalidated.
+func (s *userService) RestoreEmail(c *fiber.Ctx, id, email string) (*model.User, error) {
+	result := s.DB.WithContext(c.Context()).Model(&model.User{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"email":              email,
+			"pending_email":      nil,
+			"verified_email":     true,
+			"tokens_valid_after": tokensValidAfterNow(),
+		})
+
+	if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+		return nil, fiber.NewError(fiber.StatusConflict, "Email is already in use")
+	}
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed to restore email: %+v", result.Error)
+		return nil, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, fiber.NewError(fiber.StatusNotFound, "User not found")
+	}
+
+	return s.GetUserByID(c, id)
+}
+
 func (s *userService) UpdatePassOrVerify(c *fiber.Ctx, req *validation.UpdatePassOrVerify, id string) error {
 	if err := s.Validate.Struct(req); err != nil {
 		return err
@@ -181,7 +318,18 @@ func (s *userService) UpdatePassOrVerify(c *fiber.Ctx, req *validation.UpdatePas
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid Request")
 	}
 
+	var user *model.User
+
 	if req.Password != "" {
+		var err error
+		if user, err = s.GetUserByID(c, id); err != nil {
+			return err
+		}
+
+		if err := s.checkPasswordHistory(c, user, req.Password); err != nil {
+			return err
+		}
+
 		hashedPassword, err := utils.HashPassword(req.Password)
 		if err != nil {
 			return err
@@ -194,6 +342,11 @@ func (s *userService) UpdatePassOrVerify(c *fiber.Ctx, req *validation.UpdatePas
 		VerifiedEmail: req.VerifiedEmail,
 	}
 
+	if req.Password != "" {
+		updateBody.TokensValidAfter = tokensValidAfterNow()
+		updateBody.PasswordChangedAt = updateBody.TokensValidAfter
+	}
+
 	result := s.DB.WithContext(c.Context()).Where("id = ?", id).Updates(updateBody)
 
 	if result.RowsAffected == 0 {
@@ -202,9 +355,79 @@ func (s *userService) UpdatePassOrVerify(c *fiber.Ctx, req *validation.UpdatePas
 
 	if result.Error != nil {
 		s.Log.Errorf("Failed to update user password or verifiedEmail: %+v", result.Error)
+		return result.Error
 	}
 
-	return result.Error
+	if user != nil {
+		return s.recordPassword(c, user)
+	}
+
+	return nil
+}
+
+// checkPasswordHistory rejects the current password of the user and the passwords before it,
+// up to PASSWORD_HISTORY passwords in total
+func (s *userService) checkPasswordHistory(c *fiber.Ctx, user *model.User, password string) error {
+	if config.PasswordHistory <= 0 {
+		return nil
+	}
+
+	var histories []model.PasswordHistory
+
+	if err := s.DB.WithContext(c.Context()).
+		Where("user_id = ?", user.ID).
+		Order("created_at DESC").
+		Limit(config.PasswordHistory - 1).
+		Find(&histories).Error; err != nil {
+		s.Log.Errorf("Failed to get password history: %+v", err)
+		return err
+	}
+
+	hashes := []string{user.Password}
+	for _, history := range histories {
+		hashes = append(hashes, history.Password)
+	}
+
+	for _, hash := range hashes {
+		if hash != "" && utils.CheckPasswordHash(password, hash) {
+			return fiber.NewError(fiber.StatusBadRequest,
+				fmt.Sprintf("The password can not be one of your last %d passwords", config.PasswordHistory))
+		}
+	}
+
+	return nil
+}
+
+// recordPassword keeps the password the user had before a change, older entries past PASSWORD_HISTORY are dropped
+func (s *userService) recordPassword(c *fiber.Ctx, user *model.User) error {
+	if config.PasswordHistory <= 1 || user.Password == "" {
+		return nil
+	}
+
+	history := &model.PasswordHistory{
+		UserID:   user.ID,
+		Password: user.Password,
+	}
+
+	if err := s.DB.WithContext(c.Context()).Create(history).Error; err != nil {
+		s.Log.Errorf("Failed to record password history: %+v", err)
+		return err
+	}
+
+	kept := s.DB.Model(&model.PasswordHistory{}).
+		Select("id").
+		Where("user_id = ?", user.ID).
+		Order("created_at DESC").
+		Limit(config.PasswordHistory - 1)
+
+	if err := s.DB.WithContext(c.Context()).
+		Where("user_id = ? AND id NOT IN (?)", user.ID, kept).
+		Delete(&model.PasswordHistory{}).Error; err != nil {
+		s.Log.Errorf("Failed to prune password history: %+v", err)
+		return err
+	}
+
+	return nil
 }
 
 func (s *userService) DeleteUser(c *fiber.Ctx, id string) error {
@@ -223,36 +446,65 @@ func (s *userService) DeleteUser(c *fiber.Ctx, id string) error {
 	return result.Error
 }
 
-func (s *userService) CreateGoogleUser(c *fiber.Ctx, req *validation.GoogleLogin) (*model.User, error) {
-	if err := s.Validate.Struct(req); err != nil {
+// GetRights returns the rights granted by the role of the user, and by its membership while an organization is active.
+// Requests authenticated with an API key only keep the rights listed in the scopes of the key.
+func (s *userService) GetRights(c *fiber.Ctx, user *model.User) ([]string, error) {
+	rights, err := s.RoleService.GetRights(c, user.Role)
+	if err != nil {
 		return nil, err
 	}
 
-	userFromDB, err := s.GetUserByEmail(c, req.Email)
-	if err != nil {
-		if err.Error() == "User not found" {
-			user := &model.User{
-				Name:          req.Name,
-				Email:         req.Email,
-				VerifiedEmail: req.VerifiedEmail,
-			}
-
-			if createErr := s.DB.WithContext(c.Context()).Create(user).Error; createErr != nil {
-				s.Log.Errorf("Failed to create user: %+v", createErr)
-				return nil, createErr
-			}
-
-			return user, nil
-		}
+	// The rights of a role are cached and shared, they must not be modified in place
+	if membership := ActiveMembership(c); membership != nil && membership.UserID == user.ID {
+		rights = append(slices.Clone(rights), config.OrganizationRoles[membership.Role]...)
+	}
 
-		return nil, err
+	rights, _ = scopeRights(c, user.ID, rights)
+
+	return rights, nil
+}
+
+// scopeRights limits the rights of the user to the scopes of the request's API key or OAuth token,
+// the bool reports whether the request was made with one of them
+func scopeRights(c *fiber.Ctx, userID uuid.UUID, rights []string) ([]string, bool) {
+	scoped := false
+
+	if apiKey := ActiveAPIKey(c); apiKey != nil && apiKey.UserID == userID {
+		rights, scoped = limitToScopes(rights, utils.SplitList(apiKey.Scopes)), true
+	}
+
+	if access := ActiveOAuthAccess(c); access != nil && access.UserID == userID {
+		rights, scoped = limitToScopes(rights, access.Scopes), true
 	}
 
-	userFromDB.VerifiedEmail = req.VerifiedEmail
-	if updateErr := s.DB.WithContext(c.Context()).Save(userFromDB).Error; updateErr != nil {
-		s.Log.Errorf("Failed to update user: %+v", updateErr)
-		return nil, updateErr
+	return rights, scoped
+}
+
+// limitToScopes returns the rights that are also scopes of the request's API key or OAuth token
+func limitToScopes(rights, scopes []string) []string {
+	return slices.DeleteFunc(slices.Clone(rights), func(right string) bool {
+		return !slices.Contains(scopes, right)
+	})
+}
+
+func (s *userService) GetMembership(c *fiber.Ctx, user *model.User, organizationID string) (*model.Membership, error) {
+	return s.OrganizationService.GetMembership(c, user.ID.String(), organizationID)
+}
+
+// scopeToActiveOrganization limits a users query to the members of the active organization
+func (s *userService) scopeToActiveOrganization(c *fiber.Ctx, query *gorm.DB) *gorm.DB {
+	organizationID := ActiveOrganizationID(c)
+	if organizationID == nil {
+		return query
 	}
 
-	return userFromDB, nil
+	return query.Where("users.id IN (?)",
+		s.DB.Model(&model.Membership{}).Select("user_id").Where("organization_id = ?", *organizationID))
+}
+
+// tokensValidAfterNow invalidates every token issued up to now. It is truncated to the microsecond the
+// column keeps, the iat of new tokens has the same precision, see issuedAtNow
+func tokensValidAfterNow() *time.Time {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	return &now
 }
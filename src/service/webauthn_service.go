@@ -0,0 +1,518 @@
+package service
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/utils"
+	"app/src/validation"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	webAuthnCeremonyRegistration = "registration"
+	webAuthnCeremonyLogin        = "login"
+)
+
+// WebAuthnService registers passkeys and logs users in with them. Every ceremony is split in a begin call,
+// returning the options for navigator.credentials, and a finish call verifying the authenticator response.
+type WebAuthnService interface {
+	BeginRegistration(c *fiber.Ctx, user *model.User) (*protocol.CredentialCreation, string, error)
+	FinishRegistration(
+		c *fiber.Ctx, user *model.User, req *validation.FinishWebAuthnRegistration,
+	) (*model.WebAuthnCredential, error)
+	BeginLogin(c *fiber.Ctx, req *validation.BeginWebAuthnLogin) (*protocol.CredentialAssertion, string, error)
+	FinishLogin(c *fiber.Ctx, req *validation.FinishWebAuthnLogin) (*model.User, error)
+}
+
+type webAuthnService struct {
+	Log         *logrus.Logger
+	DB          *gorm.DB
+	Validate    *validator.Validate
+	UserService UserService
+}
+
+func NewWebAuthnService(db *gorm.DB, validate *validator.Validate, userService UserService) WebAuthnService {
+	return &webAuthnService{
+		Log:         utils.Log,
+		DB:          db,
+		Validate:    validate,
+		UserService: userService,
+	}
+}
+
+// webAuthnUser adapts a user and its passkeys to the webauthn.User interface
+type webAuthnUser struct {
+	user        *model.User
+	credentials []model.WebAuthnCredential
+}
+
+func (u *webAuthnUser) WebAuthnID() []byte {
+	return u.user.ID[:]
+}
+
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	return u.user.Name
+}
+
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.credentials))
+
+	for _, credential := range u.credentials {
+		var transports []protocol.AuthenticatorTransport
+		for _, transport := range utils.SplitList(credential.Transports) {
+			transports = append(transports, protocol.AuthenticatorTransport(transport))
+		}
+
+		credentials = append(credentials, webauthn.Credential{
+			ID:              credential.CredentialID,
+			PublicKey:       credential.PublicKey,
+			AttestationType: credential.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: credential.BackupEligible,
+				BackupState:    credential.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    credential.AAGUID,
+				SignCount: credential.SignCount,
+			},
+		})
+	}
+
+	return credentials
+}
+
+func (s *webAuthnService) BeginRegistration(
+	c *fiber.Ctx, user *model.User,
+) (*protocol.CredentialCreation, string, error) {
+	relyingParty, err := s.relyingParty()
+	if err != nil {
+		return nil, "", err
+	}
+
+	owner, err := s.webAuthnUser(c, user)
+	if err != nil {
+		return nil, "", err
+	}
+
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(owner.credentials))
+	for _, credential := range owner.WebAuthnCredentials() {
+		exclusions = append(exclusions, credential.Descriptor())
+	}
+
+	creation, session, err := relyingParty.BeginRegistration(owner,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithAuthenticatorSelection(protocol.AuthenticatorSelection{
+			ResidentKey:      protocol.ResidentKeyRequirementPreferred,
+			UserVerification: protocol.VerificationRequired,
+		}),
+	)
+	if err != nil {
+		s.Log.Errorf("Failed to begin passkey registration: %+v", err)
+		return nil, "", err
+	}
+
+	sessionID, err := s.saveSession(c, webAuthnCeremonyRegistration, &user.ID, session)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return creation, sessionID, nil
+}
+
+func (s *webAuthnService) FinishRegistration(
+	c *fiber.Ctx, user *model.User, req *validation.FinishWebAuthnRegistration,
+) (*model.WebAuthnCredential, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	relyingParty, err := s.relyingParty()
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.consumeSession(c, req.SessionID, webAuthnCeremonyRegistration, &user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(req.Credential)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid credential")
+	}
+
+	owner, err := s.webAuthnUser(c, user)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := relyingParty.CreateCredential(owner, *session, parsed)
+	if err != nil {
+		s.Log.Warnf("Failed to verify passkey registration: %s", protocolErrorDetails(err))
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Passkey verification failed")
+	}
+
+	transports := make([]string, 0, len(created.Transport))
+	for _, transport := range created.Transport {
+		transports = append(transports, string(transport))
+	}
+
+	name := req.Name
+	if name == "" {
+		name = "Passkey"
+	}
+
+	credential := &model.WebAuthnCredential{
+		UserID:          user.ID,
+		CredentialID:    created.ID,
+		PublicKey:       created.PublicKey,
+		AttestationType: created.AttestationType,
+		AAGUID:          created.Authenticator.AAGUID,
+		Transports:      strings.Join(transports, ","),
+		SignCount:       created.Authenticator.SignCount,
+		BackupEligible:  created.Flags.BackupEligible,
+		BackupState:     created.Flags.BackupState,
+		Name:            name,
+	}
+
+	result := s.DB.WithContext(c.Context()).Create(credential)
+
+	if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+		return nil, fiber.NewError(fiber.StatusConflict, "Passkey is already registered")
+	}
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed to save passkey: %+v", result.Error)
+		return nil, result.Error
+	}
+
+	return credential, nil
+}
+
+// BeginLogin asks for a passkey of the user with the email. Without an email, or when the email has no passkeys,
+// the browser offers every passkey it holds for the relying party, so the response never reveals an account.
+func (s *webAuthnService) BeginLogin(
+	c *fiber.Ctx, req *validation.BeginWebAuthnLogin,
+) (*protocol.CredentialAssertion, string, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, "", err
+	}
+
+	relyingParty, err := s.relyingParty()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var owner *webAuthnUser
+
+	if req.Email != "" {
+		user, errUser := s.UserService.GetUserByEmail(c, req.Email)
+
+		var fiberErr *fiber.Error
+		if errUser != nil && !(errors.As(errUser, &fiberErr) && fiberErr.Code == fiber.StatusNotFound) {
+			return nil, "", errUser
+		}
+
+		if user != nil && errUser == nil {
+			if owner, err = s.webAuthnUser(c, user); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+
+	var (
+		assertion *protocol.CredentialAssertion
+		session   *webauthn.SessionData
+		userID    *uuid.UUID
+	)
+
+	if owner != nil && len(owner.credentials) > 0 {
+		assertion, session, err = relyingParty.BeginLogin(owner,
+			webauthn.WithUserVerification(protocol.VerificationRequired))
+		userID = &owner.user.ID
+	} else {
+		assertion, session, err = relyingParty.BeginDiscoverableLogin(
+			webauthn.WithUserVerification(protocol.VerificationRequired))
+	}
+
+	if err != nil {
+		s.Log.Errorf("Failed to begin passkey login: %+v", err)
+		return nil, "", err
+	}
+
+	sessionID, err := s.saveSession(c, webAuthnCeremonyLogin, userID, session)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return assertion, sessionID, nil
+}
+
+func (s *webAuthnService) FinishLogin(c *fiber.Ctx, req *validation.FinishWebAuthnLogin) (*model.User, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	relyingParty, err := s.relyingParty()
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.consumeSession(c, req.SessionID, webAuthnCeremonyLogin, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(req.Credential)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid credential")
+	}
+
+	var (
+		owner     *webAuthnUser
+		validated *webauthn.Credential
+	)
+
+	if session.UserID != nil {
+		userID, errID := uuid.FromBytes(session.UserID)
+		if errID != nil {
+			return nil, fiber.NewError(fiber.StatusUnauthorized, "Passkey verification failed")
+		}
+
+		if owner, err = s.webAuthnUserByID(c, userID); err != nil {
+			return nil, err
+		}
+
+		validated, err = relyingParty.ValidateLogin(owner, *session, parsed)
+	} else {
+		validated, err = relyingParty.ValidateDiscoverableLogin(
+			func(_, userHandle []byte) (webauthn.User, error) {
+				userID, errID := uuid.FromBytes(userHandle)
+				if errID != nil {
+					return nil, errID
+				}
+
+				owner, errID = s.webAuthnUserByID(c, userID)
+				return owner, errID
+			}, *session, parsed)
+	}
+
+	if err != nil {
+		s.Log.Warnf("Failed to verify passkey login: %s", protocolErrorDetails(err))
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Passkey verification failed")
+	}
+
+	credential := owner.credential(validated.ID)
+	if credential == nil {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Passkey verification failed")
+	}
+
+	if validated.Authenticator.CloneWarning {
+		return nil, s.reportClonedCredential(c, credential, validated.Authenticator.SignCount)
+	}
+
+	now := time.Now().UTC()
+
+	if err := s.DB.WithContext(c.Context()).Model(credential).Updates(map[string]interface{}{
+		"sign_count":   validated.Authenticator.SignCount,
+		"backup_state": validated.Flags.BackupState,
+		"last_used_at": now,
+	}).Error; err != nil {
+		s.Log.Errorf("Failed to update passkey: %+v", err)
+		return nil, err
+	}
+
+	return owner.user, nil
+}
+
+// reportClonedCredential disables a passkey whose signature counter went backwards. Two authenticators
+// are using the same private key, and it can not be told which one belongs to the user.
+func (s *webAuthnService) reportClonedCredential(
+	c *fiber.Ctx, credential *model.WebAuthnCredential, signCount uint32,
+) error {
+	s.Log.WithFields(logrus.Fields{
+		"event":         "webauthn_clone_warning",
+		"user_id":       credential.UserID.String(),
+		"credential_id": credential.ID.String(),
+		"stored_count":  credential.SignCount,
+		"sign_count":    signCount,
+		"ip":            c.IP(),
+	}).Warn("Passkey signature counter did not increase, disabling the passkey")
+
+	if err := s.DB.WithContext(c.Context()).Model(credential).
+		Update("clone_warning", true).Error; err != nil {
+		s.Log.Errorf("Failed to disable cloned passkey: %+v", err)
+	}
+
+	return fiber.NewError(fiber.StatusUnauthorized, "This passkey may have been cloned and has been disabled")
+}
+
+// relyingParty is built on every request so the configuration can be changed at runtime, e.g. in tests
+func (s *webAuthnService) relyingParty() (*webauthn.WebAuthn, error) {
+	relyingParty, err := webauthn.New(&webauthn.Config{
+		RPID:          config.WebAuthnRPID,
+		RPDisplayName: config.WebAuthnRPName,
+		RPOrigins:     config.WebAuthnRPOrigins,
+		Timeouts: webauthn.TimeoutsConfig{
+			Login: webauthn.TimeoutConfig{
+				Enforce: true,
+				Timeout: time.Minute * config.WebAuthnSessionExpMinutes,
+			},
+			Registration: webauthn.TimeoutConfig{
+				Enforce: true,
+				Timeout: time.Minute * config.WebAuthnSessionExpMinutes,
+			},
+		},
+	})
+	if err != nil {
+		s.Log.Errorf("Invalid webauthn configuration: %+v", err)
+		return nil, fiber.NewError(fiber.StatusServiceUnavailable, "Passkeys are not configured")
+	}
+
+	return relyingParty, nil
+}
+
+// webAuthnUser loads the passkeys of the user, passkeys flagged as cloned can no longer be used
+func (s *webAuthnService) webAuthnUser(c *fiber.Ctx, user *model.User) (*webAuthnUser, error) {
+	var credentials []model.WebAuthnCredential
+
+	if err := s.DB.WithContext(c.Context()).
+		Where("user_id = ? AND clone_warning = ?", user.ID, false).
+		Order("created_at ASC").
+		Find(&credentials).Error; err != nil {
+		s.Log.Errorf("Failed to get passkeys: %+v", err)
+		return nil, err
+	}
+
+	return &webAuthnUser{user: user, credentials: credentials}, nil
+}
+
+func (s *webAuthnService) webAuthnUserByID(c *fiber.Ctx, userID uuid.UUID) (*webAuthnUser, error) {
+	user, err := s.UserService.GetUserByID(c, userID.String())
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Passkey verification failed")
+	}
+
+	return s.webAuthnUser(c, user)
+}
+
+func (u *webAuthnUser) credential(credentialID []byte) *model.WebAuthnCredential {
+	for i := range u.credentials {
+		if bytes.Equal(u.credentials[i].CredentialID, credentialID) {
+			return &u.credentials[i]
+		}
+	}
+
+	return nil
+}
+
+// saveSession keeps the challenge server side and returns the id the client sends back on finish
+func (s *webAuthnService) saveSession(
+	c *fiber.Ctx, ceremony string, userID *uuid.UUID, session *webauthn.SessionData,
+) (string, error) {
+	sessionID, err := utils.GenerateRandomString(32)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return "", err
+	}
+
+	// Abandoned ceremonies never reach finish, their sessions are removed when new ones are created
+	if err := s.DB.WithContext(c.Context()).
+		Where("expires_at <= ?", time.Now().UTC()).
+		Delete(&model.WebAuthnSession{}).Error; err != nil {
+		s.Log.Errorf("Failed to prune webauthn sessions: %+v", err)
+		return "", err
+	}
+
+	if err := s.DB.WithContext(c.Context()).Create(&model.WebAuthnSession{
+		ID:        utils.HashToken(sessionID),
+		Ceremony:  ceremony,
+		UserID:    userID,
+		Data:      string(data),
+		ExpiresAt: time.Now().UTC().Add(time.Minute * config.WebAuthnSessionExpMinutes),
+	}).Error; err != nil {
+		s.Log.Errorf("Failed to save webauthn session: %+v", err)
+		return "", err
+	}
+
+	return sessionID, nil
+}
+
+// consumeSession deletes the session so every challenge can be answered once
+func (s *webAuthnService) consumeSession(
+	c *fiber.Ctx, sessionID, ceremony string, userID *uuid.UUID,
+) (*webauthn.SessionData, error) {
+	stored := new(model.WebAuthnSession)
+	invalid := fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired session")
+
+	err := s.DB.WithContext(c.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND ceremony = ? AND expires_at > ?",
+			utils.HashToken(sessionID), ceremony, time.Now().UTC()).Take(stored).Error; err != nil {
+			return err
+		}
+
+		result := tx.Where("id = ?", stored.ID).Delete(&model.WebAuthnSession{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return nil
+	})
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, invalid
+	}
+
+	if err != nil {
+		s.Log.Errorf("Failed to consume webauthn session: %+v", err)
+		return nil, err
+	}
+
+	// A registration started by one user can not be finished by another
+	if userID != nil && (stored.UserID == nil || *stored.UserID != *userID) {
+		return nil, invalid
+	}
+
+	session := new(webauthn.SessionData)
+	if err := json.Unmarshal([]byte(stored.Data), session); err != nil {
+		s.Log.Errorf("Failed to decode webauthn session: %+v", err)
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// protocolErrorDetails adds the details of webauthn protocol errors, their message alone is too generic to debug
+func protocolErrorDetails(err error) string {
+	var protocolErr *protocol.Error
+	if errors.As(err, &protocolErr) {
+		return protocolErr.Error() + ": " + protocolErr.Details + " " + protocolErr.DevInfo
+	}
+
+	return err.Error()
+}
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"strings"
+)
+
+var randomEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateRandomString returns a random, URL-safe string built from n random bytes
+func GenerateRandomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return strings.ToLower(randomEncoding.EncodeToString(b)), nil
+}
+
+// HashToken returns the hex encoded SHA-256 digest of a high-entropy secret
+// such as a recovery code. Use HashPassword for user chosen passwords instead.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// SniffLength is how many bytes from the start of a file are read to detect its type
+const SniffLength = 3072
+
+// extensionTypes are the MIME types of common extensions, the standard library only knows a few of them
+var extensionTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".pdf":  "application/pdf",
+	".doc":  "application/msword",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xls":  "application/vnd.ms-excel",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".ppt":  "application/vnd.ms-powerpoint",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".txt":  "text/plain",
+	".csv":  "text/csv",
+	".json": "application/json",
+	".zip":  "application/zip",
+	".mp3":  "audio/mpeg",
+	".mp4":  "video/mp4",
+}
+
+// ExtensionType returns the MIME type of a file extension, an empty string when it is unknown
+func ExtensionType(ext string) string {
+	ext = strings.ToLower(ext)
+	if mimeType, ok := extensionTypes[ext]; ok {
+		return mimeType
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(mime.TypeByExtension(ext))
+	return mediaType
+}
+
+// ReadHead reads the first SniffLength bytes of r, or less for smaller content
+func ReadHead(r io.Reader) ([]byte, error) {
+	head, err := io.ReadAll(io.LimitReader(r, SniffLength))
+	if err != nil {
+		return nil, err
+	}
+	return head, nil
+}
+
+// DetectFileType returns the MIME type detected from the magic bytes of the content, without parameters
+func DetectFileType(head []byte) string {
+	mediaType, _, _ := mime.ParseMediaType(mimetype.Detect(head).String())
+	return mediaType
+}
+
+// ContentMatchesType reports whether content with these first bytes can be a file of the MIME type.
+// The detected type or one of its parents must match, e.g. a docx is also a zip and a csv also plain text.
+func ContentMatchesType(head []byte, mimeType string) bool {
+	if mimeType == "" {
+		return false
+	}
+
+	for detected := mimetype.Detect(head); detected != nil; detected = detected.Parent() {
+		if detected.Is(mimeType) {
+			return true
+		}
+	}
+
+	return false
+}
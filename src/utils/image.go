@@ -0,0 +1,292 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+var (
+	jpegSignature = []byte{0xff, 0xd8}
+	pngSignature  = []byte("\x89PNG\r\n\x1a\n")
+	exifHeader    = []byte("Exif\x00\x00")
+)
+
+// ErrInvalidImage is returned for JPEG and PNG data whose segments or chunks can not be read
+var ErrInvalidImage = errors.New("invalid image data")
+
+// ImageOrientation reads the EXIF orientation (1-8) of a JPEG or PNG, 1 when the image has none
+func ImageOrientation(data []byte) int {
+	var exif []byte
+
+	switch {
+	case bytes.HasPrefix(data, jpegSignature):
+		_ = walkJPEG(data, func(marker byte, segment []byte) bool {
+			if marker == 0xe1 && len(segment) > 4 && bytes.HasPrefix(segment[4:], exifHeader) {
+				exif = segment[4+len(exifHeader):]
+			}
+			return true
+		})
+	case bytes.HasPrefix(data, pngSignature):
+		_ = walkPNG(data, func(chunkType string, chunk []byte) bool {
+			if chunkType == "eXIf" {
+				exif = chunk[8 : len(chunk)-4]
+			}
+			return true
+		})
+	}
+
+	if orientation := tiffOrientation(exif); orientation >= 1 && orientation <= 8 {
+		return orientation
+	}
+
+	return 1
+}
+
+// StripImageMetadata removes EXIF (including GPS), XMP, IPTC and comments from a JPEG or PNG without re-encoding.
+// ICC color profiles are kept. Other formats are returned unchanged.
+func StripImageMetadata(data []byte) ([]byte, error) {
+	var stripped bytes.Buffer
+
+	switch {
+	case bytes.HasPrefix(data, jpegSignature):
+		stripped.Write(jpegSignature)
+		err := walkJPEG(data, func(marker byte, segment []byte) bool {
+			// APP1 (EXIF and XMP), APP12, APP13 (IPTC) and COM
+			if marker != 0xe1 && marker != 0xec && marker != 0xed && marker != 0xfe {
+				stripped.Write(segment)
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	case bytes.HasPrefix(data, pngSignature):
+		stripped.Write(pngSignature)
+		err := walkPNG(data, func(chunkType string, chunk []byte) bool {
+			switch chunkType {
+			case "eXIf", "tEXt", "zTXt", "iTXt", "tIME":
+			default:
+				stripped.Write(chunk)
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return data, nil
+	}
+
+	return stripped.Bytes(), nil
+}
+
+// OrientImage rotates and mirrors the image so it is displayed upright without the EXIF orientation
+func OrientImage(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	src := toNRGBA(img)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+
+	dstWidth, dstHeight := w, h
+	if orientation >= 5 {
+		dstWidth, dstHeight = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+
+			from, to := src.PixOffset(x, y), dst.PixOffset(dx, dy)
+			copy(dst.Pix[to:to+4], src.Pix[from:from+4])
+		}
+	}
+
+	return dst
+}
+
+// ResizeImage scales the image down to fit inside width x height. With crop the image fills the whole box
+// and the overflow is cut from the center. Images are never scaled up.
+func ResizeImage(img image.Image, width, height int, crop bool) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	source := bounds
+
+	var dstWidth, dstHeight int
+	if crop {
+		// Cut the part of the source with the aspect ratio of the box
+		cropWidth, cropHeight := w, h
+		if w*height > h*width {
+			cropWidth = max(1, h*width/height)
+		} else {
+			cropHeight = max(1, w*height/width)
+		}
+
+		x0 := bounds.Min.X + (w-cropWidth)/2
+		y0 := bounds.Min.Y + (h-cropHeight)/2
+		source = image.Rect(x0, y0, x0+cropWidth, y0+cropHeight)
+
+		dstWidth, dstHeight = cropWidth, cropHeight
+		if cropWidth > width {
+			dstWidth, dstHeight = width, height
+		}
+	} else {
+		dstWidth, dstHeight = w, h
+		if w > width || h > height {
+			scale := min(float64(width)/float64(w), float64(height)/float64(h))
+			dstWidth = max(1, int(float64(w)*scale+0.5))
+			dstHeight = max(1, int(float64(h)*scale+0.5))
+		}
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, source, xdraw.Src, nil)
+
+	return dst
+}
+
+func toNRGBA(img image.Image) *image.NRGBA {
+	if nrgba, ok := img.(*image.NRGBA); ok && nrgba.Bounds().Min == (image.Point{}) {
+		return nrgba
+	}
+
+	nrgba := image.NewNRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(nrgba, nrgba.Bounds(), img, img.Bounds().Min, draw.Src)
+
+	return nrgba
+}
+
+// walkJPEG calls fn with every marker segment before the image data, segment includes the marker.
+// The entropy coded data after the start of scan is passed as a single segment with marker 0xda.
+func walkJPEG(data []byte, fn func(marker byte, segment []byte) bool) error {
+	i := len(jpegSignature)
+
+	for i < len(data) {
+		if data[i] != 0xff || i+1 >= len(data) {
+			return ErrInvalidImage
+		}
+
+		marker := data[i+1]
+		switch {
+		case marker == 0xff:
+			// Fill byte
+			i++
+			continue
+		case marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7):
+			// Markers without length
+			if !fn(marker, data[i:i+2]) {
+				return nil
+			}
+			i += 2
+			continue
+		case marker == 0xd9:
+			fn(marker, data[i:])
+			return nil
+		}
+
+		if i+4 > len(data) {
+			return ErrInvalidImage
+		}
+
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+		if end > len(data) || end < i+4 {
+			return ErrInvalidImage
+		}
+
+		if marker == 0xda {
+			fn(marker, data[i:])
+			return nil
+		}
+
+		if !fn(marker, data[i:end]) {
+			return nil
+		}
+		i = end
+	}
+
+	return nil
+}
+
+// walkPNG calls fn with every chunk, chunk includes the length, type and CRC
+func walkPNG(data []byte, fn func(chunkType string, chunk []byte) bool) error {
+	i := len(pngSignature)
+
+	for i < len(data) {
+		if i+12 > len(data) {
+			return ErrInvalidImage
+		}
+
+		end := i + 12 + int(binary.BigEndian.Uint32(data[i:]))
+		if end > len(data) || end < i+12 {
+			return ErrInvalidImage
+		}
+
+		chunkType := string(data[i+4 : i+8])
+		if !fn(chunkType, data[i:end]) || chunkType == "IEND" {
+			return nil
+		}
+		i = end
+	}
+
+	return nil
+}
+
+// tiffOrientation reads tag 0x0112 of the first IFD of EXIF data in TIFF layout
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	offset := int(order.Uint32(tiff[4:]))
+	if offset+2 > len(tiff) || offset < 8 {
+		return 0
+	}
+
+	entries := int(order.Uint16(tiff[offset:]))
+	for n := 0; n < entries; n++ {
+		entry := offset + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+
+		// SHORT value, stored in the first bytes of the value field
+		if order.Uint16(tiff[entry:]) == 0x0112 && order.Uint16(tiff[entry+2:]) == 3 {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+
+	return 0
+}
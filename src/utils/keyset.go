@@ -0,0 +1,264 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTKey is a key of the keyset, only the signing key holds a private part
+type JWTKey struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PrivateKey interface{}
+	PublicKey  interface{}
+}
+
+// KeySet signs tokens with one key and verifies them against every active key,
+// so tokens signed by the previous key stay valid while keys are rotated
+type KeySet struct {
+	signing *JWTKey
+	keys    map[string]*JWTKey
+}
+
+// JWK is the public part of a key as published in the JWKS document (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewHMACKeySet creates a keyset signing and verifying with a shared HS256 secret
+func NewHMACKeySet(secret string) *KeySet {
+	key := &JWTKey{
+		Method:     jwt.SigningMethodHS256,
+		PrivateKey: []byte(secret),
+		PublicKey:  []byte(secret),
+	}
+
+	return &KeySet{
+		signing: key,
+		keys:    map[string]*JWTKey{"": key},
+	}
+}
+
+// LoadKeySet reads the PEM encoded private signing key and the optional verification keys,
+// which can be public or private keys of the previous rotations
+func LoadKeySet(algorithm, signingKeyFile string, verificationKeyFiles []string) (*KeySet, error) {
+	method := jwt.GetSigningMethod(algorithm)
+	if method == nil || (method != jwt.SigningMethodRS256 && method != jwt.SigningMethodEdDSA) {
+		return nil, fmt.Errorf("unsupported jwt algorithm %q", algorithm)
+	}
+
+	signing, err := loadJWTKey(signingKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if signing.PrivateKey == nil {
+		return nil, fmt.Errorf("signing key %s is not a private key", signingKeyFile)
+	}
+
+	if signing.Method != method {
+		return nil, fmt.Errorf("signing key %s can not be used with %s", signingKeyFile, algorithm)
+	}
+
+	keySet := &KeySet{
+		signing: signing,
+		keys:    map[string]*JWTKey{signing.ID: signing},
+	}
+
+	for _, file := range verificationKeyFiles {
+		key, err := loadJWTKey(file)
+		if err != nil {
+			return nil, err
+		}
+
+		// Only the current key signs, the others are kept for verification
+		key.PrivateKey = nil
+		keySet.keys[key.ID] = key
+	}
+
+	return keySet, nil
+}
+
+// Sign signs the claims with the current key and sets its kid header
+func (k *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.signing.Method, claims)
+	if k.signing.ID != "" {
+		token.Header["kid"] = k.signing.ID
+	}
+
+	return token.SignedString(k.signing.PrivateKey)
+}
+
+// Keyfunc selects the verification key by the kid header and rejects any other algorithm than the key's own
+func (k *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+
+	return key.PublicKey, nil
+}
+
+// Algorithms returns the signing algorithms accepted by the keyset
+func (k *KeySet) Algorithms() []string {
+	algorithms := make([]string, 0, len(k.keys))
+	seen := make(map[string]bool, len(k.keys))
+
+	for _, key := range k.keys {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			algorithms = append(algorithms, alg)
+		}
+	}
+
+	return algorithms
+}
+
+// JWKS returns the public keys of the keyset, a shared HMAC secret is never published
+func (k *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+
+	for _, key := range k.keys {
+		if jwk, ok := publicJWK(key); ok {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+
+	return jwks
+}
+
+func loadJWTKey(file string) (*JWTKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jwt key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("jwt key %s is not PEM encoded", file)
+	}
+
+	key := new(JWTKey)
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, errParse := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if errParse != nil {
+			return nil, fmt.Errorf("failed to parse jwt key %s: %w", file, errParse)
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("jwt key %s is not a signing key", file)
+		}
+		key.PrivateKey = parsed
+		key.PublicKey = signer.Public()
+	case "RSA PRIVATE KEY":
+		parsed, errParse := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if errParse != nil {
+			return nil, fmt.Errorf("failed to parse jwt key %s: %w", file, errParse)
+		}
+		key.PrivateKey = parsed
+		key.PublicKey = &parsed.PublicKey
+	case "PUBLIC KEY":
+		parsed, errParse := x509.ParsePKIXPublicKey(block.Bytes)
+		if errParse != nil {
+			return nil, fmt.Errorf("failed to parse jwt key %s: %w", file, errParse)
+		}
+		key.PublicKey = parsed
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q in jwt key %s", block.Type, file)
+	}
+
+	switch key.PublicKey.(type) {
+	case *rsa.PublicKey:
+		key.Method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("jwt key %s must be an RSA or Ed25519 key", file)
+	}
+
+	jwk, _ := publicJWK(key)
+	key.ID = jwkThumbprint(jwk)
+
+	return key, nil
+}
+
+func publicJWK(key *JWTKey) (JWK, bool) {
+	jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+
+	switch publicKey := key.PublicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+	default:
+		return jwk, false
+	}
+
+	return jwk, true
+}
+
+// jwkThumbprint computes the RFC 7638 thumbprint, so every instance derives the same kid from a key
+func jwkThumbprint(jwk JWK) string {
+	var members map[string]string
+
+	if jwk.Kty == "RSA" {
+		members = map[string]string{"e": jwk.E, "kty": jwk.Kty, "n": jwk.N}
+	} else {
+		members = map[string]string{"crv": jwk.Crv, "kty": jwk.Kty, "x": jwk.X}
+	}
+
+	// encoding/json sorts map keys, which gives the lexicographic order required by the RFC
+	data, _ := json.Marshal(members)
+	sum := sha256.Sum256(data)
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// SplitList splits a comma separated config value, ignoring empty entries
+func SplitList(value string) []string {
+	var items []string
+
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
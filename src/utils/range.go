@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrRangeNotSatisfiable is returned for a valid Range header that is outside of the content
+var ErrRangeNotSatisfiable = errors.New("range not satisfiable")
+
+// ByteRange is the part of the content requested with a Range header
+type ByteRange struct {
+	Start  int64
+	Length int64
+}
+
+// ParseRange reads a single byte range of a Range header (RFC 9110 14.2). A missing or malformed header and
+// requests for multiple ranges return nil, the whole content is sent for those.
+func ParseRange(header string, size int64) (*ByteRange, error) {
+	spec, ok := strings.CutPrefix(strings.TrimSpace(header), "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return nil, nil
+	}
+
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return nil, nil
+	}
+
+	// Suffix range: the last n bytes
+	if first == "" {
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return nil, nil
+		}
+		if n == 0 || size == 0 {
+			return nil, ErrRangeNotSatisfiable
+		}
+
+		n = min(n, size)
+		return &ByteRange{Start: size - n, Length: n}, nil
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return nil, nil
+	}
+
+	end := size - 1
+	if last != "" {
+		if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+			return nil, nil
+		}
+		end = min(end, size-1)
+	}
+
+	if start >= size {
+		return nil, ErrRangeNotSatisfiable
+	}
+
+	return &ByteRange{Start: start, Length: end - start + 1}, nil
+}
@@ -6,29 +6,37 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func VerifyToken(tokenStr, secret, tokenType string) (string, error) {
-	token, err := jwt.Parse(tokenStr, func(_ *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
-	})
+func VerifyToken(tokenStr string, keys *KeySet, tokenType string) (string, error) {
+	claims, err := ParseToken(tokenStr, keys, tokenType)
+	if err != nil {
+		return "", err
+	}
+
+	userID, ok := claims["sub"].(string)
+	if !ok {
+		return "", errors.New("invalid token sub")
+	}
+
+	return userID, nil
+}
+
+// ParseToken verifies the token like VerifyToken and returns all of its claims
+func ParseToken(tokenStr string, keys *KeySet, tokenType string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenStr, keys.Keyfunc, jwt.WithValidMethods(keys.Algorithms()))
 
 	if err != nil || !token.Valid {
-		return "", err
+		return nil, err
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return "", errors.New("invalid token claims")
+		return nil, errors.New("invalid token claims")
 	}
 
 	jwtType, ok := claims["type"].(string)
 	if !ok || jwtType != tokenType {
-		return "", errors.New("invalid token type")
+		return nil, errors.New("invalid token type")
 	}
 
-	userID, ok := claims["sub"].(string)
-	if !ok {
-		return "", errors.New("invalid token sub")
-	}
-
-	return userID, nil
+	return claims, nil
 }
@@ -0,0 +1,9 @@
+package validation
+
+import "time"
+
+type CreateAPIKey struct {
+	Name      string     `json:"name" validate:"required,max=50" example:"CI deploy"`
+	Scopes    []string   `json:"scopes" validate:"max=20,dive,required,max=50" example:"getUsers"`
+	ExpiresAt *time.Time `json:"expires_at" validate:"omitempty,gt" example:"2025-10-07T00:00:00Z"`
+}
@@ -3,32 +3,46 @@ package validation
 type Register struct {
 	Name     string `json:"name" validate:"required,max=50" example:"fake name"`
 	Email    string `json:"email" validate:"required,email,max=50" example:"fake@example.com"`
-	Password string `json:"password" validate:"required,min=8,max=20,password" example:"password1"`
+	Password string `json:"password" validate:"required,password_length,password,password_common" example:"s3cure-fiber"`
 }
 
 type Login struct {
 	Email    string `json:"email" validate:"required,email,max=50" example:"admin@gmail.com"`
-	Password string `json:"password" validate:"required,min=8,max=20" example:"password"`
+	Password string `json:"password" validate:"required,max=72" example:"password"`
 }
 
-type GoogleLogin struct {
+type OAuthLogin struct {
+	Provider      string `json:"provider" validate:"required,max=50"`
+	Subject       string `json:"sub" validate:"required,max=255"`
 	Name          string `json:"name" validate:"required,max=50"`
 	Email         string `json:"email" validate:"required,email,max=50"`
-	VerifiedEmail bool   `json:"verified_email" validate:"required"`
+	VerifiedEmail bool   `json:"verified_email"`
+}
+
+type OAuthCode struct {
+	Code string `json:"code" validate:"required,max=128" example:"k3xqj7w2m5r9t4y8u1p6a0s3d7f2g5h8"`
+}
+
+type SetPassword struct {
+	Password string `json:"password" validate:"required,password_length,password,password_common" example:"s3cure-fiber"`
 }
 
 type Logout struct {
-	RefreshToken string `json:"refresh_token" validate:"required,max=255"`
+	RefreshToken string `json:"refresh_token" validate:"required,max=1024"`
 }
 
 type RefreshToken struct {
-	RefreshToken string `json:"refresh_token" validate:"required,max=255"`
+	RefreshToken string `json:"refresh_token" validate:"required,max=1024"`
 }
 
 type ForgotPassword struct {
 	Email string `json:"email" validate:"required,email,max=50" example:"fake@example.com"`
 }
 
+type MagicLink struct {
+	Email string `json:"email" validate:"required,email,max=50" example:"fake@example.com"`
+}
+
 type Token struct {
-	Token string `json:"token" validate:"required,max=255"`
+	Token string `json:"token" validate:"required,max=1024"`
 }
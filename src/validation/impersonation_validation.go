@@ -0,0 +1,5 @@
+package validation
+
+type StartImpersonation struct {
+	Reason string `json:"reason" validate:"required,max=255" example:"Reproducing ticket #1234"`
+}
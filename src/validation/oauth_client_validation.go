@@ -0,0 +1,42 @@
+package validation
+
+type CreateOAuthClient struct {
+	Name         string   `json:"name" validate:"required,max=50" example:"Partner CRM"`
+	RedirectURIs []string `json:"redirect_uris" validate:"max=10,dive,required,url,max=2048" example:"https://partner.example.com/callback"`
+	Scopes       []string `json:"scopes" validate:"max=20,dive,required,max=50" example:"getUsers"`
+	GrantTypes   []string `json:"grant_types" validate:"required,min=1,max=2,dive,oneof=authorization_code client_credentials" example:"authorization_code"`
+	Confidential bool     `json:"confidential" example:"true"`
+}
+
+// OAuthAuthorize is the authorization request of a client, read from the query by GET /oauth/authorize
+// and sent again with the decision of the user to POST /oauth/authorize
+type OAuthAuthorize struct {
+	ResponseType        string `json:"response_type" query:"response_type" validate:"required,oneof=code" example:"code"`
+	ClientID            string `json:"client_id" query:"client_id" validate:"required,uuid" example:"e088d183-9eea-4a11-8d5d-74d7ec91bdf5"`
+	RedirectURI         string `json:"redirect_uri" query:"redirect_uri" validate:"required,max=2048" example:"https://partner.example.com/callback"`
+	Scope               string `json:"scope" query:"scope" validate:"max=1024" example:"getUsers"`
+	State               string `json:"state" query:"state" validate:"max=512" example:"af0ifjsldkj"`
+	CodeChallenge       string `json:"code_challenge" query:"code_challenge" validate:"required,min=43,max=128" example:"E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"`
+	CodeChallengeMethod string `json:"code_challenge_method" query:"code_challenge_method" validate:"required,oneof=S256" example:"S256"`
+	Approve             bool   `json:"approve" query:"-" example:"true"`
+}
+
+// OAuthToken is the form posted to /oauth/token. The client may authenticate with HTTP Basic instead of
+// client_id and client_secret.
+type OAuthToken struct {
+	GrantType    string `form:"grant_type" validate:"required,max=50"`
+	Code         string `form:"code" validate:"required_if=GrantType authorization_code,max=128"`
+	RedirectURI  string `form:"redirect_uri" validate:"required_if=GrantType authorization_code,max=2048"`
+	CodeVerifier string `form:"code_verifier" validate:"required_if=GrantType authorization_code,max=128"`
+	Scope        string `form:"scope" validate:"max=1024"`
+	ClientID     string `form:"client_id" validate:"max=36"`
+	ClientSecret string `form:"client_secret" validate:"max=128"`
+}
+
+// OAuthTokenLookup is the form posted to /oauth/introspect and /oauth/revoke
+type OAuthTokenLookup struct {
+	Token         string `form:"token" validate:"required,max=4096"`
+	TokenTypeHint string `form:"token_type_hint" validate:"max=50"`
+	ClientID      string `form:"client_id" validate:"max=36"`
+	ClientSecret  string `form:"client_secret" validate:"max=128"`
+}
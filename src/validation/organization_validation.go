@@ -0,0 +1,18 @@
+package validation
+
+type CreateOrganization struct {
+	Name string `json:"name" validate:"required,max=100" example:"Acme Inc"`
+}
+
+type UpdateOrganization struct {
+	Name string `json:"name" validate:"required,max=100" example:"Acme Inc"`
+}
+
+type UpdateMembership struct {
+	Role string `json:"role" validate:"required,oneof=owner admin member" example:"admin"`
+}
+
+type CreateInvitation struct {
+	Email string `json:"email" validate:"required,email,max=50" example:"fake@example.com"`
+	Role  string `json:"role" validate:"required,oneof=owner admin member" example:"member"`
+}
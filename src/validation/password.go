@@ -0,0 +1,124 @@
+package validation
+
+import (
+	"bufio"
+	_ "embed"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// bcrypt ignores everything after the 72nd byte, longer passwords are rejected whatever the policy says
+const maxPasswordBytes = 72
+
+// PasswordPolicy holds the rules of the password_length, password and password_common tags
+type PasswordPolicy struct {
+	MinLength        int
+	MaxLength        int
+	RequireLetter    bool
+	RequireMixedCase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	RejectCommon     bool
+}
+
+// PasswordRules is the policy applied to every new password, the config package loads it from the PASSWORD_* variables
+var PasswordRules = PasswordPolicy{
+	MinLength:     8,
+	MaxLength:     20,
+	RequireLetter: true,
+	RequireDigit:  true,
+	RejectCommon:  true,
+}
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+var commonPasswords = loadCommonPasswords()
+
+func loadCommonPasswords() map[string]struct{} {
+	passwords := make(map[string]struct{})
+
+	scanner := bufio.NewScanner(strings.NewReader(commonPasswordList))
+	for scanner.Scan() {
+		if password := strings.TrimSpace(scanner.Text()); password != "" && !strings.HasPrefix(password, "#") {
+			passwords[strings.ToLower(password)] = struct{}{}
+		}
+	}
+
+	return passwords
+}
+
+// IsCommonPassword reports whether the password, ignoring case, is in the bundled list of common passwords
+func IsCommonPassword(password string) bool {
+	_, ok := commonPasswords[strings.ToLower(password)]
+	return ok
+}
+
+func registerPasswordValidations(validate *validator.Validate) {
+	validate.RegisterValidation("password_length", func(fl validator.FieldLevel) bool {
+		password := fl.Field().String()
+		length := utf8.RuneCountInString(password)
+		return length >= PasswordRules.MinLength && length <= PasswordRules.MaxLength && len(password) <= maxPasswordBytes
+	})
+
+	validate.RegisterValidation("password", func(fl validator.FieldLevel) bool {
+		return PasswordRules.classesMatch(fl.Field().String())
+	})
+
+	validate.RegisterValidation("password_common", func(fl validator.FieldLevel) bool {
+		return !PasswordRules.RejectCommon || !IsCommonPassword(fl.Field().String())
+	})
+}
+
+func (p PasswordPolicy) classesMatch(password string) bool {
+	var hasLower, hasUpper, hasDigit, hasSymbol bool
+
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+
+	return (!p.RequireLetter || hasLower || hasUpper) &&
+		(!p.RequireMixedCase || (hasLower && hasUpper)) &&
+		(!p.RequireDigit || hasDigit) &&
+		(!p.RequireSymbol || hasSymbol)
+}
+
+// requirements lists the character classes of the policy for the error message of the password tag
+func (p PasswordPolicy) requirements() string {
+	var classes []string
+
+	if p.RequireMixedCase {
+		classes = append(classes, "an uppercase and a lowercase letter")
+	} else if p.RequireLetter {
+		classes = append(classes, "a letter")
+	}
+
+	if p.RequireDigit {
+		classes = append(classes, "a number")
+	}
+
+	if p.RequireSymbol {
+		classes = append(classes, "a symbol")
+	}
+
+	switch len(classes) {
+	case 0:
+		return ""
+	case 1:
+		return classes[0]
+	default:
+		return strings.Join(classes[:len(classes)-1], ", ") + " and " + classes[len(classes)-1]
+	}
+}
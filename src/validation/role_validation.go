@@ -0,0 +1,13 @@
+package validation
+
+type CreateRole struct {
+	Name        string   `json:"name" validate:"required,alphanum,max=50" example:"editor"`
+	Description string   `json:"description" validate:"omitempty,max=255" example:"Reads users"`
+	Permissions []string `json:"permissions" validate:"omitempty,dive,max=50" example:"getUsers"`
+}
+
+// UpdateRole replaces the fields that are sent, the name of a role can not be changed
+type UpdateRole struct {
+	Description *string   `json:"description,omitempty" validate:"omitempty,max=255" example:"Reads users"`
+	Permissions *[]string `json:"permissions,omitempty" validate:"omitempty,dive,max=50" example:"getUsers"`
+}
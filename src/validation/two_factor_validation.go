@@ -0,0 +1,18 @@
+package validation
+
+type TwoFactorCode struct {
+	Code string `json:"code" validate:"required,len=6,numeric" example:"123456"`
+}
+
+// DisableTwoFactor takes the password, users without one confirm with a code or a recovery code instead
+type DisableTwoFactor struct {
+	Password     string `json:"password,omitempty" validate:"omitempty,max=72" example:"password1"`
+	Code         string `json:"code,omitempty" validate:"omitempty,len=6,numeric" example:"123456"`
+	RecoveryCode string `json:"recovery_code,omitempty" validate:"omitempty,max=20" example:"abcdefgh-ijklmnop"`
+}
+
+type VerifyTwoFactor struct {
+	MFAToken     string `json:"mfa_token" validate:"required,max=1024"`
+	Code         string `json:"code,omitempty" validate:"required_without=RecoveryCode,omitempty,len=6,numeric" example:"123456"`
+	RecoveryCode string `json:"recovery_code,omitempty" validate:"required_without=Code,omitempty,max=20" example:"abcdefgh-ijklmnop"`
+}
@@ -0,0 +1,16 @@
+package validation
+
+// CreateUpload dibaca dari header Upload-Length dan Upload-Metadata, bukan dari body
+type CreateUpload struct {
+	FileName    string `validate:"required,max=255"`
+	ContentType string `validate:"max=100"`
+	Folder      string `validate:"required,max=100,excludes=..,startsnotwith=/"`
+	Size        int64  `validate:"gt=0"`
+}
+
+type CreatePresignedUpload struct {
+	FileName    string `json:"file_name" validate:"required,max=255" example:"report.pdf"`
+	ContentType string `json:"content_type" validate:"required,max=100" example:"application/pdf"`
+	Size        int64  `json:"size" validate:"gt=0" example:"1048576"`
+	Folder      string `json:"folder" validate:"omitempty,max=100,excludes=..,startsnotwith=/" example:"general"`
+}
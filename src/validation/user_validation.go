@@ -3,18 +3,18 @@ package validation
 type CreateUser struct {
 	Name     string `json:"name" validate:"required,max=50" example:"fake name"`
 	Email    string `json:"email" validate:"required,email,max=50" example:"fake@example.com"`
-	Password string `json:"password" validate:"required,min=8,max=20,password" example:"password1"`
-	Role     string `json:"role" validate:"required,oneof=user admin,max=50" example:"user"`
+	Password string `json:"password" validate:"required,password_length,password,password_common" example:"s3cure-fiber"`
+	Role     string `json:"role" validate:"required,max=50" example:"user"`
 }
 
 type UpdateUser struct {
 	Name     string `json:"name,omitempty" validate:"omitempty,max=50" example:"fake name"`
 	Email    string `json:"email" validate:"omitempty,email,max=50" example:"fake@example.com"`
-	Password string `json:"password,omitempty" validate:"omitempty,min=8,max=20,password" example:"password1"`
+	Password string `json:"password,omitempty" validate:"omitempty,password_length,password,password_common" example:"s3cure-fiber"`
 }
 
 type UpdatePassOrVerify struct {
-	Password      string `json:"password,omitempty" validate:"omitempty,min=8,max=20,password" example:"password1"`
+	Password      string `json:"password,omitempty" validate:"omitempty,password_length,password,password_common" example:"s3cure-fiber"`
 	VerifiedEmail bool   `json:"verified_email" swaggerignore:"true" validate:"omitempty,boolean"`
 }
 
@@ -3,21 +3,25 @@ package validation
 import (
 	"errors"
 	"fmt"
-	"regexp"
 
 	"github.com/go-playground/validator/v10"
 )
 
 var customMessages = map[string]string{
-	"required": "Field %s must be filled",
-	"email":    "Invalid email address for field %s",
-	"min":      "Field %s must have a minimum length of %s characters",
-	"max":      "Field %s must have a maximum length of %s characters",
-	"len":      "Field %s must be exactly %s characters long",
-	"number":   "Field %s must be a number",
-	"positive": "Field %s must be a positive number",
-	"alphanum": "Field %s must contain only alphanumeric characters",
-	"oneof":    "Invalid value for field %s",
+	"required":         "Field %s must be filled",
+	"email":            "Invalid email address for field %s",
+	"min":              "Field %s must have a minimum length of %s characters",
+	"max":              "Field %s must have a maximum length of %s characters",
+	"len":              "Field %s must be exactly %s characters long",
+	"number":           "Field %s must be a number",
+	"numeric":          "Field %s must contain only digits",
+	"positive":         "Field %s must be a positive number",
+	"alphanum":         "Field %s must contain only alphanumeric characters",
+	"oneof":            "Invalid value for field %s",
+	"required_without": "Field %s must be filled",
+	"password_length":  "Field %s must have between %d and %d characters",
+	"password":         "Field %s must contain at least %s",
+	"password_common":  "Field %s is a commonly used password, please choose another one",
 }
 
 func CustomErrorMessages(err error) map[string]string {
@@ -45,8 +49,13 @@ func generateErrorMessages(validationErrors validator.ValidationErrors) map[stri
 }
 
 func formatErrorMessage(customMessage string, err validator.FieldError, tag string) string {
-	if tag == "min" || tag == "max" || tag == "len" {
+	switch tag {
+	case "min", "max", "len":
 		return fmt.Sprintf(customMessage, err.Field(), err.Param())
+	case "password_length":
+		return fmt.Sprintf(customMessage, err.Field(), PasswordRules.MinLength, PasswordRules.MaxLength)
+	case "password":
+		return fmt.Sprintf(customMessage, err.Field(), PasswordRules.requirements())
 	}
 	return fmt.Sprintf(customMessage, err.Field())
 }
@@ -58,13 +67,8 @@ func defaultErrorMessage(err validator.FieldError) string {
 func Validator() *validator.Validate {
 	validate := validator.New()
 
-	// Custom validation for password: must contain at least one letter and one number
-	validate.RegisterValidation("password", func(fl validator.FieldLevel) bool {
-		password := fl.Field().String()
-		hasLetter := regexp.MustCompile(`[a-zA-Z]`).MatchString(password)
-		hasNumber := regexp.MustCompile(`[0-9]`).MatchString(password)
-		return hasLetter && hasNumber
-	})
+	// Custom validations for new passwords, following PasswordRules
+	registerPasswordValidations(validate)
 
 	return validate
 }
@@ -0,0 +1,18 @@
+package validation
+
+import "encoding/json"
+
+type BeginWebAuthnLogin struct {
+	Email string `json:"email" validate:"omitempty,email,max=50" example:"fake@example.com"`
+}
+
+type FinishWebAuthnRegistration struct {
+	SessionID  string          `json:"session_id" validate:"required,max=128"`
+	Name       string          `json:"name" validate:"omitempty,max=50" example:"MacBook Touch ID"`
+	Credential json.RawMessage `json:"credential" validate:"required" swaggertype:"object"`
+}
+
+type FinishWebAuthnLogin struct {
+	SessionID  string          `json:"session_id" validate:"required,max=128"`
+	Credential json.RawMessage `json:"credential" validate:"required" swaggertype:"object"`
+}
@@ -11,6 +11,7 @@ var ExpiresAccessToken = time.Now().UTC().Add(time.Minute * time.Duration(config
 var ExpiresRefreshToken = time.Now().UTC().Add(time.Hour * 24 * time.Duration(config.JWTRefreshExp))
 var ExpiresResetPasswordToken = time.Now().UTC().Add(time.Minute * time.Duration(config.JWTResetPasswordExp))
 var ExpiresVerifyEmailToken = time.Now().UTC().Add(time.Minute * time.Duration(config.JWTVerifyEmailExp))
+var ExpiresMagicLinkToken = time.Now().UTC().Add(time.Minute * time.Duration(config.JWTMagicLinkExp))
 
 func AccessToken(user *model.User) (string, error) {
 	accessToken, err := helper.GenerateToken(user.ID.String(), ExpiresAccessToken, config.TokenTypeAccess)
@@ -45,3 +46,11 @@ func VerifyEmailToken(user *model.User) (string, error) {
 	}
 	return verifyEmailToken, nil
 }
+
+func MagicLinkToken(user *model.User) (string, error) {
+	magicLinkToken, err := helper.GenerateToken(user.ID.String(), ExpiresMagicLinkToken, config.TokenTypeMagicLink)
+	if err != nil {
+		return magicLinkToken, err
+	}
+	return magicLinkToken, nil
+}
@@ -15,7 +15,12 @@ import (
 
 func ClearAll(db *gorm.DB) {
 	ClearToken(db)
+	ClearLoginAttempts(db)
+	ClearFiles(db)
+	ClearOrganizations(db)
+	ClearImpersonations(db)
 	ClearUsers(db)
+	ClearRoles(db)
 }
 
 func ClearUsers(db *gorm.DB) {
@@ -30,6 +35,43 @@ func ClearToken(db *gorm.DB) {
 	if err != nil {
 		logrus.Fatalf("Failed clear user token : %+v", err)
 	}
+
+	err = db.Where("id is not null").Delete(&model.RevokedToken{}).Error
+	if err != nil {
+		logrus.Fatalf("Failed clear revoked token : %+v", err)
+	}
+}
+
+func ClearLoginAttempts(db *gorm.DB) {
+	err := db.Where("id is not null").Delete(&model.LoginAttempt{}).Error
+	if err != nil {
+		logrus.Fatalf("Failed clear login attempts : %+v", err)
+	}
+}
+
+func ClearFiles(db *gorm.DB) {
+	err := db.Where("id is not null").Delete(&model.Upload{}).Error
+	if err != nil {
+		logrus.Fatalf("Failed clear uploads : %+v", err)
+	}
+
+	err = db.Where("id is not null").Delete(&model.File{}).Error
+	if err != nil {
+		logrus.Fatalf("Failed clear files : %+v", err)
+	}
+}
+
+// ClearRoles removes the roles created by tests, the default roles are kept
+func ClearRoles(db *gorm.DB) {
+	defaultRoles := make([]string, 0, len(config.DefaultRoles))
+	for role := range config.DefaultRoles {
+		defaultRoles = append(defaultRoles, role)
+	}
+
+	err := db.Where("name NOT IN ?", defaultRoles).Delete(&model.Role{}).Error
+	if err != nil {
+		logrus.Fatalf("Failed clear roles : %+v", err)
+	}
 }
 
 func CreateUser(db *gorm.DB, email, password, name string) {
@@ -73,6 +115,11 @@ func SaveToken(db *gorm.DB, token, userID, tokenType string, expires time.Time)
 		return err
 	}
 
+	return InsertToken(db, token, userID, tokenType, expires)
+}
+
+// InsertToken stores the token without deleting the other tokens of the same type
+func InsertToken(db *gorm.DB, token, userID, tokenType string, expires time.Time) error {
 	tokenDoc := &model.Token{
 		Token:   token,
 		UserID:  uuid.MustParse(userID),
@@ -98,13 +145,12 @@ func GenerateToken(
 ) (string, error) {
 	claims := jwt.MapClaims{
 		"sub":  userID,
-		"iat":  time.Now().Unix(),
+		"iat":  float64(time.Now().UnixMicro()) / 1e6,
 		"exp":  expires.Unix(),
 		"type": tokenType,
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
-	return token.SignedString([]byte(config.JWTSecret))
+	return config.JWTKeySet.Sign(claims)
 }
 
 func GenerateInvalidToken(
@@ -112,7 +158,7 @@ func GenerateInvalidToken(
 ) (string, error) {
 	claims := jwt.MapClaims{
 		"sub":  userID,
-		"iat":  time.Now().Unix(),
+		"iat":  float64(time.Now().UnixMicro()) / 1e6,
 		"exp":  expires.Unix(),
 		"type": tokenType,
 	}
@@ -122,7 +168,7 @@ func GenerateInvalidToken(
 }
 
 func GetTokenByUserID(db *gorm.DB, tokenStr string) (*model.Token, error) {
-	userID, err := utils.VerifyToken(tokenStr, config.JWTSecret, config.TokenTypeRefresh)
+	userID, err := utils.VerifyToken(tokenStr, config.JWTKeySet, config.TokenTypeRefresh)
 	if err != nil {
 		return nil, err
 	}
@@ -165,3 +211,354 @@ func GetUserByID(db *gorm.DB, id string) (*model.User, error) {
 
 	return user, result.Error
 }
+
+func EnableTwoFactor(db *gorm.DB, userID, secret string) {
+	err := db.Model(&model.User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{
+			"two_factor_enabled": true,
+			"two_factor_secret":  secret,
+		}).Error
+	if err != nil {
+		logrus.Errorf("Failed enable two-factor authentication : %+v", err)
+	}
+}
+
+func InsertRecoveryCode(db *gorm.DB, userID, code string) {
+	recoveryCode := &model.RecoveryCode{
+		UserID:   uuid.MustParse(userID),
+		CodeHash: utils.HashToken(code),
+	}
+
+	if err := db.Create(recoveryCode).Error; err != nil {
+		logrus.Errorf("Failed create recovery code : %+v", err)
+	}
+}
+
+func InsertSession(db *gorm.DB, userID, userAgent string, expires time.Time) (uuid.UUID, error) {
+	sessionID := uuid.New()
+
+	refreshToken, err := GenerateToken(userID, expires, config.TokenTypeRefresh)
+	if err != nil {
+		return sessionID, err
+	}
+
+	now := time.Now().UTC()
+	tokenDoc := &model.Token{
+		Token:      refreshToken,
+		UserID:     uuid.MustParse(userID),
+		Type:       config.TokenTypeRefresh,
+		Expires:    expires,
+		FamilyID:   &sessionID,
+		UserAgent:  userAgent,
+		IPAddress:  "0.0.0.0",
+		LastUsedAt: &now,
+	}
+
+	return sessionID, db.Create(tokenDoc).Error
+}
+
+func GenerateSessionAccessToken(userID string, sessionID uuid.UUID, expires time.Time) (string, error) {
+	claims := jwt.MapClaims{
+		"sub":  userID,
+		"iat":  float64(time.Now().UnixMicro()) / 1e6,
+		"exp":  expires.Unix(),
+		"type": config.TokenTypeAccess,
+		"sid":  sessionID.String(),
+	}
+
+	return config.JWTKeySet.Sign(claims)
+}
+
+func CountTokens(db *gorm.DB, userID, tokenType string) int64 {
+	var count int64
+
+	if err := db.Model(&model.Token{}).Where("user_id = ? AND type = ?", userID, tokenType).
+		Count(&count).Error; err != nil {
+		logrus.Errorf("Failed count tokens : %+v", err)
+	}
+
+	return count
+}
+
+func SetTokensValidAfter(db *gorm.DB, userID string, validAfter time.Time) {
+	err := db.Model(&model.User{}).Where("id = ?", userID).
+		Update("tokens_valid_after", validAfter.UTC()).Error
+	if err != nil {
+		logrus.Errorf("Failed set tokens valid after : %+v", err)
+	}
+}
+
+func RemovePassword(db *gorm.DB, userID string) {
+	err := db.Model(&model.User{}).Where("id = ?", userID).Update("password", "").Error
+	if err != nil {
+		logrus.Errorf("Failed remove password : %+v", err)
+	}
+}
+
+func InsertIdentity(db *gorm.DB, userID, provider, subject, email string) *model.UserIdentity {
+	identity := &model.UserIdentity{
+		UserID:   uuid.MustParse(userID),
+		Provider: provider,
+		Subject:  subject,
+		Email:    email,
+	}
+
+	if err := db.Create(identity).Error; err != nil {
+		logrus.Errorf("Failed create identity : %+v", err)
+	}
+
+	return identity
+}
+
+func CountIdentities(db *gorm.DB, userID string) int64 {
+	var count int64
+
+	if err := db.Model(&model.UserIdentity{}).Where("user_id = ?", userID).
+		Count(&count).Error; err != nil {
+		logrus.Errorf("Failed count identities : %+v", err)
+	}
+
+	return count
+}
+
+func GetPasskeys(db *gorm.DB, userID string) []model.WebAuthnCredential {
+	var passkeys []model.WebAuthnCredential
+
+	if err := db.Where("user_id = ?", userID).Order("created_at ASC").
+		Find(&passkeys).Error; err != nil {
+		logrus.Errorf("Failed get passkeys : %+v", err)
+	}
+
+	return passkeys
+}
+
+func LockUser(db *gorm.DB, userID string, until time.Time) {
+	err := db.Model(&model.User{}).Where("id = ?", userID).Update("locked_until", until.UTC()).Error
+	if err != nil {
+		logrus.Errorf("Failed lock user : %+v", err)
+	}
+}
+
+func CountLoginAttempts(db *gorm.DB, email string) int64 {
+	var count int64
+
+	if err := db.Model(&model.LoginAttempt{}).Where("email = ?", email).
+		Count(&count).Error; err != nil {
+		logrus.Errorf("Failed count login attempts : %+v", err)
+	}
+
+	return count
+}
+
+func GetRoleByName(db *gorm.DB, name string) (*model.Role, error) {
+	role := new(model.Role)
+
+	err := db.Preload("Permissions").First(role, "name = ?", name).Error
+	if err != nil {
+		logrus.Errorf("Failed get role by name : %+v", err)
+		return nil, err
+	}
+
+	return role, nil
+}
+
+func InsertRole(db *gorm.DB, name string, rights ...string) *model.Role {
+	var permissions []model.Permission
+	if len(rights) > 0 {
+		if err := db.Where("name IN ?", rights).Find(&permissions).Error; err != nil {
+			logrus.Fatalf("Failed get permissions : %+v", err)
+		}
+	}
+
+	role := &model.Role{Name: name, Permissions: permissions}
+	if err := db.Omit("Permissions.*").Create(role).Error; err != nil {
+		logrus.Fatalf("Failed insert role : %+v", err)
+	}
+
+	return role
+}
+
+// InsertFile stores a file record without writing the file to the storage
+func InsertFile(db *gorm.DB, uploadedBy *uuid.UUID, filePath string) *model.File {
+	return InsertOrganizationFile(db, uploadedBy, nil, filePath)
+}
+
+func InsertOrganizationFile(db *gorm.DB, uploadedBy, organizationID *uuid.UUID, filePath string) *model.File {
+	file := &model.File{
+		FileName:       filePath,
+		FilePath:       filePath,
+		FileSize:       1,
+		FileURL:        "/uploads/" + filePath,
+		ContentType:    "text/plain",
+		Folder:         "general",
+		UploadedBy:     uploadedBy,
+		OrganizationID: organizationID,
+	}
+
+	if err := db.Create(file).Error; err != nil {
+		logrus.Fatalf("Failed insert file : %+v", err)
+	}
+
+	return file
+}
+
+func GetUploadByID(db *gorm.DB, id string) (*model.Upload, error) {
+	upload := new(model.Upload)
+
+	if err := db.First(upload, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+
+	return upload, nil
+}
+
+func GetFileByID(db *gorm.DB, id string) (*model.File, error) {
+	file := new(model.File)
+
+	if err := db.First(file, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// ClearOrganizations removes the organizations, their memberships and invitations are cascaded
+func ClearOrganizations(db *gorm.DB) {
+	err := db.Where("id is not null").Delete(&model.Organization{}).Error
+	if err != nil {
+		logrus.Fatalf("Failed clear organization data : %+v", err)
+	}
+}
+
+func InsertOrganization(db *gorm.DB, name string) *model.Organization {
+	organization := &model.Organization{Name: name}
+	if err := db.Create(organization).Error; err != nil {
+		logrus.Fatalf("Failed insert organization : %+v", err)
+	}
+
+	return organization
+}
+
+func InsertMembership(db *gorm.DB, organizationID, userID uuid.UUID, role string) *model.Membership {
+	membership := &model.Membership{OrganizationID: organizationID, UserID: userID, Role: role}
+	if err := db.Omit("Organization", "User").Create(membership).Error; err != nil {
+		logrus.Fatalf("Failed insert membership : %+v", err)
+	}
+
+	return membership
+}
+
+// InsertInvitation stores an invitation of the given plain token
+func InsertInvitation(
+	db *gorm.DB, organizationID uuid.UUID, email, role, token string, expires time.Time,
+) *model.Invitation {
+	invitation := &model.Invitation{
+		OrganizationID: organizationID,
+		Email:          email,
+		Role:           role,
+		Token:          utils.HashToken(token),
+		ExpiresAt:      expires,
+	}
+
+	if err := db.Create(invitation).Error; err != nil {
+		logrus.Fatalf("Failed insert invitation : %+v", err)
+	}
+
+	return invitation
+}
+
+func GetMembership(db *gorm.DB, organizationID, userID uuid.UUID) (*model.Membership, error) {
+	membership := new(model.Membership)
+
+	if err := db.First(membership, "organization_id = ? AND user_id = ?", organizationID, userID).Error; err != nil {
+		return nil, err
+	}
+
+	return membership, nil
+}
+
+func CountInvitations(db *gorm.DB, organizationID uuid.UUID) int64 {
+	var count int64
+
+	if err := db.Model(&model.Invitation{}).Where("organization_id = ?", organizationID).
+		Count(&count).Error; err != nil {
+		logrus.Errorf("Failed count invitations : %+v", err)
+	}
+
+	return count
+}
+
+// InsertAPIKey stores an API key of the given plain key
+func InsertAPIKey(db *gorm.DB, userID uuid.UUID, key string, scopes string, expires *time.Time) *model.APIKey {
+	apiKey := &model.APIKey{
+		UserID:    userID,
+		Name:      "test key",
+		Prefix:    key[:min(len(key), 12)],
+		Key:       utils.HashToken(key),
+		Scopes:    scopes,
+		ExpiresAt: expires,
+	}
+
+	if err := db.Omit("User").Create(apiKey).Error; err != nil {
+		logrus.Fatalf("Failed insert api key : %+v", err)
+	}
+
+	return apiKey
+}
+
+func GetAPIKeyByID(db *gorm.DB, id string) (*model.APIKey, error) {
+	apiKey := new(model.APIKey)
+
+	if err := db.First(apiKey, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+
+	return apiKey, nil
+}
+
+func InsertOAuthClient(db *gorm.DB, userID uuid.UUID, secret, redirectURIs, scopes, grantTypes string) *model.OAuthClient {
+	client := &model.OAuthClient{
+		UserID:       userID,
+		Name:         "test client",
+		RedirectURIs: redirectURIs,
+		Scopes:       scopes,
+		GrantTypes:   grantTypes,
+	}
+
+	if secret != "" {
+		client.Secret = utils.HashToken(secret)
+	}
+
+	if err := db.Omit("User").Create(client).Error; err != nil {
+		logrus.Fatalf("Failed insert oauth client : %+v", err)
+	}
+
+	return client
+}
+
+func CountOAuthAuthorizationCodes(db *gorm.DB, clientID uuid.UUID) int64 {
+	var count int64
+
+	if err := db.Model(&model.OAuthAuthorizationCode{}).Where("client_id = ?", clientID).Count(&count).Error; err != nil {
+		logrus.Fatalf("Failed count authorization codes : %+v", err)
+	}
+
+	return count
+}
+
+func ClearImpersonations(db *gorm.DB) {
+	err := db.Where("id is not null").Delete(&model.Impersonation{}).Error
+	if err != nil {
+		logrus.Fatalf("Failed clear impersonation data : %+v", err)
+	}
+}
+
+func GetImpersonationByID(db *gorm.DB, id string) (*model.Impersonation, error) {
+	impersonation := new(model.Impersonation)
+
+	if err := db.First(impersonation, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+
+	return impersonation, nil
+}
@@ -0,0 +1,250 @@
+package helper
+
+import (
+	"app/src/config"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const mockOIDCKeyID = "mock-oidc-key"
+
+// MockOIDCUser is the identity returned by the mock provider for an authorization code
+type MockOIDCUser struct {
+	Subject       string
+	Name          string
+	Email         string
+	EmailVerified bool
+	// Audience overrides the aud claim of the id token, e.g. to test tokens issued to another client
+	Audience string
+	// Nonce is echoed in the id token, CodeChallenge is checked against the code_verifier when set
+	Nonce         string
+	CodeChallenge string
+}
+
+// MockOIDCServer is a minimal OpenID Connect provider with discovery, JWKS, token and userinfo endpoints
+type MockOIDCServer struct {
+	Server   *httptest.Server
+	ClientID string
+
+	key   *rsa.PrivateKey
+	mu    sync.Mutex
+	codes map[string]MockOIDCUser
+	users map[string]MockOIDCUser
+}
+
+func NewMockOIDCServer(clientID string) *MockOIDCServer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	mock := &MockOIDCServer{
+		ClientID: clientID,
+		key:      key,
+		codes:    make(map[string]MockOIDCUser),
+		users:    make(map[string]MockOIDCUser),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", mock.discovery)
+	mux.HandleFunc("/authorize", mock.authorize)
+	mux.HandleFunc("/token", mock.token)
+	mux.HandleFunc("/jwks", mock.jwks)
+	mux.HandleFunc("/userinfo", mock.userInfo)
+
+	mock.Server = httptest.NewServer(mux)
+
+	return mock
+}
+
+func (m *MockOIDCServer) Issuer() string {
+	return m.Server.URL
+}
+
+// Provider returns the registry entry pointing to the mock server
+func (m *MockOIDCServer) Provider(name string) config.OAuthProvider {
+	return config.OAuthProvider{
+		Name:         name,
+		Issuer:       m.Issuer(),
+		ClientID:     m.ClientID,
+		ClientSecret: "mock-secret",
+		Scopes:       []string{"openid", "email", "profile"},
+		RedirectURL:  "http://localhost/v1/auth/oauth/" + name + "/callback",
+	}
+}
+
+// IssueCode returns a single use authorization code that logs in as the user
+func (m *MockOIDCServer) IssueCode(user MockOIDCUser) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	code := uuid.New().String()
+	m.codes[code] = user
+
+	return code
+}
+
+func (m *MockOIDCServer) Close() {
+	m.Server.Close()
+}
+
+func (m *MockOIDCServer) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, map[string]string{
+		"issuer":                 m.Issuer(),
+		"authorization_endpoint": m.Issuer() + "/authorize",
+		"token_endpoint":         m.Issuer() + "/token",
+		"userinfo_endpoint":      m.Issuer() + "/userinfo",
+		"jwks_uri":               m.Issuer() + "/jwks",
+	})
+}
+
+// authorize skips the login page and redirects back with a code for a fixed user
+func (m *MockOIDCServer) authorize(w http.ResponseWriter, r *http.Request) {
+	code := m.IssueCode(MockOIDCUser{
+		Subject:       "mock-user",
+		Name:          "Mock User",
+		Email:         "mock@example.com",
+		EmailVerified: true,
+		Nonce:         r.URL.Query().Get("nonce"),
+		CodeChallenge: r.URL.Query().Get("code_challenge"),
+	})
+
+	redirect, err := url.Parse(r.URL.Query().Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	query := redirect.Query()
+	query.Set("code", code)
+	query.Set("state", r.URL.Query().Get("state"))
+	redirect.RawQuery = query.Encode()
+
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (m *MockOIDCServer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+
+	m.mu.Lock()
+	user, ok := m.codes[r.PostForm.Get("code")]
+	delete(m.codes, r.PostForm.Get("code"))
+	m.mu.Unlock()
+
+	if !ok || !verifyCodeChallenge(user.CodeChallenge, r.PostForm.Get("code_verifier")) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	audience := user.Audience
+	if audience == "" {
+		audience = m.ClientID
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            m.Issuer(),
+		"sub":            user.Subject,
+		"aud":            audience,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Minute * 5).Unix(),
+		"name":           user.Name,
+		"email":          user.Email,
+		"email_verified": user.EmailVerified,
+	}
+
+	if user.Nonce != "" {
+		claims["nonce"] = user.Nonce
+	}
+
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = mockOIDCKeyID
+
+	signed, err := idToken.SignedString(m.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	accessToken := uuid.New().String()
+
+	m.mu.Lock()
+	m.users[accessToken] = user
+	m.mu.Unlock()
+
+	writeJSON(w, map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     signed,
+	})
+}
+
+func (m *MockOIDCServer) jwks(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": mockOIDCKeyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(m.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(m.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (m *MockOIDCServer) userInfo(w http.ResponseWriter, r *http.Request) {
+	accessToken := r.Header.Get("Authorization")
+	if len(accessToken) > len("Bearer ") {
+		accessToken = accessToken[len("Bearer "):]
+	}
+
+	m.mu.Lock()
+	user, ok := m.users[accessToken]
+	m.mu.Unlock()
+
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"sub":            user.Subject,
+		"name":           user.Name,
+		"email":          user.Email,
+		"email_verified": user.EmailVerified,
+	})
+}
+
+// verifyCodeChallenge checks the PKCE S256 challenge, codes issued without a challenge accept any verifier
+func verifyCodeChallenge(challenge, verifier string) bool {
+	if challenge == "" {
+		return true
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:]) == challenge
+}
+
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,157 @@
+package helper
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+)
+
+const (
+	authenticatorFlagUserPresent  = 0x01
+	authenticatorFlagUserVerified = 0x04
+	authenticatorFlagAttestedData = 0x40
+)
+
+// MockAuthenticator is a software passkey with an ES256 key and "none" attestation,
+// it answers the options returned by the begin endpoints like navigator.credentials would
+type MockAuthenticator struct {
+	RPID         string
+	Origin       string
+	CredentialID []byte
+	UserHandle   []byte
+	// SignCount is increased before every assertion, set it back to simulate a cloned authenticator
+	SignCount uint32
+
+	key *ecdsa.PrivateKey
+}
+
+func NewMockAuthenticator(rpID, origin string) *MockAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	credentialID := make([]byte, 32)
+	if _, err := rand.Read(credentialID); err != nil {
+		panic(err)
+	}
+
+	return &MockAuthenticator{
+		RPID:         rpID,
+		Origin:       origin,
+		CredentialID: credentialID,
+		key:          key,
+	}
+}
+
+// Register returns the credential for navigator.credentials.create, challenge and userHandle are taken from the options
+func (a *MockAuthenticator) Register(challenge string, userHandle []byte) json.RawMessage {
+	a.UserHandle = userHandle
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  1, // P-256
+		XCoord: a.key.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	authData := a.authenticatorData(authenticatorFlagUserPresent|authenticatorFlagUserVerified|authenticatorFlagAttestedData, 0)
+	authData = append(authData, make([]byte, 16)...) // AAGUID
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.CredentialID)))
+	authData = append(authData, a.CredentialID...)
+	authData = append(authData, publicKey...)
+
+	attestationObject, err := webauthncbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": authData,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return a.credential(map[string]interface{}{
+		"clientDataJSON":    encode(a.clientData("webauthn.create", challenge)),
+		"attestationObject": encode(attestationObject),
+		"transports":        []string{"internal"},
+	})
+}
+
+// Assert returns the credential for navigator.credentials.get, signed with the next signature counter
+func (a *MockAuthenticator) Assert(challenge string) json.RawMessage {
+	a.SignCount++
+
+	clientData := a.clientData("webauthn.get", challenge)
+	authData := a.authenticatorData(authenticatorFlagUserPresent|authenticatorFlagUserVerified, a.SignCount)
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		panic(err)
+	}
+
+	return a.credential(map[string]interface{}{
+		"clientDataJSON":    encode(clientData),
+		"authenticatorData": encode(authData),
+		"signature":         encode(signature),
+		"userHandle":        encode(a.UserHandle),
+	})
+}
+
+func (a *MockAuthenticator) authenticatorData(flags byte, signCount uint32) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.RPID))
+
+	authData := append([]byte{}, rpIDHash[:]...)
+	authData = append(authData, flags)
+
+	return binary.BigEndian.AppendUint32(authData, signCount)
+}
+
+func (a *MockAuthenticator) clientData(ceremony, challenge string) []byte {
+	clientData, err := json.Marshal(map[string]interface{}{
+		"type":        ceremony,
+		"challenge":   challenge,
+		"origin":      a.Origin,
+		"crossOrigin": false,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return clientData
+}
+
+func (a *MockAuthenticator) credential(response map[string]interface{}) json.RawMessage {
+	credential, err := json.Marshal(map[string]interface{}{
+		"id":                      encode(a.CredentialID),
+		"rawId":                   encode(a.CredentialID),
+		"type":                    "public-key",
+		"authenticatorAttachment": "platform",
+		"clientExtensionResults":  map[string]interface{}{},
+		"response":                response,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return credential
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
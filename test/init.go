@@ -1,6 +1,7 @@
 package test
 
 import (
+	"app/src/config"
 	"app/src/database"
 	"app/src/router"
 	"app/src/utils"
@@ -12,6 +13,7 @@ import (
 var App = fiber.New(fiber.Config{
 	CaseSensitive: true,
 	ErrorHandler:  utils.ErrorHandler,
+	BodyLimit:     config.BodyLimit(),
 })
 var DB *gorm.DB
 var Log = utils.Log
@@ -0,0 +1,234 @@
+package integration
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/response"
+	"app/src/utils"
+	"app/src/validation"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeys(t *testing.T) {
+	const key = config.APIKeyPrefix + "k3xqj7w2m5r9t4y8u1p6a0s3d7f2g5h8"
+
+	// request authenticates with the access token of the user, or with the API key when user is nil
+	request := func(method, target string, user *model.User, body interface{}) *http.Response {
+		var reader io.Reader
+		if body != nil {
+			bodyJSON, err := json.Marshal(body)
+			assert.Nil(t, err)
+			reader = strings.NewReader(string(bodyJSON))
+		}
+
+		request := httptest.NewRequest(method, target, reader)
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Accept", "application/json")
+
+		if user != nil {
+			accessToken, err := fixture.AccessToken(user)
+			assert.Nil(t, err)
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+		} else {
+			request.Header.Set(config.HeaderAPIKey, key)
+		}
+
+		apiResponse, err := test.App.Test(request)
+		assert.Nil(t, err)
+
+		return apiResponse
+	}
+
+	t.Run("POST /v1/auth/api-keys", func(t *testing.T) {
+		t.Run("should return 201 and the key once, only its hash is stored", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			apiResponse := request(http.MethodPost, "/v1/auth/api-keys", fixture.Admin,
+				validation.CreateAPIKey{Name: "CI deploy", Scopes: []string{"getUsers"}})
+			assert.Equal(t, http.StatusCreated, apiResponse.StatusCode)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.SuccessWithAPIKey)
+			assert.Nil(t, json.Unmarshal(bytes, responseBody))
+			assert.True(t, strings.HasPrefix(responseBody.Key, config.APIKeyPrefix))
+			assert.True(t, strings.HasPrefix(responseBody.Key, responseBody.APIKey.Prefix))
+			assert.Equal(t, []string{"getUsers"}, responseBody.APIKey.Scopes)
+
+			apiKey, err := helper.GetAPIKeyByID(test.DB, responseBody.APIKey.ID.String())
+			assert.Nil(t, err)
+			assert.Equal(t, utils.HashToken(responseBody.Key), apiKey.Key)
+		})
+
+		t.Run("should return 400 error if a scope is not granted to the user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			apiResponse := request(http.MethodPost, "/v1/auth/api-keys", fixture.UserOne,
+				validation.CreateAPIKey{Name: "CI deploy", Scopes: []string{"manageUsers"}})
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 400 error if the expiry is in the past", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			expires := time.Now().UTC().Add(-time.Hour)
+			apiResponse := request(http.MethodPost, "/v1/auth/api-keys", fixture.UserOne,
+				validation.CreateAPIKey{Name: "CI deploy", ExpiresAt: &expires})
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 403 error if the request uses an API key", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			helper.InsertAPIKey(test.DB, fixture.UserOne.ID, key, "", nil)
+
+			apiResponse := request(http.MethodPost, "/v1/auth/api-keys", nil, validation.CreateAPIKey{Name: "Another key"})
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("GET /v1/auth/api-keys", func(t *testing.T) {
+		t.Run("should return 200 and only the keys of the user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+			apiKey := helper.InsertAPIKey(test.DB, fixture.UserOne.ID, key, "", nil)
+			helper.InsertAPIKey(test.DB, fixture.UserTwo.ID, config.APIKeyPrefix+"another", "", nil)
+
+			apiResponse := request(http.MethodGet, "/v1/auth/api-keys", fixture.UserOne, nil)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.SuccessWithAPIKeys)
+			assert.Nil(t, json.Unmarshal(bytes, responseBody))
+			assert.Len(t, responseBody.APIKeys, 1)
+			assert.Equal(t, apiKey.ID, responseBody.APIKeys[0].ID)
+			assert.NotContains(t, string(bytes), apiKey.Key)
+		})
+	})
+
+	t.Run("DELETE /v1/auth/api-keys/:apiKeyId", func(t *testing.T) {
+		t.Run("should return 200 and the key no longer authenticates", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			apiKey := helper.InsertAPIKey(test.DB, fixture.UserOne.ID, key, "", nil)
+
+			apiResponse := request(http.MethodDelete, "/v1/auth/api-keys/"+apiKey.ID.String(), fixture.UserOne, nil)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			apiResponse = request(http.MethodGet, "/v1/files/my-files", nil, nil)
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 404 error if the key belongs to another user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+			apiKey := helper.InsertAPIKey(test.DB, fixture.UserTwo.ID, key, "", nil)
+
+			apiResponse := request(http.MethodDelete, "/v1/auth/api-keys/"+apiKey.ID.String(), fixture.UserOne, nil)
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+
+			_, err := helper.GetAPIKeyByID(test.DB, apiKey.ID.String())
+			assert.Nil(t, err)
+		})
+	})
+
+	t.Run("X-API-Key header", func(t *testing.T) {
+		t.Run("should authenticate the user and record the last use", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+			apiKey := helper.InsertAPIKey(test.DB, fixture.Admin.ID, key, "manageFiles", nil)
+
+			apiResponse := request(http.MethodGet, "/v1/files/my-files", nil, nil)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			dbAPIKey, err := helper.GetAPIKeyByID(test.DB, apiKey.ID.String())
+			assert.Nil(t, err)
+			assert.NotNil(t, dbAPIKey.LastUsedAt)
+			assert.NotEmpty(t, dbAPIKey.LastUsedIP)
+		})
+
+		t.Run("should only grant the rights in the scopes of the key", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+			helper.InsertAPIKey(test.DB, fixture.Admin.ID, key, "getUsers", nil)
+
+			apiResponse := request(http.MethodGet, "/v1/users/paginated", nil, nil)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			apiResponse = request(http.MethodGet, "/v1/roles", nil, nil)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+
+		t.Run("should not let the key act on resources of the user without a scope", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			helper.InsertAPIKey(test.DB, fixture.UserOne.ID, key, "", nil)
+
+			apiResponse := request(http.MethodPatch, "/v1/users/"+fixture.UserOne.ID.String(), nil,
+				validation.UpdateUser{Name: "Changed by a key"})
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 403 error on the own files of the user without the manageFiles scope", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			helper.InsertAPIKey(test.DB, fixture.UserOne.ID, key, "", nil)
+
+			apiResponse := request(http.MethodGet, "/v1/files/my-files", nil, nil)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+
+			apiResponse = request(http.MethodPost, "/v1/files/presigned-uploads", nil, nil)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 403 error on routes that manage how the user logs in", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			helper.InsertAPIKey(test.DB, fixture.UserOne.ID, key, "", nil)
+
+			apiResponse := request(http.MethodGet, "/v1/auth/sessions", nil, nil)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+
+			apiResponse = request(http.MethodPost, "/v1/auth/2fa/enroll", nil, nil)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+
+			apiResponse = request(http.MethodGet, "/v1/auth/identities", nil, nil)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 401 error if the key has expired", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			expires := time.Now().UTC().Add(-time.Minute)
+			helper.InsertAPIKey(test.DB, fixture.UserOne.ID, key, "", &expires)
+
+			apiResponse := request(http.MethodGet, "/v1/files/my-files", nil, nil)
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 401 error if the key is unknown", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			apiResponse := request(http.MethodGet, "/v1/files/my-files", nil, nil)
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+	})
+}
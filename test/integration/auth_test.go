@@ -2,6 +2,7 @@ package integration
 
 import (
 	"app/src/config"
+	"app/src/model"
 	"app/src/response"
 	"app/src/utils"
 	"app/src/validation"
@@ -25,7 +26,7 @@ func TestAuthRoutes(t *testing.T) {
 		var requestBody = validation.Register{
 			Name:     "Test",
 			Email:    "test@gmail.com",
-			Password: "password1",
+			Password: "s3cure-fiber",
 		}
 
 		t.Run("should return 201 and successfully register user if request data is ok", func(t *testing.T) {
@@ -316,6 +317,111 @@ func TestAuthRoutes(t *testing.T) {
 			assert.Equal(t, dbRefreshTokenDoc.Type, config.TokenTypeRefresh)
 		})
 
+		t.Run("should revoke the whole token family if a rotated refresh token is reused", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			refreshToken, err := fixture.RefreshToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			err = helper.SaveToken(test.DB, refreshToken, fixture.UserOne.ID.String(), config.TokenTypeRefresh, fixture.ExpiresRefreshToken)
+			assert.Nil(t, err)
+
+			bodyJSON, err := json.Marshal(validation.RefreshToken{RefreshToken: refreshToken})
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "/v1/auth/refresh-tokens", strings.NewReader(string(bodyJSON)))
+			request.Header.Set("Content-Type", "application/json")
+			request.Header.Set("Accept", "application/json")
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.RefreshToken)
+			err = json.Unmarshal(bytes, responseBody)
+			assert.Nil(t, err)
+
+			rotatedTokenDoc, err := helper.GetTokenByUserID(test.DB, responseBody.Tokens.Refresh.Token)
+			assert.Nil(t, err)
+			assert.NotNil(t, rotatedTokenDoc.ParentID)
+			assert.Nil(t, rotatedTokenDoc.RevokedAt)
+
+			request = httptest.NewRequest(http.MethodPost, "/v1/auth/refresh-tokens", strings.NewReader(string(bodyJSON)))
+			request.Header.Set("Content-Type", "application/json")
+			request.Header.Set("Accept", "application/json")
+
+			apiResponse, err = test.App.Test(request)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+
+			rotatedTokenDoc, err = helper.GetTokenByUserID(test.DB, responseBody.Tokens.Refresh.Token)
+			assert.Nil(t, err)
+			assert.NotNil(t, rotatedTokenDoc.RevokedAt)
+		})
+
+		t.Run("should return 401 error and delete the session if refresh token was issued before the password was changed", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			refreshToken, err := fixture.RefreshToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			err = helper.SaveToken(test.DB, refreshToken, fixture.UserOne.ID.String(), config.TokenTypeRefresh, fixture.ExpiresRefreshToken)
+			assert.Nil(t, err)
+
+			helper.SetTokensValidAfter(test.DB, fixture.UserOne.ID.String(), time.Now())
+
+			bodyJSON, err := json.Marshal(validation.RefreshToken{RefreshToken: refreshToken})
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "/v1/auth/refresh-tokens", strings.NewReader(string(bodyJSON)))
+			request.Header.Set("Content-Type", "application/json")
+			request.Header.Set("Accept", "application/json")
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+
+			_, err = helper.GetTokenByUserID(test.DB, refreshToken)
+			assert.NotNil(t, err)
+		})
+
+		t.Run("should keep refresh tokens of other devices when logging in", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+
+			// InsertUser replaces the password of the fixtures with its hash, so the user logging in is a new one
+			user := &model.User{Name: "Test User", Email: "test@gmail.com", Password: "password1", Role: "user"}
+			helper.InsertUser(test.DB, user)
+
+			refreshToken, err := fixture.RefreshToken(user)
+			assert.Nil(t, err)
+
+			err = helper.SaveToken(test.DB, refreshToken, user.ID.String(), config.TokenTypeRefresh, fixture.ExpiresRefreshToken)
+			assert.Nil(t, err)
+
+			bodyJSON, err := json.Marshal(validation.Login{
+				Email:    user.Email,
+				Password: "password1",
+			})
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "/v1/auth/login", strings.NewReader(string(bodyJSON)))
+			request.Header.Set("Content-Type", "application/json")
+			request.Header.Set("Accept", "application/json")
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			dbRefreshTokenDoc, err := helper.GetTokenByUserID(test.DB, refreshToken)
+			assert.Nil(t, err)
+			assert.Nil(t, dbRefreshTokenDoc.RevokedAt)
+		})
+
 		t.Run("should return 400 error if refresh token is missing from request body", func(t *testing.T) {
 			request := httptest.NewRequest(http.MethodPost, "/v1/auth/refresh-tokens", nil)
 			request.Header.Set("Content-Type", "application/json")
@@ -691,7 +797,7 @@ func TestAuthMiddleware(t *testing.T) {
 		request := httptest.NewRequest(http.MethodGet, "/v1/users", nil)
 		request.Header.Set("Authorization", "Bearer "+token)
 
-		userID, err := utils.VerifyToken(token, config.JWTSecret, config.TokenTypeAccess)
+		userID, err := utils.VerifyToken(token, config.JWTKeySet, config.TokenTypeAccess)
 		assert.Nil(t, err)
 
 		assert.Equal(t, fixture.UserOne.ID.String(), userID)
@@ -828,4 +934,52 @@ func TestAuthMiddleware(t *testing.T) {
 
 		assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
 	})
+
+	t.Run("should call next with unauthorized error if the session of the access token was logged out", func(t *testing.T) {
+		helper.ClearAll(test.DB)
+		helper.InsertUser(test.DB, fixture.UserOne)
+
+		sessionID, err := helper.InsertSession(test.DB, fixture.UserOne.ID.String(), "Laptop", fixture.ExpiresRefreshToken)
+		assert.Nil(t, err)
+
+		accessToken, err := helper.GenerateSessionAccessToken(fixture.UserOne.ID.String(), sessionID, fixture.ExpiresAccessToken)
+		assert.Nil(t, err)
+
+		refreshTokenDoc, err := helper.GetTokenByType(test.DB, fixture.UserOne.ID.String(), config.TokenTypeRefresh)
+		assert.Nil(t, err)
+
+		bodyJSON, err := json.Marshal(validation.Logout{RefreshToken: refreshTokenDoc.Token})
+		assert.Nil(t, err)
+
+		request := httptest.NewRequest(http.MethodPost, "/v1/auth/logout", strings.NewReader(string(bodyJSON)))
+		request.Header.Set("Content-Type", "application/json")
+
+		apiResponse, err := test.App.Test(request)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+		request = httptest.NewRequest(http.MethodGet, "/v1/users/"+fixture.UserOne.ID.String(), nil)
+		request.Header.Set("Authorization", "Bearer "+accessToken)
+
+		apiResponse, err = test.App.Test(request)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+	})
+
+	t.Run("should call next with unauthorized error if access token was issued before the password was reset", func(t *testing.T) {
+		helper.ClearAll(test.DB)
+		helper.InsertUser(test.DB, fixture.UserOne)
+
+		accessToken, err := fixture.AccessToken(fixture.UserOne)
+		assert.Nil(t, err)
+
+		helper.SetTokensValidAfter(test.DB, fixture.UserOne.ID.String(), time.Now().Add(time.Minute))
+
+		request := httptest.NewRequest(http.MethodGet, "/v1/users/"+fixture.UserOne.ID.String(), nil)
+		request.Header.Set("Authorization", "Bearer "+accessToken)
+
+		apiResponse, err := test.App.Test(request)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+	})
 }
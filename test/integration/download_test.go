@@ -0,0 +1,143 @@
+package integration
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadRoutes(t *testing.T) {
+	request := func(target string, user *model.User, headers map[string]string) *http.Response {
+		request := httptest.NewRequest(http.MethodGet, target, nil)
+
+		if user != nil {
+			accessToken, err := fixture.AccessToken(user)
+			assert.Nil(t, err)
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+		}
+
+		for key, value := range headers {
+			request.Header.Set(key, value)
+		}
+
+		apiResponse, err := test.App.Test(request)
+		assert.Nil(t, err)
+
+		return apiResponse
+	}
+
+	// insertStoredFile menyimpan file di local storage beserta datanya
+	insertStoredFile := func(user *model.User, filePath, content string) *model.File {
+		fullPath := filepath.Join(config.StorageLocalPath, filePath)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		assert.Nil(t, os.WriteFile(fullPath, []byte(content), 0644))
+		t.Cleanup(func() { os.Remove(fullPath) })
+
+		file := helper.InsertFile(test.DB, &user.ID, filePath)
+		assert.Nil(t, test.DB.Model(file).Update("original_name", "Laporan Mei.txt").Error)
+
+		return file
+	}
+
+	readBody := func(apiResponse *http.Response) string {
+		bytes, err := io.ReadAll(apiResponse.Body)
+		assert.Nil(t, err)
+
+		return string(bytes)
+	}
+
+	t.Run("GET /v1/files/:fileId/download", func(t *testing.T) {
+		t.Run("should return 200 and the file with its original name", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			file := insertStoredFile(fixture.UserOne, "general/download-one.txt", "hello world")
+
+			apiResponse := request("/v1/files/"+file.ID.String()+"/download", fixture.UserOne, nil)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, "hello world", readBody(apiResponse))
+			assert.Equal(t, "bytes", apiResponse.Header.Get("Accept-Ranges"))
+			assert.NotEmpty(t, apiResponse.Header.Get("ETag"))
+			assert.NotEmpty(t, apiResponse.Header.Get("Last-Modified"))
+			assert.Contains(t, apiResponse.Header.Get("Content-Disposition"), `filename="Laporan Mei.txt"`)
+		})
+
+		t.Run("should return 206 and the requested range", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			file := insertStoredFile(fixture.UserOne, "general/download-two.txt", "hello world")
+
+			apiResponse := request("/v1/files/"+file.ID.String()+"/download", fixture.UserOne, map[string]string{
+				"Range": "bytes=6-10",
+			})
+			assert.Equal(t, http.StatusPartialContent, apiResponse.StatusCode)
+			assert.Equal(t, "world", readBody(apiResponse))
+			assert.Equal(t, "bytes 6-10/11", apiResponse.Header.Get("Content-Range"))
+		})
+
+		t.Run("should return the whole file if If-Range does not match", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			file := insertStoredFile(fixture.UserOne, "general/download-three.txt", "hello world")
+
+			apiResponse := request("/v1/files/"+file.ID.String()+"/download", fixture.UserOne, map[string]string{
+				"Range": "bytes=6-10", "If-Range": `"outdated"`,
+			})
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, "hello world", readBody(apiResponse))
+		})
+
+		t.Run("should return 304 if the ETag matches", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			file := insertStoredFile(fixture.UserOne, "general/download-four.txt", "hello world")
+
+			apiResponse := request("/v1/files/"+file.ID.String()+"/download", fixture.UserOne, nil)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			apiResponse = request("/v1/files/"+file.ID.String()+"/download", fixture.UserOne, map[string]string{
+				"If-None-Match": apiResponse.Header.Get("ETag"),
+			})
+			assert.Equal(t, http.StatusNotModified, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 416 error if the range is outside of the file", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			file := insertStoredFile(fixture.UserOne, "general/download-five.txt", "hello world")
+
+			apiResponse := request("/v1/files/"+file.ID.String()+"/download", fixture.UserOne, map[string]string{
+				"Range": "bytes=20-",
+			})
+			assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, apiResponse.StatusCode)
+			assert.Equal(t, "bytes */11", apiResponse.Header.Get("Content-Range"))
+		})
+
+		t.Run("should return 404 error if the file belongs to another user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+			file := insertStoredFile(fixture.UserTwo, "general/download-six.txt", "hello world")
+
+			apiResponse := request("/v1/files/"+file.ID.String()+"/download", fixture.UserOne, nil)
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 401 error if access token is missing", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			file := insertStoredFile(fixture.UserOne, "general/download-seven.txt", "hello world")
+
+			apiResponse := request("/v1/files/"+file.ID.String()+"/download", nil, nil)
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+	})
+}
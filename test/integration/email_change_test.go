@@ -0,0 +1,169 @@
+package integration
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/validation"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailChangeRoutes(t *testing.T) {
+	const newEmail = "new-address@gmail.com"
+
+	request := func(method, target, accessToken string, body interface{}) *http.Response {
+		var reader io.Reader
+		if body != nil {
+			bodyJSON, err := json.Marshal(body)
+			assert.Nil(t, err)
+			reader = strings.NewReader(string(bodyJSON))
+		}
+
+		request := httptest.NewRequest(method, target, reader)
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Accept", "application/json")
+		if accessToken != "" {
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+		}
+
+		apiResponse, err := test.App.Test(request)
+		assert.Nil(t, err)
+
+		return apiResponse
+	}
+
+	// requestChange asks for newEmail as the email of UserOne and returns the confirmation token
+	requestChange := func() string {
+		accessToken, err := fixture.AccessToken(fixture.UserOne)
+		assert.Nil(t, err)
+
+		apiResponse := request(http.MethodPatch, "/v1/users/"+fixture.UserOne.ID.String(), accessToken,
+			validation.UpdateUser{Email: newEmail})
+		assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+		tokenDoc, err := helper.GetTokenByType(test.DB, fixture.UserOne.ID.String(), config.TokenTypeChangeEmail)
+		assert.Nil(t, err)
+
+		return tokenDoc.Token
+	}
+
+	// confirmChange confirms the change and returns the revert token sent to the previous email
+	confirmChange := func() string {
+		apiResponse := request(http.MethodPost, "/v1/auth/confirm-email-change?token="+requestChange(), "", nil)
+		assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+		tokenDoc, err := helper.GetTokenByType(test.DB, fixture.UserOne.ID.String(), config.TokenTypeRevertEmail)
+		assert.Nil(t, err)
+
+		return tokenDoc.Token
+	}
+
+	t.Run("POST /v1/auth/confirm-email-change", func(t *testing.T) {
+		t.Run("should return 200, change the email and mark it verified", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			resetPasswordToken, err := fixture.ResetPasswordToken(fixture.UserOne)
+			assert.Nil(t, err)
+			assert.Nil(t, helper.SaveToken(test.DB, resetPasswordToken, fixture.UserOne.ID.String(),
+				config.TokenTypeResetPassword, fixture.ExpiresResetPasswordToken))
+
+			revertToken := confirmChange()
+			assert.NotEmpty(t, revertToken)
+
+			user, err := helper.GetUserByID(test.DB, fixture.UserOne.ID.String())
+			assert.Nil(t, err)
+			assert.Equal(t, newEmail, user.Email)
+			assert.Nil(t, user.PendingEmail)
+			assert.True(t, user.VerifiedEmail)
+
+			// Links sent to the previous email stop working
+			apiResponse := request(http.MethodPost, "/v1/auth/reset-password?token="+resetPasswordToken, "",
+				validation.UpdatePassOrVerify{Password: "password2"})
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 401 error if the token was already used", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			token := requestChange()
+
+			apiResponse := request(http.MethodPost, "/v1/auth/confirm-email-change?token="+token, "", nil)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			apiResponse = request(http.MethodPost, "/v1/auth/confirm-email-change?token="+token, "", nil)
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 401 error if a newer change was requested", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			token := requestChange()
+			requestChange()
+
+			apiResponse := request(http.MethodPost, "/v1/auth/confirm-email-change?token="+token, "", nil)
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 409 error if the email was taken in the meantime", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			token := requestChange()
+			helper.InsertUser(test.DB, &model.User{Name: "Other", Email: newEmail, Password: "password1", Role: "user"})
+
+			apiResponse := request(http.MethodPost, "/v1/auth/confirm-email-change?token="+token, "", nil)
+			assert.Equal(t, http.StatusConflict, apiResponse.StatusCode)
+
+			user, err := helper.GetUserByID(test.DB, fixture.UserOne.ID.String())
+			assert.Nil(t, err)
+			assert.Equal(t, fixture.UserOne.Email, user.Email)
+		})
+
+		t.Run("should return 400 error if token is missing", func(t *testing.T) {
+			apiResponse := request(http.MethodPost, "/v1/auth/confirm-email-change", "", nil)
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("POST /v1/auth/revert-email-change", func(t *testing.T) {
+		t.Run("should return 200, restore the email and log out every session", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			refreshToken, err := fixture.RefreshToken(fixture.UserOne)
+			assert.Nil(t, err)
+			assert.Nil(t, helper.SaveToken(test.DB, refreshToken, fixture.UserOne.ID.String(),
+				config.TokenTypeRefresh, fixture.ExpiresRefreshToken))
+			revertToken := confirmChange()
+
+			apiResponse := request(http.MethodPost, "/v1/auth/revert-email-change?token="+revertToken, "", nil)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			user, err := helper.GetUserByID(test.DB, fixture.UserOne.ID.String())
+			assert.Nil(t, err)
+			assert.Equal(t, fixture.UserOne.Email, user.Email)
+			assert.Nil(t, user.PendingEmail)
+			assert.True(t, user.VerifiedEmail)
+			assert.NotNil(t, user.TokensValidAfter)
+			assert.Equal(t, int64(0), helper.CountTokens(test.DB, user.ID.String(), config.TokenTypeRefresh))
+
+			apiResponse = request(http.MethodPost, "/v1/auth/revert-email-change?token="+revertToken, "", nil)
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 401 error if the token is a confirmation token", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			apiResponse := request(http.MethodPost, "/v1/auth/revert-email-change?token="+requestChange(), "", nil)
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+	})
+}
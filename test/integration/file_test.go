@@ -0,0 +1,168 @@
+package integration
+
+import (
+	"app/src/model"
+	"app/src/response"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileRoutes(t *testing.T) {
+	request := func(method, target, accessToken string) *http.Response {
+		request := httptest.NewRequest(method, target, nil)
+		request.Header.Set("Authorization", "Bearer "+accessToken)
+
+		apiResponse, err := test.App.Test(request)
+		assert.Nil(t, err)
+
+		return apiResponse
+	}
+
+	t.Run("GET /v1/files/:fileId", func(t *testing.T) {
+		t.Run("should return 200 and the file if the user uploaded it", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			file := helper.InsertFile(test.DB, &fixture.UserOne.ID, "general/one.txt")
+
+			userOneAccessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			apiResponse := request(http.MethodGet, "/v1/files/"+file.ID.String(), userOneAccessToken)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.Response)
+			assert.Nil(t, json.Unmarshal(bytes, responseBody))
+
+			data, ok := responseBody.Data.(map[string]interface{})
+			assert.True(t, ok)
+			assert.Equal(t, file.ID.String(), data["id"])
+			assert.Equal(t, file.FilePath, data["file_path"])
+		})
+
+		t.Run("should return 404 error if the file belongs to another user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+			file := helper.InsertFile(test.DB, &fixture.UserTwo.ID, "general/two.txt")
+
+			userOneAccessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			apiResponse := request(http.MethodGet, "/v1/files/"+file.ID.String(), userOneAccessToken)
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 200 if admin gets the file of another user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.Admin)
+			file := helper.InsertFile(test.DB, &fixture.UserOne.ID, "general/one.txt")
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			apiResponse := request(http.MethodGet, "/v1/files/"+file.ID.String(), adminAccessToken)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 404 error if file is not found", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			apiResponse := request(http.MethodGet, "/v1/files/"+uuid.NewString(), adminAccessToken)
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 400 error if fileId is not a valid id", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			userOneAccessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			apiResponse := request(http.MethodGet, "/v1/files/invalidId", userOneAccessToken)
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 401 error if access token is missing", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+
+			apiResponse := request(http.MethodGet, "/v1/files/"+uuid.NewString(), "")
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("DELETE /v1/files/:fileId", func(t *testing.T) {
+		t.Run("should return 200 and delete the file if the user uploaded it", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			file := helper.InsertFile(test.DB, &fixture.UserOne.ID, "general/one.txt")
+
+			userOneAccessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			apiResponse := request(http.MethodDelete, "/v1/files/"+file.ID.String(), userOneAccessToken)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			_, err = helper.GetFileByID(test.DB, file.ID.String())
+			assert.NotNil(t, err)
+		})
+
+		t.Run("should return 404 error and keep the file if it belongs to another user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+			file := helper.InsertFile(test.DB, &fixture.UserTwo.ID, "general/two.txt")
+
+			userOneAccessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			apiResponse := request(http.MethodDelete, "/v1/files/"+file.ID.String(), userOneAccessToken)
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+
+			dbFile, err := helper.GetFileByID(test.DB, file.ID.String())
+			assert.Nil(t, err)
+			assert.Equal(t, file.ID, dbFile.ID)
+		})
+
+		t.Run("should return 404 error for files without an uploader", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			file := helper.InsertFile(test.DB, nil, "general/orphan.txt")
+
+			userOneAccessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			apiResponse := request(http.MethodDelete, "/v1/files/"+file.ID.String(), userOneAccessToken)
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 200 if admin deletes the file of another user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.Admin)
+			file := helper.InsertFile(test.DB, &fixture.UserOne.ID, "general/one.txt")
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			apiResponse := request(http.MethodDelete, "/v1/files/"+file.ID.String(), adminAccessToken)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			var count int64
+			test.DB.Model(&model.File{}).Where("id = ?", file.ID).Count(&count)
+			assert.Equal(t, int64(0), count)
+		})
+	})
+}
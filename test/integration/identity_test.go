@@ -0,0 +1,154 @@
+package integration
+
+import (
+	"app/src/response"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdentityRoutes(t *testing.T) {
+	t.Run("GET /v1/auth/identities", func(t *testing.T) {
+		t.Run("should return 200 and the linked providers of the user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+			helper.InsertIdentity(test.DB, fixture.UserOne.ID.String(), "google", "google-1", fixture.UserOne.Email)
+			helper.InsertIdentity(test.DB, fixture.UserOne.ID.String(), "github", "github-1", fixture.UserOne.Email)
+			helper.InsertIdentity(test.DB, fixture.UserTwo.ID.String(), "google", "google-2", fixture.UserTwo.Email)
+
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodGet, "/v1/auth/identities", nil)
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.SuccessWithIdentities)
+
+			err = json.Unmarshal(bytes, responseBody)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Len(t, responseBody.Identities, 2)
+			assert.NotContains(t, string(bytes), "google-1")
+		})
+	})
+
+	t.Run("DELETE /v1/auth/identities/:identityId", func(t *testing.T) {
+		t.Run("should return 200 and unlink the provider", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			identity := helper.InsertIdentity(test.DB, fixture.UserOne.ID.String(), "google", "google-1", fixture.UserOne.Email)
+
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodDelete, "/v1/auth/identities/"+identity.ID.String(), nil)
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, int64(0), helper.CountIdentities(test.DB, fixture.UserOne.ID.String()))
+		})
+
+		t.Run("should return 400 when unlinking the last provider of an account without password", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			helper.RemovePassword(test.DB, fixture.UserOne.ID.String())
+			identity := helper.InsertIdentity(test.DB, fixture.UserOne.ID.String(), "google", "google-1", fixture.UserOne.Email)
+
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodDelete, "/v1/auth/identities/"+identity.ID.String(), nil)
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+			assert.Equal(t, int64(1), helper.CountIdentities(test.DB, fixture.UserOne.ID.String()))
+		})
+
+		t.Run("should return 404 if the identity belongs to another user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+			identity := helper.InsertIdentity(test.DB, fixture.UserTwo.ID.String(), "google", "google-2", fixture.UserTwo.Email)
+
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodDelete, "/v1/auth/identities/"+identity.ID.String(), nil)
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+			assert.Equal(t, int64(1), helper.CountIdentities(test.DB, fixture.UserTwo.ID.String()))
+		})
+	})
+
+	t.Run("POST /v1/auth/set-password", func(t *testing.T) {
+		t.Run("should return 200 and let an account without password log in with it", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			helper.RemovePassword(test.DB, fixture.UserOne.ID.String())
+
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "/v1/auth/set-password",
+				strings.NewReader(`{"password":"newPassword1"}`))
+			request.Header.Set("Content-Type", "application/json")
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			request = httptest.NewRequest(http.MethodPost, "/v1/auth/login",
+				strings.NewReader(`{"email":"`+fixture.UserOne.Email+`","password":"newPassword1"}`))
+			request.Header.Set("Content-Type", "application/json")
+
+			apiResponse, err = test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 409 if the account already has a password", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "/v1/auth/set-password",
+				strings.NewReader(`{"password":"newPassword1"}`))
+			request.Header.Set("Content-Type", "application/json")
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusConflict, apiResponse.StatusCode)
+		})
+	})
+}
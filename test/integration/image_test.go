@@ -0,0 +1,219 @@
+package integration
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/service"
+	"app/src/utils"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"image"
+	"image/jpeg"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageProcessing(t *testing.T) {
+	imageService := service.NewImageService(test.DB, service.NewLocalStorageService(test.DB))
+
+	// exifImage membuat JPEG 40x20 dengan orientasi EXIF 6 (diputar 90 derajat)
+	exifImage := func() []byte {
+		var buf bytes.Buffer
+		assert.Nil(t, jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 40, 20)), nil))
+
+		exif := []byte("Exif\x00\x00MM\x00\x2a\x00\x00\x00\x08\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01\x00\x06\x00\x00")
+		segment := binary.BigEndian.AppendUint16([]byte{0xff, 0xe1}, uint16(len(exif)+2))
+		return append(append(append([]byte{}, buf.Bytes()[:2]...), append(segment, exif...)...), buf.Bytes()[2:]...)
+	}
+
+	// insertImage menyimpan gambar dari exifImage di local storage sebagai file yang belum diproses
+	insertImage := func(user *model.User, filePath string) *model.File {
+		fullPath := filepath.Join(config.StorageLocalPath, filePath)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		assert.Nil(t, os.WriteFile(fullPath, exifImage(), 0644))
+		t.Cleanup(func() { os.RemoveAll(filepath.Join(filepath.Dir(fullPath), "variants")) })
+		t.Cleanup(func() { os.Remove(fullPath) })
+
+		file := helper.InsertFile(test.DB, &user.ID, filePath)
+		assert.Nil(t, test.DB.Model(file).Updates(map[string]interface{}{
+			"content_type": "image/jpeg", "processing_status": model.FileProcessingPending,
+		}).Error)
+
+		return file
+	}
+
+	t.Run("ProcessFile", func(t *testing.T) {
+		t.Run("should create the variants upright", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			file := insertImage(fixture.UserOne, "general/photo.jpg")
+
+			assert.Nil(t, imageService.ProcessFile(context.Background(), file.ID))
+
+			processed := new(model.File)
+			assert.Nil(t, test.DB.Preload("Variants").First(processed, "id = ?", file.ID).Error)
+			assert.Equal(t, model.FileProcessingReady, processed.ProcessingStatus)
+			assert.Len(t, processed.Variants, len(config.ImageVariants))
+
+			for _, variant := range processed.Variants {
+				_, err := os.Stat(filepath.Join(config.StorageLocalPath, variant.FilePath))
+				assert.Nil(t, err, variant.Name)
+				if variant.Name == "medium" {
+					assert.Equal(t, 20, variant.Width)
+					assert.Equal(t, 40, variant.Height)
+				}
+			}
+		})
+
+		t.Run("should skip files that are not pending", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			file := insertImage(fixture.UserOne, "general/done.jpg")
+			assert.Nil(t, test.DB.Model(file).Update("processing_status", model.FileProcessingReady).Error)
+
+			assert.Nil(t, imageService.ProcessFile(context.Background(), file.ID))
+
+			var count int64
+			assert.Nil(t, test.DB.Model(&model.FileVariant{}).Where("file_id = ?", file.ID).Count(&count).Error)
+			assert.Equal(t, int64(0), count)
+		})
+	})
+
+	t.Run("EnqueuePending", func(t *testing.T) {
+		t.Run("should queue files left processing by a stopped instance again", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			stale := insertImage(fixture.UserOne, "general/stale.jpg")
+			running := insertImage(fixture.UserOne, "general/running.jpg")
+			assert.Nil(t, test.DB.Model(&model.File{}).Where("id IN ?", []interface{}{stale.ID, running.ID}).
+				Update("processing_status", model.FileProcessingProcessing).Error)
+			assert.Nil(t, test.DB.Model(stale).UpdateColumn("updated_at", time.Now().Add(-time.Hour)).Error)
+
+			queueService := service.NewImageService(test.DB, service.NewLocalStorageService(test.DB))
+			assert.Nil(t, queueService.EnqueuePending(context.Background()))
+
+			assert.Equal(t, stale.ID, <-queueService.Queue())
+			assert.Empty(t, queueService.Queue())
+
+			file, err := helper.GetFileByID(test.DB, running.ID.String())
+			assert.Nil(t, err)
+			assert.Equal(t, model.FileProcessingProcessing, file.ProcessingStatus)
+		})
+	})
+
+	t.Run("POST /v1/files/upload", func(t *testing.T) {
+		t.Run("should store the original upright and without metadata before it is served", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			var body bytes.Buffer
+			form := multipart.NewWriter(&body)
+			part, err := form.CreateFormFile("file", "photo.jpg")
+			assert.Nil(t, err)
+			_, err = part.Write(exifImage())
+			assert.Nil(t, err)
+			assert.Nil(t, form.Close())
+
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "/v1/files/upload", &body)
+			request.Header.Set("Content-Type", form.FormDataContentType())
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			file := new(model.File)
+			assert.Nil(t, test.DB.First(file, "uploaded_by = ?", fixture.UserOne.ID).Error)
+			assert.Equal(t, model.FileProcessingPending, file.ProcessingStatus)
+
+			fullPath := filepath.Join(config.StorageLocalPath, file.FilePath)
+			t.Cleanup(func() { os.Remove(fullPath) })
+
+			data, err := os.ReadFile(fullPath)
+			assert.Nil(t, err)
+			assert.Equal(t, 1, utils.ImageOrientation(data))
+			assert.Equal(t, int64(len(data)), file.FileSize)
+
+			original, err := jpeg.DecodeConfig(bytes.NewReader(data))
+			assert.Nil(t, err)
+			assert.Equal(t, 20, original.Width)
+			assert.Equal(t, 40, original.Height)
+		})
+	})
+
+	t.Run("GET /v1/files/my-files", func(t *testing.T) {
+		t.Run("should return the variants with their urls", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			file := insertImage(fixture.UserOne, "general/listed.jpg")
+			assert.Nil(t, imageService.ProcessFile(context.Background(), file.ID))
+
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodGet, "/v1/files/my-files", nil)
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := struct {
+				Data []model.File `json:"data"`
+			}{}
+			assert.Nil(t, json.Unmarshal(bytes, &responseBody))
+			assert.Len(t, responseBody.Data, 1)
+			assert.Len(t, responseBody.Data[0].Variants, len(config.ImageVariants))
+
+			for _, variant := range responseBody.Data[0].Variants {
+				assert.NotEmpty(t, variant.FileURL, variant.Name)
+			}
+		})
+	})
+
+	t.Run("DELETE /v1/files/:fileId", func(t *testing.T) {
+		t.Run("should delete the stored variants", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			file := insertImage(fixture.UserOne, "general/deleted.jpg")
+			assert.Nil(t, imageService.ProcessFile(context.Background(), file.ID))
+
+			var variants []model.FileVariant
+			assert.Nil(t, test.DB.Where("file_id = ?", file.ID).Find(&variants).Error)
+
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodDelete, "/v1/files/"+file.ID.String(), nil)
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			for _, variant := range variants {
+				_, err := os.Stat(filepath.Join(config.StorageLocalPath, variant.FilePath))
+				assert.True(t, os.IsNotExist(err), variant.Name)
+			}
+		})
+	})
+}
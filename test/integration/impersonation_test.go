@@ -0,0 +1,344 @@
+package integration
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/response"
+	"app/src/utils"
+	"app/src/validation"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImpersonation(t *testing.T) {
+	request := func(method, target, accessToken string, body interface{}) *http.Response {
+		var reader io.Reader
+		if body != nil {
+			bodyJSON, err := json.Marshal(body)
+			assert.Nil(t, err)
+			reader = strings.NewReader(string(bodyJSON))
+		}
+
+		request := httptest.NewRequest(method, target, reader)
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Accept", "application/json")
+		request.Header.Set("Authorization", "Bearer "+accessToken)
+
+		apiResponse, err := test.App.Test(request)
+		assert.Nil(t, err)
+
+		return apiResponse
+	}
+
+	// requestIn sends the request with the organization selected by the header
+	requestIn := func(method, target, accessToken, organizationID string, body interface{}) *http.Response {
+		var reader io.Reader
+		if body != nil {
+			bodyJSON, err := json.Marshal(body)
+			assert.Nil(t, err)
+			reader = strings.NewReader(string(bodyJSON))
+		}
+
+		request := httptest.NewRequest(method, target, reader)
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Accept", "application/json")
+		request.Header.Set("Authorization", "Bearer "+accessToken)
+		request.Header.Set(config.HeaderOrganizationID, organizationID)
+
+		apiResponse, err := test.App.Test(request)
+		assert.Nil(t, err)
+
+		return apiResponse
+	}
+
+	accessToken := func(user *model.User) string {
+		token, err := fixture.AccessToken(user)
+		assert.Nil(t, err)
+
+		return token
+	}
+
+	// impersonate lets the admin impersonate UserOne and returns the impersonation with its token
+	impersonate := func() *response.SuccessWithImpersonation {
+		apiResponse := request(http.MethodPost, "/v1/users/"+fixture.UserOne.ID.String()+"/impersonate",
+			accessToken(fixture.Admin), validation.StartImpersonation{Reason: "Reproducing ticket #1234"})
+		assert.Equal(t, http.StatusCreated, apiResponse.StatusCode)
+
+		bytes, err := io.ReadAll(apiResponse.Body)
+		assert.Nil(t, err)
+
+		responseBody := new(response.SuccessWithImpersonation)
+		assert.Nil(t, json.Unmarshal(bytes, responseBody))
+
+		return responseBody
+	}
+
+	t.Run("POST /v1/users/:userId/impersonate", func(t *testing.T) {
+		t.Run("should return 201 and a token of the user with the admin as actor", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.Admin)
+
+			responseBody := impersonate()
+
+			claims, err := utils.ParseToken(responseBody.Access.Token, config.JWTKeySet, config.TokenTypeAccess)
+			assert.Nil(t, err)
+			assert.Equal(t, fixture.UserOne.ID.String(), claims["sub"])
+			assert.Equal(t, map[string]interface{}{"sub": fixture.Admin.ID.String()}, claims["act"])
+			assert.Equal(t, responseBody.Impersonation.ID.String(), claims["jti"])
+
+			impersonation, err := helper.GetImpersonationByID(test.DB, responseBody.Impersonation.ID.String())
+			assert.Nil(t, err)
+			assert.Equal(t, fixture.Admin.ID, *impersonation.ActorID)
+			assert.Equal(t, fixture.UserOne.ID, *impersonation.UserID)
+			assert.Equal(t, "Reproducing ticket #1234", impersonation.Reason)
+			assert.Nil(t, impersonation.EndedAt)
+		})
+
+		t.Run("should return 400 error if the reason is missing", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.Admin)
+
+			apiResponse := request(http.MethodPost, "/v1/users/"+fixture.UserOne.ID.String()+"/impersonate",
+				accessToken(fixture.Admin), validation.StartImpersonation{})
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 403 error if the user can not impersonate", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+
+			apiResponse := request(http.MethodPost, "/v1/users/"+fixture.UserTwo.ID.String()+"/impersonate",
+				accessToken(fixture.UserOne), validation.StartImpersonation{Reason: "Curious"})
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 403 error if the user can impersonate as well", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			otherAdmin := &model.User{Name: "Other Admin", Email: "admin2@gmail.com", Password: "password1", Role: "admin"}
+			helper.InsertUser(test.DB, fixture.Admin, otherAdmin)
+
+			apiResponse := request(http.MethodPost, "/v1/users/"+otherAdmin.ID.String()+"/impersonate",
+				accessToken(fixture.Admin), validation.StartImpersonation{Reason: "Reproducing ticket #1234"})
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 403 error if the user has rights the actor does not have", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertRole(test.DB, "support", "impersonateUsers", "getUsers")
+			helper.InsertRole(test.DB, "auditor", "getUsers", "manageFiles")
+			support := &model.User{Name: "Support", Email: "support@gmail.com", Password: "password1", Role: "support"}
+			auditor := &model.User{Name: "Auditor", Email: "auditor@gmail.com", Password: "password1", Role: "auditor"}
+			helper.InsertUser(test.DB, fixture.UserOne, support, auditor)
+
+			apiResponse := request(http.MethodPost, "/v1/users/"+auditor.ID.String()+"/impersonate",
+				accessToken(support), validation.StartImpersonation{Reason: "Reproducing ticket #1234"})
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+
+			apiResponse = request(http.MethodPost, "/v1/users/"+fixture.UserOne.ID.String()+"/impersonate",
+				accessToken(support), validation.StartImpersonation{Reason: "Reproducing ticket #1234"})
+			assert.Equal(t, http.StatusCreated, apiResponse.StatusCode)
+		})
+
+		t.Run("should bind the impersonation to the active organization of the admin", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.Admin)
+			organization := helper.InsertOrganization(test.DB, "Acme")
+			helper.InsertMembership(test.DB, organization.ID, fixture.Admin.ID, config.OrganizationRoleOwner)
+			helper.InsertMembership(test.DB, organization.ID, fixture.UserOne.ID, config.OrganizationRoleMember)
+
+			apiResponse := requestIn(http.MethodPost, "/v1/users/"+fixture.UserOne.ID.String()+"/impersonate",
+				accessToken(fixture.Admin), organization.ID.String(), validation.StartImpersonation{Reason: "Reproducing ticket #1234"})
+			assert.Equal(t, http.StatusCreated, apiResponse.StatusCode)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.SuccessWithImpersonation)
+			assert.Nil(t, json.Unmarshal(bytes, responseBody))
+
+			claims, err := utils.ParseToken(responseBody.Access.Token, config.JWTKeySet, config.TokenTypeAccess)
+			assert.Nil(t, err)
+			assert.Equal(t, organization.ID.String(), claims["org"])
+
+			impersonation, err := helper.GetImpersonationByID(test.DB, responseBody.Impersonation.ID.String())
+			assert.Nil(t, err)
+			assert.Equal(t, organization.ID, *impersonation.OrganizationID)
+		})
+
+		t.Run("should return 400 error if the user is not a member of the active organization", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.Admin)
+			organization := helper.InsertOrganization(test.DB, "Acme")
+			helper.InsertMembership(test.DB, organization.ID, fixture.Admin.ID, config.OrganizationRoleOwner)
+
+			apiResponse := requestIn(http.MethodPost, "/v1/users/"+fixture.UserOne.ID.String()+"/impersonate",
+				accessToken(fixture.Admin), organization.ID.String(), validation.StartImpersonation{Reason: "Reproducing ticket #1234"})
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 403 error if the user has organization rights the actor does not have", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.Admin)
+			organization := helper.InsertOrganization(test.DB, "Acme")
+			helper.InsertMembership(test.DB, organization.ID, fixture.Admin.ID, config.OrganizationRoleMember)
+			helper.InsertMembership(test.DB, organization.ID, fixture.UserOne.ID, config.OrganizationRoleOwner)
+
+			apiResponse := requestIn(http.MethodPost, "/v1/users/"+fixture.UserOne.ID.String()+"/impersonate",
+				accessToken(fixture.Admin), organization.ID.String(), validation.StartImpersonation{Reason: "Reproducing ticket #1234"})
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 404 error if the user is not found", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			apiResponse := request(http.MethodPost, "/v1/users/"+fixture.UserOne.ID.String()+"/impersonate",
+				accessToken(fixture.Admin), validation.StartImpersonation{Reason: "Reproducing ticket #1234"})
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("impersonation token", func(t *testing.T) {
+		t.Run("should act as the user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.Admin)
+			token := impersonate().Access.Token
+
+			apiResponse := request(http.MethodPatch, "/v1/users/"+fixture.UserOne.ID.String(), token,
+				validation.UpdateUser{Name: "Renamed by support"})
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			apiResponse = request(http.MethodGet, "/v1/users/"+fixture.Admin.ID.String(), token, nil)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+
+		t.Run("should not reach organizations the impersonation is not bound to", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.Admin)
+			organization := helper.InsertOrganization(test.DB, "Acme")
+			helper.InsertMembership(test.DB, organization.ID, fixture.UserOne.ID, config.OrganizationRoleOwner)
+			token := impersonate().Access.Token
+
+			// The header is ignored, so the rights of the owner membership are not granted
+			apiResponse := requestIn(http.MethodGet, "/v1/users/paginated", token, organization.ID.String(), nil)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+
+			apiResponse = request(http.MethodPatch, "/v1/organizations/"+organization.ID.String(), token,
+				validation.UpdateOrganization{Name: "Renamed by support"})
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 403 error when changing the password", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.Admin)
+			token := impersonate().Access.Token
+
+			apiResponse := request(http.MethodPatch, "/v1/users/"+fixture.UserOne.ID.String(), token,
+				validation.UpdateUser{Password: "password2"})
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 403 error on the 2FA routes", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.Admin)
+			token := impersonate().Access.Token
+
+			apiResponse := request(http.MethodPost, "/v1/auth/2fa/enroll", token, nil)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+
+			apiResponse = request(http.MethodPost, "/v1/auth/2fa/disable", token, validation.DisableTwoFactor{Password: "password1"})
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 403 error on the sessions of the user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.Admin)
+			token := impersonate().Access.Token
+			sessionID, err := helper.InsertSession(test.DB, fixture.UserOne.ID.String(), "Firefox", fixture.ExpiresRefreshToken)
+			assert.Nil(t, err)
+
+			apiResponse := request(http.MethodGet, "/v1/users/"+fixture.UserOne.ID.String()+"/sessions", token, nil)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+
+			apiResponse = request(http.MethodDelete, "/v1/users/"+fixture.UserOne.ID.String()+"/sessions", token, nil)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+
+			apiResponse = request(http.MethodDelete,
+				"/v1/users/"+fixture.UserOne.ID.String()+"/sessions/"+sessionID.String(), token, nil)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 401 error once the admin can no longer impersonate", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.Admin)
+			token := impersonate().Access.Token
+
+			assert.Nil(t, test.DB.Model(&model.User{}).Where("id = ?", fixture.Admin.ID).Update("role", "user").Error)
+
+			apiResponse := request(http.MethodGet, "/v1/users/"+fixture.UserOne.ID.String(), token, nil)
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("POST /v1/auth/impersonation/stop", func(t *testing.T) {
+		t.Run("should return 200, record the end and revoke the token", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.Admin)
+			responseBody := impersonate()
+
+			apiResponse := request(http.MethodPost, "/v1/auth/impersonation/stop", responseBody.Access.Token, nil)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			impersonation, err := helper.GetImpersonationByID(test.DB, responseBody.Impersonation.ID.String())
+			assert.Nil(t, err)
+			assert.NotNil(t, impersonation.EndedAt)
+
+			apiResponse = request(http.MethodGet, "/v1/users/"+fixture.UserOne.ID.String(), responseBody.Access.Token, nil)
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 400 error if the request is not impersonated", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			apiResponse := request(http.MethodPost, "/v1/auth/impersonation/stop", accessToken(fixture.UserOne), nil)
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("GET /v1/impersonations", func(t *testing.T) {
+		t.Run("should return 200 and the recorded impersonations", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.Admin)
+			responseBody := impersonate()
+
+			apiResponse := request(http.MethodGet, "/v1/impersonations", accessToken(fixture.Admin), nil)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			listBody := new(response.SuccessWithPaginate[model.Impersonation])
+			assert.Nil(t, json.Unmarshal(bytes, listBody))
+			assert.Equal(t, int64(1), listBody.TotalResults)
+			assert.Equal(t, responseBody.Impersonation.ID, listBody.Results[0].ID)
+		})
+
+		t.Run("should return 403 error if the user can not impersonate", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			apiResponse := request(http.MethodGet, "/v1/impersonations", accessToken(fixture.UserOne), nil)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+	})
+}
@@ -0,0 +1,37 @@
+package integration
+
+import (
+	"app/src/utils"
+	"app/test"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWellKnownRoutes(t *testing.T) {
+	t.Run("GET /.well-known/jwks.json", func(t *testing.T) {
+		t.Run("should return 200 and the public keys of the keyset", func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(utils.JWKS)
+			err = json.Unmarshal(bytes, responseBody)
+			assert.Nil(t, err)
+			assert.NotNil(t, responseBody.Keys)
+
+			for _, key := range responseBody.Keys {
+				assert.NotEmpty(t, key.Kid)
+			}
+		})
+	})
+}
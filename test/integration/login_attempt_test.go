@@ -0,0 +1,177 @@
+package integration
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/validation"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginThrottling(t *testing.T) {
+	login := func(email, password string) *http.Response {
+		bodyJSON, err := json.Marshal(validation.Login{Email: email, Password: password})
+		assert.Nil(t, err)
+
+		request := httptest.NewRequest(http.MethodPost, "/v1/auth/login", strings.NewReader(string(bodyJSON)))
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Accept", "application/json")
+
+		apiResponse, err := test.App.Test(request)
+		assert.Nil(t, err)
+
+		return apiResponse
+	}
+
+	// insertUser keeps the plain password, InsertUser replaces it with the hash
+	insertUser := func() *model.User {
+		user := &model.User{Name: "Throttled", Email: "throttled@gmail.com", Password: "password1", Role: "user"}
+		helper.InsertUser(test.DB, user)
+
+		return user
+	}
+
+	t.Run("should return 429 with a Retry-After header once the free attempts are used", func(t *testing.T) {
+		helper.ClearAll(test.DB)
+		user := insertUser()
+
+		for i := 0; i < 3; i++ {
+			apiResponse := login(user.Email, "wrongPassword1")
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		}
+
+		apiResponse := login(user.Email, "password1")
+		assert.Equal(t, http.StatusTooManyRequests, apiResponse.StatusCode)
+
+		retryAfter, err := strconv.Atoi(apiResponse.Header.Get("Retry-After"))
+		assert.Nil(t, err)
+		assert.Greater(t, retryAfter, 0)
+	})
+
+	t.Run("should count attempts of an email regardless of its case", func(t *testing.T) {
+		helper.ClearAll(test.DB)
+		user := insertUser()
+
+		login("Throttled@gmail.com", "wrongPassword1")
+		login("THROTTLED@gmail.com", "wrongPassword1")
+
+		assert.Equal(t, int64(2), helper.CountLoginAttempts(test.DB, user.Email))
+	})
+
+	t.Run("should throttle emails that do not belong to any user", func(t *testing.T) {
+		helper.ClearAll(test.DB)
+
+		for i := 0; i < 3; i++ {
+			apiResponse := login("nonexistent@gmail.com", "wrongPassword1")
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		}
+
+		apiResponse := login("nonexistent@gmail.com", "wrongPassword1")
+		assert.Equal(t, http.StatusTooManyRequests, apiResponse.StatusCode)
+	})
+
+	t.Run("should clear the failed attempts after a successful login", func(t *testing.T) {
+		helper.ClearAll(test.DB)
+		user := insertUser()
+
+		login(user.Email, "wrongPassword1")
+		login(user.Email, "wrongPassword1")
+
+		apiResponse := login(user.Email, "password1")
+		assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+		assert.Equal(t, int64(0), helper.CountLoginAttempts(test.DB, user.Email))
+	})
+
+	t.Run("should return 423 and lock the account after too many failures", func(t *testing.T) {
+		maxAttempts := config.LoginMaxAttempts
+		config.LoginMaxAttempts = 3
+		defer func() { config.LoginMaxAttempts = maxAttempts }()
+
+		helper.ClearAll(test.DB)
+		user := insertUser()
+
+		for i := 0; i < 2; i++ {
+			apiResponse := login(user.Email, "wrongPassword1")
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		}
+
+		apiResponse := login(user.Email, "wrongPassword1")
+		assert.Equal(t, http.StatusLocked, apiResponse.StatusCode)
+
+		dbUser, err := helper.GetUserByID(test.DB, user.ID.String())
+		assert.Nil(t, err)
+		assert.NotNil(t, dbUser.LockedUntil)
+		assert.True(t, dbUser.LockedUntil.After(time.Now()))
+	})
+
+	t.Run("should return 423 for a locked account even if the password is right", func(t *testing.T) {
+		helper.ClearAll(test.DB)
+		user := insertUser()
+		helper.LockUser(test.DB, user.ID.String(), time.Now().Add(time.Hour))
+
+		apiResponse := login(user.Email, "password1")
+		assert.Equal(t, http.StatusLocked, apiResponse.StatusCode)
+	})
+
+	t.Run("should return 200 once the lock has expired", func(t *testing.T) {
+		helper.ClearAll(test.DB)
+		user := insertUser()
+		helper.LockUser(test.DB, user.ID.String(), time.Now().Add(-time.Minute))
+
+		apiResponse := login(user.Email, "password1")
+		assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+	})
+
+	t.Run("should block an ip failing for many emails", func(t *testing.T) {
+		ipMaxAttempts := config.LoginIPMaxAttempts
+		config.LoginIPMaxAttempts = 3
+		defer func() { config.LoginIPMaxAttempts = ipMaxAttempts }()
+
+		helper.ClearAll(test.DB)
+		user := insertUser()
+
+		for i := 0; i < 3; i++ {
+			apiResponse := login("sprayed"+strconv.Itoa(i)+"@gmail.com", "wrongPassword1")
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		}
+
+		apiResponse := login(user.Email, "password1")
+		assert.Equal(t, http.StatusTooManyRequests, apiResponse.StatusCode)
+	})
+
+	t.Run("should unlock the account when the password is reset", func(t *testing.T) {
+		helper.ClearAll(test.DB)
+		helper.InsertUser(test.DB, fixture.UserOne)
+		helper.LockUser(test.DB, fixture.UserOne.ID.String(), time.Now().Add(time.Hour))
+
+		resetPasswordToken, err := fixture.ResetPasswordToken(fixture.UserOne)
+		assert.Nil(t, err)
+
+		err = helper.SaveToken(test.DB, resetPasswordToken, fixture.UserOne.ID.String(), config.TokenTypeResetPassword, fixture.ExpiresResetPasswordToken)
+		assert.Nil(t, err)
+
+		bodyJSON, err := json.Marshal(validation.UpdatePassOrVerify{Password: "password2"})
+		assert.Nil(t, err)
+
+		request := httptest.NewRequest(http.MethodPost, "/v1/auth/reset-password?token="+resetPasswordToken, strings.NewReader(string(bodyJSON)))
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Accept", "application/json")
+
+		apiResponse, err := test.App.Test(request)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+		apiResponse = login(fixture.UserOne.Email, "password2")
+		assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+	})
+}
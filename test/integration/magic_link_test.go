@@ -0,0 +1,218 @@
+package integration
+
+import (
+	"app/src/config"
+	"app/src/response"
+	"app/src/validation"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMagicLinkRoutes(t *testing.T) {
+	requestLink := func(email string) *http.Response {
+		bodyJSON, err := json.Marshal(validation.MagicLink{Email: email})
+		assert.Nil(t, err)
+
+		request := httptest.NewRequest(http.MethodPost, "/v1/auth/magic-link", strings.NewReader(string(bodyJSON)))
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Accept", "application/json")
+
+		apiResponse, err := test.App.Test(request)
+		assert.Nil(t, err)
+
+		return apiResponse
+	}
+
+	verifyLink := func(token string) *http.Response {
+		request := httptest.NewRequest(http.MethodPost, "/v1/auth/magic-link/verify?token="+token, nil)
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Accept", "application/json")
+
+		apiResponse, err := test.App.Test(request)
+		assert.Nil(t, err)
+
+		return apiResponse
+	}
+
+	t.Run("POST /v1/auth/magic-link", func(t *testing.T) {
+		t.Run("should return 200 and send a magic link email to the user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			apiResponse := requestLink(fixture.UserOne.Email)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			dbMagicLinkTokenDoc, _ := helper.GetTokenByType(test.DB, fixture.UserOne.ID.String(), config.TokenTypeMagicLink)
+			assert.NotNil(t, dbMagicLinkTokenDoc)
+		})
+
+		t.Run("should return 429 if too many links are requested for the same email", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+
+			for i := 0; i < config.MagicLinkMaxRequests; i++ {
+				apiResponse := requestLink(fixture.UserOne.Email)
+				assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			}
+
+			apiResponse := requestLink(fixture.UserOne.Email)
+			assert.Equal(t, http.StatusTooManyRequests, apiResponse.StatusCode)
+			assert.Equal(t, int64(config.MagicLinkMaxRequests),
+				helper.CountTokens(test.DB, fixture.UserOne.ID.String(), config.TokenTypeMagicLink))
+
+			// The limit is per email, other users can still request links
+			apiResponse = requestLink(fixture.UserTwo.Email)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 400 if email is missing", func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/v1/auth/magic-link", nil)
+			request.Header.Set("Content-Type", "application/json")
+			request.Header.Set("Accept", "application/json")
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 404 if email does not belong to any user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+
+			apiResponse := requestLink(fixture.UserOne.Email)
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("POST /v1/auth/magic-link/verify", func(t *testing.T) {
+		t.Run("should return 200 with auth tokens and verify the email", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			magicLinkToken, err := fixture.MagicLinkToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			err = helper.SaveToken(test.DB, magicLinkToken, fixture.UserOne.ID.String(), config.TokenTypeMagicLink, fixture.ExpiresMagicLinkToken)
+			assert.Nil(t, err)
+
+			apiResponse := verifyLink(magicLinkToken)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.SuccessWithTokens)
+
+			err = json.Unmarshal(bytes, responseBody)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, fixture.UserOne.Email, responseBody.User.Email)
+			assert.True(t, responseBody.User.VerifiedEmail)
+			assert.NotEmpty(t, responseBody.Tokens.Access.Token)
+			assert.NotEmpty(t, responseBody.Tokens.Refresh.Token)
+
+			user, err := helper.GetUserByID(test.DB, fixture.UserOne.ID.String())
+			assert.Nil(t, err)
+			assert.True(t, user.VerifiedEmail)
+		})
+
+		t.Run("should return 423 if the account is locked", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			helper.LockUser(test.DB, fixture.UserOne.ID.String(), time.Now().Add(time.Hour))
+
+			magicLinkToken, err := fixture.MagicLinkToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			err = helper.SaveToken(test.DB, magicLinkToken, fixture.UserOne.ID.String(), config.TokenTypeMagicLink, fixture.ExpiresMagicLinkToken)
+			assert.Nil(t, err)
+
+			apiResponse := verifyLink(magicLinkToken)
+			assert.Equal(t, http.StatusLocked, apiResponse.StatusCode)
+			assert.Equal(t, int64(0), helper.CountTokens(test.DB, fixture.UserOne.ID.String(), config.TokenTypeRefresh))
+		})
+
+		t.Run("should return 401 if the link is used twice and invalidate the other links", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			magicLinkToken, err := fixture.MagicLinkToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			err = helper.SaveToken(test.DB, magicLinkToken, fixture.UserOne.ID.String(), config.TokenTypeMagicLink, fixture.ExpiresMagicLinkToken)
+			assert.Nil(t, err)
+
+			olderToken, err := helper.GenerateToken(fixture.UserOne.ID.String(), fixture.ExpiresMagicLinkToken.Add(-time.Minute), config.TokenTypeMagicLink)
+			assert.Nil(t, err)
+
+			err = helper.InsertToken(test.DB, olderToken, fixture.UserOne.ID.String(), config.TokenTypeMagicLink, fixture.ExpiresMagicLinkToken)
+			assert.Nil(t, err)
+
+			apiResponse := verifyLink(magicLinkToken)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			apiResponse = verifyLink(magicLinkToken)
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+
+			apiResponse = verifyLink(olderToken)
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+
+			assert.Equal(t, int64(0), helper.CountTokens(test.DB, fixture.UserOne.ID.String(), config.TokenTypeMagicLink))
+		})
+
+		t.Run("should return an mfa token if two-factor authentication is enabled", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			helper.EnableTwoFactor(test.DB, fixture.UserOne.ID.String(), "JBSWY3DPEHPK3PXP")
+
+			magicLinkToken, err := fixture.MagicLinkToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			err = helper.SaveToken(test.DB, magicLinkToken, fixture.UserOne.ID.String(), config.TokenTypeMagicLink, fixture.ExpiresMagicLinkToken)
+			assert.Nil(t, err)
+
+			apiResponse := verifyLink(magicLinkToken)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.MFARequired)
+
+			err = json.Unmarshal(bytes, responseBody)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.NotEmpty(t, responseBody.MFAToken.Token)
+			assert.NotContains(t, string(bytes), "refresh")
+		})
+
+		t.Run("should return 401 if the token is of another type", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			resetPasswordToken, err := fixture.ResetPasswordToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			err = helper.SaveToken(test.DB, resetPasswordToken, fixture.UserOne.ID.String(), config.TokenTypeResetPassword, fixture.ExpiresResetPasswordToken)
+			assert.Nil(t, err)
+
+			apiResponse := verifyLink(resetPasswordToken)
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 400 if magic link token is missing", func(t *testing.T) {
+			apiResponse := verifyLink("")
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+	})
+}
@@ -0,0 +1,475 @@
+package integration
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/response"
+	"app/src/utils"
+	"app/src/validation"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+func TestOAuthServer(t *testing.T) {
+	const (
+		secret      = config.OAuthClientSecretPrefix + "k3xqj7w2m5r9t4y8u1p6a0s3d7f2g5h8"
+		redirectURI = "https://partner.example.com/callback"
+	)
+
+	verifier := oauth2.GenerateVerifier()
+
+	request := func(method, target, accessToken string, body interface{}) *http.Response {
+		var reader io.Reader
+		if body != nil {
+			bodyJSON, err := json.Marshal(body)
+			assert.Nil(t, err)
+			reader = strings.NewReader(string(bodyJSON))
+		}
+
+		request := httptest.NewRequest(method, target, reader)
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Accept", "application/json")
+		if accessToken != "" {
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+		}
+
+		apiResponse, err := test.App.Test(request)
+		assert.Nil(t, err)
+
+		return apiResponse
+	}
+
+	// form posts to the token, introspection and revocation endpoints, authenticated with HTTP Basic
+	form := func(target string, client *model.OAuthClient, clientSecret string, values url.Values) *http.Response {
+		request := httptest.NewRequest(http.MethodPost, target, strings.NewReader(values.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		request.Header.Set("Accept", "application/json")
+		request.SetBasicAuth(client.ID.String(), clientSecret)
+
+		apiResponse, err := test.App.Test(request)
+		assert.Nil(t, err)
+
+		return apiResponse
+	}
+
+	accessToken := func(user *model.User) string {
+		token, err := fixture.AccessToken(user)
+		assert.Nil(t, err)
+
+		return token
+	}
+
+	decode := func(apiResponse *http.Response, body interface{}) {
+		bytes, err := io.ReadAll(apiResponse.Body)
+		assert.Nil(t, err)
+		assert.Nil(t, json.Unmarshal(bytes, body))
+	}
+
+	authorization := func(client *model.OAuthClient) validation.OAuthAuthorize {
+		return validation.OAuthAuthorize{
+			ResponseType:        "code",
+			ClientID:            client.ID.String(),
+			RedirectURI:         redirectURI,
+			Scope:               "getUsers",
+			State:               "af0ifjsldkj",
+			CodeChallenge:       oauth2.S256ChallengeFromVerifier(verifier),
+			CodeChallengeMethod: "S256",
+			Approve:             true,
+		}
+	}
+
+	// authorize lets the admin consent to the client and returns the authorization code
+	authorize := func(client *model.OAuthClient) string {
+		apiResponse := request(http.MethodPost, "/v1/oauth/authorize", accessToken(fixture.Admin), authorization(client))
+		assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+		responseBody := new(response.OAuthRedirect)
+		decode(apiResponse, responseBody)
+
+		redirectURL, err := url.Parse(responseBody.URL)
+		assert.Nil(t, err)
+		assert.Equal(t, "af0ifjsldkj", redirectURL.Query().Get("state"))
+
+		return redirectURL.Query().Get("code")
+	}
+
+	exchange := func(client *model.OAuthClient, code string) *http.Response {
+		return form("/v1/oauth/token", client, secret, url.Values{
+			"grant_type":    {config.GrantTypeAuthorizationCode},
+			"code":          {code},
+			"redirect_uri":  {redirectURI},
+			"code_verifier": {verifier},
+		})
+	}
+
+	// issueToken runs the authorization code flow for the admin and returns the access token of the client
+	issueToken := func(client *model.OAuthClient) string {
+		apiResponse := exchange(client, authorize(client))
+		assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+		responseBody := new(response.OAuthToken)
+		decode(apiResponse, responseBody)
+
+		return responseBody.AccessToken
+	}
+
+	insertClient := func() *model.OAuthClient {
+		return helper.InsertOAuthClient(test.DB, fixture.Admin.ID, secret, redirectURI, "getUsers,manageUsers",
+			config.GrantTypeAuthorizationCode+","+config.GrantTypeClientCredentials)
+	}
+
+	t.Run("POST /v1/oauth/clients", func(t *testing.T) {
+		t.Run("should return 201 and the secret once, only its hash is stored", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			apiResponse := request(http.MethodPost, "/v1/oauth/clients", accessToken(fixture.Admin), validation.CreateOAuthClient{
+				Name:         "Partner CRM",
+				RedirectURIs: []string{redirectURI},
+				Scopes:       []string{"getUsers"},
+				GrantTypes:   []string{config.GrantTypeAuthorizationCode},
+				Confidential: true,
+			})
+			assert.Equal(t, http.StatusCreated, apiResponse.StatusCode)
+
+			responseBody := new(response.SuccessWithOAuthClient)
+			decode(apiResponse, responseBody)
+			assert.True(t, strings.HasPrefix(responseBody.ClientSecret, config.OAuthClientSecretPrefix))
+			assert.True(t, responseBody.Client.Confidential)
+			assert.Equal(t, []string{"getUsers"}, responseBody.Client.Scopes)
+
+			client := new(model.OAuthClient)
+			assert.Nil(t, test.DB.First(client, "id = ?", responseBody.Client.ID).Error)
+			assert.Equal(t, utils.HashToken(responseBody.ClientSecret), client.Secret)
+		})
+
+		t.Run("should return 400 error if a public client asks for client_credentials", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			apiResponse := request(http.MethodPost, "/v1/oauth/clients", accessToken(fixture.Admin), validation.CreateOAuthClient{
+				Name:       "Partner CRM",
+				GrantTypes: []string{config.GrantTypeClientCredentials},
+			})
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 403 error if the user can not manage clients", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			apiResponse := request(http.MethodPost, "/v1/oauth/clients", accessToken(fixture.UserOne), validation.CreateOAuthClient{
+				Name:         "Partner CRM",
+				RedirectURIs: []string{redirectURI},
+				GrantTypes:   []string{config.GrantTypeAuthorizationCode},
+			})
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("GET /v1/oauth/authorize", func(t *testing.T) {
+		t.Run("should return 200 and the consent details", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+			client := insertClient()
+			req := authorization(client)
+
+			query := url.Values{
+				"response_type":         {req.ResponseType},
+				"client_id":             {req.ClientID},
+				"redirect_uri":          {req.RedirectURI},
+				"scope":                 {req.Scope},
+				"code_challenge":        {req.CodeChallenge},
+				"code_challenge_method": {req.CodeChallengeMethod},
+			}
+
+			apiResponse := request(http.MethodGet, "/v1/oauth/authorize?"+query.Encode(), "", nil)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			responseBody := new(response.SuccessWithOAuthConsent)
+			decode(apiResponse, responseBody)
+			assert.Equal(t, client.ID, responseBody.Consent.ClientID)
+			assert.Equal(t, "getUsers", responseBody.Consent.Scopes[0].Name)
+		})
+
+		t.Run("should redirect to the client if the code challenge is missing", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+			client := insertClient()
+
+			query := url.Values{
+				"response_type": {"code"},
+				"client_id":     {client.ID.String()},
+				"redirect_uri":  {redirectURI},
+				"state":         {"af0ifjsldkj"},
+			}
+
+			apiResponse := request(http.MethodGet, "/v1/oauth/authorize?"+query.Encode(), "", nil)
+			assert.Equal(t, http.StatusSeeOther, apiResponse.StatusCode)
+
+			location, err := url.Parse(apiResponse.Header.Get("Location"))
+			assert.Nil(t, err)
+			assert.Equal(t, "invalid_request", location.Query().Get("error"))
+			assert.Equal(t, "af0ifjsldkj", location.Query().Get("state"))
+		})
+
+		t.Run("should return 400 error and not redirect if the redirect uri is not registered", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+			client := insertClient()
+
+			query := url.Values{
+				"response_type": {"code"},
+				"client_id":     {client.ID.String()},
+				"redirect_uri":  {"https://attacker.example.com/callback"},
+			}
+
+			apiResponse := request(http.MethodGet, "/v1/oauth/authorize?"+query.Encode(), "", nil)
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("POST /v1/oauth/authorize", func(t *testing.T) {
+		t.Run("should redirect with access_denied if the user declines", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+			client := insertClient()
+			req := authorization(client)
+			req.Approve = false
+
+			apiResponse := request(http.MethodPost, "/v1/oauth/authorize", accessToken(fixture.Admin), req)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			responseBody := new(response.OAuthRedirect)
+			decode(apiResponse, responseBody)
+			assert.Contains(t, responseBody.URL, "error=access_denied")
+			assert.Equal(t, int64(0), helper.CountOAuthAuthorizationCodes(test.DB, client.ID))
+		})
+
+		t.Run("should return 403 error with a token issued to a client", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+			client := insertClient()
+
+			apiResponse := request(http.MethodPost, "/v1/oauth/authorize", issueToken(client), authorization(client))
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("POST /v1/oauth/token", func(t *testing.T) {
+		t.Run("should return a token limited to the consented scopes", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin, fixture.UserOne)
+			client := insertClient()
+
+			apiResponse := exchange(client, authorize(client))
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, "no-store", apiResponse.Header.Get("Cache-Control"))
+
+			responseBody := new(response.OAuthToken)
+			decode(apiResponse, responseBody)
+			assert.Equal(t, "Bearer", responseBody.TokenType)
+			assert.Equal(t, "getUsers", responseBody.Scope)
+
+			claims, err := utils.ParseToken(responseBody.AccessToken, config.JWTKeySet, config.TokenTypeAccess)
+			assert.Nil(t, err)
+			assert.Equal(t, fixture.Admin.ID.String(), claims["sub"])
+			assert.Equal(t, client.ID.String(), claims["client_id"])
+
+			apiResponse = request(http.MethodGet, "/v1/users/paginated", responseBody.AccessToken, nil)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			apiResponse = request(http.MethodDelete, "/v1/users/"+fixture.UserOne.ID.String(), responseBody.AccessToken, nil)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+
+			apiResponse = request(http.MethodPost, "/v1/auth/2fa/enroll", responseBody.AccessToken, nil)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 403 error on the files and identities of the user without a scope for them", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+			token := issueToken(insertClient())
+
+			apiResponse := request(http.MethodGet, "/v1/files/my-files", token, nil)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+
+			apiResponse = request(http.MethodGet, "/v1/auth/identities", token, nil)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+
+			apiResponse = request(http.MethodGet, "/v1/organizations", token, nil)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 403 error when the token lists or revokes the API keys of the user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+			client := insertClient()
+			apiKey := helper.InsertAPIKey(test.DB, fixture.Admin.ID, config.APIKeyPrefix+"q8w2e6r4t1y9u3i7o5p0a2s4d6f8g1h3", "", nil)
+			token := issueToken(client)
+
+			apiResponse := request(http.MethodGet, "/v1/auth/api-keys", token, nil)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+
+			apiResponse = request(http.MethodDelete, "/v1/auth/api-keys/"+apiKey.ID.String(), token, nil)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+
+			_, err := helper.GetAPIKeyByID(test.DB, apiKey.ID.String())
+			assert.Nil(t, err)
+		})
+
+		t.Run("should limit the organization rights of the user to the consented scopes", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+			organization := helper.InsertOrganization(test.DB, "Acme Inc")
+			helper.InsertMembership(test.DB, organization.ID, fixture.Admin.ID, config.OrganizationRoleOwner)
+			token := issueToken(insertClient())
+
+			apiResponse := request(http.MethodPatch, "/v1/organizations/"+organization.ID.String(), token,
+				validation.UpdateOrganization{Name: "Acme Corp"})
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+
+			apiResponse = request(http.MethodDelete, "/v1/organizations/"+organization.ID.String(), token, nil)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+
+			apiResponse = request(http.MethodPost, "/v1/organizations", token, validation.CreateOrganization{Name: "Acme Corp"})
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+
+		t.Run("should return invalid_grant if the code is used twice", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+			client := insertClient()
+			code := authorize(client)
+
+			assert.Equal(t, http.StatusOK, exchange(client, code).StatusCode)
+
+			apiResponse := exchange(client, code)
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+
+			responseBody := new(response.OAuthError)
+			decode(apiResponse, responseBody)
+			assert.Equal(t, "invalid_grant", responseBody.Error)
+		})
+
+		t.Run("should return invalid_grant if the code verifier does not match", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+			client := insertClient()
+
+			apiResponse := form("/v1/oauth/token", client, secret, url.Values{
+				"grant_type":    {config.GrantTypeAuthorizationCode},
+				"code":          {authorize(client)},
+				"redirect_uri":  {redirectURI},
+				"code_verifier": {oauth2.GenerateVerifier()},
+			})
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+
+			responseBody := new(response.OAuthError)
+			decode(apiResponse, responseBody)
+			assert.Equal(t, "invalid_grant", responseBody.Error)
+		})
+
+		t.Run("should return a token of the client owner for client_credentials", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+			client := insertClient()
+
+			apiResponse := form("/v1/oauth/token", client, secret, url.Values{
+				"grant_type": {config.GrantTypeClientCredentials},
+				"scope":      {"getUsers"},
+			})
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			responseBody := new(response.OAuthToken)
+			decode(apiResponse, responseBody)
+
+			apiResponse = request(http.MethodGet, "/v1/users/paginated", responseBody.AccessToken, nil)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+		})
+
+		t.Run("should return invalid_client if the secret is wrong", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+			client := insertClient()
+
+			apiResponse := form("/v1/oauth/token", client, "wrong", url.Values{
+				"grant_type": {config.GrantTypeClientCredentials},
+			})
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+
+			responseBody := new(response.OAuthError)
+			decode(apiResponse, responseBody)
+			assert.Equal(t, "invalid_client", responseBody.Error)
+		})
+	})
+
+	t.Run("POST /v1/oauth/introspect and /v1/oauth/revoke", func(t *testing.T) {
+		t.Run("should report the token active until it is revoked", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+			client := insertClient()
+			token := issueToken(client)
+
+			apiResponse := form("/v1/oauth/introspect", client, secret, url.Values{"token": {token}})
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			introspection := new(response.OAuthIntrospection)
+			decode(apiResponse, introspection)
+			assert.True(t, introspection.Active)
+			assert.Equal(t, "getUsers", introspection.Scope)
+			assert.Equal(t, fixture.Admin.ID.String(), introspection.Subject)
+
+			apiResponse = form("/v1/oauth/revoke", client, secret, url.Values{"token": {token}})
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			apiResponse = form("/v1/oauth/introspect", client, secret, url.Values{"token": {token}})
+			introspection = new(response.OAuthIntrospection)
+			decode(apiResponse, introspection)
+			assert.False(t, introspection.Active)
+
+			apiResponse = request(http.MethodGet, "/v1/users/paginated", token, nil)
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+
+		t.Run("should report tokens of other clients as inactive", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+			client := insertClient()
+			otherClient := insertClient()
+
+			apiResponse := form("/v1/oauth/introspect", otherClient, secret, url.Values{"token": {issueToken(client)}})
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			introspection := new(response.OAuthIntrospection)
+			decode(apiResponse, introspection)
+			assert.False(t, introspection.Active)
+		})
+	})
+
+	t.Run("DELETE /v1/oauth/clients/:clientId", func(t *testing.T) {
+		t.Run("should return 200 and stop the tokens of the client", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+			client := insertClient()
+			token := issueToken(client)
+
+			apiResponse := request(http.MethodDelete, "/v1/oauth/clients/"+client.ID.String(), accessToken(fixture.Admin), nil)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			apiResponse = request(http.MethodGet, "/v1/users/paginated", token, nil)
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+	})
+}
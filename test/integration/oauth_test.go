@@ -0,0 +1,465 @@
+package integration
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/response"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOAuthRoutes(t *testing.T) {
+	mock := helper.NewMockOIDCServer("mock-client")
+	defer mock.Close()
+
+	config.OAuthProviders["mock"] = mock.Provider("mock")
+	defer delete(config.OAuthProviders, "mock")
+
+	// begin starts a flow like the browser would and binds the mock user to its nonce and code challenge
+	begin := func(t *testing.T, request *http.Request, user helper.MockOIDCUser) (string, string) {
+		apiResponse, err := test.App.Test(request)
+		assert.Nil(t, err)
+
+		authURL := apiResponse.Header.Get("Location")
+		if authURL == "" {
+			redirect := new(response.OAuthRedirect)
+			bytes, errRead := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, errRead)
+			assert.Nil(t, json.Unmarshal(bytes, redirect))
+			authURL = redirect.URL
+		}
+
+		parsed, err := url.Parse(authURL)
+		assert.Nil(t, err)
+
+		user.Nonce = parsed.Query().Get("nonce")
+		user.CodeChallenge = parsed.Query().Get("code_challenge")
+
+		state := ""
+		for _, cookie := range apiResponse.Cookies() {
+			if cookie.Name == "oauth_state" {
+				state = cookie.Value
+			}
+		}
+		assert.NotEmpty(t, state)
+
+		return mock.IssueCode(user), state
+	}
+
+	login := func(t *testing.T, user helper.MockOIDCUser) (string, string) {
+		return begin(t, httptest.NewRequest(http.MethodGet, "/v1/auth/oauth/mock", nil), user)
+	}
+
+	callback := func(code, state string) *http.Request {
+		request := httptest.NewRequest(http.MethodGet,
+			"/v1/auth/oauth/mock/callback?code="+url.QueryEscape(code)+"&state="+url.QueryEscape(state), nil)
+		request.Header.Set("Cookie", "oauth_state="+state)
+		return request
+	}
+
+	t.Run("GET /v1/auth/oauth/:provider", func(t *testing.T) {
+		t.Run("should redirect to the provider's authorization endpoint with a state", func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/v1/auth/oauth/mock", nil)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusSeeOther, apiResponse.StatusCode)
+
+			location := apiResponse.Header.Get("Location")
+			assert.True(t, strings.HasPrefix(location, mock.Issuer()+"/authorize"))
+			assert.Contains(t, location, "client_id=mock-client")
+			assert.Contains(t, location, "state=")
+			assert.Contains(t, location, "nonce=")
+			assert.Contains(t, location, "code_challenge_method=S256")
+			assert.Contains(t, apiResponse.Header.Get("Set-Cookie"), "oauth_state=")
+		})
+
+		t.Run("should return 404 if the provider is not configured", func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/v1/auth/oauth/unknown", nil)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("GET /v1/auth/oauth/:provider/callback", func(t *testing.T) {
+		t.Run("should return 200 and create a verified user from the id token", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+
+			code, state := login(t, helper.MockOIDCUser{
+				Subject:       "oidc-user-1",
+				Name:          "OIDC User",
+				Email:         "oidc@example.com",
+				EmailVerified: true,
+			})
+
+			apiResponse, err := test.App.Test(callback(code, state))
+			assert.Nil(t, err)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.SuccessWithTokens)
+
+			err = json.Unmarshal(bytes, responseBody)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, "oidc@example.com", responseBody.User.Email)
+			assert.Equal(t, "OIDC User", responseBody.User.Name)
+			assert.True(t, responseBody.User.VerifiedEmail)
+			assert.False(t, responseBody.User.LocalLogin)
+			assert.Equal(t, int64(1), helper.CountIdentities(test.DB, responseBody.User.ID.String()))
+			assert.NotEmpty(t, responseBody.Tokens.Access.Token)
+			assert.NotEmpty(t, responseBody.Tokens.Refresh.Token)
+		})
+
+		t.Run("should log in by provider subject even if the email changed", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			helper.InsertIdentity(test.DB, fixture.UserOne.ID.String(), "mock", "oidc-user-2", fixture.UserOne.Email)
+
+			code, state := login(t, helper.MockOIDCUser{
+				Subject:       "oidc-user-2",
+				Name:          "Renamed",
+				Email:         "renamed@example.com",
+				EmailVerified: true,
+			})
+
+			apiResponse, err := test.App.Test(callback(code, state))
+			assert.Nil(t, err)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.SuccessWithTokens)
+
+			err = json.Unmarshal(bytes, responseBody)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, fixture.UserOne.ID, responseBody.User.ID)
+			assert.Equal(t, fixture.UserOne.Email, responseBody.User.Email)
+		})
+
+		t.Run("should return 409 if an account with the email exists, even if the provider verified it", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			code, state := login(t, helper.MockOIDCUser{
+				Subject:       "oidc-user-3",
+				Name:          "Someone Else",
+				Email:         fixture.UserOne.Email,
+				EmailVerified: true,
+			})
+
+			apiResponse, err := test.App.Test(callback(code, state))
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusConflict, apiResponse.StatusCode)
+			assert.Equal(t, int64(0), helper.CountIdentities(test.DB, fixture.UserOne.ID.String()))
+		})
+
+		t.Run("should link a legacy oauth account without password and identities by verified email", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			helper.RemovePassword(test.DB, fixture.UserOne.ID.String())
+
+			code, state := login(t, helper.MockOIDCUser{
+				Subject:       "oidc-user-3",
+				Name:          "Test1",
+				Email:         fixture.UserOne.Email,
+				EmailVerified: true,
+			})
+
+			apiResponse, err := test.App.Test(callback(code, state))
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, int64(1), helper.CountIdentities(test.DB, fixture.UserOne.ID.String()))
+
+			user, err := helper.GetUserByID(test.DB, fixture.UserOne.ID.String())
+			assert.Nil(t, err)
+			assert.True(t, user.VerifiedEmail)
+		})
+
+		t.Run("should return 403 and create no user if the provider did not verify the email", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+
+			code, state := login(t, helper.MockOIDCUser{
+				Subject: "oidc-user-11",
+				Name:    "Attacker",
+				Email:   "victim@example.com",
+			})
+
+			apiResponse, err := test.App.Test(callback(code, state))
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+
+			user := new(model.User)
+			err = test.DB.Where("email = ?", "victim@example.com").Take(user).Error
+			assert.NotNil(t, err)
+		})
+
+		t.Run("should return 403 for a legacy oauth account if the provider did not verify the email", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			helper.RemovePassword(test.DB, fixture.UserOne.ID.String())
+
+			code, state := login(t, helper.MockOIDCUser{
+				Subject: "oidc-user-3",
+				Name:    "Attacker",
+				Email:   fixture.UserOne.Email,
+			})
+
+			apiResponse, err := test.App.Test(callback(code, state))
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+			assert.Equal(t, int64(0), helper.CountIdentities(test.DB, fixture.UserOne.ID.String()))
+		})
+
+		t.Run("should return 401 if the id token was issued to another client", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+
+			code, state := login(t, helper.MockOIDCUser{
+				Subject:       "oidc-user-4",
+				Name:          "OIDC User",
+				Email:         "oidc@example.com",
+				EmailVerified: true,
+				Audience:      "another-client",
+			})
+
+			apiResponse, err := test.App.Test(callback(code, state))
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+
+			user := new(model.User)
+			err = test.DB.Where("email = ?", "oidc@example.com").Take(user).Error
+			assert.NotNil(t, err)
+		})
+
+		t.Run("should return 401 if the state does not match", func(t *testing.T) {
+			code, state := login(t, helper.MockOIDCUser{
+				Subject:       "oidc-user-5",
+				Name:          "OIDC User",
+				Email:         "oidc@example.com",
+				EmailVerified: true,
+			})
+
+			request := callback(code, state)
+			request.Header.Set("Cookie", "oauth_state=another-state")
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 401 if the authorization code is invalid", func(t *testing.T) {
+			_, state := login(t, helper.MockOIDCUser{Subject: "oidc-user-6", Email: "oidc@example.com"})
+
+			apiResponse, err := test.App.Test(callback("invalid-code", state))
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 401 if the state was not issued by the server", func(t *testing.T) {
+			code := mock.IssueCode(helper.MockOIDCUser{Subject: "oidc-user-7", Email: "oidc@example.com"})
+
+			apiResponse, err := test.App.Test(callback(code, "forged-state"))
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 401 if the state is used twice", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+
+			user := helper.MockOIDCUser{Subject: "oidc-user-8", Email: "oidc@example.com", EmailVerified: true}
+			code, state := login(t, user)
+
+			apiResponse, err := test.App.Test(callback(code, state))
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			user.Nonce = ""
+			apiResponse, err = test.App.Test(callback(mock.IssueCode(user), state))
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+
+		t.Run("should redirect to the frontend with a one-time code that can be exchanged once", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+
+			config.OAuthFrontendURL = "http://localhost:5173/oauth/callback"
+			defer func() { config.OAuthFrontendURL = "" }()
+
+			code, state := login(t, helper.MockOIDCUser{
+				Subject:       "oidc-user-9",
+				Name:          "OIDC User",
+				Email:         "oidc@example.com",
+				EmailVerified: true,
+			})
+
+			apiResponse, err := test.App.Test(callback(code, state))
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusSeeOther, apiResponse.StatusCode)
+
+			location, err := url.Parse(apiResponse.Header.Get("Location"))
+			assert.Nil(t, err)
+			assert.Equal(t, "localhost:5173", location.Host)
+			assert.Equal(t, "/oauth/callback", location.Path)
+			assert.NotContains(t, location.RawQuery, "token")
+
+			oneTimeCode := location.Query().Get("code")
+			assert.NotEmpty(t, oneTimeCode)
+
+			exchange := func() *http.Response {
+				request := httptest.NewRequest(http.MethodPost, "/v1/auth/oauth/exchange",
+					strings.NewReader(`{"code":"`+oneTimeCode+`"}`))
+				request.Header.Set("Content-Type", "application/json")
+
+				exchangeResponse, errExchange := test.App.Test(request)
+				assert.Nil(t, errExchange)
+
+				return exchangeResponse
+			}
+
+			apiResponse = exchange()
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.SuccessWithTokens)
+			err = json.Unmarshal(bytes, responseBody)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, "oidc@example.com", responseBody.User.Email)
+			assert.NotEmpty(t, responseBody.Tokens.Access.Token)
+
+			apiResponse = exchange()
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+
+		t.Run("should redirect errors to the frontend", func(t *testing.T) {
+			config.OAuthFrontendURL = "http://localhost:5173/oauth/callback"
+			defer func() { config.OAuthFrontendURL = "" }()
+
+			code := mock.IssueCode(helper.MockOIDCUser{Subject: "oidc-user-10", Email: "oidc@example.com"})
+
+			apiResponse, err := test.App.Test(callback(code, "forged-state"))
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusSeeOther, apiResponse.StatusCode)
+
+			location, err := url.Parse(apiResponse.Header.Get("Location"))
+			assert.Nil(t, err)
+			assert.Equal(t, "Invalid or expired state", location.Query().Get("error"))
+			assert.Empty(t, location.Query().Get("code"))
+		})
+	})
+
+	t.Run("POST /v1/auth/oauth/exchange", func(t *testing.T) {
+		t.Run("should return 401 if the code is invalid", func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/v1/auth/oauth/exchange",
+				strings.NewReader(`{"code":"invalid-code"}`))
+			request.Header.Set("Content-Type", "application/json")
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 400 if the code is missing", func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/v1/auth/oauth/exchange", strings.NewReader(`{}`))
+			request.Header.Set("Content-Type", "application/json")
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("POST /v1/auth/oauth/:provider/link", func(t *testing.T) {
+		link := func(t *testing.T, user helper.MockOIDCUser) (string, string) {
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "/v1/auth/oauth/mock/link", nil)
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+
+			return begin(t, request, user)
+		}
+
+		t.Run("should link the provider account on callback", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			code, state := link(t, helper.MockOIDCUser{
+				Subject:       "oidc-user-link",
+				Name:          "Linked",
+				Email:         "linked@example.com",
+				EmailVerified: true,
+			})
+
+			apiResponse, err := test.App.Test(callback(code, state))
+			assert.Nil(t, err)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.SuccessWithIdentity)
+			err = json.Unmarshal(bytes, responseBody)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, "mock", responseBody.Identity.Provider)
+			assert.Equal(t, "linked@example.com", responseBody.Identity.Email)
+			assert.Equal(t, int64(1), helper.CountIdentities(test.DB, fixture.UserOne.ID.String()))
+		})
+
+		t.Run("should return 401 if the callback is completed in another browser", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			code, state := link(t, helper.MockOIDCUser{Subject: "oidc-user-link", Email: "linked@example.com"})
+
+			request := callback(code, state)
+			request.Header.Set("Cookie", "oauth_state=other")
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+			assert.Equal(t, int64(0), helper.CountIdentities(test.DB, fixture.UserOne.ID.String()))
+		})
+
+		t.Run("should return 401 if access token is missing", func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/v1/auth/oauth/mock/link", nil)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+	})
+}
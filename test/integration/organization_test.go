@@ -0,0 +1,530 @@
+package integration
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/response"
+	"app/src/utils"
+	"app/src/validation"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrganizationRoutes(t *testing.T) {
+	const apiKey = config.APIKeyPrefix + "q8w3e5r7t9y2u4i6o1p3a5s7d9f2g4h6"
+
+	request := func(method, target, accessToken string, body interface{}, headers ...string) *http.Response {
+		var reader io.Reader
+		if body != nil {
+			bodyJSON, err := json.Marshal(body)
+			assert.Nil(t, err)
+			reader = strings.NewReader(string(bodyJSON))
+		}
+
+		request := httptest.NewRequest(method, target, reader)
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Accept", "application/json")
+		if accessToken != "" {
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+		}
+
+		for i := 0; i+1 < len(headers); i += 2 {
+			request.Header.Set(headers[i], headers[i+1])
+		}
+
+		apiResponse, err := test.App.Test(request)
+		assert.Nil(t, err)
+
+		return apiResponse
+	}
+
+	accessToken := func(user *model.User) string {
+		token, err := fixture.AccessToken(user)
+		assert.Nil(t, err)
+
+		return token
+	}
+
+	// insertOrganization creates an organization owned by UserOne
+	insertOrganization := func() *model.Organization {
+		organization := helper.InsertOrganization(test.DB, "Acme Inc")
+		helper.InsertMembership(test.DB, organization.ID, fixture.UserOne.ID, config.OrganizationRoleOwner)
+
+		return organization
+	}
+
+	t.Run("POST /v1/organizations", func(t *testing.T) {
+		t.Run("should return 201 and make the user the owner", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			apiResponse := request(http.MethodPost, "/v1/organizations", accessToken(fixture.UserOne),
+				validation.CreateOrganization{Name: "Acme Inc"})
+			assert.Equal(t, http.StatusCreated, apiResponse.StatusCode)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.SuccessWithMembership)
+			assert.Nil(t, json.Unmarshal(bytes, responseBody))
+			assert.Equal(t, config.OrganizationRoleOwner, responseBody.Membership.Role)
+			assert.Equal(t, "Acme Inc", responseBody.Membership.Organization.Name)
+
+			membership, err := helper.GetMembership(test.DB, responseBody.Membership.OrganizationID, fixture.UserOne.ID)
+			assert.Nil(t, err)
+			assert.Equal(t, config.OrganizationRoleOwner, membership.Role)
+		})
+
+		t.Run("should return 400 error if name is empty", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			apiResponse := request(http.MethodPost, "/v1/organizations", accessToken(fixture.UserOne),
+				validation.CreateOrganization{})
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 401 error if access token is missing", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+
+			apiResponse := request(http.MethodPost, "/v1/organizations", "", validation.CreateOrganization{Name: "Acme Inc"})
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 403 error with an API key", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			helper.InsertAPIKey(test.DB, fixture.UserOne.ID, apiKey, "getUsers,manageOrganization", nil)
+
+			apiResponse := request(http.MethodPost, "/v1/organizations", "", validation.CreateOrganization{Name: "Acme Inc"},
+				"X-API-Key", apiKey)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("GET /v1/organizations", func(t *testing.T) {
+		t.Run("should return 200 and only the organizations of the user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+			organization := insertOrganization()
+			other := helper.InsertOrganization(test.DB, "Other Inc")
+			helper.InsertMembership(test.DB, other.ID, fixture.UserTwo.ID, config.OrganizationRoleOwner)
+
+			apiResponse := request(http.MethodGet, "/v1/organizations", accessToken(fixture.UserOne), nil)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.SuccessWithMemberships)
+			assert.Nil(t, json.Unmarshal(bytes, responseBody))
+			assert.Len(t, responseBody.Memberships, 1)
+			assert.Equal(t, organization.ID, responseBody.Memberships[0].OrganizationID)
+		})
+	})
+
+	t.Run("GET /v1/organizations/:organizationId", func(t *testing.T) {
+		t.Run("should return 200 for members", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			organization := insertOrganization()
+
+			apiResponse := request(http.MethodGet, "/v1/organizations/"+organization.ID.String(), accessToken(fixture.UserOne), nil)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 404 error for users who are not members", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+			organization := insertOrganization()
+
+			apiResponse := request(http.MethodGet, "/v1/organizations/"+organization.ID.String(), accessToken(fixture.UserTwo), nil)
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 400 error if organizationId is not a valid id", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			apiResponse := request(http.MethodGet, "/v1/organizations/invalidId", accessToken(fixture.UserOne), nil)
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("PATCH /v1/organizations/:organizationId", func(t *testing.T) {
+		t.Run("should return 200 if an owner renames the organization", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			organization := insertOrganization()
+
+			apiResponse := request(http.MethodPatch, "/v1/organizations/"+organization.ID.String(), accessToken(fixture.UserOne),
+				validation.UpdateOrganization{Name: "Acme Corp"})
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.SuccessWithOrganization)
+			assert.Nil(t, json.Unmarshal(bytes, responseBody))
+			assert.Equal(t, "Acme Corp", responseBody.Organization.Name)
+		})
+
+		t.Run("should return 403 error if a member renames the organization", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+			organization := insertOrganization()
+			helper.InsertMembership(test.DB, organization.ID, fixture.UserTwo.ID, config.OrganizationRoleMember)
+
+			apiResponse := request(http.MethodPatch, "/v1/organizations/"+organization.ID.String(), accessToken(fixture.UserTwo),
+				validation.UpdateOrganization{Name: "Acme Corp"})
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 403 error if the API key of an owner is not scoped to manageOrganization", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			organization := insertOrganization()
+			helper.InsertAPIKey(test.DB, fixture.UserOne.ID, apiKey, "getUsers", nil)
+
+			apiResponse := request(http.MethodPatch, "/v1/organizations/"+organization.ID.String(), "",
+				validation.UpdateOrganization{Name: "Acme Corp"}, "X-API-Key", apiKey)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+
+			apiResponse = request(http.MethodPost, "/v1/organizations/"+organization.ID.String()+"/invitations", "",
+				validation.CreateInvitation{Email: fixture.UserTwo.Email, Role: config.OrganizationRoleAdmin},
+				"X-API-Key", apiKey)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 200 with an API key of an owner scoped to manageOrganization", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			organization := insertOrganization()
+			helper.InsertAPIKey(test.DB, fixture.UserOne.ID, apiKey, "manageOrganization", nil)
+
+			apiResponse := request(http.MethodPatch, "/v1/organizations/"+organization.ID.String(), "",
+				validation.UpdateOrganization{Name: "Acme Corp"}, "X-API-Key", apiKey)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("DELETE /v1/organizations/:organizationId", func(t *testing.T) {
+		t.Run("should return 200 and delete the memberships", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			organization := insertOrganization()
+
+			apiResponse := request(http.MethodDelete, "/v1/organizations/"+organization.ID.String(), accessToken(fixture.UserOne), nil)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			_, err := helper.GetMembership(test.DB, organization.ID, fixture.UserOne.ID)
+			assert.NotNil(t, err)
+		})
+
+		t.Run("should return 409 error while the organization still has files", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			organization := insertOrganization()
+			helper.InsertOrganizationFile(test.DB, &fixture.UserOne.ID, &organization.ID, "general/acme.txt")
+
+			apiResponse := request(http.MethodDelete, "/v1/organizations/"+organization.ID.String(), accessToken(fixture.UserOne), nil)
+			assert.Equal(t, http.StatusConflict, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("PATCH /v1/organizations/:organizationId/members/:userId", func(t *testing.T) {
+		t.Run("should return 200 if an owner changes the role of a member", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+			organization := insertOrganization()
+			helper.InsertMembership(test.DB, organization.ID, fixture.UserTwo.ID, config.OrganizationRoleMember)
+
+			apiResponse := request(http.MethodPatch,
+				"/v1/organizations/"+organization.ID.String()+"/members/"+fixture.UserTwo.ID.String(),
+				accessToken(fixture.UserOne), validation.UpdateMembership{Role: config.OrganizationRoleAdmin})
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			membership, err := helper.GetMembership(test.DB, organization.ID, fixture.UserTwo.ID)
+			assert.Nil(t, err)
+			assert.Equal(t, config.OrganizationRoleAdmin, membership.Role)
+		})
+
+		t.Run("should return 403 error if an admin makes an owner", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+			organization := insertOrganization()
+			helper.InsertMembership(test.DB, organization.ID, fixture.UserTwo.ID, config.OrganizationRoleAdmin)
+
+			apiResponse := request(http.MethodPatch,
+				"/v1/organizations/"+organization.ID.String()+"/members/"+fixture.UserTwo.ID.String(),
+				accessToken(fixture.UserTwo), validation.UpdateMembership{Role: config.OrganizationRoleOwner})
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 400 error if the last owner is demoted", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			organization := insertOrganization()
+
+			apiResponse := request(http.MethodPatch,
+				"/v1/organizations/"+organization.ID.String()+"/members/"+fixture.UserOne.ID.String(),
+				accessToken(fixture.UserOne), validation.UpdateMembership{Role: config.OrganizationRoleMember})
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+
+			membership, err := helper.GetMembership(test.DB, organization.ID, fixture.UserOne.ID)
+			assert.Nil(t, err)
+			assert.Equal(t, config.OrganizationRoleOwner, membership.Role)
+		})
+	})
+
+	t.Run("DELETE /v1/organizations/:organizationId/members/:userId", func(t *testing.T) {
+		t.Run("should return 200 if a member leaves the organization", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+			organization := insertOrganization()
+			helper.InsertMembership(test.DB, organization.ID, fixture.UserTwo.ID, config.OrganizationRoleMember)
+
+			apiResponse := request(http.MethodDelete,
+				"/v1/organizations/"+organization.ID.String()+"/members/"+fixture.UserTwo.ID.String(),
+				accessToken(fixture.UserTwo), nil)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			_, err := helper.GetMembership(test.DB, organization.ID, fixture.UserTwo.ID)
+			assert.NotNil(t, err)
+		})
+
+		t.Run("should return 403 error if a member leaves with an API key", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+			organization := insertOrganization()
+			helper.InsertMembership(test.DB, organization.ID, fixture.UserTwo.ID, config.OrganizationRoleMember)
+			helper.InsertAPIKey(test.DB, fixture.UserTwo.ID, apiKey, "getUsers", nil)
+
+			apiResponse := request(http.MethodDelete,
+				"/v1/organizations/"+organization.ID.String()+"/members/"+fixture.UserTwo.ID.String(), "", nil,
+				"X-API-Key", apiKey)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+
+			_, err := helper.GetMembership(test.DB, organization.ID, fixture.UserTwo.ID)
+			assert.Nil(t, err)
+		})
+
+		t.Run("should return 403 error if a member removes another member", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+			organization := insertOrganization()
+			helper.InsertMembership(test.DB, organization.ID, fixture.UserTwo.ID, config.OrganizationRoleMember)
+
+			apiResponse := request(http.MethodDelete,
+				"/v1/organizations/"+organization.ID.String()+"/members/"+fixture.UserOne.ID.String(),
+				accessToken(fixture.UserTwo), nil)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 400 error if the last owner leaves", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			organization := insertOrganization()
+
+			apiResponse := request(http.MethodDelete,
+				"/v1/organizations/"+organization.ID.String()+"/members/"+fixture.UserOne.ID.String(),
+				accessToken(fixture.UserOne), nil)
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("POST /v1/organizations/:organizationId/invitations", func(t *testing.T) {
+		t.Run("should return 201 and replace a pending invitation of the same email", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			organization := insertOrganization()
+
+			for i := 0; i < 2; i++ {
+				apiResponse := request(http.MethodPost, "/v1/organizations/"+organization.ID.String()+"/invitations",
+					accessToken(fixture.UserOne), validation.CreateInvitation{Email: "invited@gmail.com", Role: config.OrganizationRoleMember})
+				assert.Equal(t, http.StatusCreated, apiResponse.StatusCode)
+			}
+
+			assert.Equal(t, int64(1), helper.CountInvitations(test.DB, organization.ID))
+		})
+
+		t.Run("should return 409 error if the user is already a member", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+			organization := insertOrganization()
+			helper.InsertMembership(test.DB, organization.ID, fixture.UserTwo.ID, config.OrganizationRoleMember)
+
+			apiResponse := request(http.MethodPost, "/v1/organizations/"+organization.ID.String()+"/invitations",
+				accessToken(fixture.UserOne), validation.CreateInvitation{Email: fixture.UserTwo.Email, Role: config.OrganizationRoleMember})
+			assert.Equal(t, http.StatusConflict, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 403 error if a member invites", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+			organization := insertOrganization()
+			helper.InsertMembership(test.DB, organization.ID, fixture.UserTwo.ID, config.OrganizationRoleMember)
+
+			apiResponse := request(http.MethodPost, "/v1/organizations/"+organization.ID.String()+"/invitations",
+				accessToken(fixture.UserTwo), validation.CreateInvitation{Email: "invited@gmail.com", Role: config.OrganizationRoleMember})
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("POST /v1/invitations/accept", func(t *testing.T) {
+		t.Run("should return 200 and add the user with the invited role", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+			organization := insertOrganization()
+			helper.InsertInvitation(test.DB, organization.ID, fixture.UserTwo.Email, config.OrganizationRoleAdmin,
+				"invitationToken", time.Now().UTC().Add(time.Hour))
+
+			apiResponse := request(http.MethodPost, "/v1/invitations/accept?token=invitationToken", accessToken(fixture.UserTwo), nil)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			membership, err := helper.GetMembership(test.DB, organization.ID, fixture.UserTwo.ID)
+			assert.Nil(t, err)
+			assert.Equal(t, config.OrganizationRoleAdmin, membership.Role)
+			assert.Equal(t, int64(0), helper.CountInvitations(test.DB, organization.ID))
+		})
+
+		t.Run("should return 403 error if the invitation was sent to another email", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+			organization := insertOrganization()
+			helper.InsertInvitation(test.DB, organization.ID, "invited@gmail.com", config.OrganizationRoleMember,
+				"invitationToken", time.Now().UTC().Add(time.Hour))
+
+			apiResponse := request(http.MethodPost, "/v1/invitations/accept?token=invitationToken", accessToken(fixture.UserTwo), nil)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+
+			_, err := helper.GetMembership(test.DB, organization.ID, fixture.UserTwo.ID)
+			assert.NotNil(t, err)
+		})
+
+		t.Run("should return 404 error if the invitation has expired", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+			organization := insertOrganization()
+			helper.InsertInvitation(test.DB, organization.ID, fixture.UserTwo.Email, config.OrganizationRoleMember,
+				"invitationToken", time.Now().UTC().Add(-time.Minute))
+
+			apiResponse := request(http.MethodPost, "/v1/invitations/accept?token=invitationToken", accessToken(fixture.UserTwo), nil)
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("POST /v1/invitations/decline", func(t *testing.T) {
+		t.Run("should return 200 and delete the invitation without authentication", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			organization := insertOrganization()
+			helper.InsertInvitation(test.DB, organization.ID, "invited@gmail.com", config.OrganizationRoleMember,
+				"invitationToken", time.Now().UTC().Add(time.Hour))
+
+			apiResponse := request(http.MethodPost, "/v1/invitations/decline?token=invitationToken", "", nil)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, int64(0), helper.CountInvitations(test.DB, organization.ID))
+		})
+	})
+
+	t.Run("active organization", func(t *testing.T) {
+		t.Run("should scope GET /v1/users/paginated to the members of the organization", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo, fixture.Admin)
+			organization := insertOrganization()
+			helper.InsertMembership(test.DB, organization.ID, fixture.UserTwo.ID, config.OrganizationRoleMember)
+
+			apiResponse := request(http.MethodGet, "/v1/users/paginated", accessToken(fixture.UserOne), nil,
+				config.HeaderOrganizationID, organization.ID.String())
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.SuccessWithPaginate[model.User])
+			assert.Nil(t, json.Unmarshal(bytes, responseBody))
+			assert.Equal(t, int64(2), responseBody.TotalResults)
+		})
+
+		t.Run("should not grant the organization rights without an active organization", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			insertOrganization()
+
+			apiResponse := request(http.MethodGet, "/v1/users/paginated", accessToken(fixture.UserOne), nil)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 404 error if the user is not a member of the organization in the header", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+			organization := insertOrganization()
+
+			apiResponse := request(http.MethodGet, "/v1/organizations", accessToken(fixture.UserTwo), nil,
+				config.HeaderOrganizationID, organization.ID.String())
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+
+		t.Run("should activate the organization with the token of POST /v1/organizations/:organizationId/token", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+			organization := insertOrganization()
+			helper.InsertMembership(test.DB, organization.ID, fixture.UserTwo.ID, config.OrganizationRoleMember)
+
+			apiResponse := request(http.MethodPost, "/v1/organizations/"+organization.ID.String()+"/token",
+				accessToken(fixture.UserOne), nil)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.SuccessWithAccessToken)
+			assert.Nil(t, json.Unmarshal(bytes, responseBody))
+
+			claims, err := utils.ParseToken(responseBody.Access.Token, config.JWTKeySet, config.TokenTypeAccess)
+			assert.Nil(t, err)
+			assert.Equal(t, organization.ID.String(), claims["org"])
+
+			apiResponse = request(http.MethodGet, "/v1/users/paginated", responseBody.Access.Token, nil)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+		})
+
+		t.Run("should hide files of another organization", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			organization := insertOrganization()
+			other := helper.InsertOrganization(test.DB, "Other Inc")
+			helper.InsertMembership(test.DB, other.ID, fixture.UserOne.ID, config.OrganizationRoleOwner)
+			file := helper.InsertOrganizationFile(test.DB, &fixture.UserOne.ID, &other.ID, "general/other.txt")
+
+			apiResponse := request(http.MethodGet, "/v1/files/"+file.ID.String(), accessToken(fixture.UserOne), nil,
+				config.HeaderOrganizationID, organization.ID.String())
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+
+			apiResponse = request(http.MethodGet, "/v1/files/"+file.ID.String(), accessToken(fixture.UserOne), nil,
+				config.HeaderOrganizationID, other.ID.String())
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 400 error if the header is not a valid id", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			apiResponse := request(http.MethodGet, "/v1/organizations", accessToken(fixture.UserOne), nil,
+				config.HeaderOrganizationID, uuid.NewString()[:8])
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+	})
+}
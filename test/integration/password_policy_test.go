@@ -0,0 +1,162 @@
+package integration
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/response"
+	"app/src/validation"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	request := func(method, target, accessToken string, body interface{}) *http.Response {
+		bodyJSON, err := json.Marshal(body)
+		assert.Nil(t, err)
+
+		request := httptest.NewRequest(method, target, strings.NewReader(string(bodyJSON)))
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Accept", "application/json")
+		if accessToken != "" {
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+		}
+
+		apiResponse, err := test.App.Test(request)
+		assert.Nil(t, err)
+
+		return apiResponse
+	}
+
+	// changePassword updates the password of UserOne with a fresh access token, since every change
+	// invalidates the tokens issued before it
+	changePassword := func(password string) *http.Response {
+		accessToken, err := fixture.AccessToken(fixture.UserOne)
+		assert.Nil(t, err)
+
+		return request(http.MethodPatch, "/v1/users/"+fixture.UserOne.ID.String(), accessToken,
+			validation.UpdateUser{Password: password})
+	}
+
+	withHistory := func(t *testing.T, history int) {
+		previous := config.PasswordHistory
+		config.PasswordHistory = history
+		t.Cleanup(func() { config.PasswordHistory = previous })
+	}
+
+	t.Run("common passwords", func(t *testing.T) {
+		t.Run("should return 400 error with the message of the field", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+
+			apiResponse := request(http.MethodPost, "/v1/auth/register", "",
+				validation.Register{Name: "Test", Email: "test@gmail.com", Password: "Qwerty123"})
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.ErrorDetails)
+			assert.Nil(t, json.Unmarshal(bytes, responseBody))
+			assert.Equal(t, "Field Password is a commonly used password, please choose another one",
+				responseBody.Errors.(map[string]interface{})["Register.Password"])
+		})
+	})
+
+	t.Run("password history", func(t *testing.T) {
+		t.Run("should return 400 error when reusing one of the last passwords", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			withHistory(t, 3)
+
+			assert.Equal(t, http.StatusOK, changePassword("s3cure-fiber").StatusCode)
+			assert.Equal(t, http.StatusOK, changePassword("an0ther-fiber").StatusCode)
+			assert.Equal(t, http.StatusBadRequest, changePassword("an0ther-fiber").StatusCode)
+			assert.Equal(t, http.StatusBadRequest, changePassword("s3cure-fiber").StatusCode)
+		})
+
+		t.Run("should accept a password older than the history", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			withHistory(t, 2)
+
+			assert.Equal(t, http.StatusOK, changePassword("s3cure-fiber").StatusCode)
+			assert.Equal(t, http.StatusOK, changePassword("an0ther-fiber").StatusCode)
+			assert.Equal(t, http.StatusOK, changePassword("th1rd-fiber").StatusCode)
+			assert.Equal(t, http.StatusOK, changePassword("s3cure-fiber").StatusCode)
+
+			var count int64
+			assert.Nil(t, test.DB.Model(&model.PasswordHistory{}).
+				Where("user_id = ?", fixture.UserOne.ID).Count(&count).Error)
+			assert.Equal(t, int64(1), count)
+		})
+
+		t.Run("should return 400 error when resetting to the current password", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			withHistory(t, 3)
+			assert.Equal(t, http.StatusOK, changePassword("s3cure-fiber").StatusCode)
+
+			resetPasswordToken, err := fixture.ResetPasswordToken(fixture.UserOne)
+			assert.Nil(t, err)
+			assert.Nil(t, helper.SaveToken(test.DB, resetPasswordToken, fixture.UserOne.ID.String(),
+				config.TokenTypeResetPassword, fixture.ExpiresResetPasswordToken))
+
+			apiResponse := request(http.MethodPost, "/v1/auth/reset-password?token="+resetPasswordToken, "",
+				validation.UpdatePassOrVerify{Password: "s3cure-fiber"})
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("password max age", func(t *testing.T) {
+		// insertUser keeps the plain password, InsertUser replaces it with the hash
+		insertUser := func(passwordChangedAt time.Time) *model.User {
+			user := &model.User{
+				Name: "Expired", Email: "expired@gmail.com", Password: "password1", Role: "user",
+				PasswordChangedAt: &passwordChangedAt,
+			}
+			helper.InsertUser(test.DB, user)
+
+			return user
+		}
+
+		login := func(user *model.User) *http.Response {
+			return request(http.MethodPost, "/v1/auth/login", "", validation.Login{Email: user.Email, Password: "password1"})
+		}
+
+		t.Run("should return 403 error and send a reset link if the password is too old", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			previous := config.PasswordMaxAge
+			config.PasswordMaxAge = 90
+			defer func() { config.PasswordMaxAge = previous }()
+			user := insertUser(time.Now().UTC().AddDate(0, 0, -91))
+
+			apiResponse := login(user)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+
+			resetPasswordTokenDoc, err := helper.GetTokenByType(test.DB, user.ID.String(), config.TokenTypeResetPassword)
+			assert.Nil(t, err)
+			assert.NotNil(t, resetPasswordTokenDoc)
+			assert.Equal(t, int64(0), helper.CountTokens(test.DB, user.ID.String(), config.TokenTypeRefresh))
+		})
+
+		t.Run("should return 200 if the password is recent enough", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			previous := config.PasswordMaxAge
+			config.PasswordMaxAge = 90
+			defer func() { config.PasswordMaxAge = previous }()
+			user := insertUser(time.Now().UTC().AddDate(0, 0, -89))
+
+			apiResponse := login(user)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+		})
+	})
+}
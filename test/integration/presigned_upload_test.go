@@ -0,0 +1,212 @@
+package integration
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/validation"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPresignedUploadRoutes(t *testing.T) {
+	request := func(method, target string, user *model.User, headers map[string]string, body []byte) *http.Response {
+		request := httptest.NewRequest(method, target, bytes.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+
+		if user != nil {
+			accessToken, err := fixture.AccessToken(user)
+			assert.Nil(t, err)
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+		}
+
+		for key, value := range headers {
+			request.Header.Set(key, value)
+		}
+
+		apiResponse, err := test.App.Test(request)
+		assert.Nil(t, err)
+
+		return apiResponse
+	}
+
+	// decode membaca field data dari response
+	decode := func(apiResponse *http.Response) map[string]interface{} {
+		bytes, err := io.ReadAll(apiResponse.Body)
+		assert.Nil(t, err)
+
+		responseBody := struct {
+			Data map[string]interface{} `json:"data"`
+		}{}
+		assert.Nil(t, json.Unmarshal(bytes, &responseBody))
+
+		return responseBody.Data
+	}
+
+	// relative membuang scheme dan host dari URL local storage agar dapat dikirim ke test.App
+	relative := func(presignedURL string) string {
+		parsed, err := url.Parse(presignedURL)
+		assert.Nil(t, err)
+
+		return parsed.RequestURI()
+	}
+
+	createUpload := func(user *model.User, content string) (string, map[string]interface{}) {
+		body, err := json.Marshal(validation.CreatePresignedUpload{
+			FileName: "notes.txt", ContentType: "text/plain", Size: int64(len(content)),
+		})
+		assert.Nil(t, err)
+
+		apiResponse := request(http.MethodPost, "/v1/files/presigned-uploads", user, nil, body)
+		assert.Equal(t, http.StatusCreated, apiResponse.StatusCode)
+
+		data := decode(apiResponse)
+		upload := data["upload"].(map[string]interface{})
+
+		return upload["id"].(string), data
+	}
+
+	t.Run("POST /v1/files/presigned-uploads", func(t *testing.T) {
+		t.Run("should register the file after it was sent to the presigned url", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			uploadID, data := createUpload(fixture.UserOne, "hello world")
+			assert.Equal(t, http.MethodPut, data["method"])
+
+			apiResponse := request(http.MethodPut, relative(data["url"].(string)), nil, map[string]string{
+				"Content-Type": "text/plain",
+			}, []byte("hello world"))
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			apiResponse = request(http.MethodPost, "/v1/files/presigned-uploads/"+uploadID+"/complete", fixture.UserOne, nil, nil)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			file := decode(apiResponse)
+			assert.Equal(t, fixture.UserOne.ID.String(), file["uploaded_by"])
+			assert.Equal(t, float64(11), file["file_size"])
+
+			apiResponse = request(http.MethodGet, relative(file["file_url"].(string)), nil, nil, nil)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			content, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+			assert.Equal(t, "hello world", string(content))
+
+			os.Remove(filepath.Join(config.StorageLocalPath, file["file_path"].(string)))
+		})
+
+		t.Run("should return 400 error if the extension is not allowed", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			body, err := json.Marshal(validation.CreatePresignedUpload{
+				FileName: "script.sh", ContentType: "text/plain", Size: 10,
+			})
+			assert.Nil(t, err)
+
+			apiResponse := request(http.MethodPost, "/v1/files/presigned-uploads", fixture.UserOne, nil, body)
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 400 error if the content type does not match the extension", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			body, err := json.Marshal(validation.CreatePresignedUpload{
+				FileName: "notes.txt", ContentType: "image/png", Size: 10,
+			})
+			assert.Nil(t, err)
+
+			apiResponse := request(http.MethodPost, "/v1/files/presigned-uploads", fixture.UserOne, nil, body)
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("POST /v1/files/presigned-uploads/:uploadId/complete", func(t *testing.T) {
+		t.Run("should return 409 error if the file was not uploaded", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			uploadID, _ := createUpload(fixture.UserOne, "hello world")
+
+			apiResponse := request(http.MethodPost, "/v1/files/presigned-uploads/"+uploadID+"/complete", fixture.UserOne, nil, nil)
+			assert.Equal(t, http.StatusConflict, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 404 error if the upload belongs to another user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+			uploadID, _ := createUpload(fixture.UserTwo, "hello world")
+
+			apiResponse := request(http.MethodPost, "/v1/files/presigned-uploads/"+uploadID+"/complete", fixture.UserOne, nil, nil)
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("PUT /v1/files/signed/*", func(t *testing.T) {
+		t.Run("should accept a file larger than the default body limit of fiber", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			content := strings.Repeat("a", 5<<20)
+			uploadID, data := createUpload(fixture.UserOne, content)
+
+			apiResponse := request(http.MethodPut, relative(data["url"].(string)), nil, map[string]string{
+				"Content-Type": "text/plain",
+			}, []byte(content))
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			apiResponse = request(http.MethodPost, "/v1/files/presigned-uploads/"+uploadID+"/complete", fixture.UserOne, nil, nil)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			file := decode(apiResponse)
+			assert.Equal(t, float64(len(content)), file["file_size"])
+			os.Remove(filepath.Join(config.StorageLocalPath, file["file_path"].(string)))
+		})
+
+		t.Run("should return 403 error if the content type is not the signed one", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			_, data := createUpload(fixture.UserOne, "hello world")
+
+			apiResponse := request(http.MethodPut, relative(data["url"].(string)), nil, map[string]string{
+				"Content-Type": "text/html",
+			}, []byte("hello world"))
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 400 error if the path is not inside the storage", func(t *testing.T) {
+			for _, target := range []string{
+				"/v1/files/signed/..%2F..%2Fsecret.txt",
+				"/v1/files/signed/general%2F..%2F..%2Fsecret.txt",
+				"/v1/files/signed/%2Fetc%2Fpasswd",
+			} {
+				apiResponse := request(http.MethodPut, target+"?expires=9999999999&signature=invalid", nil, map[string]string{
+					"Content-Type": "text/plain",
+				}, []byte("hello world"))
+				assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode, target)
+			}
+		})
+	})
+
+	t.Run("GET /v1/files/:fileId/download-url", func(t *testing.T) {
+		t.Run("should return 404 error if the file belongs to another user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+			file := helper.InsertFile(test.DB, &fixture.UserTwo.ID, "general/two.txt")
+
+			apiResponse := request(http.MethodGet, "/v1/files/"+file.ID.String()+"/download-url", fixture.UserOne, nil, nil)
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+	})
+}
@@ -0,0 +1,381 @@
+package integration
+
+import (
+	"app/src/model"
+	"app/src/response"
+	"app/src/validation"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleRoutes(t *testing.T) {
+	request := func(method, target string, body interface{}, accessToken string) *http.Response {
+		var reader io.Reader
+		if body != nil {
+			bodyJSON, err := json.Marshal(body)
+			assert.Nil(t, err)
+			reader = strings.NewReader(string(bodyJSON))
+		}
+
+		request := httptest.NewRequest(method, target, reader)
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Accept", "application/json")
+		if accessToken != "" {
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+		}
+
+		apiResponse, err := test.App.Test(request)
+		assert.Nil(t, err)
+
+		return apiResponse
+	}
+
+	decodeRole := func(apiResponse *http.Response) *response.SuccessWithRole {
+		bytes, err := io.ReadAll(apiResponse.Body)
+		assert.Nil(t, err)
+
+		responseBody := new(response.SuccessWithRole)
+		assert.Nil(t, json.Unmarshal(bytes, responseBody))
+
+		return responseBody
+	}
+
+	t.Run("GET /v1/roles", func(t *testing.T) {
+		t.Run("should return 200 and the default roles with their permissions", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			apiResponse := request(http.MethodGet, "/v1/roles", nil, adminAccessToken)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.SuccessWithRoles)
+			assert.Nil(t, json.Unmarshal(bytes, responseBody))
+
+			rights := make(map[string][]string)
+			for _, role := range responseBody.Roles {
+				rights[role.Name] = role.Rights()
+			}
+
+			assert.ElementsMatch(t, []string{"getUsers", "manageUsers", "getRoles", "manageRoles", "manageFiles", "impersonateUsers", "manageOAuthClients"}, rights["admin"])
+			assert.Contains(t, rights, "user")
+			assert.Empty(t, rights["user"])
+		})
+
+		t.Run("should return 401 error if access token is missing", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+
+			apiResponse := request(http.MethodGet, "/v1/roles", nil, "")
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 403 error if logged in user is not admin", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			userOneAccessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			apiResponse := request(http.MethodGet, "/v1/roles", nil, userOneAccessToken)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("GET /v1/roles/permissions", func(t *testing.T) {
+		t.Run("should return 200 and every permission", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			apiResponse := request(http.MethodGet, "/v1/roles/permissions", nil, adminAccessToken)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.SuccessWithPermissions)
+			assert.Nil(t, json.Unmarshal(bytes, responseBody))
+
+			names := make([]string, 0, len(responseBody.Permissions))
+			for _, permission := range responseBody.Permissions {
+				names = append(names, permission.Name)
+			}
+
+			assert.ElementsMatch(t, []string{"getUsers", "manageUsers", "getRoles", "manageRoles", "manageFiles", "impersonateUsers", "manageOAuthClients"}, names)
+		})
+	})
+
+	t.Run("POST /v1/roles", func(t *testing.T) {
+		newRole := validation.CreateRole{
+			Name:        "moderator",
+			Description: "Reads users",
+			Permissions: []string{"getUsers"},
+		}
+
+		t.Run("should return 201 and create the role with its permissions", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			apiResponse := request(http.MethodPost, "/v1/roles", newRole, adminAccessToken)
+			assert.Equal(t, http.StatusCreated, apiResponse.StatusCode)
+
+			responseBody := decodeRole(apiResponse)
+			assert.Equal(t, newRole.Name, responseBody.Role.Name)
+			assert.Equal(t, newRole.Description, responseBody.Role.Description)
+			assert.Equal(t, []string{"getUsers"}, responseBody.Role.Rights())
+
+			role, err := helper.GetRoleByName(test.DB, newRole.Name)
+			assert.Nil(t, err)
+			assert.Equal(t, responseBody.Role.ID, role.ID)
+		})
+
+		t.Run("should return 409 error if the role already exists", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+			helper.InsertRole(test.DB, newRole.Name)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			apiResponse := request(http.MethodPost, "/v1/roles", newRole, adminAccessToken)
+			assert.Equal(t, http.StatusConflict, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 400 error if a permission is unknown", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			apiResponse := request(http.MethodPost, "/v1/roles", validation.CreateRole{
+				Name:        newRole.Name,
+				Permissions: []string{"getUsers", "launchRockets"},
+			}, adminAccessToken)
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+
+			_, err = helper.GetRoleByName(test.DB, newRole.Name)
+			assert.NotNil(t, err)
+		})
+
+		t.Run("should return 403 error if logged in user is not admin", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			userOneAccessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			apiResponse := request(http.MethodPost, "/v1/roles", newRole, userOneAccessToken)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("GET /v1/roles/:roleId", func(t *testing.T) {
+		t.Run("should return 200 and the role", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+			role := helper.InsertRole(test.DB, "moderator", "getUsers")
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			apiResponse := request(http.MethodGet, "/v1/roles/"+role.ID.String(), nil, adminAccessToken)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			responseBody := decodeRole(apiResponse)
+			assert.Equal(t, "moderator", responseBody.Role.Name)
+			assert.Equal(t, []string{"getUsers"}, responseBody.Role.Rights())
+		})
+
+		t.Run("should return 400 error if roleId is not a valid id", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			apiResponse := request(http.MethodGet, "/v1/roles/invalidId", nil, adminAccessToken)
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 404 error if role is not found", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			apiResponse := request(http.MethodGet, "/v1/roles/"+uuid.NewString(), nil, adminAccessToken)
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("PATCH /v1/roles/:roleId", func(t *testing.T) {
+		t.Run("should return 200 and replace the permissions of the role", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+			role := helper.InsertRole(test.DB, "moderator", "getUsers")
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			description := "Reads roles"
+			permissions := []string{"getRoles"}
+
+			apiResponse := request(http.MethodPatch, "/v1/roles/"+role.ID.String(), validation.UpdateRole{
+				Description: &description,
+				Permissions: &permissions,
+			}, adminAccessToken)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			responseBody := decodeRole(apiResponse)
+			assert.Equal(t, description, responseBody.Role.Description)
+			assert.Equal(t, permissions, responseBody.Role.Rights())
+
+			dbRole, err := helper.GetRoleByName(test.DB, "moderator")
+			assert.Nil(t, err)
+			assert.Equal(t, permissions, dbRole.Rights())
+		})
+
+		t.Run("should grant the new rights to the users of the role right away", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.Admin)
+
+			userRole, err := helper.GetRoleByName(test.DB, "user")
+			assert.Nil(t, err)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			userOneAccessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			// Caches the rights of the user role before they change
+			apiResponse := request(http.MethodGet, "/v1/users/paginated", nil, userOneAccessToken)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+
+			permissions := []string{"getUsers"}
+			apiResponse = request(http.MethodPatch, "/v1/roles/"+userRole.ID.String(),
+				validation.UpdateRole{Permissions: &permissions}, adminAccessToken)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			defer func() {
+				permissions := []string{}
+				apiResponse := request(http.MethodPatch, "/v1/roles/"+userRole.ID.String(),
+					validation.UpdateRole{Permissions: &permissions}, adminAccessToken)
+				assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			}()
+
+			apiResponse = request(http.MethodGet, "/v1/users/paginated", nil, userOneAccessToken)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 400 error if a permission is unknown", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+			role := helper.InsertRole(test.DB, "moderator", "getUsers")
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			permissions := []string{"launchRockets"}
+			apiResponse := request(http.MethodPatch, "/v1/roles/"+role.ID.String(),
+				validation.UpdateRole{Permissions: &permissions}, adminAccessToken)
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+
+			dbRole, err := helper.GetRoleByName(test.DB, "moderator")
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"getUsers"}, dbRole.Rights())
+		})
+
+		t.Run("should return 404 error if role is not found", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			description := "Missing"
+			apiResponse := request(http.MethodPatch, "/v1/roles/"+uuid.NewString(),
+				validation.UpdateRole{Description: &description}, adminAccessToken)
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("DELETE /v1/roles/:roleId", func(t *testing.T) {
+		t.Run("should return 200 and delete the role", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+			role := helper.InsertRole(test.DB, "moderator", "getUsers")
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			apiResponse := request(http.MethodDelete, "/v1/roles/"+role.ID.String(), nil, adminAccessToken)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			_, err = helper.GetRoleByName(test.DB, "moderator")
+			assert.NotNil(t, err)
+		})
+
+		t.Run("should return 409 error if the role is still assigned to users", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			role := helper.InsertRole(test.DB, "moderator", "getUsers")
+			helper.InsertUser(test.DB, fixture.Admin, &model.User{
+				Name: "Moderator", Email: "moderator@gmail.com", Password: "password1", Role: role.Name,
+			})
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			apiResponse := request(http.MethodDelete, "/v1/roles/"+role.ID.String(), nil, adminAccessToken)
+			assert.Equal(t, http.StatusConflict, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 400 error if the role is the default role", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			userRole, err := helper.GetRoleByName(test.DB, "user")
+			assert.Nil(t, err)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			apiResponse := request(http.MethodDelete, "/v1/roles/"+userRole.ID.String(), nil, adminAccessToken)
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 403 error if logged in user is not admin", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			role := helper.InsertRole(test.DB, "moderator")
+
+			userOneAccessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			apiResponse := request(http.MethodDelete, "/v1/roles/"+role.ID.String(), nil, userOneAccessToken)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+	})
+}
@@ -0,0 +1,185 @@
+package integration
+
+import (
+	"app/src/config"
+	"app/src/response"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionRoutes(t *testing.T) {
+	t.Run("GET /v1/auth/sessions", func(t *testing.T) {
+		t.Run("should return 200 and the active sessions with the current one flagged", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+
+			currentSessionID, err := helper.InsertSession(test.DB, fixture.UserOne.ID.String(), "Laptop", fixture.ExpiresRefreshToken)
+			assert.Nil(t, err)
+			_, err = helper.InsertSession(test.DB, fixture.UserOne.ID.String(), "Phone", fixture.ExpiresRefreshToken)
+			assert.Nil(t, err)
+			_, err = helper.InsertSession(test.DB, fixture.UserTwo.ID.String(), "Tablet", fixture.ExpiresRefreshToken)
+			assert.Nil(t, err)
+
+			accessToken, err := helper.GenerateSessionAccessToken(fixture.UserOne.ID.String(), currentSessionID, fixture.ExpiresAccessToken)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodGet, "/v1/auth/sessions", nil)
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.SuccessWithSessions)
+			err = json.Unmarshal(bytes, responseBody)
+			assert.Nil(t, err)
+
+			assert.Len(t, responseBody.Sessions, 2)
+			for _, session := range responseBody.Sessions {
+				assert.Equal(t, session.ID == currentSessionID, session.Current)
+			}
+		})
+
+		t.Run("should return 401 error if access token is missing", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+
+			request := httptest.NewRequest(http.MethodGet, "/v1/auth/sessions", nil)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("DELETE /v1/auth/sessions", func(t *testing.T) {
+		t.Run("should return 200 and revoke every session except the current one", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			currentSessionID, err := helper.InsertSession(test.DB, fixture.UserOne.ID.String(), "Laptop", fixture.ExpiresRefreshToken)
+			assert.Nil(t, err)
+			_, err = helper.InsertSession(test.DB, fixture.UserOne.ID.String(), "Phone", fixture.ExpiresRefreshToken)
+			assert.Nil(t, err)
+
+			accessToken, err := helper.GenerateSessionAccessToken(fixture.UserOne.ID.String(), currentSessionID, fixture.ExpiresAccessToken)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodDelete, "/v1/auth/sessions", nil)
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, int64(1), helper.CountTokens(test.DB, fixture.UserOne.ID.String(), config.TokenTypeRefresh))
+		})
+
+		t.Run("should return 400 error if the access token has no session", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodDelete, "/v1/auth/sessions", nil)
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("DELETE /v1/auth/sessions/:sessionId", func(t *testing.T) {
+		t.Run("should return 200 and revoke the session", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			sessionID, err := helper.InsertSession(test.DB, fixture.UserOne.ID.String(), "Phone", fixture.ExpiresRefreshToken)
+			assert.Nil(t, err)
+
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodDelete, "/v1/auth/sessions/"+sessionID.String(), nil)
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, int64(0), helper.CountTokens(test.DB, fixture.UserOne.ID.String(), config.TokenTypeRefresh))
+		})
+
+		t.Run("should return 404 error if the session belongs to another user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+
+			sessionID, err := helper.InsertSession(test.DB, fixture.UserTwo.ID.String(), "Phone", fixture.ExpiresRefreshToken)
+			assert.Nil(t, err)
+
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodDelete, "/v1/auth/sessions/"+sessionID.String(), nil)
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+			assert.Equal(t, int64(1), helper.CountTokens(test.DB, fixture.UserTwo.ID.String(), config.TokenTypeRefresh))
+		})
+	})
+
+	t.Run("GET /v1/users/:userId/sessions", func(t *testing.T) {
+		t.Run("should return 200 if admin lists the sessions of another user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.Admin)
+
+			_, err := helper.InsertSession(test.DB, fixture.UserOne.ID.String(), "Phone", fixture.ExpiresRefreshToken)
+			assert.Nil(t, err)
+
+			accessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodGet, "/v1/users/"+fixture.UserOne.ID.String()+"/sessions", nil)
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.SuccessWithSessions)
+			err = json.Unmarshal(bytes, responseBody)
+			assert.Nil(t, err)
+			assert.Len(t, responseBody.Sessions, 1)
+		})
+
+		t.Run("should return 403 error if a user lists the sessions of another user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodGet, "/v1/users/"+fixture.UserTwo.ID.String()+"/sessions", nil)
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+	})
+}
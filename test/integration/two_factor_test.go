@@ -0,0 +1,310 @@
+package integration
+
+import (
+	"app/src/config"
+	"app/src/response"
+	"app/src/utils"
+	"app/src/validation"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTwoFactorRoutes(t *testing.T) {
+	t.Run("POST /v1/auth/2fa/enroll", func(t *testing.T) {
+		t.Run("should return 200 with a secret and recovery codes", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "/v1/auth/2fa/enroll", nil)
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.SuccessWithTwoFactorSetup)
+
+			err = json.Unmarshal(bytes, responseBody)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.NotEmpty(t, responseBody.TwoFactor.Secret)
+			assert.Contains(t, responseBody.TwoFactor.ProvisioningURI, "otpauth://totp/")
+			assert.Len(t, responseBody.TwoFactor.RecoveryCodes, 10)
+
+			user, err := helper.GetUserByID(test.DB, fixture.UserOne.ID.String())
+			assert.Nil(t, err)
+			assert.Equal(t, responseBody.TwoFactor.Secret, user.TwoFactorSecret)
+			assert.False(t, user.TwoFactorEnabled)
+		})
+
+		t.Run("should return 409 if two-factor authentication is already enabled", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			secret, err := utils.GenerateTOTPSecret()
+			assert.Nil(t, err)
+			helper.EnableTwoFactor(test.DB, fixture.UserOne.ID.String(), secret)
+
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "/v1/auth/2fa/enroll", nil)
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusConflict, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("POST /v1/auth/2fa/disable", func(t *testing.T) {
+		// setup enables two-factor authentication for a user without a password
+		setup := func(t *testing.T) string {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			helper.RemovePassword(test.DB, fixture.UserOne.ID.String())
+
+			secret, err := utils.GenerateTOTPSecret()
+			assert.Nil(t, err)
+			helper.EnableTwoFactor(test.DB, fixture.UserOne.ID.String(), secret)
+
+			return secret
+		}
+
+		disable := func(t *testing.T, body *validation.DisableTwoFactor) *http.Response {
+			bodyJSON, err := json.Marshal(body)
+			assert.Nil(t, err)
+
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "/v1/auth/2fa/disable", strings.NewReader(string(bodyJSON)))
+			request.Header.Set("Content-Type", "application/json")
+			request.Header.Set("Accept", "application/json")
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			return apiResponse
+		}
+
+		t.Run("should return 200 if a user without a password sends a valid code", func(t *testing.T) {
+			secret := setup(t)
+
+			code, err := utils.GenerateTOTPCode(secret, time.Now())
+			assert.Nil(t, err)
+
+			apiResponse := disable(t, &validation.DisableTwoFactor{Code: code})
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			user, err := helper.GetUserByID(test.DB, fixture.UserOne.ID.String())
+			assert.Nil(t, err)
+			assert.False(t, user.TwoFactorEnabled)
+			assert.Empty(t, user.TwoFactorSecret)
+		})
+
+		t.Run("should return 200 if a user without a password sends a recovery code", func(t *testing.T) {
+			setup(t)
+			helper.InsertRecoveryCode(test.DB, fixture.UserOne.ID.String(), "abcdefgh-ijklmnop")
+
+			apiResponse := disable(t, &validation.DisableTwoFactor{RecoveryCode: "abcdefgh-ijklmnop"})
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 401 if a user without a password sends an invalid code", func(t *testing.T) {
+			setup(t)
+
+			apiResponse := disable(t, &validation.DisableTwoFactor{Code: "000000"})
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 400 if a user without a password sends neither code nor recovery code", func(t *testing.T) {
+			setup(t)
+
+			apiResponse := disable(t, &validation.DisableTwoFactor{Password: "password1"})
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("POST /v1/auth/login", func(t *testing.T) {
+		t.Run("should return an mfa token instead of auth tokens if two-factor authentication is enabled", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.CreateUser(test.DB, "test@gmail.com", "test1234", "Test User")
+
+			user := new(struct{ ID string })
+			assert.Nil(t, test.DB.Table("users").Select("id").Where("email = ?", "test@gmail.com").Scan(user).Error)
+
+			secret, err := utils.GenerateTOTPSecret()
+			assert.Nil(t, err)
+			helper.EnableTwoFactor(test.DB, user.ID, secret)
+
+			bodyJSON, err := json.Marshal(&validation.Login{Email: "test@gmail.com", Password: "test1234"})
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "/v1/auth/login", strings.NewReader(string(bodyJSON)))
+			request.Header.Set("Content-Type", "application/json")
+			request.Header.Set("Accept", "application/json")
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.MFARequired)
+
+			err = json.Unmarshal(bytes, responseBody)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.NotEmpty(t, responseBody.MFAToken.Token)
+			assert.NotContains(t, string(bytes), "refresh")
+		})
+	})
+
+	t.Run("POST /v1/auth/2fa/verify", func(t *testing.T) {
+		setup := func(t *testing.T) (string, string) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			secret, err := utils.GenerateTOTPSecret()
+			assert.Nil(t, err)
+			helper.EnableTwoFactor(test.DB, fixture.UserOne.ID.String(), secret)
+
+			expires := time.Now().UTC().Add(5 * time.Minute)
+			mfaToken, err := helper.GenerateToken(fixture.UserOne.ID.String(), expires, config.TokenTypeMFAPending)
+			assert.Nil(t, err)
+
+			err = helper.SaveToken(test.DB, mfaToken, fixture.UserOne.ID.String(), config.TokenTypeMFAPending, expires)
+			assert.Nil(t, err)
+
+			return secret, mfaToken
+		}
+
+		verify := func(t *testing.T, body *validation.VerifyTwoFactor) *http.Response {
+			bodyJSON, err := json.Marshal(body)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "/v1/auth/2fa/verify", strings.NewReader(string(bodyJSON)))
+			request.Header.Set("Content-Type", "application/json")
+			request.Header.Set("Accept", "application/json")
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			return apiResponse
+		}
+
+		t.Run("should return 200 and auth tokens if the code is valid", func(t *testing.T) {
+			secret, mfaToken := setup(t)
+
+			code, err := utils.GenerateTOTPCode(secret, time.Now())
+			assert.Nil(t, err)
+
+			apiResponse := verify(t, &validation.VerifyTwoFactor{MFAToken: mfaToken, Code: code})
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.SuccessWithTokens)
+
+			err = json.Unmarshal(bytes, responseBody)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.NotEmpty(t, responseBody.Tokens.Access.Token)
+			assert.NotEmpty(t, responseBody.Tokens.Refresh.Token)
+
+			_, err = helper.GetTokenByType(test.DB, fixture.UserOne.ID.String(), config.TokenTypeMFAPending)
+			assert.NotNil(t, err)
+		})
+
+		t.Run("should return 401 if the code is invalid", func(t *testing.T) {
+			_, mfaToken := setup(t)
+
+			apiResponse := verify(t, &validation.VerifyTwoFactor{MFAToken: mfaToken, Code: "000000"})
+
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 401 if the code was already used", func(t *testing.T) {
+			secret, mfaToken := setup(t)
+
+			code, err := utils.GenerateTOTPCode(secret, time.Now())
+			assert.Nil(t, err)
+
+			apiResponse := verify(t, &validation.VerifyTwoFactor{MFAToken: mfaToken, Code: code})
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			expires := time.Now().UTC().Add(5 * time.Minute)
+			mfaToken, err = helper.GenerateToken(fixture.UserOne.ID.String(), expires, config.TokenTypeMFAPending)
+			assert.Nil(t, err)
+			err = helper.SaveToken(test.DB, mfaToken, fixture.UserOne.ID.String(), config.TokenTypeMFAPending, expires)
+			assert.Nil(t, err)
+
+			apiResponse = verify(t, &validation.VerifyTwoFactor{MFAToken: mfaToken, Code: code})
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 401 and delete the mfa token after too many invalid codes", func(t *testing.T) {
+			secret, mfaToken := setup(t)
+
+			for range 5 {
+				apiResponse := verify(t, &validation.VerifyTwoFactor{MFAToken: mfaToken, Code: "000000"})
+				assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+			}
+
+			code, err := utils.GenerateTOTPCode(secret, time.Now())
+			assert.Nil(t, err)
+
+			apiResponse := verify(t, &validation.VerifyTwoFactor{MFAToken: mfaToken, Code: code})
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+
+			_, err = helper.GetTokenByType(test.DB, fixture.UserOne.ID.String(), config.TokenTypeMFAPending)
+			assert.NotNil(t, err)
+		})
+
+		t.Run("should accept a recovery code only once", func(t *testing.T) {
+			_, mfaToken := setup(t)
+			helper.InsertRecoveryCode(test.DB, fixture.UserOne.ID.String(), "abcdefgh-ijklmnop")
+
+			apiResponse := verify(t, &validation.VerifyTwoFactor{MFAToken: mfaToken, RecoveryCode: "abcdefgh-ijklmnop"})
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			expires := time.Now().UTC().Add(5 * time.Minute)
+			mfaToken, err := helper.GenerateToken(fixture.UserOne.ID.String(), expires, config.TokenTypeMFAPending)
+			assert.Nil(t, err)
+			err = helper.SaveToken(test.DB, mfaToken, fixture.UserOne.ID.String(), config.TokenTypeMFAPending, expires)
+			assert.Nil(t, err)
+
+			apiResponse = verify(t, &validation.VerifyTwoFactor{MFAToken: mfaToken, RecoveryCode: "abcdefgh-ijklmnop"})
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 400 if neither code nor recovery code is provided", func(t *testing.T) {
+			_, mfaToken := setup(t)
+
+			apiResponse := verify(t, &validation.VerifyTwoFactor{MFAToken: mfaToken})
+
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+	})
+}
@@ -0,0 +1,287 @@
+package integration
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadRoutes(t *testing.T) {
+	request := func(method, target string, user *model.User, headers map[string]string, body []byte) *http.Response {
+		request := httptest.NewRequest(method, target, bytes.NewReader(body))
+		request.Header.Set("Tus-Resumable", "1.0.0")
+
+		if user != nil {
+			accessToken, err := fixture.AccessToken(user)
+			assert.Nil(t, err)
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+		}
+
+		for key, value := range headers {
+			request.Header.Set(key, value)
+		}
+
+		apiResponse, err := test.App.Test(request)
+		assert.Nil(t, err)
+
+		return apiResponse
+	}
+
+	metadata := func(fileName string) string {
+		return "filename " + base64.StdEncoding.EncodeToString([]byte(fileName)) +
+			",filetype " + base64.StdEncoding.EncodeToString([]byte("text/plain"))
+	}
+
+	// createUpload membuat upload milik user dan mengembalikan path dari header Location
+	createUpload := func(user *model.User, size int) string {
+		apiResponse := request(http.MethodPost, "/v1/files/uploads", user, map[string]string{
+			"Upload-Length":   strconv.Itoa(size),
+			"Upload-Metadata": metadata("notes.txt"),
+		}, nil)
+		assert.Equal(t, http.StatusCreated, apiResponse.StatusCode)
+		assert.Equal(t, "1.0.0", apiResponse.Header.Get("Tus-Resumable"))
+		assert.NotEmpty(t, apiResponse.Header.Get("Upload-Expires"))
+
+		location := apiResponse.Header.Get("Location")
+		return location[strings.Index(location, "/v1/"):]
+	}
+
+	patch := func(target string, user *model.User, offset int, chunk string) *http.Response {
+		return request(http.MethodPatch, target, user, map[string]string{
+			"Content-Type":  "application/offset+octet-stream",
+			"Upload-Offset": strconv.Itoa(offset),
+		}, []byte(chunk))
+	}
+
+	t.Run("OPTIONS /v1/files/uploads", func(t *testing.T) {
+		t.Run("should return 204 and the tus capabilities", func(t *testing.T) {
+			apiResponse := request(http.MethodOptions, "/v1/files/uploads", nil, nil, nil)
+			assert.Equal(t, http.StatusNoContent, apiResponse.StatusCode)
+			assert.Equal(t, "1.0.0", apiResponse.Header.Get("Tus-Version"))
+			assert.Contains(t, apiResponse.Header.Get("Tus-Extension"), "termination")
+			assert.Equal(t, strconv.FormatInt(config.StorageMaxFileSize, 10), apiResponse.Header.Get("Tus-Max-Size"))
+		})
+	})
+
+	t.Run("POST /v1/files/uploads", func(t *testing.T) {
+		t.Run("should return 412 error if Tus-Resumable is missing", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			apiResponse := request(http.MethodPost, "/v1/files/uploads", fixture.UserOne, map[string]string{
+				"Tus-Resumable":   "",
+				"Upload-Length":   "10",
+				"Upload-Metadata": metadata("notes.txt"),
+			}, nil)
+			assert.Equal(t, http.StatusPreconditionFailed, apiResponse.StatusCode)
+			assert.Equal(t, "1.0.0", apiResponse.Header.Get("Tus-Version"))
+		})
+
+		t.Run("should return 400 error if the extension is not allowed", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			apiResponse := request(http.MethodPost, "/v1/files/uploads", fixture.UserOne, map[string]string{
+				"Upload-Length":   "10",
+				"Upload-Metadata": metadata("script.sh"),
+			}, nil)
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 400 error if the folder is not a clean relative path", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			for _, folder := range []string{"general/../avatars", "/avatars", "avatars//2024", "avatars/"} {
+				apiResponse := request(http.MethodPost, "/v1/files/uploads", fixture.UserOne, map[string]string{
+					"Upload-Length":   "10",
+					"Upload-Metadata": metadata("notes.txt") + ",folder " + base64.StdEncoding.EncodeToString([]byte(folder)),
+				}, nil)
+				assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode, folder)
+			}
+		})
+
+		t.Run("should return 413 error if the file is too large", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			apiResponse := request(http.MethodPost, "/v1/files/uploads", fixture.UserOne, map[string]string{
+				"Upload-Length":   strconv.FormatInt(config.StorageMaxFileSize+1, 10),
+				"Upload-Metadata": metadata("notes.txt"),
+			}, nil)
+			assert.Equal(t, http.StatusRequestEntityTooLarge, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 409 error if the folder already has the maximum number of files", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			helper.InsertFile(test.DB, &fixture.UserOne.ID, "general/notes.txt")
+
+			folderPolicies := config.StorageFolderPolicies
+			policy := config.StorageDefaultPolicy
+			policy.Folder, policy.MaxCount = "general", 1
+			config.StorageFolderPolicies = map[string]config.FilePolicy{"general": policy}
+			defer func() { config.StorageFolderPolicies = folderPolicies }()
+
+			apiResponse := request(http.MethodPost, "/v1/files/uploads", fixture.UserOne, map[string]string{
+				"Upload-Length":   "10",
+				"Upload-Metadata": metadata("notes.txt"),
+			}, nil)
+			assert.Equal(t, http.StatusConflict, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 401 error if access token is missing", func(t *testing.T) {
+			apiResponse := request(http.MethodPost, "/v1/files/uploads", nil, map[string]string{
+				"Upload-Length":   "10",
+				"Upload-Metadata": metadata("notes.txt"),
+			}, nil)
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("PATCH /v1/files/uploads/:uploadId", func(t *testing.T) {
+		t.Run("should accept chunks and create the file after the last one", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			target := createUpload(fixture.UserOne, 11)
+
+			apiResponse := patch(target, fixture.UserOne, 0, "hello ")
+			assert.Equal(t, http.StatusNoContent, apiResponse.StatusCode)
+			assert.Equal(t, "6", apiResponse.Header.Get("Upload-Offset"))
+
+			apiResponse = request(http.MethodHead, target, fixture.UserOne, nil, nil)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, "6", apiResponse.Header.Get("Upload-Offset"))
+			assert.Equal(t, "11", apiResponse.Header.Get("Upload-Length"))
+			assert.Equal(t, "no-store", apiResponse.Header.Get("Cache-Control"))
+
+			apiResponse = patch(target, fixture.UserOne, 6, "world")
+			assert.Equal(t, http.StatusNoContent, apiResponse.StatusCode)
+			assert.Equal(t, "11", apiResponse.Header.Get("Upload-Offset"))
+
+			file, err := helper.GetFileByID(test.DB, apiResponse.Header.Get("Upload-File-Id"))
+			assert.Nil(t, err)
+			assert.Equal(t, fixture.UserOne.ID, *file.UploadedBy)
+			assert.Equal(t, int64(11), file.FileSize)
+			assert.Equal(t, "general", file.Folder)
+
+			fullPath := filepath.Join(config.StorageLocalPath, file.FilePath)
+			content, err := os.ReadFile(fullPath)
+			assert.Nil(t, err)
+			assert.Equal(t, "hello world", string(content))
+			os.Remove(fullPath)
+
+			_, err = os.Stat(filepath.Join(config.StorageUploadTempPath, filepath.Base(target)))
+			assert.True(t, os.IsNotExist(err))
+		})
+
+		t.Run("should accept a chunk larger than the default body limit of fiber", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			size := 5 << 20
+			target := createUpload(fixture.UserOne, size)
+
+			apiResponse := patch(target, fixture.UserOne, 0, strings.Repeat("a", size))
+			assert.Equal(t, http.StatusNoContent, apiResponse.StatusCode)
+			assert.Equal(t, strconv.Itoa(size), apiResponse.Header.Get("Upload-Offset"))
+
+			file, err := helper.GetFileByID(test.DB, apiResponse.Header.Get("Upload-File-Id"))
+			assert.Nil(t, err)
+			assert.Equal(t, int64(size), file.FileSize)
+			os.Remove(filepath.Join(config.StorageLocalPath, file.FilePath))
+		})
+
+		t.Run("should return 409 error if the offset does not match", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			target := createUpload(fixture.UserOne, 11)
+
+			apiResponse := patch(target, fixture.UserOne, 3, "hello")
+			assert.Equal(t, http.StatusConflict, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 415 error if the content type is wrong", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			target := createUpload(fixture.UserOne, 11)
+
+			apiResponse := request(http.MethodPatch, target, fixture.UserOne, map[string]string{
+				"Content-Type":  "application/octet-stream",
+				"Upload-Offset": "0",
+			}, []byte("hello"))
+			assert.Equal(t, http.StatusUnsupportedMediaType, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 415 error and remove the upload if the content does not match the extension",
+			func(t *testing.T) {
+				helper.ClearAll(test.DB)
+				helper.InsertUser(test.DB, fixture.UserOne)
+				target := createUpload(fixture.UserOne, 11)
+
+				apiResponse := patch(target, fixture.UserOne, 0, "\x89PNG\r\n\x1a\n\x00\x00\x00")
+				assert.Equal(t, http.StatusUnsupportedMediaType, apiResponse.StatusCode)
+
+				_, err := helper.GetUploadByID(test.DB, filepath.Base(target))
+				assert.NotNil(t, err)
+
+				var count int64
+				test.DB.Model(&model.File{}).Count(&count)
+				assert.Equal(t, int64(0), count)
+			})
+
+		t.Run("should return 404 error if the upload belongs to another user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+			target := createUpload(fixture.UserTwo, 11)
+
+			apiResponse := patch(target, fixture.UserOne, 0, "hello")
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 410 error if the upload has expired", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			target := createUpload(fixture.UserOne, 11)
+
+			err := test.DB.Model(&model.Upload{}).Where("id is not null").
+				Update("expires_at", "2000-01-01").Error
+			assert.Nil(t, err)
+
+			apiResponse := patch(target, fixture.UserOne, 0, "hello")
+			assert.Equal(t, http.StatusGone, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("DELETE /v1/files/uploads/:uploadId", func(t *testing.T) {
+		t.Run("should return 204 and remove the upload", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			target := createUpload(fixture.UserOne, 11)
+
+			apiResponse := patch(target, fixture.UserOne, 0, "hello ")
+			assert.Equal(t, http.StatusNoContent, apiResponse.StatusCode)
+
+			apiResponse = request(http.MethodDelete, target, fixture.UserOne, nil, nil)
+			assert.Equal(t, http.StatusNoContent, apiResponse.StatusCode)
+
+			_, err := helper.GetUploadByID(test.DB, filepath.Base(target))
+			assert.NotNil(t, err)
+
+			apiResponse = request(http.MethodHead, target, fixture.UserOne, nil, nil)
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+	})
+}
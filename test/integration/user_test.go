@@ -1,6 +1,7 @@
 package integration
 
 import (
+	"app/src/config"
 	"app/src/model"
 	"app/src/response"
 	"app/src/validation"
@@ -13,6 +14,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -22,7 +24,7 @@ func TestUserRoutes(t *testing.T) {
 		var newUser = validation.CreateUser{
 			Name:     "Test",
 			Email:    "test@gmail.com",
-			Password: "password1",
+			Password: "s3cure-fiber",
 			Role:     "user",
 		}
 
@@ -246,7 +248,7 @@ func TestUserRoutes(t *testing.T) {
 			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
 		})
 
-		t.Run("should return 400 error if role is neither user nor admin", func(t *testing.T) {
+		t.Run("should return 400 error if role does not exist", func(t *testing.T) {
 			helper.ClearAll(test.DB)
 			helper.InsertUser(test.DB, fixture.Admin)
 			newUser.Role = "invalid"
@@ -643,7 +645,8 @@ func TestUserRoutes(t *testing.T) {
 			assert.NotContains(t, string(bytes), "password")
 			assert.Equal(t, fixture.UserOne.ID, responseBody.User.ID)
 			assert.Equal(t, updateBody.Name, responseBody.User.Name)
-			assert.Equal(t, updateBody.Email, responseBody.User.Email)
+			assert.Equal(t, fixture.UserOne.Email, responseBody.User.Email)
+			assert.Equal(t, updateBody.Email, *responseBody.User.PendingEmail)
 			assert.Equal(t, "user", responseBody.User.Role)
 			assert.Equal(t, false, responseBody.User.VerifiedEmail)
 
@@ -653,8 +656,13 @@ func TestUserRoutes(t *testing.T) {
 			assert.NotNil(t, user)
 			assert.NotEqual(t, user.Password, updateBody.Password)
 			assert.Equal(t, user.Name, updateBody.Name)
-			assert.Equal(t, user.Email, updateBody.Email)
+			assert.Equal(t, user.Email, fixture.UserOne.Email)
+			assert.Equal(t, *user.PendingEmail, updateBody.Email)
 			assert.Equal(t, user.Role, "user")
+
+			changeEmailTokenDoc, err := helper.GetTokenByType(test.DB, user.ID.String(), config.TokenTypeChangeEmail)
+			assert.Nil(t, err)
+			assert.NotNil(t, changeEmailTokenDoc)
 		})
 
 		t.Run("should return 401 error if access token is missing", func(t *testing.T) {
@@ -908,4 +916,77 @@ func TestUserRoutes(t *testing.T) {
 			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
 		})
 	})
+
+	t.Run("POST /v1/users/:userId/unlock", func(t *testing.T) {
+		t.Run("should return 200 and unlock the user if admin", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.Admin)
+			helper.LockUser(test.DB, fixture.UserOne.ID.String(), time.Now().Add(time.Hour))
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "/v1/users/"+fixture.UserOne.ID.String()+"/unlock", nil)
+			request.Header.Set("Authorization", "Bearer "+adminAccessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			user, err := helper.GetUserByID(test.DB, fixture.UserOne.ID.String())
+			assert.Nil(t, err)
+			assert.Nil(t, user.LockedUntil)
+		})
+
+		t.Run("should return 403 error if user is trying to unlock another user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+			helper.LockUser(test.DB, fixture.UserTwo.ID.String(), time.Now().Add(time.Hour))
+
+			userOneAccessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "/v1/users/"+fixture.UserTwo.ID.String()+"/unlock", nil)
+			request.Header.Set("Authorization", "Bearer "+userOneAccessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 403 error if user is trying to unlock itself", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+			helper.LockUser(test.DB, fixture.UserOne.ID.String(), time.Now().Add(time.Hour))
+
+			userOneAccessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "/v1/users/"+fixture.UserOne.ID.String()+"/unlock", nil)
+			request.Header.Set("Authorization", "Bearer "+userOneAccessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 404 error if user is not found", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.Admin)
+
+			adminAccessToken, err := fixture.AccessToken(fixture.Admin)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "/v1/users/"+fixture.UserOne.ID.String()+"/unlock", nil)
+			request.Header.Set("Authorization", "Bearer "+adminAccessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+	})
 }
@@ -0,0 +1,308 @@
+package integration
+
+import (
+	"app/src/config"
+	"app/src/response"
+	"app/src/validation"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// webAuthnOptions is the part of the begin response a browser reads to answer the ceremony
+type webAuthnOptions struct {
+	SessionID string `json:"session_id"`
+	Options   struct {
+		PublicKey struct {
+			Challenge string `json:"challenge"`
+			User      struct {
+				ID string `json:"id"`
+			} `json:"user"`
+			AllowCredentials []interface{} `json:"allowCredentials"`
+		} `json:"publicKey"`
+	} `json:"options"`
+}
+
+func TestWebAuthnRoutes(t *testing.T) {
+	post := func(path, accessToken string, body interface{}) *http.Response {
+		bodyJSON, err := json.Marshal(body)
+		assert.Nil(t, err)
+
+		request := httptest.NewRequest(http.MethodPost, path, strings.NewReader(string(bodyJSON)))
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Accept", "application/json")
+		if accessToken != "" {
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+		}
+
+		apiResponse, err := test.App.Test(request)
+		assert.Nil(t, err)
+
+		return apiResponse
+	}
+
+	readOptions := func(apiResponse *http.Response) *webAuthnOptions {
+		bytes, err := io.ReadAll(apiResponse.Body)
+		assert.Nil(t, err)
+
+		options := new(webAuthnOptions)
+		assert.Nil(t, json.Unmarshal(bytes, options))
+
+		return options
+	}
+
+	// register runs a whole registration ceremony for the user with a new authenticator
+	register := func(t *testing.T, accessToken string) *helper.MockAuthenticator {
+		apiResponse := post("/v1/auth/webauthn/register/begin", accessToken, nil)
+		assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+		options := readOptions(apiResponse)
+		userHandle, err := base64.RawURLEncoding.DecodeString(options.Options.PublicKey.User.ID)
+		assert.Nil(t, err)
+
+		authenticator := helper.NewMockAuthenticator(config.WebAuthnRPID, config.WebAuthnRPOrigins[0])
+
+		apiResponse = post("/v1/auth/webauthn/register/finish", accessToken, validation.FinishWebAuthnRegistration{
+			SessionID:  options.SessionID,
+			Name:       "Test Passkey",
+			Credential: authenticator.Register(options.Options.PublicKey.Challenge, userHandle),
+		})
+		assert.Equal(t, http.StatusCreated, apiResponse.StatusCode)
+
+		return authenticator
+	}
+
+	beginLogin := func(t *testing.T, email string) *webAuthnOptions {
+		apiResponse := post("/v1/auth/webauthn/login/begin", "", validation.BeginWebAuthnLogin{Email: email})
+		assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+		return readOptions(apiResponse)
+	}
+
+	finishLogin := func(sessionID string, credential json.RawMessage) *http.Response {
+		return post("/v1/auth/webauthn/login/finish", "", validation.FinishWebAuthnLogin{
+			SessionID:  sessionID,
+			Credential: credential,
+		})
+	}
+
+	t.Run("POST /v1/auth/webauthn/register", func(t *testing.T) {
+		t.Run("should return 201 and store the passkey of the user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			authenticator := register(t, accessToken)
+
+			passkeys := helper.GetPasskeys(test.DB, fixture.UserOne.ID.String())
+			assert.Len(t, passkeys, 1)
+			assert.Equal(t, "Test Passkey", passkeys[0].Name)
+			assert.Equal(t, authenticator.CredentialID, passkeys[0].CredentialID)
+			assert.Equal(t, fixture.UserOne.ID[:], authenticator.UserHandle)
+		})
+
+		t.Run("should return 401 if the session is used twice", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			options := readOptions(post("/v1/auth/webauthn/register/begin", accessToken, nil))
+			authenticator := helper.NewMockAuthenticator(config.WebAuthnRPID, config.WebAuthnRPOrigins[0])
+			body := validation.FinishWebAuthnRegistration{
+				SessionID:  options.SessionID,
+				Credential: authenticator.Register(options.Options.PublicKey.Challenge, fixture.UserOne.ID[:]),
+			}
+
+			apiResponse := post("/v1/auth/webauthn/register/finish", accessToken, body)
+			assert.Equal(t, http.StatusCreated, apiResponse.StatusCode)
+
+			apiResponse = post("/v1/auth/webauthn/register/finish", accessToken, body)
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 401 if the registration was started by another user", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne, fixture.UserTwo)
+
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			otherAccessToken, err := fixture.AccessToken(fixture.UserTwo)
+			assert.Nil(t, err)
+
+			options := readOptions(post("/v1/auth/webauthn/register/begin", accessToken, nil))
+			authenticator := helper.NewMockAuthenticator(config.WebAuthnRPID, config.WebAuthnRPOrigins[0])
+
+			apiResponse := post("/v1/auth/webauthn/register/finish", otherAccessToken, validation.FinishWebAuthnRegistration{
+				SessionID:  options.SessionID,
+				Credential: authenticator.Register(options.Options.PublicKey.Challenge, fixture.UserOne.ID[:]),
+			})
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+			assert.Len(t, helper.GetPasskeys(test.DB, fixture.UserTwo.ID.String()), 0)
+		})
+
+		t.Run("should return 400 if the credential answers another challenge", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			options := readOptions(post("/v1/auth/webauthn/register/begin", accessToken, nil))
+			other := readOptions(post("/v1/auth/webauthn/register/begin", accessToken, nil))
+			authenticator := helper.NewMockAuthenticator(config.WebAuthnRPID, config.WebAuthnRPOrigins[0])
+
+			apiResponse := post("/v1/auth/webauthn/register/finish", accessToken, validation.FinishWebAuthnRegistration{
+				SessionID:  options.SessionID,
+				Credential: authenticator.Register(other.Options.PublicKey.Challenge, fixture.UserOne.ID[:]),
+			})
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 401 if access token is missing", func(t *testing.T) {
+			apiResponse := post("/v1/auth/webauthn/register/begin", "", nil)
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("POST /v1/auth/webauthn/login", func(t *testing.T) {
+		t.Run("should return 200 with auth tokens for a passkey of the email", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			authenticator := register(t, accessToken)
+
+			options := beginLogin(t, fixture.UserOne.Email)
+			assert.Len(t, options.Options.PublicKey.AllowCredentials, 1)
+
+			apiResponse := finishLogin(options.SessionID, authenticator.Assert(options.Options.PublicKey.Challenge))
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.SuccessWithTokens)
+			assert.Nil(t, json.Unmarshal(bytes, responseBody))
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, "Login successfully", responseBody.Message)
+			assert.Equal(t, fixture.UserOne.Email, responseBody.User.Email)
+			assert.NotEmpty(t, responseBody.Tokens.Access.Token)
+			assert.NotEmpty(t, responseBody.Tokens.Refresh.Token)
+
+			passkeys := helper.GetPasskeys(test.DB, fixture.UserOne.ID.String())
+			assert.Equal(t, authenticator.SignCount, passkeys[0].SignCount)
+			assert.NotNil(t, passkeys[0].LastUsedAt)
+		})
+
+		t.Run("should return 200 with auth tokens for a discoverable passkey", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			authenticator := register(t, accessToken)
+
+			options := beginLogin(t, "")
+			assert.Len(t, options.Options.PublicKey.AllowCredentials, 0)
+
+			apiResponse := finishLogin(options.SessionID, authenticator.Assert(options.Options.PublicKey.Challenge))
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+		})
+
+		t.Run("should not reveal whether the email has an account", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+
+			options := beginLogin(t, "unknown@example.com")
+			assert.NotEmpty(t, options.SessionID)
+			assert.Len(t, options.Options.PublicKey.AllowCredentials, 0)
+		})
+
+		t.Run("should return 401 if the session is used twice", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			authenticator := register(t, accessToken)
+
+			options := beginLogin(t, fixture.UserOne.Email)
+
+			apiResponse := finishLogin(options.SessionID, authenticator.Assert(options.Options.PublicKey.Challenge))
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			apiResponse = finishLogin(options.SessionID, authenticator.Assert(options.Options.PublicKey.Challenge))
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 401 if the passkey is not registered", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			authenticator := helper.NewMockAuthenticator(config.WebAuthnRPID, config.WebAuthnRPOrigins[0])
+			authenticator.UserHandle = fixture.UserOne.ID[:]
+
+			options := beginLogin(t, "")
+
+			apiResponse := finishLogin(options.SessionID, authenticator.Assert(options.Options.PublicKey.Challenge))
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 401 and disable the passkey if the sign count goes backwards", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.InsertUser(test.DB, fixture.UserOne)
+
+			accessToken, err := fixture.AccessToken(fixture.UserOne)
+			assert.Nil(t, err)
+
+			authenticator := register(t, accessToken)
+
+			for i := 0; i < 2; i++ {
+				options := beginLogin(t, fixture.UserOne.Email)
+				apiResponse := finishLogin(options.SessionID, authenticator.Assert(options.Options.PublicKey.Challenge))
+				assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			}
+
+			// A copy of the key that has only been used once answers with a lower counter
+			authenticator.SignCount = 0
+
+			options := beginLogin(t, fixture.UserOne.Email)
+			apiResponse := finishLogin(options.SessionID, authenticator.Assert(options.Options.PublicKey.Challenge))
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+
+			passkeys := helper.GetPasskeys(test.DB, fixture.UserOne.ID.String())
+			assert.True(t, passkeys[0].CloneWarning)
+
+			// The disabled passkey can not be used anymore, even with a higher counter
+			authenticator.SignCount = 10
+
+			options = beginLogin(t, "")
+			apiResponse = finishLogin(options.SessionID, authenticator.Assert(options.Options.PublicKey.Challenge))
+			assert.Equal(t, http.StatusUnauthorized, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 400 if the credential is missing", func(t *testing.T) {
+			options := beginLogin(t, "")
+
+			apiResponse := post("/v1/auth/webauthn/login/finish", "", validation.FinishWebAuthnLogin{SessionID: options.SessionID})
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+	})
+}
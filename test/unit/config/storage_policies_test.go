@@ -0,0 +1,43 @@
+package config_test
+
+import (
+	"app/src/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFolderPolicy(t *testing.T) {
+	defaultPolicy, folderPolicies := config.StorageDefaultPolicy, config.StorageFolderPolicies
+	t.Cleanup(func() {
+		config.StorageDefaultPolicy, config.StorageFolderPolicies = defaultPolicy, folderPolicies
+	})
+
+	config.StorageDefaultPolicy = config.FilePolicy{AllowedTypes: []string{".pdf"}, MaxSize: 100}
+	config.StorageFolderPolicies = map[string]config.FilePolicy{
+		"avatars": {Folder: "avatars", AllowedTypes: []string{"image/*"}, MaxSize: 50, MaxCount: 1},
+		"docs":    {Folder: "docs", AllowedTypes: []string{"application/pdf", ".txt"}, MaxSize: 500},
+	}
+
+	t.Run("should return the policy of the folder or its parent", func(t *testing.T) {
+		assert.Equal(t, "avatars", config.FolderPolicy("avatars").Folder)
+		assert.Equal(t, "avatars", config.FolderPolicy("/avatars/2026/").Folder)
+		assert.Equal(t, "", config.FolderPolicy("general").Folder)
+		assert.Equal(t, int64(100), config.FolderPolicy("").MaxSize)
+	})
+
+	t.Run("should allow extensions and MIME types", func(t *testing.T) {
+		avatars := config.FolderPolicy("avatars")
+		assert.True(t, avatars.Allows(".png", "image/png"))
+		assert.False(t, avatars.Allows(".pdf", "application/pdf"))
+
+		docs := config.FolderPolicy("docs")
+		assert.True(t, docs.Allows(".pdf", "application/pdf"))
+		assert.True(t, docs.Allows(".TXT", "text/plain"))
+		assert.False(t, docs.Allows(".csv", "text/csv"))
+	})
+
+	t.Run("should return the largest size of all policies", func(t *testing.T) {
+		assert.Equal(t, int64(500), config.MaxUploadSize())
+	})
+}
@@ -16,7 +16,7 @@ func TestUserModel(t *testing.T) {
 		var newUser = validation.CreateUser{
 			Name:     "John Doe",
 			Email:    "johndoe@gmail.com",
-			Password: "password1",
+			Password: "s3cure-fiber",
 			Role:     "user",
 		}
 
@@ -60,7 +60,7 @@ func TestUserModel(t *testing.T) {
 		var updateUser = validation.UpdateUser{
 			Name:     "John Doe",
 			Email:    "johndoe@gmail.com",
-			Password: "password1",
+			Password: "s3cure-fiber",
 		}
 
 		t.Run("should correctly validate a valid user", func(t *testing.T) {
@@ -95,7 +95,7 @@ func TestUserModel(t *testing.T) {
 
 	t.Run("Update user password validation", func(t *testing.T) {
 		var newPassword = validation.UpdatePassOrVerify{
-			Password: "password1",
+			Password: "s3cure-fiber",
 		}
 
 		t.Run("should correctly validate a valid user password", func(t *testing.T) {
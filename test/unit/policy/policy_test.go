@@ -0,0 +1,175 @@
+package policy_test
+
+import (
+	"app/src/model"
+	"app/src/policy"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// allows runs the rule inside a request to target, routed by pattern
+func allows(t *testing.T, rule policy.Rule, pattern, target string, userID uuid.UUID, rights ...string) (bool, error) {
+	var allowed bool
+	var ruleErr error
+
+	app := fiber.New()
+	app.Get(pattern, func(c *fiber.Ctx) error {
+		allowed, ruleErr = rule.Allows(c, userID, rights)
+		return nil
+	})
+
+	_, err := app.Test(httptest.NewRequest(http.MethodGet, target, nil))
+	assert.Nil(t, err)
+
+	return allowed, ruleErr
+}
+
+type fileFinder map[uuid.UUID]*model.File
+
+func (f fileFinder) GetFileByID(fileID uuid.UUID) (*model.File, error) {
+	file, ok := f[fileID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return file, nil
+}
+
+func TestRule(t *testing.T) {
+	owner := uuid.New()
+	other := uuid.New()
+
+	t.Run("should allow users with all rights without resolving the owner", func(t *testing.T) {
+		resolved := false
+		rule := policy.OwnerOr(policy.Resource{Owner: func(c *fiber.Ctx) (uuid.UUID, error) {
+			resolved = true
+			return owner, nil
+		}}, "getUsers", "manageUsers")
+
+		allowed, err := allows(t, rule, "/", "/", other, "getUsers", "manageUsers")
+		assert.Nil(t, err)
+		assert.True(t, allowed)
+		assert.False(t, resolved)
+	})
+
+	t.Run("should deny users missing one of the rights", func(t *testing.T) {
+		rule := policy.Rule{Rights: []string{"getUsers", "manageUsers"}}
+
+		allowed, err := allows(t, rule, "/", "/", other, "getUsers")
+		assert.Nil(t, err)
+		assert.False(t, allowed)
+	})
+
+	t.Run("should allow the owner only when the rule lets owners in", func(t *testing.T) {
+		resource := policy.Resource{Owner: func(c *fiber.Ctx) (uuid.UUID, error) { return owner, nil }}
+
+		allowed, err := allows(t, policy.OwnerOr(resource, "manageUsers"), "/", "/", owner)
+		assert.Nil(t, err)
+		assert.True(t, allowed)
+
+		allowed, err = allows(t, policy.Rule{Resource: resource, Rights: []string{"manageUsers"}}, "/", "/", owner)
+		assert.Nil(t, err)
+		assert.False(t, allowed)
+	})
+
+	t.Run("should deny everyone but privileged users for resources without an owner", func(t *testing.T) {
+		resource := policy.Resource{Owner: func(c *fiber.Ctx) (uuid.UUID, error) { return uuid.Nil, nil }}
+
+		allowed, err := allows(t, policy.OwnerOr(resource, "manageUsers"), "/", "/", uuid.Nil)
+		assert.Nil(t, err)
+		assert.False(t, allowed)
+	})
+
+	t.Run("should return the error of the owner resolver", func(t *testing.T) {
+		resolverErr := fiber.NewError(fiber.StatusNotFound, "Not found")
+		resource := policy.Resource{Owner: func(c *fiber.Ctx) (uuid.UUID, error) { return uuid.Nil, resolverErr }}
+
+		allowed, err := allows(t, policy.OwnerOr(resource, "manageUsers"), "/", "/", owner)
+		assert.True(t, errors.Is(err, resolverErr))
+		assert.False(t, allowed)
+	})
+}
+
+func TestUserResource(t *testing.T) {
+	self := policy.OwnerOr(policy.User("userId"), "getUsers")
+	userID := uuid.New()
+
+	t.Run("should let users access their own account", func(t *testing.T) {
+		allowed, err := allows(t, self, "/users/:userId", "/users/"+userID.String(), userID)
+		assert.Nil(t, err)
+		assert.True(t, allowed)
+	})
+
+	t.Run("should deny access to another account", func(t *testing.T) {
+		allowed, err := allows(t, self, "/users/:userId", "/users/"+uuid.NewString(), userID)
+		assert.Nil(t, err)
+		assert.False(t, allowed)
+	})
+
+	t.Run("should deny access when the id is invalid", func(t *testing.T) {
+		allowed, err := allows(t, self, "/users/:userId", "/users/invalidId", userID)
+		assert.Nil(t, err)
+		assert.False(t, allowed)
+	})
+}
+
+func TestFileResource(t *testing.T) {
+	uploader := uuid.New()
+	owned, anonymous := uuid.New(), uuid.New()
+	files := fileFinder{
+		owned:     {UploadedBy: &uploader},
+		anonymous: {},
+	}
+	ownFile := policy.OwnerOr(policy.File(files, "fileId"), "manageFiles")
+
+	t.Run("should let users access the files they uploaded", func(t *testing.T) {
+		allowed, err := allows(t, ownFile, "/files/:fileId", "/files/"+owned.String(), uploader)
+		assert.Nil(t, err)
+		assert.True(t, allowed)
+	})
+
+	t.Run("should deny access to files of other users", func(t *testing.T) {
+		allowed, err := allows(t, ownFile, "/files/:fileId", "/files/"+owned.String(), uuid.New())
+		assert.Nil(t, err)
+		assert.False(t, allowed)
+
+		allowed, err = allows(t, ownFile, "/files/:fileId", "/files/"+anonymous.String(), uploader)
+		assert.Nil(t, err)
+		assert.False(t, allowed)
+	})
+
+	t.Run("should let users with manageFiles access any file", func(t *testing.T) {
+		allowed, err := allows(t, ownFile, "/files/:fileId", "/files/"+owned.String(), uuid.New(), "manageFiles")
+		assert.Nil(t, err)
+		assert.True(t, allowed)
+	})
+
+	t.Run("should hide the files of other users as not found", func(t *testing.T) {
+		var fiberErr *fiber.Error
+		assert.True(t, errors.As(ownFile.Resource.NotFound, &fiberErr))
+		assert.Equal(t, fiber.StatusNotFound, fiberErr.Code)
+	})
+
+	t.Run("should return 404 for unknown files", func(t *testing.T) {
+		_, err := allows(t, ownFile, "/files/:fileId", "/files/"+uuid.NewString(), uploader)
+
+		var fiberErr *fiber.Error
+		assert.True(t, errors.As(err, &fiberErr))
+		assert.Equal(t, fiber.StatusNotFound, fiberErr.Code)
+	})
+
+	t.Run("should return 400 for invalid ids", func(t *testing.T) {
+		_, err := allows(t, ownFile, "/files/:fileId", "/files/invalidId", uploader)
+
+		var fiberErr *fiber.Error
+		assert.True(t, errors.As(err, &fiberErr))
+		assert.Equal(t, fiber.StatusBadRequest, fiberErr.Code)
+	})
+}
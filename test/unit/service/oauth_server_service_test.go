@@ -0,0 +1,43 @@
+package service_test
+
+import (
+	"app/src/service"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientRedirectURI(t *testing.T) {
+	t.Run("should keep the query of the registered uri", func(t *testing.T) {
+		redirectURI := service.ClientRedirectURI("https://partner.example.com/callback?tenant=42",
+			map[string]string{"code": "abc", "state": "xyz"})
+
+		redirectURL, err := url.Parse(redirectURI)
+		assert.Nil(t, err)
+		assert.Equal(t, "/callback", redirectURL.Path)
+		assert.Equal(t, "42", redirectURL.Query().Get("tenant"))
+		assert.Equal(t, "abc", redirectURL.Query().Get("code"))
+		assert.Equal(t, "xyz", redirectURL.Query().Get("state"))
+	})
+
+	t.Run("should leave out empty values", func(t *testing.T) {
+		redirectURI := service.ClientRedirectURI("https://partner.example.com/callback",
+			map[string]string{"error": "access_denied", "state": ""})
+
+		assert.Equal(t, "https://partner.example.com/callback?error=access_denied", redirectURI)
+	})
+}
+
+func TestOAuthError(t *testing.T) {
+	t.Run("should unwrap to a fiber error for the error handler", func(t *testing.T) {
+		var err error = &service.OAuthError{Status: fiber.StatusBadRequest, Code: "invalid_scope", Description: "Unknown scope"}
+
+		var fiberErr *fiber.Error
+		assert.True(t, errors.As(err, &fiberErr))
+		assert.Equal(t, fiber.StatusBadRequest, fiberErr.Code)
+		assert.Equal(t, "Unknown scope", fiberErr.Message)
+	})
+}
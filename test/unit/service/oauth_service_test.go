@@ -0,0 +1,253 @@
+package service_test
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/service"
+	"app/test/helper"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryOAuthStateStore keeps the login states in memory so the service can be tested without a database
+type memoryOAuthStateStore struct {
+	mu     sync.Mutex
+	states map[string]*model.OAuthState
+}
+
+func (s *memoryOAuthStateStore) Save(_ context.Context, state *model.OAuthState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.states[state.ID] = state
+
+	return nil
+}
+
+func (s *memoryOAuthStateStore) Consume(_ context.Context, id string) (*model.OAuthState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.states[id]
+	if !ok {
+		return nil, service.ErrOAuthStateNotFound
+	}
+
+	delete(s.states, id)
+
+	return state, nil
+}
+
+func TestOAuthService(t *testing.T) {
+	mock := helper.NewMockOIDCServer("mock-client")
+	defer mock.Close()
+
+	config.OAuthProviders["mock"] = mock.Provider("mock")
+	defer delete(config.OAuthProviders, "mock")
+
+	oauthService := service.NewOAuthService(&memoryOAuthStateStore{states: make(map[string]*model.OAuthState)})
+
+	app := fiber.New()
+	app.Get("/login/:provider", func(c *fiber.Ctx) error {
+		url, _, err := oauthService.AuthCodeURL(c, c.Params("provider"), nil)
+		if err != nil {
+			return err
+		}
+		return c.SendString(url)
+	})
+	app.Get("/exchange/:provider", func(c *fiber.Ctx) error {
+		login, _, err := oauthService.Exchange(c, c.Params("provider"), c.Query("state"), c.Query("code"))
+		if err != nil {
+			return err
+		}
+		return c.JSON(login)
+	})
+
+	// begin starts a login and returns the state, nonce and code challenge sent to the provider
+	begin := func(t *testing.T, provider string) (string, string, string) {
+		apiResponse, err := app.Test(httptest.NewRequest(http.MethodGet, "/login/"+provider, nil), -1)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+		bytes, err := io.ReadAll(apiResponse.Body)
+		assert.NoError(t, err)
+
+		authURL, err := url.Parse(string(bytes))
+		assert.NoError(t, err)
+
+		query := authURL.Query()
+
+		return query.Get("state"), query.Get("nonce"), query.Get("code_challenge")
+	}
+
+	exchange := func(t *testing.T, provider, state, code string) (int, map[string]interface{}) {
+		target := "/exchange/" + provider + "?state=" + url.QueryEscape(state) + "&code=" + url.QueryEscape(code)
+		apiResponse, err := app.Test(httptest.NewRequest(http.MethodGet, target, nil), -1)
+		assert.NoError(t, err)
+
+		body := map[string]interface{}{}
+		if apiResponse.StatusCode == http.StatusOK {
+			bytes, errRead := io.ReadAll(apiResponse.Body)
+			assert.NoError(t, errRead)
+			assert.NoError(t, json.Unmarshal(bytes, &body))
+		}
+
+		return apiResponse.StatusCode, body
+	}
+
+	t.Run("should build the authorization url from the discovered endpoints", func(t *testing.T) {
+		apiResponse, err := app.Test(httptest.NewRequest(http.MethodGet, "/login/mock", nil), -1)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+		bytes, err := io.ReadAll(apiResponse.Body)
+		assert.NoError(t, err)
+		assert.Contains(t, string(bytes), mock.Issuer()+"/authorize?")
+		assert.Contains(t, string(bytes), "scope=openid+email+profile")
+		assert.Contains(t, string(bytes), "code_challenge_method=S256")
+		assert.Contains(t, string(bytes), "nonce=")
+	})
+
+	t.Run("should return 404 for an unknown provider", func(t *testing.T) {
+		apiResponse, err := app.Test(httptest.NewRequest(http.MethodGet, "/login/unknown", nil), -1)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+	})
+
+	t.Run("should return the identity of a valid id token", func(t *testing.T) {
+		state, nonce, challenge := begin(t, "mock")
+		code := mock.IssueCode(helper.MockOIDCUser{
+			Subject:       "user-1",
+			Name:          "Mock User",
+			Email:         "Mock@Example.com",
+			EmailVerified: true,
+			Nonce:         nonce,
+			CodeChallenge: challenge,
+		})
+
+		status, body := exchange(t, "mock", state, code)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "mock", body["provider"])
+		assert.Equal(t, "user-1", body["sub"])
+		assert.Equal(t, "Mock User", body["name"])
+		assert.Equal(t, "mock@example.com", body["email"])
+		assert.Equal(t, true, body["verified_email"])
+	})
+
+	t.Run("should reject an id token issued to another client", func(t *testing.T) {
+		state, nonce, challenge := begin(t, "mock")
+		code := mock.IssueCode(helper.MockOIDCUser{
+			Subject:       "user-1",
+			Email:         "mock@example.com",
+			Audience:      "another-client",
+			Nonce:         nonce,
+			CodeChallenge: challenge,
+		})
+
+		status, _ := exchange(t, "mock", state, code)
+		assert.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("should reject an id token with the nonce of another login", func(t *testing.T) {
+		state, _, challenge := begin(t, "mock")
+		_, otherNonce, _ := begin(t, "mock")
+		code := mock.IssueCode(helper.MockOIDCUser{
+			Subject:       "user-1",
+			Email:         "mock@example.com",
+			Nonce:         otherNonce,
+			CodeChallenge: challenge,
+		})
+
+		status, _ := exchange(t, "mock", state, code)
+		assert.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("should reject a code bound to the code challenge of another login", func(t *testing.T) {
+		state, nonce, _ := begin(t, "mock")
+		_, _, otherChallenge := begin(t, "mock")
+		code := mock.IssueCode(helper.MockOIDCUser{
+			Subject:       "user-1",
+			Email:         "mock@example.com",
+			Nonce:         nonce,
+			CodeChallenge: otherChallenge,
+		})
+
+		status, _ := exchange(t, "mock", state, code)
+		assert.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("should reject a state used twice", func(t *testing.T) {
+		state, nonce, challenge := begin(t, "mock")
+		user := helper.MockOIDCUser{Subject: "user-1", Email: "mock@example.com", Nonce: nonce, CodeChallenge: challenge}
+
+		status, _ := exchange(t, "mock", state, mock.IssueCode(user))
+		assert.Equal(t, http.StatusOK, status)
+
+		status, _ = exchange(t, "mock", state, mock.IssueCode(user))
+		assert.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("should reject an unknown state", func(t *testing.T) {
+		code := mock.IssueCode(helper.MockOIDCUser{Subject: "user-1", Email: "mock@example.com"})
+
+		status, _ := exchange(t, "mock", "unknown-state", code)
+		assert.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("should reject a state started for another provider", func(t *testing.T) {
+		provider := mock.Provider("other")
+		config.OAuthProviders["other"] = provider
+		defer delete(config.OAuthProviders, "other")
+
+		state, nonce, challenge := begin(t, "other")
+		code := mock.IssueCode(helper.MockOIDCUser{
+			Subject: "user-1", Email: "mock@example.com", Nonce: nonce, CodeChallenge: challenge,
+		})
+
+		status, _ := exchange(t, "mock", state, code)
+		assert.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("should reject an authorization code used twice", func(t *testing.T) {
+		state, nonce, challenge := begin(t, "mock")
+		code := mock.IssueCode(helper.MockOIDCUser{
+			Subject: "user-1", Email: "mock@example.com", Nonce: nonce, CodeChallenge: challenge,
+		})
+
+		status, _ := exchange(t, "mock", state, code)
+		assert.Equal(t, http.StatusOK, status)
+
+		state, _, _ = begin(t, "mock")
+		status, _ = exchange(t, "mock", state, code)
+		assert.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("should read the identity from userinfo for providers without discovery", func(t *testing.T) {
+		provider := mock.Provider("plain")
+		provider.Issuer = ""
+		provider.AuthURL = mock.Issuer() + "/authorize"
+		provider.TokenURL = mock.Issuer() + "/token"
+		provider.UserInfoURL = mock.Issuer() + "/userinfo"
+		config.OAuthProviders["plain"] = provider
+		defer delete(config.OAuthProviders, "plain")
+
+		state, _, challenge := begin(t, "plain")
+		code := mock.IssueCode(helper.MockOIDCUser{
+			Subject: "user-2", Name: "Plain User", Email: "plain@example.com", CodeChallenge: challenge,
+		})
+
+		status, body := exchange(t, "plain", state, code)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "user-2", body["sub"])
+		assert.Equal(t, false, body["verified_email"])
+	})
+}
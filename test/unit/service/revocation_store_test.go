@@ -0,0 +1,101 @@
+package service_test
+
+import (
+	"app/src/service"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryRevocationStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should report a revoked id until it expires", func(t *testing.T) {
+		store := service.NewMemoryRevocationStore(10)
+
+		err := store.Revoke(ctx, "jti-1", time.Now().Add(time.Minute))
+		assert.NoError(t, err)
+
+		revoked, err := store.IsRevoked(ctx, "jti-1")
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+
+		revoked, err = store.IsRevoked(ctx, "jti-2")
+		assert.NoError(t, err)
+		assert.False(t, revoked)
+	})
+
+	t.Run("should not report an expired id", func(t *testing.T) {
+		store := service.NewMemoryRevocationStore(10)
+
+		err := store.Revoke(ctx, "jti-1", time.Now().Add(-time.Second))
+		assert.NoError(t, err)
+
+		revoked, err := store.IsRevoked(ctx, "jti-1")
+		assert.NoError(t, err)
+		assert.False(t, revoked)
+		assert.Equal(t, 0, store.Len())
+	})
+
+	t.Run("should evict the least recently used id when full", func(t *testing.T) {
+		store := service.NewMemoryRevocationStore(2)
+		expires := time.Now().Add(time.Minute)
+
+		assert.NoError(t, store.Revoke(ctx, "jti-1", expires))
+		assert.NoError(t, store.Revoke(ctx, "jti-2", expires))
+
+		// Touch jti-1 so jti-2 becomes the least recently used
+		revoked, _ := store.IsRevoked(ctx, "jti-1")
+		assert.True(t, revoked)
+
+		assert.NoError(t, store.Revoke(ctx, "jti-3", expires))
+		assert.Equal(t, 2, store.Len())
+
+		revoked, _ = store.IsRevoked(ctx, "jti-2")
+		assert.False(t, revoked)
+		revoked, _ = store.IsRevoked(ctx, "jti-1")
+		assert.True(t, revoked)
+		revoked, _ = store.IsRevoked(ctx, "jti-3")
+		assert.True(t, revoked)
+	})
+
+	t.Run("should prune ids past their expiry", func(t *testing.T) {
+		store := service.NewMemoryRevocationStore(10)
+
+		assert.NoError(t, store.Revoke(ctx, "expired", time.Now().Add(-time.Minute)))
+		assert.NoError(t, store.Revoke(ctx, "active", time.Now().Add(time.Minute)))
+
+		assert.NoError(t, store.Prune(ctx))
+		assert.Equal(t, 1, store.Len())
+
+		revoked, _ := store.IsRevoked(ctx, "active")
+		assert.True(t, revoked)
+	})
+}
+
+func TestCachedRevocationStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should read revocations made by other instances from the backing store", func(t *testing.T) {
+		backing := service.NewMemoryRevocationStore(10)
+		store := service.NewCachedRevocationStore(service.NewMemoryRevocationStore(10), backing)
+
+		assert.NoError(t, backing.Revoke(ctx, "jti-1", time.Now().Add(time.Minute)))
+
+		revoked, err := store.IsRevoked(ctx, "jti-1")
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+	})
+
+	t.Run("should write revocations to both stores", func(t *testing.T) {
+		cache := service.NewMemoryRevocationStore(10)
+		backing := service.NewMemoryRevocationStore(10)
+		store := service.NewCachedRevocationStore(cache, backing)
+
+		assert.NoError(t, store.Revoke(ctx, "jti-1", time.Now().Add(time.Minute)))
+		assert.Equal(t, 1, cache.Len())
+		assert.Equal(t, 1, backing.Len())
+	})
+}
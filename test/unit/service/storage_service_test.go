@@ -0,0 +1,173 @@
+package service_test
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/service"
+	"context"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileActor(t *testing.T) {
+	uploader := uuid.New()
+	file := &model.File{UploadedBy: &uploader}
+
+	t.Run("should let the uploader access the file", func(t *testing.T) {
+		assert.True(t, service.FileActor{UserID: uploader}.CanAccess(file))
+	})
+
+	t.Run("should not let other users access the file", func(t *testing.T) {
+		assert.False(t, service.FileActor{UserID: uuid.New(), Rights: []string{"getUsers"}}.CanAccess(file))
+		assert.False(t, service.FileActor{UserID: uploader}.CanAccess(&model.File{}))
+	})
+
+	t.Run("should let users with manageFiles access any file", func(t *testing.T) {
+		actor := service.FileActor{UserID: uuid.New(), Rights: []string{"manageFiles"}}
+
+		assert.True(t, actor.CanAccess(file))
+		assert.True(t, actor.CanAccess(&model.File{}))
+	})
+
+	t.Run("should only let actors of the organization access its files", func(t *testing.T) {
+		organizationID := uuid.New()
+		organizationFile := &model.File{UploadedBy: &uploader, OrganizationID: &organizationID}
+		otherOrganization := uuid.New()
+
+		assert.True(t, service.FileActor{UserID: uploader, OrganizationID: &organizationID}.CanAccess(organizationFile))
+		assert.False(t, service.FileActor{UserID: uploader, OrganizationID: &otherOrganization}.CanAccess(organizationFile))
+		assert.False(t, service.FileActor{UserID: uploader, OrganizationID: &organizationID}.CanAccess(file))
+
+		manager := service.FileActor{UserID: uuid.New(), Rights: []string{"manageFiles"}, OrganizationID: &otherOrganization}
+		assert.False(t, manager.CanAccess(organizationFile))
+	})
+
+	t.Run("should hide organization files without an active organization unless the role has manageFiles", func(t *testing.T) {
+		organizationID := uuid.New()
+		organizationFile := &model.File{UploadedBy: &uploader, OrganizationID: &organizationID}
+
+		assert.False(t, service.FileActor{UserID: uploader}.CanAccess(organizationFile))
+		assert.True(t, service.FileActor{UserID: uuid.New(), Rights: []string{"manageFiles"}}.CanAccess(organizationFile))
+	})
+}
+
+func TestUploadFolder(t *testing.T) {
+	storage := service.NewLocalStorageService(nil)
+	file := &multipart.FileHeader{Filename: "notes.txt", Size: 10}
+
+	t.Run("should return 400 error if the folder is not a clean relative path", func(t *testing.T) {
+		for _, folder := range []string{"", ".", "general/../avatars", "../avatars", "/avatars", "avatars/", "avatars//2024"} {
+			_, err := storage.UploadFile(context.Background(), file, folder, service.FileActor{UserID: uuid.New()})
+
+			var fiberErr *fiber.Error
+			assert.True(t, errors.As(err, &fiberErr), folder)
+			assert.Equal(t, fiber.StatusBadRequest, fiberErr.Code, folder)
+		}
+	})
+}
+
+func TestLocalFinishUpload(t *testing.T) {
+	localPath, tempPath := config.StorageLocalPath, config.StorageUploadTempPath
+	config.StorageLocalPath, config.StorageUploadTempPath = t.TempDir(), t.TempDir()
+	defer func() { config.StorageLocalPath, config.StorageUploadTempPath = localPath, tempPath }()
+
+	storage := service.NewLocalStorageService(nil)
+	ctx := context.Background()
+
+	// finishUpload menulis seluruh isi upload lalu memindahkannya ke folder tujuan
+	finishUpload := func() *model.Upload {
+		upload := &model.Upload{ID: uuid.New(), FilePath: "general/notes.txt", Size: 11}
+		assert.Nil(t, storage.BeginUpload(ctx, upload))
+		assert.Nil(t, storage.WriteChunk(ctx, upload, []byte("hello world")))
+		assert.Nil(t, storage.FinishUpload(ctx, upload))
+		return upload
+	}
+	partialPath := func(upload *model.Upload) string {
+		return filepath.Join(config.StorageUploadTempPath, upload.ID.String())
+	}
+
+	t.Run("should remove the temporary file once the upload is committed", func(t *testing.T) {
+		upload := finishUpload()
+		assert.Nil(t, storage.CommitUpload(ctx, upload))
+
+		content, err := os.ReadFile(storage.LocalPath(upload.FilePath))
+		assert.Nil(t, err)
+		assert.Equal(t, "hello world", string(content))
+
+		_, err = os.Stat(partialPath(upload))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("should keep the temporary file when the upload is reverted", func(t *testing.T) {
+		upload := finishUpload()
+		assert.Nil(t, storage.RevertUpload(ctx, upload))
+
+		_, err := os.Stat(storage.LocalPath(upload.FilePath))
+		assert.True(t, os.IsNotExist(err))
+
+		content, err := os.ReadFile(partialPath(upload))
+		assert.Nil(t, err)
+		assert.Equal(t, "hello world", string(content))
+
+		assert.Nil(t, storage.FinishUpload(ctx, upload))
+		assert.Nil(t, storage.CommitUpload(ctx, upload))
+	})
+}
+
+func TestLocalSignedURL(t *testing.T) {
+	storage := service.NewLocalStorageService(nil)
+	upload := &model.Upload{FilePath: "general/report 1.pdf", ContentType: "application/pdf", Size: 42}
+
+	// signedQuery membaca path dan query dari URL yang dibuat
+	signedQuery := func(presignedURL string) (string, string, string) {
+		parsed, err := url.Parse(presignedURL)
+		assert.Nil(t, err)
+
+		return strings.TrimPrefix(parsed.Path, "/v1/files/signed/"), parsed.Query().Get("expires"), parsed.Query().Get("signature")
+	}
+
+	t.Run("should accept the signed upload with the signed content type and size", func(t *testing.T) {
+		presigned, err := storage.PresignUpload(context.Background(), upload, time.Minute)
+		assert.Nil(t, err)
+		assert.Equal(t, http.MethodPut, presigned.Method)
+		assert.Equal(t, "42", presigned.Headers["Content-Length"])
+
+		filePath, expires, signature := signedQuery(presigned.URL)
+		assert.Equal(t, upload.FilePath, filePath)
+
+		assert.Nil(t, storage.VerifySignedURL(http.MethodPut, filePath, "application/pdf", 42, expires, signature))
+		assert.NotNil(t, storage.VerifySignedURL(http.MethodPut, filePath, "application/pdf", 43, expires, signature))
+		assert.NotNil(t, storage.VerifySignedURL(http.MethodPut, filePath, "text/html", 42, expires, signature))
+		assert.NotNil(t, storage.VerifySignedURL(http.MethodGet, filePath, "", 0, expires, signature))
+	})
+
+	t.Run("should only accept the signed download for the signed path", func(t *testing.T) {
+		presigned, err := storage.PresignDownload(context.Background(), upload.FilePath, time.Minute)
+		assert.Nil(t, err)
+
+		filePath, expires, signature := signedQuery(presigned.URL)
+
+		assert.Nil(t, storage.VerifySignedURL(http.MethodGet, filePath, "", 0, expires, signature))
+		assert.NotNil(t, storage.VerifySignedURL(http.MethodGet, "general/other.pdf", "", 0, expires, signature))
+		assert.NotNil(t, storage.VerifySignedURL(http.MethodGet, filePath, "", 0, expires+"0", signature))
+	})
+
+	t.Run("should reject expired urls", func(t *testing.T) {
+		presigned, err := storage.PresignDownload(context.Background(), upload.FilePath, -time.Minute)
+		assert.Nil(t, err)
+
+		filePath, expires, signature := signedQuery(presigned.URL)
+		assert.ErrorIs(t, storage.VerifySignedURL(http.MethodGet, filePath, "", 0, expires, signature), service.ErrInvalidSignedURL)
+	})
+}
@@ -0,0 +1,35 @@
+package service_test
+
+import (
+	"app/src/model"
+	"app/src/service"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIssuedBeforeValidity(t *testing.T) {
+	validAfter := time.UnixMicro(1700000000123456)
+	user := &model.User{TokensValidAfter: &validAfter}
+
+	claims := func(issuedAt time.Time) jwt.MapClaims {
+		return jwt.MapClaims{"iat": float64(issuedAt.UnixMicro()) / 1e6}
+	}
+
+	t.Run("should reject tokens issued up to the change", func(t *testing.T) {
+		assert.True(t, service.IssuedBeforeValidity(claims(validAfter.Add(-time.Microsecond)), user))
+		assert.True(t, service.IssuedBeforeValidity(claims(validAfter), user))
+		assert.True(t, service.IssuedBeforeValidity(jwt.MapClaims{"iat": float64(validAfter.Unix())}, user))
+	})
+
+	t.Run("should accept tokens issued after the change within the same second", func(t *testing.T) {
+		assert.False(t, service.IssuedBeforeValidity(claims(validAfter.Add(time.Microsecond)), user))
+		assert.False(t, service.IssuedBeforeValidity(claims(validAfter), &model.User{}))
+	})
+
+	t.Run("should reject tokens without iat", func(t *testing.T) {
+		assert.True(t, service.IssuedBeforeValidity(jwt.MapClaims{}, user))
+	})
+}
@@ -0,0 +1,36 @@
+package service_test
+
+import (
+	"app/src/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUploadMetadata(t *testing.T) {
+	t.Run("should decode the base64 values", func(t *testing.T) {
+		metadata, err := service.ParseUploadMetadata("filename bm90ZXMudHh0, filetype dGV4dC9wbGFpbg==,is_public")
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]string{
+			"filename":  "notes.txt",
+			"filetype":  "text/plain",
+			"is_public": "",
+		}, metadata)
+	})
+
+	t.Run("should return no metadata for an empty header", func(t *testing.T) {
+		metadata, err := service.ParseUploadMetadata("")
+		assert.Nil(t, err)
+		assert.Empty(t, metadata)
+	})
+
+	t.Run("should return error for values that are not base64", func(t *testing.T) {
+		_, err := service.ParseUploadMetadata("filename notes.txt")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("should return error for duplicated keys", func(t *testing.T) {
+		_, err := service.ParseUploadMetadata("filename bm90ZXMudHh0,filename bm90ZXMudHh0")
+		assert.NotNil(t, err)
+	})
+}
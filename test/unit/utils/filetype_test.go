@@ -0,0 +1,64 @@
+package utils_test
+
+import (
+	"app/src/utils"
+	"archive/zip"
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func pngBytes(t *testing.T) []byte {
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 2))))
+	return buf.Bytes()
+}
+
+func docxBytes(t *testing.T) []byte {
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "word/document.xml"} {
+		file, err := writer.Create(name)
+		assert.NoError(t, err)
+		_, err = file.Write([]byte("<?xml version=\"1.0\"?>"))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestExtensionType(t *testing.T) {
+	assert.Equal(t, "image/jpeg", utils.ExtensionType(".JPG"))
+	assert.Equal(t, "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		utils.ExtensionType(".docx"))
+	assert.Equal(t, "", utils.ExtensionType(".unknown-extension"))
+}
+
+func TestContentMatchesType(t *testing.T) {
+	t.Run("should match content of the type", func(t *testing.T) {
+		assert.True(t, utils.ContentMatchesType(pngBytes(t), "image/png"))
+		assert.True(t, utils.ContentMatchesType([]byte("%PDF-1.7\n"), "application/pdf"))
+		assert.True(t, utils.ContentMatchesType([]byte("hello world"), "text/plain"))
+		assert.True(t, utils.ContentMatchesType(docxBytes(t), utils.ExtensionType(".docx")))
+	})
+
+	t.Run("should match the parent types of the content", func(t *testing.T) {
+		assert.True(t, utils.ContentMatchesType(docxBytes(t), "application/zip"))
+		assert.True(t, utils.ContentMatchesType([]byte("a,b\n1,2\n"), "text/plain"))
+	})
+
+	t.Run("should not match content of another type", func(t *testing.T) {
+		assert.False(t, utils.ContentMatchesType([]byte("hello world"), "image/png"))
+		assert.False(t, utils.ContentMatchesType(pngBytes(t), "application/pdf"))
+		assert.False(t, utils.ContentMatchesType([]byte("MZ\x90\x00\x03\x00\x00\x00"), "text/plain"))
+		assert.False(t, utils.ContentMatchesType(pngBytes(t), ""))
+	})
+
+	t.Run("should detect the type from the magic bytes", func(t *testing.T) {
+		assert.Equal(t, "image/png", utils.DetectFileType(pngBytes(t)))
+		assert.Equal(t, "text/plain", utils.DetectFileType([]byte("hello world")))
+	})
+}
@@ -0,0 +1,155 @@
+package utils_test
+
+import (
+	"app/src/utils"
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// exifOrientation membuat data EXIF (TIFF big endian) dengan satu tag orientation
+func exifOrientation(orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	return append(tiff, 0, 0, 0, 0, 0, 0)
+}
+
+func testImage(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	img.Set(0, 0, color.NRGBA{R: 0xff, A: 0xff})
+	return img
+}
+
+func jpegWithExif(t *testing.T, orientation uint16) []byte {
+	var buf bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&buf, testImage(4, 2), nil))
+
+	payload := append([]byte("Exif\x00\x00"), exifOrientation(orientation)...)
+	segment := binary.BigEndian.AppendUint16([]byte{0xff, 0xe1}, uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), segment...), data[2:]...)
+}
+
+func pngChunk(chunkType string, data []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, chunkType...)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+func pngWithExif(t *testing.T, orientation uint16) []byte {
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, testImage(4, 2)))
+
+	// Signature dan IHDR (8 + 25 byte), lalu chunk metadata
+	data := buf.Bytes()
+	metadata := append(pngChunk("eXIf", exifOrientation(orientation)), pngChunk("tEXt", []byte("GPS\x00-6.2,106.8"))...)
+	return append(append(append([]byte{}, data[:33]...), metadata...), data[33:]...)
+}
+
+func TestImageOrientation(t *testing.T) {
+	t.Run("should read the orientation of a JPEG", func(t *testing.T) {
+		assert.Equal(t, 6, utils.ImageOrientation(jpegWithExif(t, 6)))
+	})
+
+	t.Run("should read the orientation of a PNG", func(t *testing.T) {
+		assert.Equal(t, 8, utils.ImageOrientation(pngWithExif(t, 8)))
+	})
+
+	t.Run("should return 1 without EXIF", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.NoError(t, jpeg.Encode(&buf, testImage(4, 2), nil))
+
+		assert.Equal(t, 1, utils.ImageOrientation(buf.Bytes()))
+		assert.Equal(t, 1, utils.ImageOrientation([]byte("not an image")))
+	})
+}
+
+func TestStripImageMetadata(t *testing.T) {
+	t.Run("should remove EXIF from a JPEG", func(t *testing.T) {
+		stripped, err := utils.StripImageMetadata(jpegWithExif(t, 6))
+		assert.NoError(t, err)
+		assert.NotContains(t, string(stripped), "Exif")
+		assert.Equal(t, 1, utils.ImageOrientation(stripped))
+
+		img, err := jpeg.Decode(bytes.NewReader(stripped))
+		assert.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 4, 2), img.Bounds())
+	})
+
+	t.Run("should remove EXIF and text chunks from a PNG", func(t *testing.T) {
+		stripped, err := utils.StripImageMetadata(pngWithExif(t, 6))
+		assert.NoError(t, err)
+		assert.NotContains(t, string(stripped), "eXIf")
+		assert.NotContains(t, string(stripped), "GPS")
+
+		_, err = png.Decode(bytes.NewReader(stripped))
+		assert.NoError(t, err)
+	})
+
+	t.Run("should return an error for truncated data", func(t *testing.T) {
+		data := jpegWithExif(t, 6)
+
+		_, err := utils.StripImageMetadata(data[:8])
+		assert.ErrorIs(t, err, utils.ErrInvalidImage)
+	})
+}
+
+func TestOrientImage(t *testing.T) {
+	t.Run("should rotate orientation 6 clockwise", func(t *testing.T) {
+		img := utils.OrientImage(testImage(4, 2), 6)
+
+		assert.Equal(t, image.Rect(0, 0, 2, 4), img.Bounds())
+		r, g, _, _ := img.At(1, 0).RGBA()
+		assert.Equal(t, uint32(0xffff), r)
+		assert.Equal(t, uint32(0), g)
+	})
+
+	t.Run("should mirror orientation 2 horizontally", func(t *testing.T) {
+		img := utils.OrientImage(testImage(4, 2), 2)
+
+		assert.Equal(t, image.Rect(0, 0, 4, 2), img.Bounds())
+		_, g, _, _ := img.At(3, 0).RGBA()
+		assert.Equal(t, uint32(0), g)
+	})
+
+	t.Run("should keep orientation 1", func(t *testing.T) {
+		img := testImage(4, 2)
+		assert.Same(t, img, utils.OrientImage(img, 1))
+	})
+}
+
+func TestResizeImage(t *testing.T) {
+	t.Run("should fit the image inside the box", func(t *testing.T) {
+		img := utils.ResizeImage(testImage(400, 200), 100, 100, false)
+		assert.Equal(t, image.Rect(0, 0, 100, 50), img.Bounds())
+	})
+
+	t.Run("should fill the box when cropping", func(t *testing.T) {
+		img := utils.ResizeImage(testImage(400, 200), 100, 100, true)
+		assert.Equal(t, image.Rect(0, 0, 100, 100), img.Bounds())
+	})
+
+	t.Run("should not scale small images up", func(t *testing.T) {
+		img := utils.ResizeImage(testImage(40, 20), 100, 100, false)
+		assert.Equal(t, image.Rect(0, 0, 40, 20), img.Bounds())
+
+		img = utils.ResizeImage(testImage(40, 20), 100, 100, true)
+		assert.Equal(t, image.Rect(0, 0, 20, 20), img.Bounds())
+	})
+}
@@ -0,0 +1,158 @@
+package utils_test
+
+import (
+	"app/src/utils"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func writePrivateKey(t *testing.T, key interface{}) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+
+	return writePEM(t, "PRIVATE KEY", der)
+}
+
+func writePublicKey(t *testing.T, key interface{}) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	assert.NoError(t, err)
+
+	return writePEM(t, "PUBLIC KEY", der)
+}
+
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	file, err := os.CreateTemp(t.TempDir(), "jwt-*.pem")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	err = pem.Encode(file, &pem.Block{Type: blockType, Bytes: der})
+	assert.NoError(t, err)
+
+	return filepath.Clean(file.Name())
+}
+
+func accessClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":  "e088d183-9eea-4a11-8d5d-74d7ec91bdf5",
+		"iat":  time.Now().Unix(),
+		"exp":  time.Now().Add(time.Minute).Unix(),
+		"type": "access",
+	}
+}
+
+func TestKeySet(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	t.Run("should sign with RS256 and a kid header and verify the token", func(t *testing.T) {
+		keySet, err := utils.LoadKeySet("RS256", writePrivateKey(t, rsaKey), nil)
+		assert.NoError(t, err)
+
+		token, err := keySet.Sign(accessClaims())
+		assert.NoError(t, err)
+
+		parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+		assert.NoError(t, err)
+		assert.Equal(t, "RS256", parsed.Method.Alg())
+		assert.NotEmpty(t, parsed.Header["kid"])
+
+		userID, err := utils.VerifyToken(token, keySet, "access")
+		assert.NoError(t, err)
+		assert.Equal(t, "e088d183-9eea-4a11-8d5d-74d7ec91bdf5", userID)
+	})
+
+	t.Run("should sign with EdDSA and verify the token", func(t *testing.T) {
+		keySet, err := utils.LoadKeySet("EdDSA", writePrivateKey(t, edKey), nil)
+		assert.NoError(t, err)
+
+		token, err := keySet.Sign(accessClaims())
+		assert.NoError(t, err)
+
+		_, err = utils.VerifyToken(token, keySet, "access")
+		assert.NoError(t, err)
+	})
+
+	t.Run("should verify tokens of the previous key during rotation", func(t *testing.T) {
+		previous, err := utils.LoadKeySet("RS256", writePrivateKey(t, rsaKey), nil)
+		assert.NoError(t, err)
+
+		token, err := previous.Sign(accessClaims())
+		assert.NoError(t, err)
+
+		current, err := utils.LoadKeySet("EdDSA", writePrivateKey(t, edKey), []string{writePublicKey(t, &rsaKey.PublicKey)})
+		assert.NoError(t, err)
+
+		_, err = utils.VerifyToken(token, current, "access")
+		assert.NoError(t, err)
+
+		withoutPrevious, err := utils.LoadKeySet("EdDSA", writePrivateKey(t, edKey), nil)
+		assert.NoError(t, err)
+
+		_, err = utils.VerifyToken(token, withoutPrevious, "access")
+		assert.Error(t, err)
+	})
+
+	t.Run("should reject an HS256 token signed with the public key", func(t *testing.T) {
+		keySet, err := utils.LoadKeySet("EdDSA", writePrivateKey(t, edKey), nil)
+		assert.NoError(t, err)
+
+		jwks := keySet.JWKS()
+		assert.Len(t, jwks.Keys, 1)
+
+		forged := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims())
+		forged.Header["kid"] = jwks.Keys[0].Kid
+		token, err := forged.SignedString([]byte(edPublic))
+		assert.NoError(t, err)
+
+		_, err = utils.VerifyToken(token, keySet, "access")
+		assert.Error(t, err)
+	})
+
+	t.Run("should publish only public keys in the JWKS", func(t *testing.T) {
+		keySet, err := utils.LoadKeySet("RS256", writePrivateKey(t, rsaKey), []string{writePublicKey(t, edPublic)})
+		assert.NoError(t, err)
+
+		jwks := keySet.JWKS()
+		assert.Len(t, jwks.Keys, 2)
+
+		for _, key := range jwks.Keys {
+			assert.NotEmpty(t, key.Kid)
+			assert.Equal(t, "sig", key.Use)
+			if key.Kty == "RSA" {
+				assert.Equal(t, "RS256", key.Alg)
+				assert.NotEmpty(t, key.N)
+				assert.Equal(t, "AQAB", key.E)
+			} else {
+				assert.Equal(t, "OKP", key.Kty)
+				assert.Equal(t, "Ed25519", key.Crv)
+				assert.NotEmpty(t, key.X)
+			}
+		}
+
+		assert.Empty(t, utils.NewHMACKeySet("secret").JWKS().Keys)
+	})
+
+	t.Run("should refuse a signing key that does not match the algorithm", func(t *testing.T) {
+		_, err := utils.LoadKeySet("RS256", writePrivateKey(t, edKey), nil)
+		assert.Error(t, err)
+
+		_, err = utils.LoadKeySet("RS256", writePublicKey(t, &rsaKey.PublicKey), nil)
+		assert.Error(t, err)
+
+		_, err = utils.LoadKeySet("HS512", writePrivateKey(t, rsaKey), nil)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,54 @@
+package utils_test
+
+import (
+	"app/src/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRange(t *testing.T) {
+	t.Run("should parse a closed range", func(t *testing.T) {
+		byteRange, err := utils.ParseRange("bytes=2-5", 10)
+		assert.NoError(t, err)
+		assert.Equal(t, &utils.ByteRange{Start: 2, Length: 4}, byteRange)
+	})
+
+	t.Run("should parse an open range until the end", func(t *testing.T) {
+		byteRange, err := utils.ParseRange("bytes=7-", 10)
+		assert.NoError(t, err)
+		assert.Equal(t, &utils.ByteRange{Start: 7, Length: 3}, byteRange)
+	})
+
+	t.Run("should parse a suffix range", func(t *testing.T) {
+		byteRange, err := utils.ParseRange("bytes=-4", 10)
+		assert.NoError(t, err)
+		assert.Equal(t, &utils.ByteRange{Start: 6, Length: 4}, byteRange)
+
+		byteRange, err = utils.ParseRange("bytes=-40", 10)
+		assert.NoError(t, err)
+		assert.Equal(t, &utils.ByteRange{Start: 0, Length: 10}, byteRange)
+	})
+
+	t.Run("should clamp the end to the size", func(t *testing.T) {
+		byteRange, err := utils.ParseRange("bytes=5-100", 10)
+		assert.NoError(t, err)
+		assert.Equal(t, &utils.ByteRange{Start: 5, Length: 5}, byteRange)
+	})
+
+	t.Run("should ignore malformed and multiple ranges", func(t *testing.T) {
+		for _, header := range []string{"", "items=0-1", "bytes=a-b", "bytes=5-2", "bytes=0-1,4-5"} {
+			byteRange, err := utils.ParseRange(header, 10)
+			assert.NoError(t, err, header)
+			assert.Nil(t, byteRange, header)
+		}
+	})
+
+	t.Run("should return an error if the range is outside of the content", func(t *testing.T) {
+		_, err := utils.ParseRange("bytes=10-", 10)
+		assert.ErrorIs(t, err, utils.ErrRangeNotSatisfiable)
+
+		_, err = utils.ParseRange("bytes=-0", 10)
+		assert.ErrorIs(t, err, utils.ErrRangeNotSatisfiable)
+	})
+}
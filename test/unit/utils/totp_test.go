@@ -0,0 +1,93 @@
+package utils_test
+
+import (
+	"app/src/utils"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Base32 encoding of the RFC 6238 SHA1 test secret "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTP(t *testing.T) {
+	t.Run("Generate code", func(t *testing.T) {
+		t.Run("should match the RFC 6238 test vectors", func(t *testing.T) {
+			vectors := map[int64]string{
+				59:          "287082",
+				1111111109:  "081804",
+				1111111111:  "050471",
+				1234567890:  "005924",
+				2000000000:  "279037",
+				20000000000: "353130",
+			}
+
+			for unix, expected := range vectors {
+				code, err := utils.GenerateTOTPCode(rfcSecret, time.Unix(unix, 0))
+				assert.NoError(t, err)
+				assert.Equal(t, expected, code)
+			}
+		})
+
+		t.Run("should return an error if secret is not base32", func(t *testing.T) {
+			_, err := utils.GenerateTOTPCode("not-base32!", time.Now())
+			assert.Error(t, err)
+		})
+	})
+
+	t.Run("Validate code", func(t *testing.T) {
+		now := time.Unix(1234567890, 0)
+
+		t.Run("should accept the code of the current time step", func(t *testing.T) {
+			assert.True(t, utils.ValidateTOTPCode(rfcSecret, "005924", now))
+		})
+
+		t.Run("should accept the code of an adjacent time step", func(t *testing.T) {
+			previous, err := utils.GenerateTOTPCode(rfcSecret, now.Add(-30*time.Second))
+			assert.NoError(t, err)
+			assert.True(t, utils.ValidateTOTPCode(rfcSecret, previous, now))
+		})
+
+		t.Run("should reject the code of a distant time step", func(t *testing.T) {
+			old, err := utils.GenerateTOTPCode(rfcSecret, now.Add(-5*time.Minute))
+			assert.NoError(t, err)
+			assert.False(t, utils.ValidateTOTPCode(rfcSecret, old, now))
+		})
+
+		t.Run("should reject a code with the wrong length", func(t *testing.T) {
+			assert.False(t, utils.ValidateTOTPCode(rfcSecret, "05924", now))
+		})
+
+		t.Run("should return the time step the code was generated for", func(t *testing.T) {
+			previous, err := utils.GenerateTOTPCode(rfcSecret, now.Add(-30*time.Second))
+			assert.NoError(t, err)
+
+			step, ok := utils.MatchTOTPCode(rfcSecret, previous, now)
+			assert.True(t, ok)
+			assert.Equal(t, now.Unix()/30-1, step)
+		})
+	})
+
+	t.Run("Generate secret", func(t *testing.T) {
+		t.Run("should generate a secret usable to compute codes", func(t *testing.T) {
+			secret, err := utils.GenerateTOTPSecret()
+			assert.NoError(t, err)
+
+			code, err := utils.GenerateTOTPCode(secret, time.Now())
+			assert.NoError(t, err)
+			assert.True(t, utils.ValidateTOTPCode(secret, code, time.Now()))
+		})
+	})
+
+	t.Run("Provisioning URI", func(t *testing.T) {
+		t.Run("should contain the issuer, account and secret", func(t *testing.T) {
+			uri := utils.TOTPProvisioningURI("Fiber API", "fake@example.com", rfcSecret)
+
+			assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Fiber%20API:fake@example.com?"))
+			assert.Contains(t, uri, "secret="+rfcSecret)
+			assert.Contains(t, uri, "issuer=Fiber+API")
+		})
+	})
+}
@@ -0,0 +1,81 @@
+package validation_test
+
+import (
+	"app/src/validation"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var validate = validation.Validator()
+
+func TestPasswordPolicy(t *testing.T) {
+	// withPolicy applies the policy for the duration of a test
+	withPolicy := func(t *testing.T, policy validation.PasswordPolicy) {
+		previous := validation.PasswordRules
+		validation.PasswordRules = policy
+		t.Cleanup(func() { validation.PasswordRules = previous })
+	}
+
+	newPassword := func(password string) *validation.UpdatePassOrVerify {
+		return &validation.UpdatePassOrVerify{Password: password}
+	}
+
+	t.Run("should accept a password following the default policy", func(t *testing.T) {
+		assert.NoError(t, validate.Struct(newPassword("s3cure-fiber")))
+	})
+
+	t.Run("should reject a common password ignoring case", func(t *testing.T) {
+		err := validate.Struct(newPassword("Password1"))
+		assert.Error(t, err)
+
+		messages := validation.CustomErrorMessages(err)
+		assert.Equal(t, "Field Password is a commonly used password, please choose another one",
+			messages["UpdatePassOrVerify.Password"])
+	})
+
+	t.Run("should accept a common password if the list is disabled", func(t *testing.T) {
+		policy := validation.PasswordRules
+		policy.RejectCommon = false
+		withPolicy(t, policy)
+
+		assert.NoError(t, validate.Struct(newPassword("password1")))
+	})
+
+	t.Run("should use the configured length bounds", func(t *testing.T) {
+		policy := validation.PasswordRules
+		policy.MinLength = 12
+		policy.MaxLength = 64
+		withPolicy(t, policy)
+
+		err := validate.Struct(newPassword("s3cure-fibr"))
+		assert.Error(t, err)
+		assert.Equal(t, "Field Password must have between 12 and 64 characters",
+			validation.CustomErrorMessages(err)["UpdatePassOrVerify.Password"])
+
+		assert.NoError(t, validate.Struct(newPassword("a-much-longer-s3cure-fiber-passphrase")))
+	})
+
+	t.Run("should reject passwords longer than 72 bytes whatever the policy", func(t *testing.T) {
+		policy := validation.PasswordRules
+		policy.MaxLength = 100
+		withPolicy(t, policy)
+
+		assert.Error(t, validate.Struct(newPassword("s3cure-"+strings.Repeat("a", 70))))
+	})
+
+	t.Run("should require the configured character classes", func(t *testing.T) {
+		policy := validation.PasswordRules
+		policy.RequireMixedCase = true
+		policy.RequireSymbol = true
+		withPolicy(t, policy)
+
+		err := validate.Struct(newPassword("s3curefiber"))
+		assert.Error(t, err)
+		assert.Equal(t, "Field Password must contain at least an uppercase and a lowercase letter, a number and a symbol",
+			validation.CustomErrorMessages(err)["UpdatePassOrVerify.Password"])
+
+		assert.NoError(t, validate.Struct(newPassword("S3cure-fiber")))
+	})
+}